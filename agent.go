@@ -0,0 +1,293 @@
+package sysstats
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Agent collects snapshots on a schedule and POSTs them as JSON to an HTTP(S)
+// endpoint, turning the package into a minimal metrics shipper. Snapshots
+// that can't be delivered after the retries are spooled to disk (if SpoolDir
+// is set) and sent again once the endpoint is back.
+type Agent struct {
+	URL        string            // Endpoint the snapshots are POSTed to
+	Interval   time.Duration     // Time between snapshots (default 1 minute)
+	Align      bool              // Align the snapshots to the wall clock boundaries of the interval
+	Jitter     time.Duration     // Max random delay added to every snapshot
+	Client     *http.Client      // HTTP client (default http.DefaultClient)
+	Headers    map[string]string // Extra headers sent with every request
+	Gzip       bool              // Compress the body with gzip
+	MaxRetries int               // # of retries before spooling a snapshot
+	Backoff    time.Duration     // Wait before the first retry, doubled every retry (default 1 second)
+	SpoolDir   string            // Directory where the undelivered snapshots are kept
+	MaxSpool   int               // Max # of spooled snapshots, the oldest are removed (default 1000)
+	Identity   *Identity         // Identity attached to the snapshots sent without one
+}
+
+// Run collects and sends a snapshot every interval until the context is
+// cancelled. Errors don't stop the agent, they are logged: the snapshots
+// some collectors of which fail are sent without their families, the ones
+// that can't be taken are skipped, and the ones that can't be delivered are
+// spooled.
+func (a *Agent) Run(ctx context.Context) error {
+	if a.URL == "" {
+		return errors.New("The agent URL is empty")
+	}
+
+	s := schedule{interval: a.Interval, align: a.Align, jitter: a.Jitter}
+	return s.run(ctx, func() {
+		snapshot, err := getSnapshot()
+		if isPartialSnapshot(err) {
+			logWarn("partial snapshot", "failed", FailedCollectors(err), "error", err)
+		} else if err != nil {
+			logWarn("skipped snapshot", "error", err)
+			return
+		}
+		if err := a.deliver(ctx, snapshot); err != nil {
+			logWarn("lost snapshot", "time", snapshot.Time, "error", err)
+		}
+	})
+}
+
+// deliver sends the spooled snapshots and then the given one, spooling it if
+// it can't be sent (unless the endpoint rejected it). It only returns an
+// error if the snapshot is lost.
+func (a *Agent) deliver(ctx context.Context, snapshot Snapshot) error {
+	if snapshot.Identity == nil {
+		snapshot.Identity = a.Identity
+	}
+	err := a.flushSpool(ctx)
+	if err == nil {
+		err = a.Send(ctx, snapshot)
+	}
+	if err != nil && a.SpoolDir != "" && !isRejected(err) {
+		return a.spool(snapshot)
+	}
+
+	return err
+}
+
+// Send POSTs the snapshot (wrapped in an Envelope) to the agent URL, retrying
+// with exponential backoff. The Identity of the agent is attached to the
+// snapshots without one.
+func (a *Agent) Send(ctx context.Context, snapshot Snapshot) error {
+	if snapshot.Identity == nil {
+		snapshot.Identity = a.Identity
+	}
+	body, err := MarshalSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	return a.post(ctx, body)
+}
+
+// post POSTs the JSON body to the agent URL, retrying with exponential
+// backoff.
+func (a *Agent) post(ctx context.Context, body []byte) (err error) {
+	if a.Gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err = zw.Write(body)
+		if err != nil {
+			return err
+		}
+		err = zw.Close()
+		if err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	backoff := a.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for retry := 0; ; retry++ {
+		err = a.postOnce(ctx, body)
+		if err == nil || retry >= a.MaxRetries || isRejected(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// postOnce POSTs the body to the agent URL once.
+func (a *Agent) postOnce(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range a.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &endpointError{URL: a.URL, Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
+
+// endpointError is returned when the endpoint of the agent answers with a
+// status other than 2xx.
+type endpointError struct {
+	URL        string
+	Status     string
+	StatusCode int
+}
+
+// Error returns the URL of the endpoint and the status it returned.
+func (e *endpointError) Error() string {
+	return fmt.Sprintf("The endpoint %s returned %s", e.URL, e.Status)
+}
+
+// isRejected reports whether the endpoint rejected the snapshot itself (4xx
+// other than 408 and 429), so sending it again would fail too.
+func isRejected(err error) bool {
+	endpointErr := &endpointError{}
+	if !errors.As(err, &endpointErr) {
+		return false
+	}
+
+	return endpointErr.StatusCode >= 400 && endpointErr.StatusCode <= 499 &&
+		endpointErr.StatusCode != http.StatusRequestTimeout && endpointErr.StatusCode != http.StatusTooManyRequests
+}
+
+// spool writes the snapshot to the spool directory, removing the oldest
+// spooled snapshots if there are more than MaxSpool.
+func (a *Agent) spool(snapshot Snapshot) error {
+	err := os.MkdirAll(a.SpoolDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	body, err := MarshalSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	name := strconv.FormatInt(snapshot.Time.UnixNano(), 10) + ".json"
+	err = os.WriteFile(filepath.Join(a.SpoolDir, name), body, 0644)
+	if err != nil {
+		return err
+	}
+
+	maxSpool := a.MaxSpool
+	if maxSpool <= 0 {
+		maxSpool = 1000
+	}
+	files, err := a.spooledFiles()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(files)-maxSpool; i++ {
+		os.Remove(files[i])
+	}
+
+	return nil
+}
+
+// quarantineSuffix is added to the name of the spooled snapshots that can't
+// be decoded or that the endpoint rejects, so they aren't sent again.
+const quarantineSuffix = ".bad"
+
+// flushSpool sends the spooled snapshots (oldest first) and removes them once
+// delivered. The ones that can't be read or decoded, or that the endpoint
+// rejects (4xx), are quarantined (renamed to *.json.bad) so they don't block
+// the others. It stops at the first other error, e.g. the endpoint is down.
+func (a *Agent) flushSpool(ctx context.Context) error {
+	if a.SpoolDir == "" {
+		return nil
+	}
+
+	files, err := a.spooledFiles()
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err == nil {
+			_, err = UnmarshalEnvelope(body)
+		}
+		if err != nil {
+			a.quarantine(file, err)
+			continue
+		}
+		err = a.post(ctx, body)
+		if isRejected(err) {
+			a.quarantine(file, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		os.Remove(file)
+	}
+
+	return nil
+}
+
+// quarantine renames a spooled snapshot that can't be delivered so it's not
+// sent again, keeping it for inspection.
+func (a *Agent) quarantine(file string, err error) {
+	logWarn("quarantined spooled snapshot", "file", file, "error", err)
+	if err := os.Rename(file, file+quarantineSuffix); err != nil {
+		logWarn("couldn't quarantine spooled snapshot", "file", file, "error", err)
+		os.Remove(file)
+	}
+}
+
+// spooledFiles returns the paths of the spooled snapshots sorted from the
+// oldest to the newest.
+func (a *Agent) spooledFiles() (files []string, err error) {
+	entries, err := os.ReadDir(a.SpoolDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	files = make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		files = append(files, filepath.Join(a.SpoolDir, entry.Name()))
+	}
+	// File names are Unix times in nanoseconds with the same # of digits
+	sort.Strings(files)
+
+	return files, nil
+}
@@ -0,0 +1,88 @@
+package sysstats
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAgentFlushSpoolQuarantine(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		envelope, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(envelope), `"host":"rejected"`) {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, string(envelope))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	agent := &Agent{URL: server.URL, SpoolDir: dir}
+	spooled := func(name string, snapshot Snapshot) {
+		body, err := MarshalSnapshot(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), body, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	spooled("1.json", Snapshot{Time: now})
+	if err := os.WriteFile(filepath.Join(dir, "2.json"), []byte(`{"version":2,"snap`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spooled("3.json", Snapshot{Time: now, Identity: &Identity{Hostname: "rejected"}})
+	spooled("4.json", Snapshot{Time: now})
+
+	if err := agent.flushSpool(context.Background()); err != nil {
+		t.Fatalf("flushSpool() error = %v", err)
+	}
+	if len(received) != 2 {
+		t.Errorf("the endpoint received %d snapshots, want 2", len(received))
+	}
+	for _, name := range []string{"2.json.bad", "3.json.bad"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s wasn't quarantined: %v", name, err)
+		}
+	}
+	if files, _ := agent.spooledFiles(); len(files) != 0 {
+		t.Errorf("spooled files left = %v, want none", files)
+	}
+}
+
+func TestAgentSendIdentity(t *testing.T) {
+	var envelope []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		envelope, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	agent := &Agent{URL: server.URL, Identity: &Identity{Hostname: "web1", Role: "web"}}
+	if err := agent.Send(context.Background(), Snapshot{Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(envelope), `"host":"web1"`) || !strings.Contains(string(envelope), `"role":"web"`) {
+		t.Errorf("envelope = %s, want the identity of the agent", envelope)
+	}
+
+	// The identity of the snapshot (e.g. set by a Monitor) is kept
+	if err := agent.Send(context.Background(), Snapshot{Time: time.Now(), Identity: &Identity{Hostname: "db1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(envelope), `"host":"db1"`) {
+		t.Errorf("envelope = %s, want the identity of the snapshot", envelope)
+	}
+}
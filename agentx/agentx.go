@@ -0,0 +1,381 @@
+// Package agentx exposes the stats of the system to SNMP managers as an
+// AgentX (RFC 2741) subagent of the master agent of the host (e.g. net-snmp
+// snmpd with "master agentx"). It's for the environments where the network
+// management systems poll SNMP instead of scraping HTTP (see
+// sysstats.NewServer).
+//
+// The stats are mapped to the objects of the HOST-RESOURCES-MIB and the
+// UCD-SNMP-MIB that net-snmp itself serves, so the existing templates of
+// the NMS work unchanged:
+//   HOST-RESOURCES-MIB   hrSystemUptime, hrSystemProcesses, hrMemorySize,
+//                        hrStorageTable (memory, swap and file systems),
+//                        hrProcessorLoad
+//   UCD-SNMP-MIB         memory (memTotalReal, memAvailReal,...), laTable,
+//                        systemStats (ssCpuUser, ssCpuRawUser,...)
+// The objects are read-only.
+package agentx
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rafacas/sysstats"
+)
+
+// Default values of the Agent.
+const (
+	DefaultNetwork  = "unix"
+	DefaultAddress  = "/var/agentx/master"
+	DefaultRefresh  = 10 * time.Second
+	DefaultPriority = 100
+)
+
+// Agent is an AgentX subagent serving the stats of the system.
+type Agent struct {
+	Network string        // Network of the master agent, unix or tcp (default unix)
+	Address string        // Address of the master agent (default /var/agentx/master)
+	Refresh time.Duration // Time the stats are reused between requests (default 10s)
+	// Priority of the registrations (default 100). The lower it is, the
+	// higher the precedence over the same subtrees registered by the master
+	// agent itself (127 in net-snmp).
+	Priority uint8
+	// Timeout the master agent waits for the responses of the agent, 0 means
+	// the default of the master agent.
+	Timeout time.Duration
+	// OnError is called when the connection to the master agent fails (the
+	// agent reconnects after Refresh) or the stats can't be collected.
+	// Errors are ignored if it's nil.
+	OnError func(err error)
+}
+
+// Run connects to the master agent, registers the subtrees of the MIBs and
+// answers its requests until the context is cancelled. The connection is
+// retried every Refresh when it's lost, so the agent survives restarts of
+// the master agent.
+func (a *Agent) Run(ctx context.Context) error {
+	refresh := a.Refresh
+	if refresh <= 0 {
+		refresh = DefaultRefresh
+	}
+
+	sysInfo, err := sysstats.GetSysInfo()
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+	bootTime := started.Add(-time.Duration(sysInfo.Uptime * float64(time.Second)))
+
+	var previous *sysstats.Snapshot
+	stats := sysstats.NewCache(refresh, func() (mib, error) {
+		snapshot, err := sysstats.GetSnapshot()
+		if err != nil {
+			// The snapshot is partial, the objects of the missing stats
+			// are served with zero values
+			a.error(err)
+		}
+		objects := buildMIB(snapshot, previous, bootTime)
+		previous = &snapshot
+		return objects, nil
+	})
+
+	for {
+		err := a.session(ctx, stats, started)
+		if ctx.Err() != nil {
+			return nil
+		}
+		a.error(err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(refresh):
+		}
+	}
+}
+
+// session opens a session with the master agent and serves it until the
+// connection is closed or the context is cancelled.
+func (a *Agent) session(ctx context.Context, stats *sysstats.Cache[mib], started time.Time) error {
+	network, address := a.Network, a.Address
+	if network == "" {
+		network = DefaultNetwork
+	}
+	if address == "" {
+		address = DefaultAddress
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock the reads when the context is cancelled
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	defer wg.Wait()
+	defer close(done)
+
+	s := &session{conn: conn, started: started}
+	if err = s.open(a.Timeout); err != nil {
+		return err
+	}
+	priority := a.Priority
+	if priority == 0 {
+		priority = DefaultPriority
+	}
+	for _, subtree := range []oid{hostResourcesMIB, ucdSnmpMIB} {
+		if err = s.register(subtree, priority); err != nil {
+			return err
+		}
+	}
+
+	err = s.serve(stats)
+	if ctx.Err() != nil {
+		s.close()
+	}
+
+	return err
+}
+
+// error reports an error to OnError.
+func (a *Agent) error(err error) {
+	if a.OnError != nil && err != nil {
+		a.OnError(err)
+	}
+}
+
+// session is an AgentX session with the master agent.
+type session struct {
+	conn     net.Conn
+	id       uint32
+	packetID uint32
+	started  time.Time
+}
+
+// request sends a PDU of the session and waits for its response. Only used
+// before serving the requests of the master agent, so the next PDU read is
+// the response.
+func (s *session) request(pduType uint8, payload []byte) (d *decoder, err error) {
+	s.packetID++
+	h := header{Type: pduType, SessionID: s.id, PacketID: s.packetID}
+	if _, err = s.conn.Write(encodePDU(h, payload)); err != nil {
+		return nil, err
+	}
+
+	response, d, err := readPDU(s.conn)
+	if err != nil {
+		return nil, err
+	}
+	if response.Type != pduResponse || response.PacketID != s.packetID {
+		return nil, errors.New("agentx: unexpected PDU from the master agent")
+	}
+	d.uint32() // sysUpTime
+	if code := d.uint16(); code != errNoError {
+		return nil, errors.New("agentx: the master agent returned the error " + errorName(code))
+	}
+	d.uint16() // index
+	if s.id == 0 {
+		s.id = response.SessionID
+	}
+
+	return d, d.err
+}
+
+// open opens the session.
+func (s *session) open(timeout time.Duration) error {
+	e := &encoder{}
+	e.uint8(uint8(timeout / time.Second))
+	e.uint8(0)
+	e.uint16(0)
+	e.oid(oid{}, false)
+	e.octets([]byte("sysstats"))
+
+	_, err := s.request(pduOpen, e.buf)
+
+	return err
+}
+
+// register registers a subtree in the session.
+func (s *session) register(subtree oid, priority uint8) error {
+	e := &encoder{}
+	e.uint8(0) // Timeout of the session
+	e.uint8(priority)
+	e.uint8(0) // No range
+	e.uint8(0)
+	e.oid(subtree, false)
+
+	_, err := s.request(pduRegister, e.buf)
+
+	return err
+}
+
+// close closes the session (reason shutdown).
+func (s *session) close() {
+	s.packetID++
+	h := header{Type: pduClose, SessionID: s.id, PacketID: s.packetID}
+	s.conn.Write(encodePDU(h, []byte{5, 0, 0, 0}))
+}
+
+// serve answers the requests of the master agent until the connection is
+// closed.
+func (s *session) serve(stats *sysstats.Cache[mib]) error {
+	for {
+		h, d, err := readPDU(s.conn)
+		if err != nil {
+			return err
+		}
+
+		var response []byte
+		switch h.Type {
+		case pduGet, pduGetNext, pduGetBulk:
+			objects, err := stats.Get()
+			if err != nil {
+				response = s.response(errProcessingError, 0, nil)
+				break
+			}
+			varbinds, err := answer(h.Type, d, objects)
+			if err != nil {
+				response = s.response(errParseError, 0, nil)
+			} else {
+				response = s.response(errNoError, 0, varbinds)
+			}
+		case pduTestSet:
+			response = s.response(errNotWritable, 1, nil)
+		case pduCommitSet, pduUndoSet:
+			response = s.response(errProcessingError, 0, nil)
+		case pduCleanupSet:
+			continue
+		case pduClose:
+			return errors.New("agentx: the master agent closed the session")
+		default:
+			// Responses to our pings and other PDUs aren't expected
+			continue
+		}
+
+		h.Type = pduResponse
+		h.Flags = 0
+		if _, err = s.conn.Write(encodePDU(h, response)); err != nil {
+			return err
+		}
+	}
+}
+
+// response encodes the payload of a Response PDU.
+func (s *session) response(code uint16, index uint16, varbinds []byte) []byte {
+	e := &encoder{}
+	e.uint32(uint32(time.Since(s.started) / (10 * time.Millisecond)))
+	e.uint16(code)
+	e.uint16(index)
+	e.buf = append(e.buf, varbinds...)
+
+	return e.buf
+}
+
+// answer encodes the variable bindings of the response to a Get, GetNext or
+// GetBulk PDU. It returns the error decoding the search ranges of the
+// request, which is answered with a parseError.
+func answer(pduType uint8, d *decoder, objects mib) ([]byte, error) {
+	e := &encoder{}
+
+	nonRepeaters, maxRepetitions := 0, 0
+	if pduType == pduGetBulk {
+		nonRepeaters = int(d.uint16())
+		maxRepetitions = int(d.uint16())
+	}
+	ranges := d.searchRanges()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	switch pduType {
+	case pduGet:
+		for _, r := range ranges {
+			if value, ok := objects.get(r.Start); ok {
+				e.varbind(r.Start, value)
+			} else {
+				e.varbind(r.Start, variable{Type: typeNoSuchObject})
+			}
+		}
+	case pduGetNext:
+		for _, r := range ranges {
+			encodeNext(e, objects, r)
+		}
+	case pduGetBulk:
+		if nonRepeaters > len(ranges) {
+			nonRepeaters = len(ranges)
+		}
+		for _, r := range ranges[:nonRepeaters] {
+			encodeNext(e, objects, r)
+		}
+		repeaters := append([]searchRange(nil), ranges[nonRepeaters:]...)
+		for i := 0; i < maxRepetitions && len(repeaters) > 0; i++ {
+			ended := true
+			for j := range repeaters {
+				next := encodeNext(e, objects, repeaters[j])
+				if next != nil {
+					ended = false
+					repeaters[j].Start = next
+					repeaters[j].Include = false
+				}
+			}
+			if ended {
+				break
+			}
+		}
+	}
+
+	return e.buf, nil
+}
+
+// encodeNext encodes the next object of a search range, or endOfMibView if
+// there isn't any. It returns the name of the object (nil if there isn't
+// any).
+func encodeNext(e *encoder, objects mib, r searchRange) oid {
+	next, ok := objects.next(r)
+	if !ok {
+		e.varbind(r.Start, variable{Type: typeEndOfMibView})
+		return nil
+	}
+	e.varbind(next.Name, next.Value)
+
+	return next.Name
+}
+
+// errorName returns the name of an error of a Response PDU.
+func errorName(code uint16) string {
+	names := map[uint16]string{
+		256: "openFailed",
+		257: "notOpen",
+		258: "indexWrongType",
+		259: "indexAlreadyAllocated",
+		260: "indexNoneAvailable",
+		261: "indexNotAllocated",
+		262: "unsupportedContext",
+		263: "duplicateRegistration",
+		264: "unknownRegistration",
+		265: "unknownAgentCaps",
+		266: "parseError",
+		267: "requestDenied",
+		268: "processingError",
+	}
+	if name, ok := names[code]; ok {
+		return name
+	}
+
+	return "#" + strconv.Itoa(int(code))
+}
@@ -0,0 +1,233 @@
+package agentx
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rafacas/sysstats"
+)
+
+// Subtrees registered by the agent.
+var (
+	hostResourcesMIB = parseOID("1.3.6.1.2.1.25")   // HOST-RESOURCES-MIB::host
+	ucdSnmpMIB       = parseOID("1.3.6.1.4.1.2021") // UCD-SNMP-MIB::ucdavis
+)
+
+// HOST-RESOURCES-MIB objects
+var (
+	hrSystemUptime        = parseOID("1.3.6.1.2.1.25.1.1.0")
+	hrSystemProcesses     = parseOID("1.3.6.1.2.1.25.1.6.0")
+	hrMemorySize          = parseOID("1.3.6.1.2.1.25.2.2.0")
+	hrStorageEntry        = parseOID("1.3.6.1.2.1.25.2.3.1")
+	hrStorageRam          = parseOID("1.3.6.1.2.1.25.2.1.2")
+	hrStorageVirtualMem   = parseOID("1.3.6.1.2.1.25.2.1.3")
+	hrStorageFixedDisk    = parseOID("1.3.6.1.2.1.25.2.1.4")
+	hrProcessorEntry      = parseOID("1.3.6.1.2.1.25.3.3.1")
+	hrProcessorIndexStart = uint32(196608) // hrDeviceIndex of the first processor, like net-snmp
+)
+
+// UCD-SNMP-MIB objects
+var (
+	ucdMemory      = parseOID("1.3.6.1.4.1.2021.4")
+	ucdLaEntry     = parseOID("1.3.6.1.4.1.2021.10.1")
+	ucdSystemStats = parseOID("1.3.6.1.4.1.2021.11")
+)
+
+// entry is an object of the MIB with its value.
+type entry struct {
+	Name  oid
+	Value variable
+}
+
+// mib is the objects the agent exposes, sorted by OID.
+type mib []entry
+
+// get returns the value of an object.
+func (m mib) get(name oid) (value variable, ok bool) {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Name.compare(name) >= 0 })
+	if i < len(m) && m[i].Name.compare(name) == 0 {
+		return m[i].Value, true
+	}
+
+	return variable{}, false
+}
+
+// next returns the first object of a search range (after its start, or at
+// it if it's included, and before its end if it has one).
+func (m mib) next(r searchRange) (e entry, ok bool) {
+	i := sort.Search(len(m), func(i int) bool {
+		c := m[i].Name.compare(r.Start)
+		return c > 0 || (c == 0 && r.Include)
+	})
+	if i == len(m) {
+		return entry{}, false
+	}
+	if len(r.End) > 0 && m[i].Name.compare(r.End) >= 0 {
+		return entry{}, false
+	}
+
+	return m[i], true
+}
+
+// builder builds a MIB from the stats of the system.
+type builder struct {
+	entries mib
+}
+
+func (b *builder) add(name oid, typ uint16, value interface{}) {
+	b.entries = append(b.entries, entry{Name: name, Value: variable{Type: typ, Value: value}})
+}
+
+func (b *builder) integer(name oid, value uint64) {
+	b.add(name, typeInteger, clampInt32(value))
+}
+
+func (b *builder) gauge(name oid, value uint64) {
+	if value > math.MaxUint32 {
+		value = math.MaxUint32
+	}
+	b.add(name, typeGauge32, uint32(value))
+}
+
+// counter adds a Counter32, which wraps around like the 32 bits counters of
+// the kernel.
+func (b *builder) counter(name oid, value uint64) {
+	b.add(name, typeCounter32, uint32(value))
+}
+
+func (b *builder) str(name oid, value string) {
+	b.add(name, typeOctetString, value)
+}
+
+func (b *builder) mib() mib {
+	sort.Slice(b.entries, func(i, j int) bool { return b.entries[i].Name.compare(b.entries[j].Name) < 0 })
+
+	return b.entries
+}
+
+// storage is a row of the hrStorageTable (sizes in kilobytes).
+type storage struct {
+	typ   oid
+	descr string
+	size  uint64
+	used  uint64
+}
+
+// allocationUnits returns the hrStorageAllocationUnits of the row, in bytes,
+// and its size and space used in those units. The units start at a
+// kilobyte and are doubled until the size fits in an Integer32, so the
+// file systems over 2 TB aren't clamped.
+func (s storage) allocationUnits() (units uint64, size uint64, used uint64) {
+	units, size, used = 1024, s.size, s.used
+	for size > math.MaxInt32 && units <= math.MaxInt32/2 {
+		units *= 2
+		size /= 2
+		used /= 2
+	}
+
+	return units, size, used
+}
+
+// clampInt32 converts a value to an Integer32, clamped to its max value.
+func clampInt32(value uint64) int32 {
+	if value > math.MaxInt32 {
+		return math.MaxInt32
+	}
+
+	return int32(value)
+}
+
+// buildMIB builds the objects of the HOST-RESOURCES-MIB and the UCD-SNMP-MIB
+// from a snapshot. The CPU percentages are calculated against the previous
+// snapshot, if any. The sizes are in kilobytes, like net-snmp reports them,
+// or in larger units when they are too big for an Integer32.
+func buildMIB(snapshot sysstats.Snapshot, previous *sysstats.Snapshot, bootTime time.Time) mib {
+	b := &builder{}
+
+	// hrSystem
+	b.add(hrSystemUptime, typeTimeTicks, uint32(snapshot.Time.Sub(bootTime)/(10*time.Millisecond)))
+	b.gauge(hrSystemProcesses, snapshot.Proc.Total)
+
+	// hrStorage: the memory, the swap and the file systems
+	mem := snapshot.Mem
+	b.integer(hrMemorySize, mem[sysstats.MemTotal])
+	storages := []storage{
+		{hrStorageRam, "Physical memory", mem[sysstats.MemTotal], mem[sysstats.MemUsed]},
+		{hrStorageVirtualMem, "Swap space", mem[sysstats.MemSwapTotal], mem[sysstats.MemSwapUsed]},
+	}
+	for _, diskUsage := range snapshot.DiskUsage {
+		storages = append(storages, storage{hrStorageFixedDisk, diskUsage.MountedOn, diskUsage.Total, diskUsage.Used})
+	}
+	for i, row := range storages {
+		index := uint32(i + 1)
+		b.integer(hrStorageEntry.append(1, index), uint64(index))
+		b.add(hrStorageEntry.append(2, index), typeObjectIdentifier, row.typ)
+		b.str(hrStorageEntry.append(3, index), row.descr)
+		units, size, used := row.allocationUnits()
+		b.integer(hrStorageEntry.append(4, index), units)
+		b.integer(hrStorageEntry.append(5, index), size)
+		b.integer(hrStorageEntry.append(6, index), used)
+	}
+
+	// hrProcessorTable and systemStats
+	var cpuStats sysstats.CpusAvgStats
+	if previous != nil {
+		cpuStats, _ = sysstats.GetCpuAvgStats(previous.Cpu, snapshot.Cpu)
+	}
+	for i := 0; ; i++ {
+		name := `cpu` + strconv.Itoa(i)
+		if _, ok := snapshot.Cpu[name]; !ok {
+			break
+		}
+		index := hrProcessorIndexStart + uint32(i)
+		b.add(hrProcessorEntry.append(1, index), typeObjectIdentifier, oid{0, 0})
+		b.integer(hrProcessorEntry.append(2, index), uint64(math.Round(cpuStats[name][sysstats.CpuTotal])))
+	}
+	cpu := snapshot.Cpu[`cpu`]
+	percents := map[uint32]string{9: sysstats.CpuUser, 10: sysstats.CpuSystem, 11: sysstats.CpuIdle}
+	for subid, key := range percents {
+		b.integer(ucdSystemStats.append(subid, 0), uint64(math.Round(cpuStats[`cpu`][key])))
+	}
+	raws := map[uint32]string{
+		50: sysstats.CpuUser,
+		51: sysstats.CpuNice,
+		52: sysstats.CpuSystem,
+		53: sysstats.CpuIdle,
+		54: sysstats.CpuIowait,
+		56: sysstats.CpuIrq,
+		61: sysstats.CpuSoftirq,
+		64: sysstats.CpuSteal,
+		65: sysstats.CpuGuest,
+		66: sysstats.CpuGuestNice,
+	}
+	for subid, key := range raws {
+		if value, ok := cpu[key]; ok {
+			b.counter(ucdSystemStats.append(subid, 0), value)
+		}
+	}
+
+	// memory
+	b.integer(ucdMemory.append(1, 0), 0)
+	b.str(ucdMemory.append(2, 0), "swap")
+	b.integer(ucdMemory.append(3, 0), mem[sysstats.MemSwapTotal])
+	b.integer(ucdMemory.append(4, 0), mem[sysstats.MemSwapFree])
+	b.integer(ucdMemory.append(5, 0), mem[sysstats.MemTotal])
+	b.integer(ucdMemory.append(6, 0), mem[sysstats.MemFree])
+	b.integer(ucdMemory.append(11, 0), mem[sysstats.MemFree]+mem[sysstats.MemSwapFree])
+	b.integer(ucdMemory.append(14, 0), mem[sysstats.MemBuffers])
+	b.integer(ucdMemory.append(15, 0), mem[sysstats.MemCached])
+
+	// laTable
+	loads := []float64{snapshot.LoadAvg.Avg1, snapshot.LoadAvg.Avg5, snapshot.LoadAvg.Avg15}
+	for i, load := range loads {
+		index := uint32(i + 1)
+		b.integer(ucdLaEntry.append(1, index), uint64(index))
+		b.str(ucdLaEntry.append(2, index), "Load-"+[]string{"1", "5", "15"}[i])
+		b.str(ucdLaEntry.append(3, index), strconv.FormatFloat(load, 'f', 2, 64))
+		b.integer(ucdLaEntry.append(5, index), uint64(math.Round(load*100)))
+	}
+
+	return b.mib()
+}
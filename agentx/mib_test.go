@@ -0,0 +1,37 @@
+package agentx
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestStorageAllocationUnits(t *testing.T) {
+	tests := []struct {
+		size      uint64
+		used      uint64
+		wantUnits uint64
+		wantSize  uint64
+		wantUsed  uint64
+	}{
+		{1000, 500, 1024, 1000, 500},
+		{math.MaxInt32, 10, 1024, math.MaxInt32, 10},
+		// 8 TB file system, in kilobytes
+		{8 << 30, 4 << 30, 8192, 1 << 30, 1 << 29},
+	}
+	for _, test := range tests {
+		units, size, used := storage{size: test.size, used: test.used}.allocationUnits()
+		if units != test.wantUnits || size != test.wantSize || used != test.wantUsed {
+			t.Errorf("allocationUnits(%d, %d) = %d, %d, %d, want %d, %d, %d", test.size, test.used,
+				units, size, used, test.wantUnits, test.wantSize, test.wantUsed)
+		}
+	}
+}
+
+func TestAnswerParseError(t *testing.T) {
+	// A search range truncated in the middle of its OID
+	d := &decoder{b: []byte{2, 0, 0, 0, 1, 0, 0}, order: binary.LittleEndian}
+	if _, err := answer(pduGet, d, nil); err == nil {
+		t.Error("answer() of a truncated search range succeeded, want an error")
+	}
+}
@@ -0,0 +1,361 @@
+package agentx
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PDU types (RFC 2741, section 6.1)
+const (
+	pduOpen       = 1
+	pduClose      = 2
+	pduRegister   = 3
+	pduGet        = 5
+	pduGetNext    = 6
+	pduGetBulk    = 7
+	pduTestSet    = 8
+	pduCommitSet  = 9
+	pduUndoSet    = 10
+	pduCleanupSet = 11
+	pduPing       = 13
+	pduResponse   = 18
+)
+
+// Header flags
+const (
+	flagNonDefaultContext = 0x08
+	flagNetworkByteOrder  = 0x10
+)
+
+// Errors of the Response PDU
+const (
+	errNoError         = 0
+	errNotWritable     = 17
+	errParseError      = 266
+	errProcessingError = 268
+)
+
+// Types of the variables
+const (
+	typeInteger          = 2
+	typeOctetString      = 4
+	typeNull             = 5
+	typeObjectIdentifier = 6
+	typeCounter32        = 65
+	typeGauge32          = 66
+	typeTimeTicks        = 67
+	typeCounter64        = 70
+	typeNoSuchObject     = 128
+	typeNoSuchInstance   = 129
+	typeEndOfMibView     = 130
+)
+
+// headerLen is the length of the header of every PDU.
+const headerLen = 20
+
+// maxPayloadLen is the max length of the payload of the PDUs read, to not
+// allocate whatever a broken master sends.
+const maxPayloadLen = 1 << 20
+
+// internetPrefix is the OID prefix (1.3.6.1) the OIDs are encoded relative
+// to.
+var internetPrefix = oid{1, 3, 6, 1}
+
+// header is the header of a PDU.
+type header struct {
+	Type          uint8
+	Flags         uint8
+	SessionID     uint32
+	TransactionID uint32
+	PacketID      uint32
+}
+
+// oid is an object identifier.
+type oid []uint32
+
+// parseOID parses an OID in dotted notation (1.3.6.1.2.1.25). It panics if
+// it isn't valid, it's only used for the OIDs of the MIBs.
+func parseOID(s string) oid {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	o := make(oid, len(parts))
+	for i, part := range parts {
+		value, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			panic("agentx: invalid OID " + s)
+		}
+		o[i] = uint32(value)
+	}
+
+	return o
+}
+
+// String returns the OID in dotted notation.
+func (o oid) String() string {
+	parts := make([]string, len(o))
+	for i, subid := range o {
+		parts[i] = strconv.FormatUint(uint64(subid), 10)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// append returns a new OID with the sub-identifiers appended.
+func (o oid) append(subids ...uint32) oid {
+	return append(append(make(oid, 0, len(o)+len(subids)), o...), subids...)
+}
+
+// compare compares 2 OIDs lexicographically. It returns -1, 0 or 1.
+func (o oid) compare(other oid) int {
+	for i := 0; i < len(o) && i < len(other); i++ {
+		if o[i] < other[i] {
+			return -1
+		}
+		if o[i] > other[i] {
+			return 1
+		}
+	}
+	switch {
+	case len(o) < len(other):
+		return -1
+	case len(o) > len(other):
+		return 1
+	}
+
+	return 0
+}
+
+// variable is the value of an object.
+type variable struct {
+	Type  uint16
+	Value interface{} // int32, uint32, uint64, string or oid, depending on the type
+}
+
+// searchRange is a range of OIDs of a Get, GetNext or GetBulk PDU.
+type searchRange struct {
+	Start   oid
+	Include bool
+	End     oid
+}
+
+// encoder encodes the payload of the PDUs sent, always in network byte
+// order.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uint8(v uint8) {
+	e.buf = append(e.buf, v)
+}
+
+func (e *encoder) uint16(v uint16) {
+	e.buf = binary.BigEndian.AppendUint16(e.buf, v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, v)
+}
+
+func (e *encoder) uint64(v uint64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, v)
+}
+
+// oid encodes an OID, with the 1.3.6.1.x prefix compressed.
+func (e *encoder) oid(o oid, include bool) {
+	prefix := uint8(0)
+	if len(o) > 4 && o[:4].compare(internetPrefix) == 0 && o[4] > 0 && o[4] < 256 {
+		prefix = uint8(o[4])
+		o = o[5:]
+	}
+	e.uint8(uint8(len(o)))
+	e.uint8(prefix)
+	if include {
+		e.uint8(1)
+	} else {
+		e.uint8(0)
+	}
+	e.uint8(0)
+	for _, subid := range o {
+		e.uint32(subid)
+	}
+}
+
+// octets encodes an octet string, padded to 4 bytes.
+func (e *encoder) octets(b []byte) {
+	e.uint32(uint32(len(b)))
+	e.buf = append(e.buf, b...)
+	for n := len(b); n%4 != 0; n++ {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// varbind encodes a variable binding.
+func (e *encoder) varbind(name oid, v variable) {
+	e.uint16(v.Type)
+	e.uint16(0)
+	e.oid(name, false)
+	switch value := v.Value.(type) {
+	case int32:
+		e.uint32(uint32(value))
+	case uint32:
+		e.uint32(value)
+	case uint64:
+		e.uint64(value)
+	case string:
+		e.octets([]byte(value))
+	case oid:
+		e.oid(value, false)
+	}
+}
+
+// decoder decodes the payload of the PDUs received in the byte order of
+// their header. The first error is kept and the following reads return
+// zero values.
+type decoder struct {
+	b     []byte
+	order binary.ByteOrder
+	err   error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.b) < n {
+		d.err = errors.New("agentx: truncated PDU")
+		return nil
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+
+	return b
+}
+
+func (d *decoder) uint8() uint8 {
+	if b := d.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint16() uint16 {
+	if b := d.next(2); b != nil {
+		return d.order.Uint16(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return d.order.Uint32(b)
+	}
+	return 0
+}
+
+// oid decodes an OID and its include field.
+func (d *decoder) oid() (o oid, include bool) {
+	n := d.uint8()
+	prefix := d.uint8()
+	include = d.uint8() != 0
+	d.uint8()
+
+	o = oid{}
+	if prefix != 0 {
+		o = internetPrefix.append(uint32(prefix))
+	}
+	for i := 0; i < int(n); i++ {
+		o = append(o, d.uint32())
+	}
+
+	return o, include
+}
+
+// octets decodes an octet string.
+func (d *decoder) octets() []byte {
+	n := d.uint32()
+	if d.err != nil {
+		return nil
+	}
+	// The length is checked before rounding it up, so it can't overflow an
+	// int (e.g. 0xffffffff on 32 bits)
+	if uint64(n) > uint64(len(d.b)) {
+		d.err = errors.New("agentx: truncated PDU")
+		return nil
+	}
+	b := d.next((int(n) + 3) &^ 3)
+	if b == nil {
+		return nil
+	}
+
+	return b[:n]
+}
+
+// searchRanges decodes the search ranges up to the end of the payload.
+func (d *decoder) searchRanges() (ranges []searchRange) {
+	for len(d.b) > 0 && d.err == nil {
+		start, include := d.oid()
+		end, _ := d.oid()
+		ranges = append(ranges, searchRange{Start: start, Include: include, End: end})
+	}
+
+	return ranges
+}
+
+// readPDU reads a PDU and returns its header and a decoder of its payload.
+func readPDU(r io.Reader) (h header, d *decoder, err error) {
+	buf := make([]byte, headerLen)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return header{}, nil, err
+	}
+	if buf[0] != 1 {
+		return header{}, nil, errors.New("agentx: unsupported version " + strconv.Itoa(int(buf[0])))
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if buf[2]&flagNetworkByteOrder != 0 {
+		order = binary.BigEndian
+	}
+	h = header{
+		Type:          buf[1],
+		Flags:         buf[2],
+		SessionID:     order.Uint32(buf[4:8]),
+		TransactionID: order.Uint32(buf[8:12]),
+		PacketID:      order.Uint32(buf[12:16]),
+	}
+
+	length := order.Uint32(buf[16:20])
+	if length > maxPayloadLen {
+		return header{}, nil, errors.New("agentx: PDU too large")
+	}
+	payload := make([]byte, length)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return header{}, nil, err
+	}
+
+	d = &decoder{b: payload, order: order}
+	// The context of the requests isn't used, only the default one is
+	// registered
+	if h.Flags&flagNonDefaultContext != 0 && h.Type != pduResponse {
+		d.octets()
+	}
+
+	return h, d, nil
+}
+
+// encodePDU encodes a PDU with its payload, in network byte order.
+func encodePDU(h header, payload []byte) []byte {
+	e := &encoder{buf: make([]byte, 0, headerLen+len(payload))}
+	e.uint8(1)
+	e.uint8(h.Type)
+	e.uint8(h.Flags | flagNetworkByteOrder)
+	e.uint8(0)
+	e.uint32(h.SessionID)
+	e.uint32(h.TransactionID)
+	e.uint32(h.PacketID)
+	e.uint32(uint32(len(payload)))
+	e.buf = append(e.buf, payload...)
+
+	return e.buf
+}
@@ -0,0 +1,118 @@
+package agentx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestReadPDU(t *testing.T) {
+	e := &encoder{}
+	e.oid(parseOID("1.3.6.1.2.1.25.1.1.0"), true)
+	e.oid(oid{}, false)
+	h := header{Type: pduGetNext, SessionID: 1, TransactionID: 2, PacketID: 3}
+
+	got, d, err := readPDU(bytes.NewReader(encodePDU(h, e.buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != h.Type || got.SessionID != 1 || got.TransactionID != 2 || got.PacketID != 3 {
+		t.Errorf("readPDU() header = %+v, want %+v", got, h)
+	}
+	ranges := d.searchRanges()
+	if d.err != nil || len(ranges) != 1 {
+		t.Fatalf("searchRanges() = %v, %v, want 1 range", ranges, d.err)
+	}
+	if ranges[0].Start.String() != "1.3.6.1.2.1.25.1.1.0" || !ranges[0].Include || len(ranges[0].End) != 0 {
+		t.Errorf("searchRanges() = %+v, want the range of hrSystemUptime", ranges[0])
+	}
+}
+
+func TestReadPDUContext(t *testing.T) {
+	e := &encoder{}
+	e.octets([]byte("ctx"))
+	e.oid(parseOID("1.3.6.1.2.1.25.1.1.0"), false)
+	e.oid(oid{}, false)
+	h := header{Type: pduGet, Flags: flagNonDefaultContext}
+
+	_, d, err := readPDU(bytes.NewReader(encodePDU(h, e.buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges := d.searchRanges(); d.err != nil || len(ranges) != 1 {
+		t.Errorf("searchRanges() after the context = %v, %v, want 1 range", ranges, d.err)
+	}
+}
+
+func TestReadPDUInvalid(t *testing.T) {
+	valid := encodePDU(header{Type: pduGet}, make([]byte, 8))
+
+	version := append([]byte{}, valid...)
+	version[0] = 2
+	oversized := append([]byte{}, valid...)
+	binary.BigEndian.PutUint32(oversized[16:20], maxPayloadLen+1)
+
+	tests := []struct {
+		name string
+		pdu  []byte
+	}{
+		{"empty", nil},
+		{"truncated header", valid[:headerLen-1]},
+		{"truncated payload", valid[:len(valid)-1]},
+		{"unsupported version", version},
+		{"oversized payload", oversized},
+	}
+	for _, test := range tests {
+		if _, _, err := readPDU(bytes.NewReader(test.pdu)); err == nil {
+			t.Errorf("%s: readPDU() succeeded, want an error", test.name)
+		}
+	}
+
+	if _, _, err := readPDU(bytes.NewReader(valid[:headerLen-1])); err != io.ErrUnexpectedEOF {
+		t.Errorf("readPDU() of a truncated header error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestDecoderOctets(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		want    string
+		wantErr bool
+	}{
+		{"padded", []byte{0, 0, 0, 3, 'a', 'b', 'c', 0}, "abc", false},
+		{"aligned", []byte{0, 0, 0, 4, 'a', 'b', 'c', 'd'}, "abcd", false},
+		{"empty", []byte{0, 0, 0, 0}, "", false},
+		{"truncated length", []byte{0, 0, 3}, "", true},
+		{"truncated string", []byte{0, 0, 0, 5, 'a', 'b', 'c', 'd'}, "", true},
+		{"missing padding", []byte{0, 0, 0, 3, 'a', 'b', 'c'}, "", true},
+		{"oversized length", []byte{0xff, 0xff, 0xff, 0xff, 'a', 'b', 'c', 'd'}, "", true},
+		{"length wrapping when rounded up", []byte{0xff, 0xff, 0xff, 0xfe, 'a', 'b', 'c', 'd'}, "", true},
+	}
+	for _, test := range tests {
+		d := &decoder{b: test.payload, order: binary.BigEndian}
+		got := d.octets()
+		if test.wantErr {
+			if d.err == nil {
+				t.Errorf("%s: octets() = %q, want an error", test.name, got)
+			}
+			continue
+		}
+		if d.err != nil || string(got) != test.want {
+			t.Errorf("%s: octets() = %q, %v, want %q", test.name, got, d.err, test.want)
+		}
+	}
+}
+
+func TestDecoderTruncatedOID(t *testing.T) {
+	// An OID of 3 sub-identifiers with only 1 of them
+	d := &decoder{b: []byte{3, 2, 0, 0, 1, 0, 0, 0}, order: binary.LittleEndian}
+	if o, _ := d.oid(); d.err == nil {
+		t.Errorf("oid() = %v, want an error", o)
+	}
+	// The reads after the first error return zero values
+	if v := d.uint32(); v != 0 || d.err == nil {
+		t.Errorf("uint32() after an error = %d, %v, want 0 and the error", v, d.err)
+	}
+}
@@ -0,0 +1,201 @@
+package sysstats
+
+import (
+	"math"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AnomalyMethod is the statistical method used to score a value against the
+// rolling window of a metric.
+type AnomalyMethod int
+
+const (
+	// ZScore scores a value by its distance to the mean of the window in
+	// standard deviations.
+	ZScore AnomalyMethod = iota
+	// MAD scores a value by its distance to the median of the window in
+	// median absolute deviations (modified z-score), which is robust to the
+	// outliers already in the window.
+	MAD
+)
+
+// anomalyMaxScore is the score of a value that differs from a window without
+// spread (stddev or MAD 0), which would be infinite otherwise (and can't be
+// encoded in JSON).
+const anomalyMaxScore = 1000.0
+
+// Anomaly represents a value of a metric that deviates from its rolling
+// window beyond the threshold of the detector.
+type Anomaly struct {
+	Metric string    `json:"metric"` // Metric name, e.g. cpu.cpu0.total
+	Time   time.Time `json:"time"`   // Time of the value
+	Value  float64   `json:"value"`  // Anomalous value
+	Center float64   `json:"center"` // Mean (ZScore) or median (MAD) of the window
+	Score  float64   `json:"score"`  // Deviation of the value in stddevs (ZScore) or MADs (MAD)
+}
+
+// AnomalyDetector detects the values of a metric stream that deviate from
+// the rolling mean/stddev (or median/MAD) of the metric. An anomaly is only
+// reported when M of the last N values of a metric deviate beyond the
+// threshold, so single spikes can be ignored.
+type AnomalyDetector struct {
+	Metrics   []string      // Patterns (path.Match syntax) of the metrics watched, e.g. cpu.*.total; none means all
+	Method    AnomalyMethod // Scoring method
+	Window    int           // # of values of the rolling window (default 60)
+	Threshold float64       // Score above which a value deviates (default 3)
+	M         int           // Min # of deviating values among the last N to report an anomaly (default 1)
+	N         int           // # of last values M is checked against (default M)
+
+	mu     sync.Mutex
+	series map[string]*anomalySeries
+}
+
+// anomalySeries is the state of the detector for one metric.
+type anomalySeries struct {
+	window  []float64 // Rolling window of values
+	flags   []bool    // Whether each of the last N values deviated
+	flagged int       // # of true values in flags
+}
+
+// Observe scores the metrics observed at time t and returns the anomalies
+// found.
+func (d *AnomalyDetector) Observe(t time.Time, metrics map[string]float64) (anomalies []Anomaly) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.series == nil {
+		d.series = map[string]*anomalySeries{}
+	}
+	window, threshold, m, n := d.Window, d.Threshold, d.M, d.N
+	if window <= 0 {
+		window = 60
+	}
+	if threshold <= 0 {
+		threshold = 3
+	}
+	if m <= 0 {
+		m = 1
+	}
+	if n < m {
+		n = m
+	}
+
+	for name, value := range metrics {
+		if !d.watches(name) {
+			continue
+		}
+		series, ok := d.series[name]
+		if !ok {
+			series = &anomalySeries{}
+			d.series[name] = series
+		}
+
+		// Score the value against the window before adding it. At least a few
+		// values are needed for the score to be meaningful.
+		deviates := false
+		center, score := 0.0, 0.0
+		if len(series.window) >= 3 {
+			center, score = d.score(series.window, value)
+			deviates = math.Abs(score) > threshold
+		}
+
+		series.flags = append(series.flags, deviates)
+		if deviates {
+			series.flagged++
+		}
+		if len(series.flags) > n {
+			if series.flags[0] {
+				series.flagged--
+			}
+			series.flags = series.flags[1:]
+		}
+		if deviates && series.flagged >= m {
+			anomalies = append(anomalies, Anomaly{
+				Metric: name,
+				Time:   t,
+				Value:  value,
+				Center: center,
+				Score:  score,
+			})
+		}
+
+		series.window = append(series.window, value)
+		if len(series.window) > window {
+			series.window = series.window[1:]
+		}
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		return anomalies[i].Metric < anomalies[j].Metric
+	})
+
+	return anomalies
+}
+
+// watches returns whether the metric matches one of the patterns.
+func (d *AnomalyDetector) watches(name string) bool {
+	if len(d.Metrics) == 0 {
+		return true
+	}
+	for _, pattern := range d.Metrics {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// score returns the center of the window and the score of the value.
+func (d *AnomalyDetector) score(window []float64, value float64) (center float64, score float64) {
+	if d.Method == MAD {
+		center = median(window)
+		deviations := make([]float64, len(window))
+		for i, v := range window {
+			deviations[i] = math.Abs(v - center)
+		}
+		mad := median(deviations)
+		if mad == 0 {
+			if value == center {
+				return center, 0
+			}
+			return center, math.Copysign(anomalyMaxScore, value-center)
+		}
+		return center, 0.6745 * (value - center) / mad
+	}
+
+	for _, v := range window {
+		center += v
+	}
+	center /= float64(len(window))
+	variance := 0.0
+	for _, v := range window {
+		variance += (v - center) * (v - center)
+	}
+	stddev := math.Sqrt(variance / float64(len(window)))
+	if stddev == 0 {
+		if value == center {
+			return center, 0
+		}
+		return center, math.Copysign(anomalyMaxScore, value-center)
+	}
+
+	return center, (value - center) / stddev
+}
+
+// median returns the median of the values.
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
@@ -0,0 +1,26 @@
+package sysstats
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAnomalyDetectorFlatWindow(t *testing.T) {
+	for _, method := range []AnomalyMethod{ZScore, MAD} {
+		d := &AnomalyDetector{Method: method}
+		for i := 0; i < 5; i++ {
+			if anomalies := d.Observe(time.Unix(int64(i), 0), map[string]float64{`app.queue.len`: 10}); len(anomalies) != 0 {
+				t.Errorf("method %d: Observe() of a flat window = %+v, want no anomalies", method, anomalies)
+			}
+		}
+
+		anomalies := d.Observe(time.Unix(5, 0), map[string]float64{`app.queue.len`: 5})
+		if len(anomalies) != 1 || anomalies[0].Score != -anomalyMaxScore {
+			t.Fatalf("method %d: Observe() = %+v, want an anomaly with score %v", method, anomalies, -anomalyMaxScore)
+		}
+		if _, err := json.Marshal(anomalies); err != nil {
+			t.Errorf("method %d: json.Marshal() error = %v", method, err)
+		}
+	}
+}
@@ -1,6 +1,11 @@
 // Package sysstats provides system statistics.
 package sysstats
 
+import (
+	"context"
+	"time"
+)
+
 // Public API
 
 // GetLoadAvg returns the load average of the system.
@@ -26,11 +31,37 @@ func GetCpuAvgStats(firstSample CpusRawStats, secondSample CpusRawStats) (CpusAv
 }
 
 // GetCpuStatsInterval returns the % CPU utilization between 2 samples where
-// the sample interval is passed as an argument (in seconds).
+// the sample interval is passed as an argument (in seconds). See
+// GetCpuStatsOver for sub-second intervals.
 func GetCpuStatsInterval(interval int64) (CpusAvgStats, error) {
 	return getCpuStatsInterval(interval)
 }
 
+// GetCpuStatsIntervalContext is like GetCpuStatsInterval but returns
+// ctx.Err() as soon as the context is cancelled or its deadline expires.
+func GetCpuStatsIntervalContext(ctx context.Context, interval int64) (CpusAvgStats, error) {
+	return GetCpuStatsOverContext(ctx, time.Duration(interval)*time.Second)
+}
+
+// GetCpuStatsOver returns the % CPU utilization between 2 samples taken d
+// apart. Sub-second durations (e.g. 250ms) are supported.
+func GetCpuStatsOver(d time.Duration) (CpusAvgStats, error) {
+	return getCpuStatsOver(d)
+}
+
+// GetCpuStatsOverContext is like GetCpuStatsOver but returns ctx.Err()
+// as soon as the context is cancelled or its deadline expires.
+func GetCpuStatsOverContext(ctx context.Context, d time.Duration) (CpusAvgStats, error) {
+	return sampleOverContext(ctx, d, getCpuRawStats, getCpuAvgStats)
+}
+
+// GetCpuStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the series of n % CPU utilizations between them, so short spikes
+// are not averaged away.
+func GetCpuStatsSampleN(n int, interval time.Duration) ([]CpusAvgStats, error) {
+	return getCpuStatsSampleN(n, interval)
+}
+
 // GetNetRawStats returns all the network interfaces statistics of the system
 func GetNetRawStats() (NetRawStats, error) {
 	return getNetRawStats()
@@ -43,11 +74,36 @@ func GetNetAvgStats(firstSample NetRawStats, secondSample NetRawStats) (NetAvgSt
 }
 
 // GetNetStatsInterval returns the network traffic between 2 samples where the
-// sample interval is passed as an argument (in seconds).
+// sample interval is passed as an argument (in seconds). See GetNetStatsOver
+// for sub-second intervals.
 func GetNetStatsInterval(interval int64) (NetAvgStats, error) {
 	return getNetStatsInterval(interval)
 }
 
+// GetNetStatsIntervalContext is like GetNetStatsInterval but returns
+// ctx.Err() as soon as the context is cancelled or its deadline expires.
+func GetNetStatsIntervalContext(ctx context.Context, interval int64) (NetAvgStats, error) {
+	return GetNetStatsOverContext(ctx, time.Duration(interval)*time.Second)
+}
+
+// GetNetStatsOver returns the network traffic between 2 samples taken d
+// apart. Sub-second durations (e.g. 250ms) are supported.
+func GetNetStatsOver(d time.Duration) (NetAvgStats, error) {
+	return getNetStatsOver(d)
+}
+
+// GetNetStatsOverContext is like GetNetStatsOver but returns ctx.Err()
+// as soon as the context is cancelled or its deadline expires.
+func GetNetStatsOverContext(ctx context.Context, d time.Duration) (NetAvgStats, error) {
+	return sampleOverContext(ctx, d, getNetRawStats, getNetAvgStats)
+}
+
+// GetNetStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the series of n network traffic averages between them.
+func GetNetStatsSampleN(n int, interval time.Duration) ([]NetAvgStats, error) {
+	return getNetStatsSampleN(n, interval)
+}
+
 // GetDiskUsage gets an array (one element per partition) with the disk
 // usage of the system
 func GetDiskUsage() ([]DiskUsage, error) {
@@ -67,11 +123,36 @@ func GetDiskAvgStats(firstSampleArr []DiskRawStats, secondSampleArr []DiskRawSta
 }
 
 // GetDiskStatsInterval returns the IO average between 2 samples where
-// the sample interval is passed as an argument (in seconds).
+// the sample interval is passed as an argument (in seconds). See
+// GetDiskStatsOver for sub-second intervals.
 func GetDiskStatsInterval(interval int64) ([]DiskAvgStats, error) {
 	return getDiskStatsInterval(interval)
 }
 
+// GetDiskStatsIntervalContext is like GetDiskStatsInterval but returns
+// ctx.Err() as soon as the context is cancelled or its deadline expires.
+func GetDiskStatsIntervalContext(ctx context.Context, interval int64) ([]DiskAvgStats, error) {
+	return GetDiskStatsOverContext(ctx, time.Duration(interval)*time.Second)
+}
+
+// GetDiskStatsOver returns the IO average between 2 samples taken d apart.
+// Sub-second durations (e.g. 250ms) are supported.
+func GetDiskStatsOver(d time.Duration) ([]DiskAvgStats, error) {
+	return getDiskStatsOver(d)
+}
+
+// GetDiskStatsOverContext is like GetDiskStatsOver but returns ctx.Err()
+// as soon as the context is cancelled or its deadline expires.
+func GetDiskStatsOverContext(ctx context.Context, d time.Duration) ([]DiskAvgStats, error) {
+	return sampleOverContext(ctx, d, getDiskRawStats, getDiskAvgStats)
+}
+
+// GetDiskStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the series of n IO averages between them.
+func GetDiskStatsSampleN(n int, interval time.Duration) ([][]DiskAvgStats, error) {
+	return getDiskStatsSampleN(n, interval)
+}
+
 // GetSockStats returns the socket statistics of the system.
 func GetSockStats() (SockStats, error) {
 	return getSockStats()
@@ -98,7 +179,60 @@ func GetProcAvgStats(firstSample ProcRawStats, secondSample ProcRawStats) (ProcA
 }
 
 // GetProcStatsInterval returns the processes stats average between 2 samples
-// where the sample interval is passed as an argument (in seconds).
+// where the sample interval is passed as an argument (in seconds). See
+// GetProcStatsOver for sub-second intervals.
 func GetProcStatsInterval(interval int64) (ProcAvgStats, error) {
 	return getProcStatsInterval(interval)
 }
+
+// GetProcStatsIntervalContext is like GetProcStatsInterval but returns
+// ctx.Err() as soon as the context is cancelled or its deadline expires.
+func GetProcStatsIntervalContext(ctx context.Context, interval int64) (ProcAvgStats, error) {
+	return GetProcStatsOverContext(ctx, time.Duration(interval)*time.Second)
+}
+
+// GetProcStatsOver returns the processes stats average between 2 samples
+// taken d apart. Sub-second durations (e.g. 250ms) are supported.
+func GetProcStatsOver(d time.Duration) (ProcAvgStats, error) {
+	return getProcStatsOver(d)
+}
+
+// GetProcStatsOverContext is like GetProcStatsOver but returns ctx.Err()
+// as soon as the context is cancelled or its deadline expires.
+func GetProcStatsOverContext(ctx context.Context, d time.Duration) (ProcAvgStats, error) {
+	return sampleOverContext(ctx, d, getProcRawStats, getProcAvgStats)
+}
+
+// GetProcStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the series of n processes stats averages between them.
+func GetProcStatsSampleN(n int, interval time.Duration) ([]ProcAvgStats, error) {
+	return getProcStatsSampleN(n, interval)
+}
+
+// GetSnapshot returns all the raw statistics of the system taken at the
+// moment the function is called. If some collectors fail or time out (see
+// SetCollectorTimeout) the snapshot of the others is returned along with
+// their errors (*TimeoutError for the ones that timed out).
+func GetSnapshot() (Snapshot, error) {
+	return getSnapshot()
+}
+
+// GetHealthScoreOver returns the health score of the host (0-100) with the
+// score of every dimension between 2 snapshots taken d apart, with the given
+// weights (see DefaultHealthWeights and HealthScore).
+func GetHealthScoreOver(d time.Duration, weights HealthWeights) (HostHealth, error) {
+	return getHealthScoreOver(d, weights)
+}
+
+// Compare returns a report of the rate changes (CPU, network, disk IO,
+// processes and memory) between 2 snapshots, where a is the older one.
+func Compare(a Snapshot, b Snapshot) (Comparison, error) {
+	return compare(a, b)
+}
+
+// GetCollectorsHealth returns the health of the collectors of the snapshots
+// (# of collections and errors, duration and bytes parsed by the last
+// collection, time of the last success) since the process started.
+func GetCollectorsHealth() map[string]CollectorHealth {
+	return getCollectorsHealth()
+}
@@ -0,0 +1,558 @@
+// +build linux
+
+package sysstats
+
+import (
+	"time"
+)
+
+// Public API (linux only)
+
+// GetBootTime returns the time the system booted.
+func GetBootTime() (time.Time, error) {
+	return getBootTime()
+}
+
+// GetCpuSinceBoot interprets a CpusRawStats sample as the % CPU usage since
+// boot, giving the "lifetime" split of the CPU time.
+func GetCpuSinceBoot(sample CpusRawStats) (CpusAvgStats, error) {
+	return cpuSinceBoot(sample)
+}
+
+// GetDiskLifetime returns the total bytes read and written per disk since boot
+// for the given DiskRawStats sample.
+func GetDiskLifetime(sample []DiskRawStats) ([]DiskLifetime, error) {
+	uptime, err := uptimeDuration()
+	if err != nil {
+		return nil, err
+	}
+	return diskLifetime(sample, uptime), nil
+}
+
+// GetNetLifetime returns the total bytes received and transmitted per network
+// interface since boot for the given NetRawStats sample.
+func GetNetLifetime(sample NetRawStats) ([]IfaceLifetime, error) {
+	uptime, err := uptimeDuration()
+	if err != nil {
+		return nil, err
+	}
+	return netLifetime(sample, uptime), nil
+}
+
+// GetSystemState returns the discrete state of the system (network
+// interfaces up/down, disks, mount points, swap devices and online CPUs).
+func GetSystemState() (SystemState, error) {
+	return getSystemState()
+}
+
+// GetProcessMemoryDetail returns the RSS, PSS, USS, shared and swap memory of
+// a process. PSS and USS don't over-count the memory shared between forked
+// processes or shared libraries as RSS does.
+func GetProcessMemoryDetail(pid int) (ProcessMemory, error) {
+	return getProcessMemoryDetail(pid)
+}
+
+// GetProcessRawStats returns the raw stats of a process at the moment the
+// function is called.
+func GetProcessRawStats(pid int) (ProcessRawStats, error) {
+	return getProcessRawStats(pid)
+}
+
+// GetProcessAvgStats calculates the CPU usage of a process between 2 samples.
+func GetProcessAvgStats(firstSample ProcessRawStats, secondSample ProcessRawStats) (ProcessAvgStats, error) {
+	return getProcessAvgStats(firstSample, secondSample)
+}
+
+// GetProcessCpuPercent returns the CPU usage of a process, relative to the
+// total CPU time of the system, between 2 samples taken d apart.
+func GetProcessCpuPercent(pid int, d time.Duration) (ProcessAvgStats, error) {
+	return getProcessCpuPercent(pid, d)
+}
+
+// GetProcessesCpuPercent returns the CPU usage of several processes between 2
+// samples taken d apart. The processes that don't exist in both samples are
+// not returned.
+func GetProcessesCpuPercent(pids []int, d time.Duration) (map[int]ProcessAvgStats, error) {
+	return getProcessesCpuPercent(pids, d)
+}
+
+// ReadPidfile returns the PID written in a pidfile.
+func ReadPidfile(path string) (int, error) {
+	return readPidfile(path)
+}
+
+// GetProcessTreeRawStats returns the raw stats of a process and all its
+// descendants at the moment the function is called.
+func GetProcessTreeRawStats(pid int) (ProcessTreeRawStats, error) {
+	return getProcessTreeRawStats(pid)
+}
+
+// GetProcessTreeAvgStats calculates the aggregated stats of a process tree
+// between 2 samples.
+func GetProcessTreeAvgStats(firstSample ProcessTreeRawStats, secondSample ProcessTreeRawStats) (ProcessTreeAvgStats, error) {
+	return getProcessTreeAvgStats(firstSample, secondSample)
+}
+
+// GetProcessTreeStatsOver returns the CPU, memory and IO of a process and all
+// its descendants between 2 samples taken d apart.
+func GetProcessTreeStatsOver(pid int, d time.Duration) (ProcessTreeAvgStats, error) {
+	return getProcessTreeStatsOver(pid, d)
+}
+
+// GetPidfileTreeStatsOver returns the CPU, memory and IO of the process whose
+// PID is in the pidfile and all its descendants between 2 samples taken d
+// apart.
+func GetPidfileTreeStatsOver(path string, d time.Duration) (ProcessTreeAvgStats, error) {
+	return getPidfileTreeStatsOver(path, d)
+}
+
+// GetNicRawStats returns the ring sizes, the per-queue drop counters and the
+// RPS/XPS configuration of a NIC at the moment the function is called.
+func GetNicRawStats(iface string) (NicRawStats, error) {
+	return getNicRawStats(iface)
+}
+
+// GetNicAvgStats calculates the drops per second of every queue of a NIC
+// between 2 samples.
+func GetNicAvgStats(firstSample NicRawStats, secondSample NicRawStats) (NicAvgStats, error) {
+	return getNicAvgStats(firstSample, secondSample)
+}
+
+// GetNicStatsOver returns the drops per second of every queue of a NIC
+// between 2 samples taken d apart.
+func GetNicStatsOver(iface string, d time.Duration) (NicAvgStats, error) {
+	return getNicStatsOver(iface, d)
+}
+
+// GetSnmp6RawStats returns the IPv6 protocol counters (/proc/net/snmp6) at
+// the moment the function is called.
+func GetSnmp6RawStats() (Snmp6RawStats, error) {
+	return getSnmp6RawStats()
+}
+
+// GetSnmp6AvgStats calculates the IPv6 protocol counters per second between
+// 2 samples.
+func GetSnmp6AvgStats(firstSample Snmp6RawStats, secondSample Snmp6RawStats) (Snmp6AvgStats, error) {
+	return getSnmp6AvgStats(firstSample, secondSample)
+}
+
+// GetSnmp6StatsOver returns the IPv6 protocol counters per second between 2
+// samples taken d apart.
+func GetSnmp6StatsOver(d time.Duration) (Snmp6AvgStats, error) {
+	return getSnmp6StatsOver(d)
+}
+
+// GetListeningPorts returns the TCP and UDP listening sockets of the system
+// with their owner process, when it can be resolved.
+func GetListeningPorts() ([]ListeningPort, error) {
+	return getListeningPorts()
+}
+
+// GetKernelLayout returns the layout of the files whose format changed across
+// kernel versions (/proc/diskstats, /proc/loadavg, /proc/net/dev), as
+// detected on the running kernel.
+func GetKernelLayout() KernelLayout {
+	return getKernelLayout()
+}
+
+// GetDiskLatencyOver samples the disk stats every step (10 Hz if step is 0)
+// for d and returns the estimated IO latency distribution (mean, p50, p95,
+// p99 and max) of every disk with IOs.
+func GetDiskLatencyOver(d time.Duration, step time.Duration) ([]DiskLatency, error) {
+	return getDiskLatencyOver(d, step)
+}
+
+// GetCpuBurstStatsOver takes n sub-samples within d and returns the mean
+// and the max busy % of every CPU, so a short saturation burst isn't hidden
+// by the mean of a long interval.
+func GetCpuBurstStatsOver(d time.Duration, n int) (CpusBurstStats, error) {
+	return getCpuBurstStatsOver(d, n)
+}
+
+// GetHypervisor returns the hypervisor the system runs on (kvm, xen,
+// vmware, hyperv,...), "unknown" if it's virtualized but the hypervisor
+// can't be identified, or an empty string on bare metal.
+func GetHypervisor() string {
+	return getHypervisor()
+}
+
+// GetVmContention returns the CPU time stolen by the hypervisor between 2
+// samples taken d apart, flagging the VM as contended when the steal % is
+// above DefaultStealThreshold.
+func GetVmContention(d time.Duration) (VmContention, error) {
+	return getVmContention(d)
+}
+
+// GetTopology returns the physical package, core and SMT siblings of every
+// online CPU.
+func GetTopology() (Topology, error) {
+	return getTopology()
+}
+
+// GetCpuStatsByPackage aggregates the CPU stats (e.g. the result of
+// GetCpuStatsOver) by physical package (package0, package1,...).
+func GetCpuStatsByPackage(cpusAvgStats CpusAvgStats) (CpusAvgStats, error) {
+	return getCpuStatsByPackage(cpusAvgStats)
+}
+
+// GetCpuStatsByCore aggregates the CPU stats (e.g. the result of
+// GetCpuStatsOver) by physical core, merging the SMT siblings
+// (package0-core0, package0-core1,...).
+func GetCpuStatsByCore(cpusAvgStats CpusAvgStats) (CpusAvgStats, error) {
+	return getCpuStatsByCore(cpusAvgStats)
+}
+
+// GetSmtInfo returns whether SMT (hyperthreading) is enabled and the groups
+// of sibling CPUs.
+func GetSmtInfo() (SmtInfo, error) {
+	return getSmtInfo()
+}
+
+// GetEffectiveUtilization calculates the utilization of the physical core
+// capacity from the CPU stats (e.g. the result of GetCpuStatsOver),
+// discounting the capacity of the SMT siblings to smtYield of a core
+// (DefaultSmtYield if smtYield is 0).
+func GetEffectiveUtilization(cpusAvgStats CpusAvgStats, smtYield float64) (EffectiveUtilization, error) {
+	return getEffectiveUtilization(cpusAvgStats, smtYield)
+}
+
+// GetCpuInfo returns the vendor, model, frequency, flags, topology and caches
+// of every CPU.
+func GetCpuInfo() (CpusInfo, error) {
+	return getCpuInfo()
+}
+
+// GetCpuClasses returns the performance classes of the CPUs (big and LITTLE
+// cores of heterogeneous systems), from the fastest to the slowest.
+func GetCpuClasses() ([]CpuClass, error) {
+	return getCpuClasses()
+}
+
+// GetCpuStatsByClass aggregates the CPU stats (e.g. the result of
+// GetCpuStatsOver) by performance class (big, little,...).
+func GetCpuStatsByClass(cpusAvgStats CpusAvgStats) (CpusAvgStats, error) {
+	return getCpuStatsByClass(cpusAvgStats)
+}
+
+// GetCpusTemperature returns the temperature of the physical core and
+// package of every CPU (cpu0, cpu1,...), so it can be correlated with the
+// CPU stats. Only the coretemp and k10temp sensors are supported.
+func GetCpusTemperature() (CpusTemperature, error) {
+	return getCpusTemperature()
+}
+
+// GetCommandsStatsOver returns the processes aggregated by command name
+// (count, threads, memory and CPU share between 2 samples taken d apart).
+func GetCommandsStatsOver(d time.Duration) (CommandsStats, error) {
+	return getCommandsStatsOver(d)
+}
+
+// GetProcLimits returns the # of processes and threads of the system against
+// kernel.pid_max and kernel.threads-max, with the % used of each limit.
+func GetProcLimits() (ProcLimits, error) {
+	return getProcLimits()
+}
+
+// GetSchedRawStats returns the scheduler raw stats (time on the CPU and
+// waiting on a runqueue) of a process at the moment the function is called.
+func GetSchedRawStats(pid int) (SchedRawStats, error) {
+	return getSchedRawStats(pid)
+}
+
+// GetSchedAvgStats calculates the scheduling delay of a process between 2
+// samples.
+func GetSchedAvgStats(firstSample SchedRawStats, secondSample SchedRawStats) (SchedAvgStats, error) {
+	return getSchedAvgStats(firstSample, secondSample)
+}
+
+// GetSchedStatsOver returns the scheduling delay (time waiting on a runqueue
+// per second) of several processes between 2 samples taken d apart.
+func GetSchedStatsOver(pids []int, d time.Duration) (map[int]SchedAvgStats, error) {
+	return getSchedStatsOver(pids, d)
+}
+
+// GetBlockedTasks returns the tasks in uninterruptible sleep (D state) with
+// the kernel function they are waiting in, turning ProcStats.Blocked into a
+// list of culprits.
+func GetBlockedTasks() ([]BlockedTask, error) {
+	return getBlockedTasks()
+}
+
+// GetWritebackStats returns the dirty page and writeback pressure (meminfo,
+// vm.dirty_* sysctls and backing devices) at the moment the function is
+// called.
+func GetWritebackStats() (WritebackStats, error) {
+	return getWritebackStats()
+}
+
+// GetWritebackRawStats returns the dirty and writeback sizes and the # of
+// pages dirtied and written back since boot.
+func GetWritebackRawStats() (WritebackRawStats, error) {
+	return getWritebackRawStats()
+}
+
+// GetWritebackRateStats calculates the rates at which pages are dirtied and
+// written back between 2 samples.
+func GetWritebackRateStats(firstSample WritebackRawStats, secondSample WritebackRawStats) (WritebackRateStats, error) {
+	return getWritebackRateStats(firstSample, secondSample)
+}
+
+// GetWritebackRatesOver returns the rates at which pages are dirtied and
+// written back between 2 samples taken d apart.
+func GetWritebackRatesOver(d time.Duration) (WritebackRateStats, error) {
+	return getWritebackRatesOver(d)
+}
+
+// GetTcpChurnRawStats returns the TCP connection counters (opens, failed
+// attempts and resets since boot) at the moment the function is called.
+func GetTcpChurnRawStats() (TcpChurnRawStats, error) {
+	return getTcpChurnRawStats()
+}
+
+// GetTcpChurnAvgStats calculates the TCP connection churn between 2 samples.
+func GetTcpChurnAvgStats(firstSample TcpChurnRawStats, secondSample TcpChurnRawStats) (TcpChurnAvgStats, error) {
+	return getTcpChurnAvgStats(firstSample, secondSample)
+}
+
+// GetTcpChurnStatsOver returns the TCP connections opened and reset per
+// second between 2 samples taken d apart.
+func GetTcpChurnStatsOver(d time.Duration) (TcpChurnAvgStats, error) {
+	return getTcpChurnStatsOver(d)
+}
+
+// GetNetHealth returns a summary of the network health (interface errors and
+// drops, TCP retransmits, listen overflows, conntrack usage and sockets)
+// between 2 samples taken interval apart.
+func GetNetHealth(interval time.Duration) (NetHealth, error) {
+	return getNetHealthOver(interval)
+}
+
+// GetThrashRawStats returns the swap, major fault and memory pressure
+// counters at the moment the function is called.
+func GetThrashRawStats() (ThrashRawStats, error) {
+	return getThrashRawStats()
+}
+
+// GetThrashStats calculates the memory thrash score between 2 samples with
+// the given weights (see DefaultThrashWeights).
+func GetThrashStats(firstSample ThrashRawStats, secondSample ThrashRawStats, weights ThrashWeights) (ThrashStats, error) {
+	return getThrashStats(firstSample, secondSample, weights)
+}
+
+// GetThrashStatsOver returns the memory thrash score between 2 samples taken
+// d apart with the given weights (see DefaultThrashWeights).
+func GetThrashStatsOver(d time.Duration, weights ThrashWeights) (ThrashStats, error) {
+	return getThrashStatsOver(d, weights)
+}
+
+// GetHeadroom returns the usage of every resource (CPU, memory, disk space,
+// disk IO, network, file handles, conntrack and PIDs) as a fraction of its
+// capacity. The rates are calculated over 1 second.
+func GetHeadroom() ([]ResourceHeadroom, error) {
+	return getHeadroomOver(time.Second)
+}
+
+// GetHeadroomOver returns the usage of every resource as a fraction of its
+// capacity, with the rates calculated between 2 samples taken d apart.
+func GetHeadroomOver(d time.Duration) ([]ResourceHeadroom, error) {
+	return getHeadroomOver(d)
+}
+
+// GetMounts returns the mount points of the system with the device numbers
+// they are on.
+func GetMounts() ([]MountInfo, error) {
+	return getMounts()
+}
+
+// GetMountDiskStats joins the mount points with the IO stats of their disks,
+// so the IO can be reported per mount point (e.g. /var/lib/postgresql).
+func GetMountDiskStats(diskAvgStatsArr []DiskAvgStats) ([]MountDiskStats, error) {
+	return getMountDiskStats(diskAvgStatsArr)
+}
+
+// GetMountDiskStatsOver returns the IO average of the disks of the mount
+// points between 2 samples taken d apart.
+func GetMountDiskStatsOver(d time.Duration) ([]MountDiskStats, error) {
+	return getMountDiskStatsOver(d)
+}
+
+// GetDevicesIdentity returns the persistent identifiers (WWID, by-id names,
+// file system UUID and label,...) of the block devices by kernel name, so
+// the disk stats can be correlated across reboots.
+func GetDevicesIdentity() (map[string]DeviceIdentity, error) {
+	return getDevicesIdentity()
+}
+
+// GetCapabilities probes which sources of the stats (files of other users'
+// processes, debugfs, PSI, ethtool, external commands,...) can be read under
+// the current privileges, so the metrics that will be missing, and why, can
+// be logged up front.
+func GetCapabilities() []Capability {
+	return getCapabilities()
+}
+
+// GetReclaimRawStats returns the pages scanned and reclaimed by kswapd and by
+// direct reclaim since boot.
+func GetReclaimRawStats() (ReclaimRawStats, error) {
+	return getReclaimRawStats()
+}
+
+// GetReclaimStats calculates the reclaim rates, the reclaim efficiency and
+// whether direct reclaim happened between 2 samples.
+func GetReclaimStats(firstSample ReclaimRawStats, secondSample ReclaimRawStats) (ReclaimStats, error) {
+	return getReclaimStats(firstSample, secondSample)
+}
+
+// GetReclaimStatsOver returns the reclaim activity between 2 samples taken d
+// apart.
+func GetReclaimStatsOver(d time.Duration) (ReclaimStats, error) {
+	return getReclaimStatsOver(d)
+}
+
+// GetWatermarkStats returns the free memory of every memory zone compared to
+// its min, low and high watermarks, and how close the closest zone is to
+// direct reclaim.
+func GetWatermarkStats() (WatermarkStats, error) {
+	return getWatermarkStats()
+}
+
+// GetNamespaces returns the distinct PID, network, mount, UTS, IPC, user and
+// cgroup namespaces in use with the # of processes in each, which shows the
+// containers running on the host.
+func GetNamespaces() ([]Namespace, error) {
+	return getNamespaces()
+}
+
+// GetNetStableRawStats returns the raw statistics of the network interfaces
+// keyed by their MAC address or interface index instead of their name, with
+// the name and alias attached as metadata.
+func GetNetStableRawStats() (NetStableRawStats, error) {
+	return getNetStableRawStats()
+}
+
+// GetNetStableAvgStats calculates the network traffic average between 2
+// samples by stable key, so the interfaces renamed between the samples are
+// still averaged.
+func GetNetStableAvgStats(firstSample NetStableRawStats, secondSample NetStableRawStats) (NetStableAvgStats, error) {
+	return getNetStableAvgStats(firstSample, secondSample)
+}
+
+// GetNetStableStatsOver returns the network traffic average by stable key
+// between 2 samples taken d apart.
+func GetNetStableStatsOver(d time.Duration) (NetStableAvgStats, error) {
+	return getNetStableStatsOver(d)
+}
+
+// GetIpOctetsRawStats returns the bytes counted at the IP layer (IPv4 and
+// IPv6) and by all the network interfaces since boot.
+func GetIpOctetsRawStats() (IpOctetsRawStats, error) {
+	return getIpOctetsRawStats()
+}
+
+// GetIpOctetsStats calculates the IP and interface bytes per second between 2
+// samples, and the gaps between them that reveal the traffic dropped between
+// the link and the IP layers.
+func GetIpOctetsStats(firstSample IpOctetsRawStats, secondSample IpOctetsRawStats) (IpOctetsStats, error) {
+	return getIpOctetsStats(firstSample, secondSample)
+}
+
+// GetIpOctetsStatsOver returns the IP and interface bytes per second between
+// 2 samples taken d apart.
+func GetIpOctetsStatsOver(d time.Duration) (IpOctetsStats, error) {
+	return getIpOctetsStatsOver(d)
+}
+
+// GetFirewallRawStats returns the packets and bytes of the named nftables
+// counter objects by family/table/name. It runs nft, so it fails when the
+// external commands are disabled (see SetExecDisabled).
+func GetFirewallRawStats() (FirewallRawStats, error) {
+	return getFirewallRawStats()
+}
+
+// GetFirewallAvgStats calculates the packets and bytes per second of the
+// named nftables counters between 2 samples.
+func GetFirewallAvgStats(firstSample FirewallRawStats, secondSample FirewallRawStats) (map[string]FirewallCounterStats, error) {
+	return getFirewallAvgStats(firstSample, secondSample)
+}
+
+// GetFirewallStatsOver returns the packets and bytes per second of the named
+// nftables counters between 2 samples taken d apart.
+func GetFirewallStatsOver(d time.Duration) (map[string]FirewallCounterStats, error) {
+	return getFirewallStatsOver(d)
+}
+
+// GetTunnelStats returns the state and counters of the tunnel interfaces
+// (WireGuard, tun/tap, GRE, IPIP, SIT, VXLAN,...) and the handshakes and
+// transfer of the WireGuard peers (which need CAP_NET_ADMIN).
+func GetTunnelStats() ([]TunnelStats, error) {
+	return getTunnelStats()
+}
+
+// GetNumaPlacement returns, by NUMA node, the resident memory and CPU time
+// of the processes placed there, and the processes with memory on remote
+// nodes (which cause cross-node memory traffic).
+func GetNumaPlacement() (NumaPlacement, error) {
+	return getNumaPlacement()
+}
+
+// GetPressureStats returns the pressure stall information (PSI) of the cpu,
+// the memory and the IO of the whole system.
+func GetPressureStats() (PressureStats, error) {
+	return getPressureStats()
+}
+
+// GetCgroupPressureStats returns the pressure stall information (PSI) of the
+// given cgroups v2 by path relative to /sys/fs/cgroup, so the pressure can be
+// attributed to services or containers. The paths can be patterns, e.g.:
+//   GetCgroupPressureStats("system.slice/*.service", "kubepods.slice")
+func GetCgroupPressureStats(cgroups ...string) (map[string]PressureStats, error) {
+	return getCgroupPressureStats(cgroups)
+}
+
+// GetIdleRawStats returns the time spent in every idle state (C-state) of
+// the CPUs since boot and the # of times they were entered.
+func GetIdleRawStats() (IdleRawStats, error) {
+	return getIdleRawStats()
+}
+
+// GetIdleAvgStats calculates the residency % of the idle states of the CPUs
+// between 2 samples.
+func GetIdleAvgStats(firstSample IdleRawStats, secondSample IdleRawStats) (IdleAvgStats, error) {
+	return getIdleAvgStats(firstSample, secondSample)
+}
+
+// GetIdleStatsOver returns the residency % of the idle states of the CPUs
+// between 2 samples taken d apart, for power tuning or to find the latency
+// caused by deep idle states.
+func GetIdleStatsOver(d time.Duration) (IdleAvgStats, error) {
+	return getIdleStatsOver(d)
+}
+
+// GetDiskIOSizesOver traces the IOs completed by the disks for d and returns
+// the IO size histogram and the read/write mix of every disk, to tell small
+// random workloads from large sequential ones. It needs tracefs (root); the
+// # of events lost because the trace buffer filled up is returned too.
+func GetDiskIOSizesOver(d time.Duration) ([]DiskIOSizes, uint64, error) {
+	return getDiskIOSizesOver(d)
+}
+
+// GetSsdWriteRawStats returns the bytes written to every SSD as counted by
+// the host and by the device (SMART). It runs smartctl (it needs root), so
+// it fails when the external commands are disabled (see SetExecDisabled).
+func GetSsdWriteRawStats() ([]SsdWriteRawStats, error) {
+	return getSsdWriteRawStats()
+}
+
+// GetWriteAmplification calculates the estimated write amplification of
+// every SSD between 2 samples. The devices update their counters coarsely
+// (every 512KB or even every GB written), so the samples should be hours
+// apart.
+func GetWriteAmplification(firstSampleArr []SsdWriteRawStats, secondSampleArr []SsdWriteRawStats) ([]WriteAmplification, error) {
+	return getWriteAmplification(firstSampleArr, secondSampleArr)
+}
+
+// GetWriteAmplificationOver returns the estimated write amplification of
+// every SSD between 2 samples taken d apart.
+func GetWriteAmplificationOver(d time.Duration) ([]WriteAmplification, error) {
+	return getWriteAmplificationOver(d)
+}
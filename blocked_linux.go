@@ -0,0 +1,101 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BlockedTask represents a task (a thread) in uninterruptible sleep (D
+// state), which is counted in ProcStats.Blocked.
+type BlockedTask struct {
+	Pid   int      `json:"pid"`             // Process ID
+	Tid   int      `json:"tid"`             // Thread ID
+	Name  string   `json:"name"`            // Command name of the thread
+	Wchan string   `json:"wchan"`           // Kernel function where the task is waiting (if exposed)
+	Stack []string `json:"stack,omitempty"` // Kernel stack of the task (only readable by root)
+}
+
+// getBlockedTasks returns the tasks that are in uninterruptible sleep,
+// sorted by PID and TID. The list is empty when /proc/stat reports no
+// blocked processes, so it's cheap to call periodically.
+func getBlockedTasks() (blockedTasks []BlockedTask, err error) {
+	procRawStats, err := getProcRawStats()
+	if err != nil {
+		return nil, err
+	}
+	blockedTasks = []BlockedTask{}
+	if procRawStats.Blocked == 0 {
+		return blockedTasks, nil
+	}
+
+	pids, err := getPids()
+	if err != nil {
+		return nil, err
+	}
+	for _, pid := range pids {
+		tasks, err := filepath.Glob(filepath.Join("/proc", strconv.Itoa(pid), "task/[0-9]*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			content, err := ioutil.ReadFile(filepath.Join(task, "stat"))
+			if err != nil {
+				// The thread exited after listing it
+				continue
+			}
+			taskRawStats, err := parseProcessRawStats(string(content))
+			if err != nil || taskRawStats.State != "D" {
+				continue
+			}
+
+			blockedTask := BlockedTask{Pid: pid, Tid: taskRawStats.Pid, Name: taskRawStats.Name}
+			blockedTask.Wchan = readSysfsString(filepath.Join(task, "wchan"))
+			if blockedTask.Wchan == "0" {
+				// Hidden by kptr_restrict or not waiting anymore
+				blockedTask.Wchan = ""
+			}
+			blockedTask.Stack = getTaskStack(task)
+			blockedTasks = append(blockedTasks, blockedTask)
+		}
+	}
+	sort.Slice(blockedTasks, func(i, j int) bool {
+		if blockedTasks[i].Pid != blockedTasks[j].Pid {
+			return blockedTasks[i].Pid < blockedTasks[j].Pid
+		}
+		return blockedTasks[i].Tid < blockedTasks[j].Tid
+	})
+
+	return blockedTasks, nil
+}
+
+// getTaskStack returns the function names of the kernel stack of a task from
+// the file /proc/[pid]/task/[tid]/stack, which has the following format:
+//   [<0>] io_schedule+0x16/0x40
+//   [<0>] folio_wait_bit_common+0x13c/0x340
+// It returns nil when the file isn't readable (it requires CAP_SYS_ADMIN).
+func getTaskStack(task string) (stack []string) {
+	content, err := ioutil.ReadFile(filepath.Join(task, "stack"))
+	if err != nil {
+		return nil
+	}
+
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		fields := strings.Fields(string(line))
+		if len(fields) < 2 {
+			continue
+		}
+		function := fields[1]
+		if plus := strings.Index(function, "+"); plus > 0 {
+			function = function[:plus]
+		}
+		stack = append(stack, function)
+	}
+
+	return stack
+}
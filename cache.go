@@ -0,0 +1,55 @@
+package sysstats
+
+import (
+	"sync"
+	"time"
+)
+
+// Cache wraps a collector (GetSnapshot, GetDiskUsage,...) so the calls within
+// the TTL of the last collection return its result instead of reading /proc
+// again. Concurrent calls while collecting wait for the same collection, so
+// many goroutines or HTTP scrapes only read the files once per TTL. Errors
+// are not cached.
+type Cache[T any] struct {
+	ttl     time.Duration
+	collect func() (T, error)
+
+	mu      sync.Mutex
+	value   T
+	expires time.Time
+}
+
+// NewCache returns a cache of the results of collect valid for ttl, e.g.:
+//   snapshots := NewCache(500*time.Millisecond, GetSnapshot)
+//   diskUsage := NewCache(time.Minute, GetDiskUsage)
+func NewCache[T any](ttl time.Duration, collect func() (T, error)) *Cache[T] {
+	return &Cache[T]{ttl: ttl, collect: collect}
+}
+
+// Get returns the cached result if it hasn't expired, or collects a new one.
+func (c *Cache[T]) Get() (T, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Now().Before(c.expires) {
+		return c.value, nil
+	}
+
+	value, err := c.collect()
+	if err != nil {
+		return value, err
+	}
+	c.value = value
+	c.expires = time.Now().Add(c.ttl)
+
+	return value, nil
+}
+
+// Invalidate discards the cached result, so the next call to Get collects a
+// new one.
+func (c *Cache[T]) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expires = time.Time{}
+}
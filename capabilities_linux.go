@@ -0,0 +1,204 @@
+// +build linux
+
+package sysstats
+
+import (
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Capability represents whether one of the sources of the stats can be read
+// under the current privileges and kernel.
+type Capability struct {
+	Source    string `json:"source"`    // File, syscall or command probed
+	Stats     string `json:"stats"`     // Stats that are missing without it
+	Available bool   `json:"available"` // Whether the source can be read
+	Reason    string `json:"reason"`    // Why it can't be read (empty if it's available)
+}
+
+// capabilityProbe probes one of the sources of the stats. needs is the
+// privilege required to read it, if any.
+type capabilityProbe struct {
+	source string
+	stats  string
+	needs  string
+	probe  func() error
+}
+
+// capabilityProbes are the sources that can be missing depending on the
+// privileges, the kernel config or the sandbox the process runs in.
+var capabilityProbes = []capabilityProbe{
+	{"/proc/[pid]/io", "IO of the processes of other users", "root or CAP_SYS_PTRACE", func() error {
+		return probeFile("/proc/1/io")
+	}},
+	{"/proc/[pid]/fd", "open files and sockets of the processes of other users (listening ports owners)",
+		"root or CAP_SYS_PTRACE", func() error {
+			_, err := os.ReadDir("/proc/1/fd")
+			return err
+		}},
+	{"/proc/[pid]/stack", "kernel stacks of the blocked tasks", "root", func() error {
+		return probeFile("/proc/1/stack")
+	}},
+	{"/proc/[pid]/numa_maps", "NUMA placement of the processes of other users", "root or CAP_SYS_PTRACE", func() error {
+		return probeFile("/proc/1/numa_maps")
+	}},
+	{"/proc/[pid]/schedstat", "scheduler stats of the processes", "", func() error {
+		return probeFile("/proc/1/schedstat")
+	}},
+	{"/proc/pressure/memory", "memory pressure (PSI) of the thrashing score", "", func() error {
+		return probeFile("/proc/pressure/memory")
+	}},
+	{"/proc/net/snmp6", "IPv6 stats", "", func() error {
+		return probeFile("/proc/net/snmp6")
+	}},
+	{"/proc/sys/net/netfilter/nf_conntrack_count", "conntrack usage", "", func() error {
+		return probeFile("/proc/sys/net/netfilter/nf_conntrack_count")
+	}},
+	{"/sys/devices/system/cpu/cpu0/cpuidle", "idle states (C-states) residency", "", func() error {
+		_, err := os.ReadDir("/sys/devices/system/cpu/cpu0/cpuidle")
+		return err
+	}},
+	{"/sys/kernel/debug/bdi", "writeback stats per backing device", "root", func() error {
+		_, err := os.ReadDir("/sys/kernel/debug/bdi")
+		return err
+	}},
+	{"/sys/kernel/tracing", "IO size histograms of the disks", "root", func() error {
+		var err error
+		for _, dir := range tracefsDirs {
+			if _, err = os.ReadDir(filepath.Join(dir, "instances")); err == nil {
+				return nil
+			}
+		}
+		return err
+	}},
+	{"/sys/class/hwmon", "CPU temperatures", "", func() error {
+		dirs, err := filepath.Glob("/sys/class/hwmon/hwmon*")
+		if err == nil && len(dirs) == 0 {
+			return fs.ErrNotExist
+		}
+		return err
+	}},
+	{"/dev/disk/by-id", "persistent identifiers of the disks", "", func() error {
+		_, err := os.ReadDir("/dev/disk/by-id")
+		return err
+	}},
+	{"ethtool netlink", "NIC rings", "", func() error {
+		conn, err := newGenlConn()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = conn.familyId("ethtool")
+		return err
+	}},
+	{"wireguard netlink", "WireGuard peers of the tunnels", "CAP_NET_ADMIN", func() error {
+		conn, err := newGenlConn()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		_, err = conn.familyId("wireguard")
+		return err
+	}},
+	{"ethtool ioctl", "NIC driver stats (queue drops)", "", probeEthtoolIoctl},
+	{"df", "disk usage (statfs is used instead when the commands are disabled)", "", func() error {
+		_, err := runCommand("df", "-kTP")
+		return err
+	}},
+	{"smartctl", "write amplification of the SSDs", "root", func() error {
+		_, err := runCommand("smartctl", "--version")
+		return err
+	}},
+	{"nft", "nftables counters", "CAP_NET_ADMIN", func() error {
+		_, err := runCommand("nft", "-j", "list", "counters")
+		return err
+	}},
+}
+
+// probeFile checks a file can be opened and read.
+func probeFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Read(make([]byte, 1))
+
+	return err
+}
+
+// probeEthtoolIoctl checks the SIOCETHTOOL ioctl can be issued on any of the
+// network interfaces (except the loopback), getting their settings
+// (ETHTOOL_GSET). It fails if the drivers of all of them don't support it
+// (EOPNOTSUPP) or the ioctl isn't permitted (EPERM), e.g. in a sandbox.
+func probeEthtoolIoctl() error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(fd)
+
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return err
+	}
+	err = errEthtoolNotSupported
+	for _, entry := range entries {
+		if entry.Name() == "lo" {
+			continue
+		}
+		// struct ethtool_cmd
+		cmd := make([]byte, 44)
+		binary.NativeEndian.PutUint32(cmd[0:4], ethtoolGSet)
+		ioctlErr := ethtoolIoctl(fd, entry.Name(), cmd)
+		switch {
+		case ioctlErr == nil:
+			return nil
+		case errors.Is(ioctlErr, syscall.EPERM):
+			return ioctlErr
+		case !errors.Is(ioctlErr, syscall.EOPNOTSUPP):
+			err = ioctlErr
+		}
+	}
+
+	return err
+}
+
+// errEthtoolNotSupported is the error of the ethtool probe when none of the
+// network interfaces supports the ioctl.
+var errEthtoolNotSupported = errors.New("Not supported by the drivers of the network interfaces")
+
+// getCapabilities probes the sources of the stats that can be missing and
+// reports which ones can be read and why the others can't.
+func getCapabilities() (capabilities []Capability) {
+	capabilities = make([]Capability, 0, len(capabilityProbes))
+	for _, probe := range capabilityProbes {
+		capability := Capability{Source: probe.source, Stats: probe.stats, Available: true}
+		if err := probe.probe(); err != nil {
+			capability.Available = false
+			capability.Reason = capabilityReason(err, probe.needs)
+		}
+		capabilities = append(capabilities, capability)
+	}
+
+	return capabilities
+}
+
+// capabilityReason explains why a source can't be read.
+func capabilityReason(err error, needs string) string {
+	switch {
+	case errors.Is(err, fs.ErrPermission) && needs != "":
+		return "Permission denied, it needs " + needs
+	case errors.Is(err, fs.ErrPermission):
+		return "Permission denied"
+	case errors.Is(err, fs.ErrNotExist):
+		return "Not supported by the kernel or not mounted"
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,333 @@
+package sysstats
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics represents the metrics of a collector flattened in a map where the
+// keys are the metric names, e.g. mem.memused or cpu.cpu0.user.
+type Metrics map[string]float64
+
+// Collector is a source of metrics that can be registered in a Registry. The
+// names of its metrics should start with its name (e.g. app.requests for a
+// collector named app), so they don't clash with the ones of the others. It
+// can also implement CounterCollector to type its metrics.
+type Collector interface {
+	Name() string                                 // Name of the collector, unique in a Registry
+	Collect(ctx context.Context) (Metrics, error) // Collects the metrics
+}
+
+// familyPrefixes are the prefixes of the metrics of the families of a
+// Snapshot whose prefix isn't the name of their collector.
+var familyPrefixes = map[string]string{
+	`loadavg`: `load.`,
+}
+
+// familyRates calculate the rates of the families of a Snapshot that are
+// counters between 2 snapshots.
+var familyRates = map[string]func(a Snapshot, b Snapshot) (Comparison, error){
+	`cpu`: func(a Snapshot, b Snapshot) (c Comparison, err error) {
+		c.Cpu, err = getCpuAvgStats(a.Cpu, b.Cpu)
+		return c, err
+	},
+	`net`: func(a Snapshot, b Snapshot) (c Comparison, err error) {
+		c.Net, err = getNetAvgStats(a.Net, b.Net)
+		return c, err
+	},
+	`disk`: func(a Snapshot, b Snapshot) (c Comparison, err error) {
+		c.Disk, err = getDiskAvgStats(a.Disk, b.Disk)
+		return c, err
+	},
+	`proc`: func(a Snapshot, b Snapshot) (c Comparison, err error) {
+		c.Proc, err = getProcAvgStats(a.Proc, b.Proc)
+		return c, err
+	},
+}
+
+// builtinCollector is the Collector of one of the families of a Snapshot.
+// The families that are counters (cpu, net, disk and proc) report the rates
+// since the previous collection, so their first collection only returns the
+// gauges along with ErrWarmingUp.
+type builtinCollector struct {
+	collector snapshotCollector
+	prefix    string
+
+	mu       sync.Mutex
+	previous *Snapshot
+}
+
+// Name returns the name of the collector.
+func (c *builtinCollector) Name() string {
+	return c.collector.name
+}
+
+// Collect collects the family of the collector and returns its metrics. It
+// returns ctx.Err() as soon as the context is done, leaving the collection
+// running in the background.
+func (c *builtinCollector) Collect(ctx context.Context) (metrics Metrics, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	family := Snapshot{}
+	go func() {
+		done <- withTimeout(c.collector.name, func() error {
+			family.Time = time.Now()
+			return c.collector.collect(&family, deviceFilter{})
+		})
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err = <-done:
+	}
+	if errors.Is(err, ErrNotSupported) {
+		return Metrics{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metrics = Metrics{}
+	for key, value := range family.Metrics() {
+		if strings.HasPrefix(key, c.prefix) {
+			metrics[key] = value
+		}
+	}
+
+	rates, ok := familyRates[c.collector.name]
+	if !ok {
+		return metrics, nil
+	}
+
+	c.mu.Lock()
+	previous := c.previous
+	c.previous = &family
+	c.mu.Unlock()
+	if previous == nil {
+		return metrics, ErrWarmingUp
+	}
+	if !family.Time.After(previous.Time) {
+		return metrics, nil
+	}
+
+	comparison, err := rates(*previous, family)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range comparison.Metrics() {
+		if strings.HasPrefix(key, c.prefix) {
+			metrics[key] = value
+		}
+	}
+
+	return metrics, nil
+}
+
+// Counters adds the counters of the family of the collector in the snapshot
+// (see CounterRegistry).
+func (c *builtinCollector) Counters(snapshot Snapshot, add func(name string, counter Counter)) {
+	if source, ok := familyCounters[c.collector.name]; ok {
+		source(snapshot, add)
+	}
+}
+
+// BuiltinCollectors returns new instances of the collectors of the families
+// of a Snapshot (loadavg, mem, cpu, net, disk, diskusage, sock, file and
+// proc), the ones a Registry has by default.
+func BuiltinCollectors() []Collector {
+	collectors := make([]Collector, 0, len(snapshotCollectors))
+	for _, collector := range snapshotCollectors {
+		prefix, ok := familyPrefixes[collector.name]
+		if !ok {
+			prefix = collector.name + `.`
+		}
+		collectors = append(collectors, &builtinCollector{collector: collector, prefix: prefix})
+	}
+
+	return collectors
+}
+
+// Registry represents a set of collectors whose metrics are collected
+// together. The collectors can be disabled and enabled again, and custom
+// ones registered along the built-in ones. The snapshots of its collectors
+// are exposed as typed counters by its CounterRegistry.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+	disabled   map[string]bool
+}
+
+// NewRegistry returns a new Registry with the built-in collectors (see
+// BuiltinCollectors) registered and enabled.
+func NewRegistry() *Registry {
+	return &Registry{collectors: BuiltinCollectors(), disabled: map[string]bool{}}
+}
+
+// defaultRegistry is the registry of the snapshots.
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry of the collectors of the snapshots
+// (GetSnapshot, Sampler, Agent, Recorder, Handler, and the Monitor without
+// Registry): the families of the built-in collectors disabled or
+// unregistered are left out of them, and the metrics of the custom
+// collectors registered are in Snapshot.Custom, e.g.:
+//   sysstats.DefaultRegistry().Disable(`diskusage`)
+//   sysstats.DefaultRegistry().Register(appCollector)
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds a collector to the registry, enabled. It fails if there's
+// already a collector with the same name.
+func (r *Registry) Register(collector Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.index(collector.Name()) >= 0 {
+		return errors.New("Collector " + collector.Name() + " already registered")
+	}
+	r.collectors = append(r.collectors, collector)
+
+	return nil
+}
+
+// Unregister removes a collector from the registry. It fails if there's no
+// collector with the given name.
+func (r *Registry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.index(name)
+	if i < 0 {
+		return errors.New("Unknown collector " + name)
+	}
+	r.collectors = append(r.collectors[:i:i], r.collectors[i+1:]...)
+	delete(r.disabled, name)
+
+	return nil
+}
+
+// Enable enables a collector disabled with Disable.
+func (r *Registry) Enable(name string) error {
+	return r.setDisabled(name, false)
+}
+
+// Disable disables a collector, whose metrics aren't collected until it's
+// enabled again.
+func (r *Registry) Disable(name string) error {
+	return r.setDisabled(name, true)
+}
+
+// setDisabled disables or enables a collector.
+func (r *Registry) setDisabled(name string, disabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.index(name) < 0 {
+		return errors.New("Unknown collector " + name)
+	}
+	if disabled {
+		r.disabled[name] = true
+	} else {
+		delete(r.disabled, name)
+	}
+
+	return nil
+}
+
+// Names returns the names of the collectors of the registry in the order
+// they were registered.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.collectors))
+	for _, collector := range r.collectors {
+		names = append(names, collector.Name())
+	}
+
+	return names
+}
+
+// Enabled tells if the collector with the given name is registered and
+// enabled.
+func (r *Registry) Enabled(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.index(name) >= 0 && !r.disabled[name]
+}
+
+// index returns the position of the collector with the given name, or -1.
+func (r *Registry) index(name string) int {
+	for i, collector := range r.collectors {
+		if collector.Name() == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// enabledCollectors returns the enabled collectors in the order they were
+// registered.
+func (r *Registry) enabledCollectors() []Collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, collector := range r.collectors {
+		if !r.disabled[collector.Name()] {
+			collectors = append(collectors, collector)
+		}
+	}
+
+	return collectors
+}
+
+// Collect runs the enabled collectors concurrently (see
+// SetCollectorConcurrency) and returns all their metrics. A collector that
+// fails doesn't stop the others: the metrics are returned without its ones
+// along with the errors of all the failed collectors (see CollectorError).
+// The first collection of the built-in collectors of counters only returns
+// their gauges, and errors.Is(err, ErrWarmingUp) is true.
+func (r *Registry) Collect(ctx context.Context) (metrics Metrics, err error) {
+	collectors := r.enabledCollectors()
+
+	collected := make([]Metrics, len(collectors))
+	errs := make([]error, len(collectors))
+	workers := make(chan struct{}, getCollectorConcurrency())
+	wg := sync.WaitGroup{}
+	for i, collector := range collectors {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			m, err := collector.Collect(ctx)
+			collected[i] = m
+			if err != nil {
+				errs[i] = &CollectorError{Collector: collector.Name(), Err: err}
+			}
+		}()
+	}
+	wg.Wait()
+
+	metrics = Metrics{}
+	for _, m := range collected {
+		for key, value := range m {
+			metrics[key] = value
+		}
+	}
+
+	return metrics, errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package sysstats
+
+import (
+	"context"
+	"testing"
+)
+
+// testCollector is a custom collector returning fixed metrics.
+type testCollector struct {
+	name    string
+	metrics Metrics
+}
+
+func (c testCollector) Name() string { return c.name }
+
+func (c testCollector) Collect(ctx context.Context) (Metrics, error) { return c.metrics, nil }
+
+// testCounterCollector is a custom collector with typed counters.
+type testCounterCollector struct {
+	testCollector
+}
+
+func (c testCounterCollector) Counters(snapshot Snapshot, add func(name string, counter Counter)) {
+	add(`queue.processed`, counter(snapshot.Custom[`queue.processed`], `jobs`))
+}
+
+func TestRegistryCounterRegistry(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Disable(`cpu`); err != nil {
+		t.Fatal(err)
+	}
+	for _, collector := range []Collector{
+		testCollector{name: `app`},
+		testCounterCollector{testCollector{name: `queue`}},
+	} {
+		if err := r.Register(collector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot := Snapshot{
+		Cpu:    CpusRawStats{`cpu`: CpuRawStats{CpuUser: 10}},
+		Mem:    MemStats{MemTotal: 1024},
+		Custom: Metrics{`app.requests`: 42, `queue.processed`: 7},
+	}
+
+	counters := r.CounterRegistry().Collect(snapshot)
+	want := map[string]Counter{
+		`mem.memtotal`:    gauge(1024, `kB`),
+		`app.requests`:    gauge(42, ``),
+		`queue.processed`: counter(7, `jobs`),
+	}
+	for name, wantCounter := range want {
+		if counters[name] != wantCounter {
+			t.Errorf("Collect()[%s] = %+v, want %+v", name, counters[name], wantCounter)
+		}
+	}
+	if _, ok := counters[`cpu.cpu.user`]; ok {
+		t.Error("Collect() has the counters of the disabled cpu collector")
+	}
+
+	// A source with the name of a collector replaces its counters
+	counterRegistry := r.CounterRegistry()
+	counterRegistry.Register(`mem`, func(s Snapshot, add func(string, Counter)) {
+		add(`mem.total`, gauge(float64(s.Mem[MemTotal]), `kB`))
+	})
+	counters = counterRegistry.Collect(snapshot)
+	if _, ok := counters[`mem.memtotal`]; ok || counters[`mem.total`].Value != 1024 {
+		t.Errorf("Collect() = %v, want mem.total instead of mem.memtotal", counters)
+	}
+}
+
+func TestRegistryCollectSnapshot(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range r.Names() {
+		if name != `file` {
+			if err := r.Disable(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := r.Register(testCollector{name: `app`, metrics: Metrics{`app.requests`: 42}}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := r.collectSnapshot(nil, deviceFilter{})
+	if err != nil && !isPartialSnapshot(err) {
+		t.Fatal(err)
+	}
+	if snapshot.Mem != nil || snapshot.Cpu != nil {
+		t.Errorf("the disabled families were collected: %+v", snapshot)
+	}
+	if _, ok := snapshot.Times[`mem`]; ok {
+		t.Error("the disabled mem collector has a time")
+	}
+	if got := snapshot.Custom[`app.requests`]; got != 42 {
+		t.Errorf("app.requests = %v, want 42", got)
+	}
+	if got := snapshot.Metrics()[`app.requests`]; got != 42 {
+		t.Errorf("Metrics()[app.requests] = %v, want 42", got)
+	}
+
+	// Only the requested collectors are collected
+	snapshot, err = r.collectSnapshot([]string{`app`}, deviceFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Times) != 1 || snapshot.Custom[`app.requests`] != 42 {
+		t.Errorf("collectSnapshot(app) times = %v, custom = %v", snapshot.Times, snapshot.Custom)
+	}
+
+	if _, err = r.collectSnapshot([]string{`unknown`}, deviceFilter{}); err == nil {
+		t.Error("collectSnapshot(unknown) succeeded, want an error")
+	}
+}
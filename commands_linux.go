@@ -0,0 +1,61 @@
+// +build linux
+
+package sysstats
+
+import (
+	"time"
+)
+
+// CommandStats represents the aggregated stats of all the processes running
+// the same command.
+type CommandStats struct {
+	Name    string  `json:"name"`            // Command name (comm, truncated to 15 characters by the kernel)
+	Count   int     `json:"count"`           // # of processes
+	Threads uint64  `json:"threads"`         // # of threads
+	Rss     uint64  `json:"rss" unit:"kB"`   // Resident set size in kilobytes
+	User    float64 `json:"user" unit:"%"`   // % of CPU time spent in user mode
+	System  float64 `json:"system" unit:"%"` // % of CPU time spent in kernel mode
+	Total   float64 `json:"total" unit:"%"`  // % of CPU time (user + system)
+}
+
+// CommandsStats represents the aggregated stats of the processes of a linux
+// system by command.
+//
+// Map keys:
+//   Name - Command name (comm).
+type CommandsStats map[string]CommandStats
+
+// getCommandsStats aggregates the processes of 2 samples of all the
+// processes by command. The count and memory are the ones of the second
+// sample, the CPU share only counts the processes present in both samples.
+func getCommandsStats(firstSample map[int]ProcessRawStats, secondSample map[int]ProcessRawStats) (commandsStats CommandsStats, err error) {
+	commandsStats = CommandsStats{}
+
+	for pid, processRawStats := range secondSample {
+		commandStats, ok := commandsStats[processRawStats.Name]
+		if !ok {
+			commandStats = CommandStats{Name: processRawStats.Name}
+		}
+		commandStats.Count++
+		commandStats.Threads += processRawStats.NumThreads
+		commandStats.Rss += processRawStats.Rss
+
+		if previous, ok := firstSample[pid]; ok {
+			processAvgStats, err := getProcessAvgStats(previous, processRawStats)
+			if err == nil {
+				commandStats.User += processAvgStats.User
+				commandStats.System += processAvgStats.System
+				commandStats.Total += processAvgStats.Total
+			}
+		}
+		commandsStats[processRawStats.Name] = commandStats
+	}
+
+	return commandsStats, nil
+}
+
+// getCommandsStatsOver returns the processes aggregated by command, with the
+// CPU share between 2 samples taken d apart.
+func getCommandsStatsOver(d time.Duration) (commandsStats CommandsStats, err error) {
+	return sampleOver(d, getAllProcessesRawStats, getCommandsStats)
+}
@@ -0,0 +1,87 @@
+package sysstats
+
+import (
+	"errors"
+	"time"
+)
+
+// MemDelta represents the change of every memory statistic between 2
+// samples (second sample - first sample), in kilobytes.
+type MemDelta map[string]int64
+
+// Comparison represents the rate changes between 2 snapshots.
+type Comparison struct {
+	From     time.Time      `json:"from"`              // Time of the first snapshot
+	To       time.Time      `json:"to"`                // Time of the second snapshot
+	Interval float64        `json:"interval" unit:"s"` // Seconds between the 2 snapshots
+	Cpu      CpusAvgStats   `json:"cpu"`               // % CPU usage between the snapshots
+	Net      NetAvgStats    `json:"net"`               // Network traffic per second
+	Disk     []DiskAvgStats `json:"disk"`              // Disk IOs per second
+	Proc     ProcAvgStats   `json:"proc"`              // Processes stats
+	Mem      MemDelta       `json:"mem"`               // Memory change
+}
+
+// compare calculates the rate changes between 2 snapshots. Only the CPUs and
+// network interfaces present in both snapshots are compared, so snapshots
+// taken before and after hardware or configuration changes can still be
+// compared.
+func compare(a Snapshot, b Snapshot) (comparison Comparison, err error) {
+	if !b.Time.After(a.Time) {
+		return Comparison{}, errors.New("The second snapshot must have been taken after the first one")
+	}
+
+	comparison = Comparison{}
+	comparison.From = a.Time
+	comparison.To = b.Time
+	comparison.Interval = b.Time.Sub(a.Time).Seconds()
+
+	// CPU
+	firstCpu, secondCpu := CpusRawStats{}, CpusRawStats{}
+	for cpuName, rawStats := range b.Cpu {
+		if firstRawStats, ok := a.Cpu[cpuName]; ok {
+			firstCpu[cpuName] = firstRawStats
+			secondCpu[cpuName] = rawStats
+		} else {
+			logDebug("skipped CPU missing in the first snapshot", "cpu", cpuName)
+		}
+	}
+	comparison.Cpu, err = getCpuAvgStats(firstCpu, secondCpu)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	// Network
+	firstNet, secondNet := NetRawStats{}, NetRawStats{}
+	for ifaceName, rawStats := range b.Net {
+		if firstRawStats, ok := a.Net[ifaceName]; ok {
+			firstNet[ifaceName] = firstRawStats
+			secondNet[ifaceName] = rawStats
+		} else {
+			logDebug("skipped network interface missing in the first snapshot", "iface", ifaceName)
+		}
+	}
+	comparison.Net, err = getNetAvgStats(firstNet, secondNet)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	// Disk IO
+	comparison.Disk, err = getDiskAvgStats(a.Disk, b.Disk)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	// Processes
+	comparison.Proc, err = getProcAvgStats(a.Proc, b.Proc)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	// Memory
+	comparison.Mem = MemDelta{}
+	for key, value := range b.Mem {
+		comparison.Mem[key] = int64(value) - int64(a.Mem[key])
+	}
+
+	return comparison, nil
+}
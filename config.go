@@ -0,0 +1,565 @@
+package sysstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config represents the configuration of a Monitor, loaded with LoadConfig.
+//
+// Example (TOML):
+//   interval = "10s"
+//   align = true
+//   collectors = ["cpu", "mem", "net", "disk"]
+//   queuesize = 100
+//   overflow = "dropoldest"
+//   [identity]
+//   role = "db"
+//   environment = "prod"
+//   [filters]
+//   ifaces = "^(eth|ens)"
+//   drop = ["^cpu\\.cpu[0-9]+\\."]
+//   derive = ["mem.memused/mem.memtotal*100 as mem.usedper"]
+//   [[sinks]]
+//   type = "statsd"
+//   addr = "localhost:8125"
+//   prefix = "host1."
+type Config struct {
+	Interval   ConfigDuration `json:"interval"`   // Time between snapshots
+	Align      bool           `json:"align"`      // Align the snapshots to the wall clock
+	Jitter     ConfigDuration `json:"jitter"`     // Max random delay of every snapshot
+	Collectors []string       `json:"collectors"` // Collectors enabled, none means all
+	Filters    ConfigFilters  `json:"filters"`    // Filters of devices and metrics
+	Sinks      []ConfigSink   `json:"sinks"`      // Outputs of the snapshots
+	QueueSize  int            `json:"queuesize"`  // # of snapshots queued per sink
+	Overflow   OverflowPolicy `json:"overflow"`   // dropoldest or block when a sink's queue is full
+	Drain      ConfigDuration `json:"drain"`      // Max time waiting for the sinks to write their queues when stopped
+	Identity   *Identity      `json:"identity"`   // Identity and labels of the host
+}
+
+// ConfigFilters represents the filters of a Config.
+type ConfigFilters struct {
+	Ifaces string   `json:"ifaces"` // Regexp of the network interfaces kept
+	Disks  string   `json:"disks"`  // Regexp of the disks kept
+	Drop   []string `json:"drop"`   // Regexps of the metrics dropped by the exporters
+	Derive []string `json:"derive"` // Derived metrics added by the exporters (see DerivedMetric)
+}
+
+// ConfigSink represents one output of a Config.
+//
+// Types and their settings:
+//   stdout   - JSON lines to the standard output
+//   file     - JSON lines appended to Path
+//   recorder - binary records appended to Path (see Recorder)
+//   http     - snapshots POSTed to URL (see Agent), Gzip
+//   statsd   - gauges sent to Addr with Prefix
+//   influx   - line protocol appended to Path
+type ConfigSink struct {
+	Name   string `json:"name"` // Name of the sink in its stats, its type by default
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	URL    string `json:"url"`
+	Addr   string `json:"addr"`
+	Prefix string `json:"prefix"`
+	Gzip   bool   `json:"gzip"`
+}
+
+// ConfigDuration is a time.Duration written in the config as a string, e.g.
+// "10s" or "500ms", or as a number of seconds.
+type ConfigDuration time.Duration
+
+// UnmarshalJSON parses the duration.
+func (d *ConfigDuration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*d = ConfigDuration(v * float64(time.Second))
+	case string:
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = ConfigDuration(duration)
+	default:
+		return errors.New("Invalid duration " + string(data))
+	}
+
+	return nil
+}
+
+// LoadConfig loads the config file at path. The format is chosen by the
+// extension: .toml, .yaml/.yml or .json. Only the subsets of TOML and YAML
+// needed to write a config are supported (tables, arrays of tables, block
+// mappings and sequences, and scalars). Unknown settings are an error. All
+// the collectors are collected every Interval: per-collector intervals
+// aren't supported (run a Monitor per interval instead).
+func LoadConfig(path string) (config *Config, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		tree, err = parseTOML(string(content))
+	case ".yaml", ".yml":
+		tree, err = parseYAML(string(content))
+	case ".json":
+		err = json.Unmarshal(content, &tree)
+	default:
+		return nil, errors.New("Unknown config format " + filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing %s: %v", path, err)
+	}
+
+	// Decode the generic tree into the Config through JSON
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	if mapping, ok := tree.(map[string]interface{}); ok {
+		if _, ok := mapping["intervals"]; ok {
+			return nil, fmt.Errorf("Error decoding %s: per-collector intervals aren't supported, all the collectors are collected every interval", path)
+		}
+	}
+	config = &Config{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(config)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding %s: %v", path, err)
+	}
+
+	return config, nil
+}
+
+// Monitor builds a ready-to-run Monitor from the config.
+func (c *Config) Monitor() (monitor *Monitor, err error) {
+	monitor = &Monitor{
+		Interval:     time.Duration(c.Interval),
+		Align:        c.Align,
+		Jitter:       time.Duration(c.Jitter),
+		Collectors:   c.Collectors,
+		QueueSize:    c.QueueSize,
+		Overflow:     c.Overflow,
+		DrainTimeout: time.Duration(c.Drain),
+		Identity:     c.Identity,
+	}
+
+	switch c.Overflow {
+	case "", OverflowDropOldest, OverflowBlock:
+	default:
+		return nil, errors.New("Unknown overflow policy " + string(c.Overflow))
+	}
+
+	known := map[string]bool{}
+	for _, name := range SnapshotCollectors() {
+		known[name] = true
+	}
+	for _, name := range c.Collectors {
+		if !known[name] {
+			return nil, errors.New("Unknown collector " + name)
+		}
+	}
+
+	if c.Filters.Ifaces != "" {
+		monitor.Ifaces, err = regexp.Compile(c.Filters.Ifaces)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.Filters.Disks != "" {
+		monitor.Disks, err = regexp.Compile(c.Filters.Disks)
+		if err != nil {
+			return nil, err
+		}
+	}
+	mapper := NewMetricMapper()
+	for _, pattern := range c.Filters.Drop {
+		err = mapper.Drop(pattern)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, definition := range c.Filters.Derive {
+		err = mapper.Derive(definition)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Close the sinks already opened if a later one fails
+	var closers []io.Closer
+	defer func() {
+		if err != nil {
+			for _, closer := range closers {
+				closer.Close()
+			}
+		}
+	}()
+	for _, sinkConfig := range c.Sinks {
+		var sink Sink
+		switch sinkConfig.Type {
+		case "stdout":
+			sink = NewJSONSink(os.Stdout)
+		case "file":
+			sink, err = NewFileSink(sinkConfig.Path)
+		case "recorder":
+			var recorder *Recorder
+			recorder, err = NewRecorder(sinkConfig.Path)
+			if err == nil {
+				recorder.Identity = c.Identity
+				sink = recorder
+			}
+		case "http":
+			sink = &Agent{URL: sinkConfig.URL, Gzip: sinkConfig.Gzip, Identity: c.Identity}
+		case "statsd":
+			var statsdSink *StatsDSink
+			statsdSink, err = NewStatsDSink(sinkConfig.Addr, sinkConfig.Prefix)
+			if err == nil {
+				statsdSink.Mapper = mapper
+				sink = statsdSink
+			}
+		case "influx":
+			var file *os.File
+			file, err = os.OpenFile(sinkConfig.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+			if err == nil {
+				closers = append(closers, file)
+				influxSink := NewInfluxSink(file)
+				influxSink.Mapper = mapper
+				sink = influxSink
+			}
+		default:
+			err = errors.New("Unknown sink type " + sinkConfig.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if closer, ok := sink.(io.Closer); ok {
+			closers = append(closers, closer)
+		}
+		monitor.Sinks = append(monitor.Sinks, sink)
+		monitor.SinkNames = append(monitor.SinkNames, sinkConfig.Name)
+	}
+
+	return monitor, nil
+}
+
+// parseTOML parses the subset of TOML used by the config files: comments,
+// key = value pairs, [tables], [[arrays of tables]], and strings, numbers,
+// booleans and single-line arrays as values.
+func parseTOML(content string) (tree map[string]interface{}, err error) {
+	tree = map[string]interface{}{}
+	current := tree
+
+	for n, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			isArray := strings.HasPrefix(line, "[[")
+			name := strings.Trim(line, "[] ")
+			if name == "" {
+				return nil, fmt.Errorf("line %d: empty table name", n+1)
+			}
+			current = tree
+			keys := strings.Split(name, ".")
+			for i, key := range keys {
+				key = strings.TrimSpace(key)
+				last := i == len(keys)-1
+				if last && isArray {
+					array, _ := current[key].([]interface{})
+					table := map[string]interface{}{}
+					current[key] = append(array, table)
+					current = table
+					break
+				}
+				switch next := current[key].(type) {
+				case map[string]interface{}:
+					current = next
+				case []interface{}:
+					// Tables inside the last element of an array of tables
+					current = next[len(next)-1].(map[string]interface{})
+				case nil:
+					table := map[string]interface{}{}
+					current[key] = table
+					current = table
+				default:
+					return nil, fmt.Errorf("line %d: %s is not a table", n+1, key)
+				}
+			}
+			continue
+		}
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		key := strings.Trim(strings.TrimSpace(line[:eq]), `"`)
+		value, err := parseConfigValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n+1, err)
+		}
+		current[key] = value
+	}
+
+	return tree, nil
+}
+
+// yamlLine is a non empty line of a YAML document.
+type yamlLine struct {
+	n      int    // Line number
+	indent int    // # of leading spaces
+	text   string // Content without indentation and comments
+}
+
+// parseYAML parses the subset of YAML used by the config files: block
+// mappings and sequences (including sequences of mappings), comments, and
+// scalars or flow sequences of scalars as values.
+func parseYAML(content string) (tree interface{}, err error) {
+	lines := make([]yamlLine, 0)
+	for n, line := range strings.Split(content, "\n") {
+		text := strings.TrimRight(stripComment(line), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", n+1)
+		}
+		lines = append(lines, yamlLine{n: n + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	tree, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[next].n)
+	}
+
+	return tree, nil
+}
+
+// parseYAMLBlock parses the block (mapping or sequence) starting at lines[i]
+// with the given indentation. It returns the index of the first line after
+// the block.
+func parseYAMLBlock(lines []yamlLine, i int, indent int) (value interface{}, next int, err error) {
+	if lines[i].text == "-" || strings.HasPrefix(lines[i].text, "- ") {
+		return parseYAMLSequence(lines, i, indent)
+	}
+
+	return parseYAMLMapping(lines, i, indent)
+}
+
+// parseYAMLSequence parses a block sequence.
+func parseYAMLSequence(lines []yamlLine, i int, indent int) (value interface{}, next int, err error) {
+	sequence := make([]interface{}, 0)
+
+	for i < len(lines) && lines[i].indent == indent && (lines[i].text == "-" || strings.HasPrefix(lines[i].text, "- ")) {
+		item := strings.TrimSpace(strings.TrimPrefix(lines[i].text, "-"))
+		switch {
+		case item == "":
+			// The item is the nested block
+			if i+1 >= len(lines) || lines[i+1].indent <= indent {
+				sequence = append(sequence, nil)
+				i++
+				continue
+			}
+			value, i, err = parseYAMLBlock(lines, i+1, lines[i+1].indent)
+		case isYAMLKey(item):
+			// The item is a mapping whose first key is on the same line as the
+			// dash: parse it as if it was indented under the dash
+			itemIndent := indent + len(lines[i].text) - len(item)
+			lines[i] = yamlLine{n: lines[i].n, indent: itemIndent, text: item}
+			value, i, err = parseYAMLMapping(lines, i, itemIndent)
+		default:
+			value, err = parseConfigValue(item)
+			i++
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		sequence = append(sequence, value)
+	}
+
+	return sequence, i, nil
+}
+
+// parseYAMLMapping parses a block mapping.
+func parseYAMLMapping(lines []yamlLine, i int, indent int) (value interface{}, next int, err error) {
+	mapping := map[string]interface{}{}
+
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		if !isYAMLKey(line.text) {
+			return nil, 0, fmt.Errorf("line %d: expected key: value", line.n)
+		}
+		colon := yamlKeyEnd(line.text)
+		key := strings.Trim(strings.TrimSpace(line.text[:colon]), `"'`)
+		rest := strings.TrimSpace(line.text[colon+1:])
+		i++
+
+		if rest != "" {
+			mapping[key], err = parseConfigValue(rest)
+			if err != nil {
+				return nil, 0, fmt.Errorf("line %d: %v", line.n, err)
+			}
+			continue
+		}
+
+		// Nested block: deeper indentation, or a sequence at the same one
+		switch {
+		case i < len(lines) && lines[i].indent > indent:
+			mapping[key], i, err = parseYAMLBlock(lines, i, lines[i].indent)
+		case i < len(lines) && lines[i].indent == indent && strings.HasPrefix(lines[i].text, "-"):
+			mapping[key], i, err = parseYAMLSequence(lines, i, indent)
+		default:
+			mapping[key] = nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return mapping, i, nil
+}
+
+// isYAMLKey returns whether the text is a "key: value" or "key:" pair.
+func isYAMLKey(text string) bool {
+	return yamlKeyEnd(text) > 0
+}
+
+// yamlKeyEnd returns the position of the colon ending the key of a
+// "key: value" pair, or -1 if the text is not a pair.
+func yamlKeyEnd(text string) int {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, `'`) {
+		end := strings.Index(text[1:], text[:1])
+		if end < 0 {
+			return -1
+		}
+		rest := text[end+2:]
+		if rest == ":" || strings.HasPrefix(rest, ": ") {
+			return end + 2
+		}
+		return -1
+	}
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return -1
+	}
+	if strings.HasSuffix(text, ":") {
+		return len(text) - 1
+	}
+
+	return strings.Index(text, ": ")
+}
+
+// stripComment removes a # comment from a line, ignoring the # inside quoted
+// strings.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// parseConfigValue parses a scalar (quoted string, boolean, number or bare
+// string) or a single-line array of scalars.
+func parseConfigValue(text string) (value interface{}, err error) {
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, errors.New("unterminated array " + text)
+		}
+		array := make([]interface{}, 0)
+		for _, item := range splitConfigArray(text[1 : len(text)-1]) {
+			value, err := parseConfigValue(item)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case strings.HasPrefix(text, `"`):
+		return strconv.Unquote(text)
+	case strings.HasPrefix(text, `'`):
+		if len(text) < 2 || !strings.HasSuffix(text, `'`) {
+			return nil, errors.New("unterminated string " + text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], `''`, `'`), nil
+	case text == "true":
+		return true, nil
+	case text == "false":
+		return false, nil
+	}
+
+	if number, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64); err == nil {
+		return number, nil
+	}
+
+	return text, nil
+}
+
+// splitConfigArray splits the items of an array by the commas that are not
+// inside quoted strings.
+func splitConfigArray(text string) (items []string) {
+	items = make([]string, 0)
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		items = append(items, last)
+	}
+
+	return items
+}
@@ -0,0 +1,220 @@
+package sysstats
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStripComment(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`interval = "10s"`, `interval = "10s"`},
+		{`interval = "10s" # every 10 seconds`, `interval = "10s" `},
+		{`# comment`, ``},
+		{`prefix = "host#1" # comment`, `prefix = "host#1" `},
+		{`prefix = 'host#1'`, `prefix = 'host#1'`},
+		{`prefix = "a\"#b"`, `prefix = "a\"#b"`},
+		{`ifaces = eth#0`, `ifaces = eth#0`},
+		{"collectors:\t# tab", "collectors:\t"},
+	}
+	for _, test := range tests {
+		if got := stripComment(test.line); got != test.want {
+			t.Errorf("stripComment(%q) = %q, want %q", test.line, got, test.want)
+		}
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name:    "scalars",
+			content: "interval = \"10s\"\nalign = true\nqueuesize = 1_000\noverflow = dropoldest\n",
+			want: map[string]interface{}{
+				`interval`: `10s`, `align`: true, `queuesize`: 1000.0, `overflow`: `dropoldest`,
+			},
+		},
+		{
+			name:    "quoting",
+			content: "\"prefix\" = 'it''s'\npath = \"/var/log/a \\\"b\\\"\"\n",
+			want:    map[string]interface{}{`prefix`: `it's`, `path`: `/var/log/a "b"`},
+		},
+		{
+			name:    "comments",
+			content: "# header\ninterval = 5 # seconds\n\n  # indented\n",
+			want:    map[string]interface{}{`interval`: 5.0},
+		},
+		{
+			name:    "lists",
+			content: "collectors = [\"cpu\", 'mem', \"a,b\"]\nempty = []\n",
+			want: map[string]interface{}{
+				`collectors`: []interface{}{`cpu`, `mem`, `a,b`}, `empty`: []interface{}{},
+			},
+		},
+		{
+			name:    "nested tables",
+			content: "[identity]\nrole = \"db\"\n[identity.labels]\nrack = \"r1\"\n",
+			want: map[string]interface{}{
+				`identity`: map[string]interface{}{
+					`role`:   `db`,
+					`labels`: map[string]interface{}{`rack`: `r1`},
+				},
+			},
+		},
+		{
+			name:    "arrays of tables",
+			content: "[[sinks]]\ntype = \"stdout\"\n[[sinks]]\ntype = \"file\"\n[sinks.options]\nsync = true\n",
+			want: map[string]interface{}{
+				`sinks`: []interface{}{
+					map[string]interface{}{`type`: `stdout`},
+					map[string]interface{}{`type`: `file`, `options`: map[string]interface{}{`sync`: true}},
+				},
+			},
+		},
+		{name: "missing equal sign", content: "interval 10s\n", wantErr: true},
+		{name: "empty table name", content: "[]\n", wantErr: true},
+		{name: "table over a value", content: "identity = 1\n[identity.labels]\n", wantErr: true},
+		{name: "unterminated array", content: "collectors = [\"cpu\"\n", wantErr: true},
+		{name: "unterminated string", content: "prefix = \"host\n", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseTOML(test.content)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: parseTOML() = %v, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parseTOML() error = %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: parseTOML() = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name:    "scalars",
+			content: "---\ninterval: 10s\nalign: true\nqueuesize: 100\n",
+			want:    map[string]interface{}{`interval`: `10s`, `align`: true, `queuesize`: 100.0},
+		},
+		{
+			name:    "quoting",
+			content: "\"prefix\": 'it''s: #1'\npath: \"a \\\"b\\\"\"\n",
+			want:    map[string]interface{}{`prefix`: `it's: #1`, `path`: `a "b"`},
+		},
+		{
+			name:    "comments",
+			content: "# header\ninterval: 5 # seconds\n\n  # indented\n",
+			want:    map[string]interface{}{`interval`: 5.0},
+		},
+		{
+			name:    "lists",
+			content: "collectors: [cpu, \"mem\"]\ndrop:\n  - \"^cpu\"\n  - '^net'\nderive:\n- a as b\n",
+			want: map[string]interface{}{
+				`collectors`: []interface{}{`cpu`, `mem`},
+				`drop`:       []interface{}{`^cpu`, `^net`},
+				`derive`:     []interface{}{`a as b`},
+			},
+		},
+		{
+			name:    "nested mappings",
+			content: "identity:\n  role: db\n  labels:\n    rack: r1\nempty:\n",
+			want: map[string]interface{}{
+				`identity`: map[string]interface{}{
+					`role`:   `db`,
+					`labels`: map[string]interface{}{`rack`: `r1`},
+				},
+				`empty`: nil,
+			},
+		},
+		{
+			name:    "sequences of mappings",
+			content: "sinks:\n  - type: stdout\n  - type: file\n    path: /tmp/a\n  -\n    type: statsd\n",
+			want: map[string]interface{}{
+				`sinks`: []interface{}{
+					map[string]interface{}{`type`: `stdout`},
+					map[string]interface{}{`type`: `file`, `path`: `/tmp/a`},
+					map[string]interface{}{`type`: `statsd`},
+				},
+			},
+		},
+		{name: "empty", content: "# nothing\n", want: map[string]interface{}{}},
+		{name: "missing colon", content: "interval 10s\n", wantErr: true},
+		{name: "bad indentation", content: "identity:\n    role: db\n  env: prod\n", wantErr: true},
+		{name: "tab indentation", content: "identity:\n\trole: db\n", wantErr: true},
+		{name: "unterminated array", content: "collectors: [cpu\n", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseYAML(test.content)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: parseYAML() = %v, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: parseYAML() error = %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: parseYAML() = %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLoadConfigRejectsUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"intervals.toml": "interval = \"10s\"\n[intervals]\ndisk = \"5m\"\n",
+		"unknown.yaml":   "interval: 10s\ncolectors: [cpu]\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded, want an error", name)
+		}
+	}
+}
+
+func TestConfigMonitorClosesSinks(t *testing.T) {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("the open files can't be counted:", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshots.json")
+	config := &Config{Sinks: []ConfigSink{{Type: `file`, Path: path}, {Type: `influx`, Path: path}, {Type: `unknown`}}}
+	if _, err := config.Monitor(); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("Monitor() error = %v, want the unknown sink type", err)
+	}
+
+	// The sinks opened before the failing one were closed
+	after, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(fds) {
+		t.Errorf("%d files open after Monitor() failed, want %d", len(after), len(fds))
+	}
+}
@@ -0,0 +1,342 @@
+package sysstats
+
+import (
+	"sort"
+	"sync"
+)
+
+// CounterType is the type of a counter of a CounterRegistry.
+type CounterType string
+
+// Types of the counters.
+const (
+	CounterGauge   CounterType = `gauge`   // Current value, e.g. memory used
+	CounterCounter CounterType = `counter` // Monotonic value since boot, e.g. bytes received
+)
+
+// Counter represents one of the values of a CounterRegistry.
+type Counter struct {
+	Value float64     `json:"value"` // Value of the counter
+	Type  CounterType `json:"type"`  // gauge or counter
+	Unit  string      `json:"unit"`  // Unit of the value (bytes, kB, ms,...), empty for plain numbers
+}
+
+// CounterSource adds the counters of one of the families of a snapshot with
+// add.
+type CounterSource func(snapshot Snapshot, add func(name string, counter Counter))
+
+// CounterCollector is a Collector whose metrics are also exposed as typed
+// counters by CounterRegistry, like the built-in collectors. The metrics of
+// the custom collectors that don't implement it are exposed as gauges
+// without unit.
+type CounterCollector interface {
+	Collector
+	// Counters adds the counters of the collector in the snapshot with add
+	Counters(snapshot Snapshot, add func(name string, counter Counter))
+}
+
+// CounterRegistry exposes the stats of the snapshots as generic counters
+// (name → value, type and unit), like the performance counters of Windows,
+// so the exporters don't depend on the Go types of each family and new
+// collectors only need to implement CounterCollector. The names follow the
+// Metrics ones, e.g.:
+//   cpu.cpu0.user, net.eth0.rxbytes, disk.sda.readios, mem.memused
+// It's a view of the collectors of a Registry: every enabled collector adds
+// its counters, and the sysstats source the health of the collectors and
+// sinks of the snapshot. Sources can be registered for other parts of the
+// snapshots, or to replace the counters of a collector.
+type CounterRegistry struct {
+	registry *Registry
+
+	mu      sync.Mutex
+	names   []string
+	sources map[string]CounterSource
+}
+
+// NewCounterRegistry returns the counter registry of the collectors of
+// DefaultRegistry.
+func NewCounterRegistry() *CounterRegistry {
+	return DefaultRegistry().CounterRegistry()
+}
+
+// CounterRegistry returns a counter registry of the collectors of the
+// registry.
+func (r *Registry) CounterRegistry() *CounterRegistry {
+	c := &CounterRegistry{registry: r, sources: map[string]CounterSource{}}
+	c.Register(`sysstats`, sysstatsCounters)
+
+	return c
+}
+
+// Register adds a source of counters, replacing the previous one with the
+// same name. A source with the name of a collector replaces its counters.
+func (r *CounterRegistry) Register(name string, source CounterSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sources[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.sources[name] = source
+}
+
+// Unregister removes a source registered with Register. The counters of a
+// collector are removed disabling it in the Registry.
+func (r *CounterRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sources[name]; !ok {
+		return
+	}
+	delete(r.sources, name)
+	for i, registered := range r.names {
+		if registered == name {
+			r.names = append(r.names[:i], r.names[i+1:]...)
+			break
+		}
+	}
+}
+
+// Sources returns the names of the enabled collectors of the Registry and
+// of the other sources registered, in the order they add their counters.
+func (r *CounterRegistry) Sources() []string {
+	names := []string{}
+	collectors := map[string]bool{}
+	for _, collector := range r.registry.enabledCollectors() {
+		names = append(names, collector.Name())
+		collectors[collector.Name()] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range r.names {
+		if !collectors[name] {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Collect returns the counters of the snapshot by name.
+func (r *CounterRegistry) Collect(snapshot Snapshot) map[string]Counter {
+	collectors := r.registry.enabledCollectors()
+	r.mu.Lock()
+	names := append([]string{}, r.names...)
+	sources := make(map[string]CounterSource, len(r.sources))
+	for name, source := range r.sources {
+		sources[name] = source
+	}
+	r.mu.Unlock()
+
+	counters := map[string]Counter{}
+	add := func(name string, counter Counter) {
+		counters[name] = counter
+	}
+	for _, collector := range collectors {
+		if source, ok := sources[collector.Name()]; ok {
+			delete(sources, collector.Name())
+			source(snapshot, add)
+		} else if counterCollector, ok := collector.(CounterCollector); ok {
+			counterCollector.Counters(snapshot, add)
+		}
+	}
+	for _, name := range names {
+		if source, ok := sources[name]; ok {
+			source(snapshot, add)
+		}
+	}
+	// The metrics of the custom collectors without counters
+	for key, value := range snapshot.Custom {
+		if _, ok := counters[key]; !ok {
+			add(key, gauge(value, ``))
+		}
+	}
+
+	return counters
+}
+
+// CollectRates returns the counters of the current snapshot with the ones of
+// type counter turned into per second gauges since the previous snapshot
+// (with the unit suffixed by /s), the same as the per second counters of
+// Windows. The counters that aren't in the previous snapshot, or that went
+// backwards (a device was reset), are skipped.
+func (r *CounterRegistry) CollectRates(previous Snapshot, current Snapshot) map[string]Counter {
+	currentCounters := r.Collect(current)
+	previousCounters := r.Collect(previous)
+	seconds := current.Time.Sub(previous.Time).Seconds()
+
+	rates := make(map[string]Counter, len(currentCounters))
+	for name, counter := range currentCounters {
+		if counter.Type != CounterCounter {
+			rates[name] = counter
+			continue
+		}
+		previousCounter, ok := previousCounters[name]
+		if !ok || seconds <= 0 || counter.Value < previousCounter.Value {
+			continue
+		}
+		unit := `/s`
+		if counter.Unit != "" {
+			unit = counter.Unit + `/s`
+		}
+		rates[name] = Counter{
+			Value: (counter.Value - previousCounter.Value) / seconds,
+			Type:  CounterGauge,
+			Unit:  unit,
+		}
+	}
+
+	return rates
+}
+
+// CounterNames returns the names of the counters sorted, e.g. for the
+// exporters to write them in a stable order.
+func CounterNames(counters map[string]Counter) []string {
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// gauge returns a gauge counter.
+func gauge(value float64, unit string) Counter {
+	return Counter{Value: value, Type: CounterGauge, Unit: unit}
+}
+
+// counter returns a monotonic counter.
+func counter(value float64, unit string) Counter {
+	return Counter{Value: value, Type: CounterCounter, Unit: unit}
+}
+
+// ifaceCounterUnits are the units of the network interface stats.
+var ifaceCounterUnits = map[string]string{
+	IfaceRxBytes: `bytes`,
+	IfaceTxBytes: `bytes`,
+	IfaceRxPkts:  `packets`,
+	IfaceTxPkts:  `packets`,
+}
+
+// familyCounters are the sources of the counters of the built-in collectors
+// by name.
+var familyCounters = map[string]CounterSource{
+	`loadavg`: func(s Snapshot, add func(string, Counter)) {
+		add(`load.avg1`, gauge(s.LoadAvg.Avg1, ``))
+		add(`load.avg5`, gauge(s.LoadAvg.Avg5, ``))
+		add(`load.avg15`, gauge(s.LoadAvg.Avg15, ``))
+	},
+	`mem`: func(s Snapshot, add func(string, Counter)) {
+		for key, value := range s.Mem {
+			add(`mem.`+key, gauge(float64(value), `kB`))
+		}
+	},
+	`cpu`: func(s Snapshot, add func(string, Counter)) {
+		for cpuName, cpuRawStats := range s.Cpu {
+			for key, value := range cpuRawStats {
+				if key == StatTime {
+					continue
+				}
+				add(`cpu.`+cpuName+`.`+key, counter(float64(value), cpuRawStatsUnit))
+			}
+		}
+	},
+	`net`: func(s Snapshot, add func(string, Counter)) {
+		for ifaceName, ifaceRawStats := range s.Net {
+			for key, value := range ifaceRawStats {
+				if key == StatTime || key == ifaceSpeedKey {
+					continue
+				}
+				add(`net.`+ifaceName+`.`+key, counter(float64(value), ifaceCounterUnits[key]))
+			}
+		}
+	},
+	`disk`: func(s Snapshot, add func(string, Counter)) {
+		for _, disk := range s.Disk {
+			prefix := `disk.` + disk.Name + `.`
+			add(prefix+`readios`, counter(float64(disk.ReadIOs), ``))
+			add(prefix+`readmerges`, counter(float64(disk.ReadMerges), ``))
+			add(prefix+`readbytes`, counter(float64(disk.sectorBytes(disk.ReadSectors)), `bytes`))
+			add(prefix+`readticks`, counter(float64(disk.ReadTicks), `ms`))
+			add(prefix+`writeios`, counter(float64(disk.WriteIOs), ``))
+			add(prefix+`writemerges`, counter(float64(disk.WriteMerges), ``))
+			add(prefix+`writebytes`, counter(float64(disk.sectorBytes(disk.WriteSectors)), `bytes`))
+			add(prefix+`writeticks`, counter(float64(disk.WriteTicks), `ms`))
+			add(prefix+`inflight`, gauge(float64(disk.InFlight), ``))
+			add(prefix+`ioticks`, counter(float64(disk.IOTicks), `ms`))
+			add(prefix+`timeinqueue`, counter(float64(disk.TimeInQueue), `ms`))
+		}
+	},
+	`diskusage`: func(s Snapshot, add func(string, Counter)) {
+		for _, diskUsage := range s.DiskUsage {
+			prefix := `diskusage.` + diskUsage.MountedOn + `.`
+			add(prefix+`total`, gauge(float64(diskUsage.Total), `kB`))
+			add(prefix+`used`, gauge(float64(diskUsage.Used), `kB`))
+			add(prefix+`available`, gauge(float64(diskUsage.Available), `kB`))
+			add(prefix+`usedper`, gauge(float64(diskUsage.UsedPer), `%`))
+		}
+	},
+	`sock`: func(s Snapshot, add func(string, Counter)) {
+		add(`sock.used`, gauge(float64(s.Sock.Used), ``))
+		add(`sock.tcpinuse`, gauge(float64(s.Sock.TcpInUse), ``))
+		add(`sock.tcporphaned`, gauge(float64(s.Sock.TcpOrphaned), ``))
+		add(`sock.tcptimewait`, gauge(float64(s.Sock.TcpTimeWait), ``))
+		add(`sock.udpinuse`, gauge(float64(s.Sock.UdpInUse), ``))
+		add(`sock.raw`, gauge(float64(s.Sock.Raw), ``))
+		add(`sock.ipfrag`, gauge(float64(s.Sock.IpFrag), ``))
+	},
+	`file`: func(s Snapshot, add func(string, Counter)) {
+		add(`file.fhalloc`, gauge(float64(s.File.FhAlloc), ``))
+		add(`file.fhfree`, gauge(float64(s.File.FhFree), ``))
+		add(`file.fhmax`, gauge(float64(s.File.FhMax), ``))
+		add(`file.inalloc`, gauge(float64(s.File.InAlloc), ``))
+		add(`file.infree`, gauge(float64(s.File.InFree), ``))
+	},
+	`proc`: func(s Snapshot, add func(string, Counter)) {
+		add(`proc.processes`, counter(float64(s.Proc.Processes), ``))
+		add(`proc.running`, gauge(float64(s.Proc.Running), ``))
+		add(`proc.blocked`, gauge(float64(s.Proc.Blocked), ``))
+		add(`proc.runqueue`, gauge(float64(s.Proc.RunQueue), ``))
+		add(`proc.total`, gauge(float64(s.Proc.Total), ``))
+	},
+	`reachability`: func(s Snapshot, add func(string, Counter)) {
+		for _, reachability := range s.Reachability {
+			if reachability.Error != "" {
+				continue
+			}
+			prefix := `reachability.` + reachability.Name + `.`
+			add(prefix+`loss`, gauge(reachability.Loss, `%`))
+			if reachability.Received > 0 {
+				add(prefix+`rttmin`, gauge(reachability.RttMin, `ms`))
+				add(prefix+`rttavg`, gauge(reachability.RttAvg, `ms`))
+				add(prefix+`rttmax`, gauge(reachability.RttMax, `ms`))
+			}
+		}
+	},
+}
+
+// sysstatsCounters is the source of the counters of the health of the
+// collectors and sinks of a snapshot (see Snapshot.Health and
+// Snapshot.Sinks).
+func sysstatsCounters(s Snapshot, add func(string, Counter)) {
+	if len(s.Times) > 0 {
+		add(`sysstats.skew`, gauge(s.Skew().Seconds(), `s`))
+	}
+	for collector, health := range s.Health {
+		prefix := `sysstats.` + collector + `.`
+		add(prefix+`collections`, counter(float64(health.Collections), ``))
+		add(prefix+`errors`, counter(float64(health.Errors), ``))
+		add(prefix+`duration`, gauge(health.Duration.Seconds(), `s`))
+		add(prefix+`bytes`, gauge(float64(health.Bytes), `bytes`))
+	}
+	for _, sinkStats := range s.Sinks {
+		prefix := `sysstats.sinks.` + sinkStats.Name + `.`
+		add(prefix+`queued`, gauge(float64(sinkStats.Queued), ``))
+		add(prefix+`written`, counter(float64(sinkStats.Written), ``))
+		add(prefix+`failed`, counter(float64(sinkStats.Failed), ``))
+		add(prefix+`dropped`, counter(float64(sinkStats.Dropped), ``))
+	}
+}
@@ -0,0 +1,98 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"time"
+)
+
+// CpuBurstStats represents the CPU usage of *one* CPU over an interval
+// measured with several sub-samples, so short saturation bursts aren't
+// hidden by the mean.
+type CpuBurstStats struct {
+	Mean     CpuAvgStats `json:"mean"`             // % CPU usage over the accepted sub-samples (weighted by their CPU time)
+	MaxBusy  float64     `json:"maxbusy" unit:"%"` // Max busy % (total) of the accepted sub-samples
+	MinBusy  float64     `json:"minbusy" unit:"%"` // Min busy % (total) of the accepted sub-samples
+	Samples  int         `json:"samples"`          // # of accepted sub-samples
+	Rejected int         `json:"rejected"`         // # of sub-samples rejected as outliers
+}
+
+// CpusBurstStats represents *all* the CPU burst statistics of a linux system.
+//
+// Map keys:
+//   Name - Name of the CPU (as it is on /proc/stat: cpu, cpu0,...).
+type CpusBurstStats map[string]CpuBurstStats
+
+// cpuTimesDelta returns the CPU times (without double counted guest time)
+// spent by every CPU between 2 samples.
+func cpuTimesDelta(firstSample CpusRawStats, secondSample CpusRawStats) (deltas CpusRawStats, err error) {
+	deltas = CpusRawStats{}
+	for cpuName, secondRawStats := range secondSample {
+		firstRawStats, ok := firstSample[cpuName]
+		if !ok {
+			continue
+		}
+		deltas[cpuName] = Delta(excludeGuest(firstRawStats), excludeGuest(secondRawStats))
+	}
+
+	return deltas, nil
+}
+
+// getCpuBurstStatsOver takes n sub-samples within d and returns the mean and
+// the max busy % of every CPU. The sub-samples with less than half the median
+// CPU time of the CPU (the sleep between them was cut short or the CPU was
+// offline) are rejected as outliers, since their few ticks give unreliable
+// percentages.
+func getCpuBurstStatsOver(d time.Duration, n int) (cpusBurstStats CpusBurstStats, err error) {
+	if n < 1 {
+		return nil, errors.New("The number of sub-samples must be at least 1")
+	}
+
+	series, err := sampleN(n, d/time.Duration(n), getCpuRawStats, cpuTimesDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	cpus := map[string][]CpuRawStats{}
+	for _, deltas := range series {
+		for cpuName, delta := range deltas {
+			cpus[cpuName] = append(cpus[cpuName], delta)
+		}
+	}
+
+	cpusBurstStats = CpusBurstStats{}
+	for cpuName, deltas := range cpus {
+		ticks := make([]float64, 0, len(deltas))
+		for _, delta := range deltas {
+			ticks = append(ticks, float64(delta[`total`]))
+		}
+		minTicks := median(ticks) / 2
+
+		burstStats := CpuBurstStats{}
+		sum := CpuRawStats{}
+		for _, delta := range deltas {
+			if float64(delta[`total`]) < minTicks || delta[`total`] == 0 {
+				burstStats.Rejected++
+				continue
+			}
+			for key, value := range delta {
+				sum[key] += value
+			}
+
+			busy := cpuPercent(delta)[`total`]
+			if burstStats.Samples == 0 || busy > burstStats.MaxBusy {
+				burstStats.MaxBusy = busy
+			}
+			if burstStats.Samples == 0 || busy < burstStats.MinBusy {
+				burstStats.MinBusy = busy
+			}
+			burstStats.Samples++
+		}
+		burstStats.Mean = cpuPercent(sum)
+
+		cpusBurstStats[cpuName] = burstStats
+	}
+
+	return cpusBurstStats, nil
+}
@@ -0,0 +1,91 @@
+// +build linux
+
+package sysstats
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// CpuClass represents a class of CPUs with the same performance (big and
+// LITTLE cores of heterogeneous systems).
+type CpuClass struct {
+	Name     string   `json:"name"`     // Name of the class (big, mid, little or classN)
+	Capacity uint64   `json:"capacity"` // Capacity of the CPUs (cpu_capacity, or max frequency in kHz)
+	Cpus     []string `json:"cpus"`     // CPUs of the class (cpu0, cpu1,...)
+}
+
+// getCpuClasses detects the performance classes of the CPUs from the
+// cpu_capacity of /sys/devices/system/cpu/cpu* or, if the kernel doesn't
+// report it, from the max frequency of cpufreq. The classes are ordered from
+// the fastest to the slowest and named big and little (and mid if there are
+// 3 of them), or class0, class1,... if there are more.
+func getCpuClasses() (cpuClasses []CpuClass, err error) {
+	dirs, err := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*")
+	if err != nil {
+		return nil, err
+	}
+
+	capacities := map[uint64][]string{}
+	for _, dir := range dirs {
+		capacity, err := strconv.ParseUint(readSysfsString(filepath.Join(dir, "cpu_capacity")), 10, 64)
+		if err != nil {
+			capacity, err = strconv.ParseUint(readSysfsString(filepath.Join(dir, "cpufreq/cpuinfo_max_freq")), 10, 64)
+			if err != nil {
+				// Unknown capacity, all the CPUs are the same
+				capacity = 0
+			}
+		}
+		cpuName := filepath.Base(dir)
+		capacities[capacity] = append(capacities[capacity], cpuName)
+	}
+
+	cpuClasses = make([]CpuClass, 0, len(capacities))
+	for capacity, cpus := range capacities {
+		sort.Slice(cpus, func(i, j int) bool {
+			return cpuNumber(cpus[i]) < cpuNumber(cpus[j])
+		})
+		cpuClasses = append(cpuClasses, CpuClass{Capacity: capacity, Cpus: cpus})
+	}
+	sort.Slice(cpuClasses, func(i, j int) bool {
+		return cpuClasses[i].Capacity > cpuClasses[j].Capacity
+	})
+
+	var names []string
+	switch len(cpuClasses) {
+	case 1:
+		names = []string{"all"}
+	case 2:
+		names = []string{"big", "little"}
+	case 3:
+		names = []string{"big", "mid", "little"}
+	}
+	for i := range cpuClasses {
+		if names != nil {
+			cpuClasses[i].Name = names[i]
+		} else {
+			cpuClasses[i].Name = "class" + strconv.Itoa(i)
+		}
+	}
+
+	return cpuClasses, nil
+}
+
+// getCpuStatsByClass aggregates the CPU stats by performance class. The keys
+// of the result are the names of the classes (see getCpuClasses).
+func getCpuStatsByClass(cpusAvgStats CpusAvgStats) (classesAvgStats CpusAvgStats, err error) {
+	cpuClasses, err := getCpuClasses()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]string{}
+	for _, cpuClass := range cpuClasses {
+		for _, cpuName := range cpuClass.Cpus {
+			groups[cpuName] = cpuClass.Name
+		}
+	}
+
+	return aggregateCpuAvgStats(cpusAvgStats, groups), nil
+}
@@ -0,0 +1,159 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IdleState represents the raw counters of an idle state (C-state) of a CPU.
+type IdleState struct {
+	Name    string `json:"name"`              // Name of the state (POLL, C1, C1E, C6,...)
+	Latency uint64 `json:"latency" unit:"us"` // Exit latency in microseconds
+	Time    uint64 `json:"time" unit:"us"`    // Microseconds spent in the state since boot
+	Usage   uint64 `json:"usage"`             // # of times the state was entered since boot
+}
+
+// IdleRawStats represents the idle states of the CPUs of a linux system.
+type IdleRawStats struct {
+	Cpus       map[string][]IdleState `json:"cpus"`                       // Idle states of every CPU (cpu0, cpu1,...), shallowest first
+	SampleTime int64                  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// IdleStateStats represents the residency of an idle state of a CPU between
+// 2 samples.
+type IdleStateStats struct {
+	Name      string  `json:"name"`               // Name of the state (POLL, C1, C1E, C6,...)
+	Latency   uint64  `json:"latency" unit:"us"`  // Exit latency in microseconds
+	Residency float64 `json:"residency" unit:"%"` // % of time spent in the state
+	Usage     float64 `json:"usage" unit:"/s"`    // # of times the state was entered per second
+}
+
+// CpuIdleStats represents the residency of the idle states of a CPU between
+// 2 samples.
+type CpuIdleStats struct {
+	Active float64          `json:"active" unit:"%"` // % of time not in any idle state (C0)
+	States []IdleStateStats `json:"states"`          // Idle states, shallowest first
+}
+
+// IdleAvgStats represents the residency of the idle states of the CPUs of a
+// linux system between 2 samples.
+//
+// Map keys:
+//   Name - Name of the CPU (as it is on /proc/stat: cpu0, cpu1,...).
+type IdleAvgStats map[string]CpuIdleStats
+
+// getIdleRawStats gets the idle states of the CPUs from the directories
+// /sys/devices/system/cpu/cpu*/cpuidle/state*. They don't exist when no
+// cpuidle driver is loaded (e.g. in most virtual machines).
+func getIdleRawStats() (idleRawStats IdleRawStats, err error) {
+	dirs, err := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpuidle/state[0-9]*")
+	if err != nil {
+		return IdleRawStats{}, err
+	}
+	if len(dirs) == 0 {
+		return IdleRawStats{}, errors.New("Couldn't find the idle states of the CPUs (no cpuidle driver)")
+	}
+
+	idleRawStats = IdleRawStats{Cpus: map[string][]IdleState{}}
+	stateNumbers := map[string][]int{}
+	for _, dir := range dirs {
+		cpuName := filepath.Base(filepath.Dir(filepath.Dir(dir)))
+		stateNumber, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "state"))
+		if err != nil {
+			continue
+		}
+
+		idleState := IdleState{Name: readSysfsString(filepath.Join(dir, "name"))}
+		values := map[string]*uint64{
+			"latency": &idleState.Latency,
+			"time":    &idleState.Time,
+			"usage":   &idleState.Usage,
+		}
+		for file, value := range values {
+			*value, err = strconv.ParseUint(readSysfsString(filepath.Join(dir, file)), 10, 64)
+			if err != nil {
+				return IdleRawStats{}, err
+			}
+		}
+
+		idleRawStats.Cpus[cpuName] = append(idleRawStats.Cpus[cpuName], idleState)
+		stateNumbers[cpuName] = append(stateNumbers[cpuName], stateNumber)
+	}
+	idleRawStats.SampleTime = time.Now().UnixNano()
+
+	// Glob sorts state10 before state2
+	for cpuName, idleStates := range idleRawStats.Cpus {
+		numbers := stateNumbers[cpuName]
+		sort.Sort(idleStatesByNumber{idleStates, numbers})
+	}
+
+	return idleRawStats, nil
+}
+
+// idleStatesByNumber sorts the idle states of a CPU by the number of their
+// directory (state0, state1,...).
+type idleStatesByNumber struct {
+	states  []IdleState
+	numbers []int
+}
+
+func (s idleStatesByNumber) Len() int           { return len(s.states) }
+func (s idleStatesByNumber) Less(i, j int) bool { return s.numbers[i] < s.numbers[j] }
+func (s idleStatesByNumber) Swap(i, j int) {
+	s.states[i], s.states[j] = s.states[j], s.states[i]
+	s.numbers[i], s.numbers[j] = s.numbers[j], s.numbers[i]
+}
+
+// getIdleAvgStats calculates the residency of the idle states of the CPUs
+// between 2 samples. The CPUs that aren't in both samples (offlined or
+// onlined in between) are left out.
+func getIdleAvgStats(firstSample IdleRawStats, secondSample IdleRawStats) (idleAvgStats IdleAvgStats, err error) {
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime)
+	if timeDelta <= 0 {
+		return nil, errors.New("The second sample must be taken after the first one")
+	}
+	microseconds := float64(timeDelta.Microseconds())
+
+	idleAvgStats = IdleAvgStats{}
+	for cpuName, secondStates := range secondSample.Cpus {
+		firstStates, ok := firstSample.Cpus[cpuName]
+		if !ok || len(firstStates) != len(secondStates) {
+			continue
+		}
+
+		cpuIdleStats := CpuIdleStats{Active: 100, States: make([]IdleStateStats, 0, len(secondStates))}
+		for i, secondState := range secondStates {
+			firstState := firstStates[i]
+			idleStateStats := IdleStateStats{Name: secondState.Name, Latency: secondState.Latency}
+			if secondState.Time >= firstState.Time {
+				idleStateStats.Residency = float64(secondState.Time-firstState.Time) * 100.00 / microseconds
+			}
+			if secondState.Usage >= firstState.Usage {
+				idleStateStats.Usage = float64(secondState.Usage-firstState.Usage) / timeDelta.Seconds()
+			}
+			cpuIdleStats.Active -= idleStateStats.Residency
+			cpuIdleStats.States = append(cpuIdleStats.States, idleStateStats)
+		}
+		// The time of the state a CPU is in is only updated when it leaves
+		// it, so the residencies can add up to a bit more than 100%
+		if cpuIdleStats.Active < 0 {
+			cpuIdleStats.Active = 0
+		}
+
+		idleAvgStats[cpuName] = cpuIdleStats
+	}
+
+	return idleAvgStats, nil
+}
+
+// getIdleStatsOver returns the residency of the idle states of the CPUs
+// between 2 samples taken d apart.
+func getIdleStatsOver(d time.Duration) (idleAvgStats IdleAvgStats, err error) {
+	return sampleOver(d, getIdleRawStats, getIdleAvgStats)
+}
@@ -0,0 +1,153 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CpuCache represents *one* cache of a CPU.
+type CpuCache struct {
+	Level    int      `json:"level"`                 // Level of the cache (1, 2, 3,...)
+	Type     string   `json:"type"`                  // Type of the cache (Data, Instruction, Unified)
+	Size     uint64   `json:"size" unit:"bytes"`     // Size in bytes
+	LineSize uint64   `json:"linesize" unit:"bytes"` // Size of a cache line in bytes
+	Ways     int      `json:"ways"`                  // Ways of associativity
+	Shared   []string `json:"shared"`                // CPUs sharing the cache (cpu0, cpu4,...), including itself
+}
+
+// CpuInfo represents the information of *one* logical CPU of a linux system.
+type CpuInfo struct {
+	Vendor   string      `json:"vendor"`         // Vendor (GenuineIntel, AuthenticAMD,...)
+	Model    string      `json:"model"`          // Model name
+	Mhz      float64     `json:"mhz" unit:"MHz"` // Current frequency in MHz
+	Flags    []string    `json:"flags"`          // Flags (or features on ARM)
+	Topology CpuTopology `json:"topology"`       // Physical topology
+	Caches   []CpuCache  `json:"caches"`         // Caches, ordered by level
+}
+
+// CpusInfo represents the information of *all* the CPUs of a linux system.
+//
+// Map keys:
+//   Name - Name of the CPU (as it is on /proc/stat: cpu0, cpu1,...).
+type CpusInfo map[string]CpuInfo
+
+// getCpuInfo gets the information of the CPUs from the file /proc/cpuinfo,
+// the topology and the caches (/sys/devices/system/cpu/cpu*/cache).
+func getCpuInfo() (cpusInfo CpusInfo, err error) {
+	cpusInfo = CpusInfo{}
+
+	cpuName := ""
+	err = scanLines("/proc/cpuinfo", func(line string) {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return
+		}
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+		if key == "processor" {
+			cpuName = "cpu" + value
+			cpusInfo[cpuName] = CpuInfo{}
+			return
+		}
+		if cpuName == "" {
+			return
+		}
+
+		cpuInfo := cpusInfo[cpuName]
+		switch key {
+		case "vendor_id", "CPU implementer":
+			cpuInfo.Vendor = value
+		case "model name", "cpu model", "Processor":
+			cpuInfo.Model = value
+		case "cpu MHz", "clock":
+			cpuInfo.Mhz, _ = strconv.ParseFloat(strings.TrimSuffix(value, "MHz"), 64)
+		case "flags", "Features":
+			cpuInfo.Flags = strings.Fields(value)
+		}
+		cpusInfo[cpuName] = cpuInfo
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(cpusInfo) == 0 {
+		return nil, errors.New("Couldn't parse /proc/cpuinfo because there aren't processors")
+	}
+
+	topology, err := getTopology()
+	if err != nil {
+		return nil, err
+	}
+	for cpuName, cpuInfo := range cpusInfo {
+		cpuInfo.Topology = topology[cpuName]
+		cpuInfo.Caches, err = getCpuCaches(cpuName)
+		if err != nil {
+			return nil, err
+		}
+		cpusInfo[cpuName] = cpuInfo
+	}
+
+	return cpusInfo, nil
+}
+
+// getCpuCaches gets the caches of a CPU from the directories
+// /sys/devices/system/cpu/<cpu>/cache/index*.
+func getCpuCaches(cpuName string) (caches []CpuCache, err error) {
+	dirs, err := filepath.Glob(filepath.Join("/sys/devices/system/cpu", cpuName, "cache/index[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+
+	caches = make([]CpuCache, 0, len(dirs))
+	for _, dir := range dirs {
+		cache := CpuCache{}
+		cache.Level, err = strconv.Atoi(readSysfsString(filepath.Join(dir, "level")))
+		if err != nil {
+			continue
+		}
+		cache.Type = readSysfsString(filepath.Join(dir, "type"))
+		cache.Size = parseCacheSize(readSysfsString(filepath.Join(dir, "size")))
+		cache.LineSize, _ = strconv.ParseUint(readSysfsString(filepath.Join(dir, "coherency_line_size")), 10, 64)
+		cache.Ways, _ = strconv.Atoi(readSysfsString(filepath.Join(dir, "ways_of_associativity")))
+		shared, err := parseCpuList(readSysfsString(filepath.Join(dir, "shared_cpu_list")))
+		if err == nil {
+			for _, cpu := range shared {
+				cache.Shared = append(cache.Shared, "cpu"+strconv.Itoa(cpu))
+			}
+		}
+		caches = append(caches, cache)
+	}
+	sort.Slice(caches, func(i, j int) bool {
+		if caches[i].Level != caches[j].Level {
+			return caches[i].Level < caches[j].Level
+		}
+		return caches[i].Type < caches[j].Type
+	})
+
+	return caches, nil
+}
+
+// parseCacheSize parses the size of a cache as it is in sysfs (32K, 2048K,
+// 1M,...) and returns it in bytes.
+func parseCacheSize(size string) uint64 {
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(size, "K"):
+		multiplier = 1024
+	case strings.HasSuffix(size, "M"):
+		multiplier = 1024 * 1024
+	case strings.HasSuffix(size, "G"):
+		multiplier = 1024 * 1024 * 1024
+	}
+
+	value, err := strconv.ParseUint(strings.TrimRight(size, "KMG"), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return value * multiplier
+}
@@ -0,0 +1,130 @@
+// +build freebsd openbsd netbsd
+
+package sysstats
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// CpuRawStats represents *one* CPU raw statistics of a BSD system.
+//
+// Map keys:
+//   User      - Time spent in user mode.
+//   Nice      - Time spent in user mode with low priority (nice).
+//   System    - Time spent in system mode.
+//   Irq       - Time servicing interrupts.
+//   Idle      - Time spent idle.
+//   Total     - Total time.
+// Note: CPU time is measured in ticks of the statistics clock (kern.clockrate).
+// The spinning time of OpenBSD is counted as System.
+type CpuRawStats map[string]uint64
+
+// cpuRawStatsUnit is the unit of the values of CpuRawStats.
+const cpuRawStatsUnit = UnitJiffies
+
+// CpuAvgStats represents *one* CPU statistics of a BSD system.
+//
+// Map keys:
+//   User      - % of CPU time spent in user mode.
+//   Nice      - % of CPU time spent in user mode with low priority (nice).
+//   System    - % of CPU time spent in system mode.
+//   Irq       - % of CPU time servicing interrupts.
+//   Idle      - % of CPU time spent idle.
+//   Total     - % of CPU time not spent idle.
+type CpuAvgStats map[string]float64
+
+// CpusRawStats represents *all* the CPU raw statistics of a BSD system.
+//
+// Map keys:
+//   Name - Name of the CPU (cpu for all of them, cpu0, cpu1,...).
+type CpusRawStats map[string]CpuRawStats
+
+// CpusAvgStats represents *all* the CPU statistics of a BSD system.
+//
+// Map keys:
+//   Name - Name of the CPU (cpu for all of them, cpu0, cpu1,...).
+type CpusAvgStats map[string]CpuAvgStats
+
+// cpuBusyKeys are the keys of the CPU time not spent idle.
+var cpuBusyKeys = []string{CpuUser, CpuNice, CpuSystem, CpuIrq}
+
+// cpuStateKeys are the keys of the CPU states in the order they are in
+// kern.cp_time (CP_USER, CP_NICE, CP_SYS, CP_INTR, CP_IDLE).
+var cpuStateKeys = []string{CpuUser, CpuNice, CpuSystem, CpuIrq, CpuIdle}
+
+// cpuRawStatsOf returns the CPU raw stats of the ticks of every CPU (in the
+// order of cpuStateKeys). The stats of all the CPUs (cpu) are the sum of the
+// stats of every CPU, like kern.cp_time and like in linux.
+func cpuRawStatsOf(ticks [][]uint64) (cpusRawStats CpusRawStats) {
+	cpusRawStats = make(CpusRawStats, len(ticks)+1)
+	allRawStats := CpuRawStats{}
+	for i, cpuTicks := range ticks {
+		rawStats := make(CpuRawStats, len(cpuStateKeys)+1)
+		for j, key := range cpuStateKeys {
+			rawStats[key] = cpuTicks[j]
+			rawStats[CpuTotal] += cpuTicks[j]
+		}
+		cpusRawStats[`cpu`+strconv.Itoa(i)] = rawStats
+
+		for key, value := range rawStats {
+			allRawStats[key] += value
+		}
+	}
+	cpusRawStats[`cpu`] = allRawStats
+
+	return cpusRawStats
+}
+
+// getCpuAvgStats calculates average between 2 CpusRawStats samples and returns
+// the % CPU usage
+func getCpuAvgStats(firstSample CpusRawStats, secondSample CpusRawStats) (cpusAvgStats CpusAvgStats, err error) {
+	cpusAvgStats = CpusAvgStats{}
+
+	for cpuName, secondRawStats := range secondSample {
+		firstRawStats, ok := firstSample[cpuName]
+		if !ok {
+			return nil, errors.New("The key " + cpuName + " doesn't exist in the first sample of CpusRawStats")
+		}
+
+		delta := Delta(firstRawStats, secondRawStats)
+		cpuStats := CpuAvgStats{}
+		total := float64(delta[CpuTotal])
+		for key, value := range delta {
+			if key == CpuTotal {
+				continue
+			}
+			if total > 0 {
+				cpuStats[key] = float64(value) * 100.00 / total
+			} else {
+				cpuStats[key] = 0
+			}
+		}
+		cpuStats[CpuTotal] = 0
+		for _, key := range cpuBusyKeys {
+			cpuStats[CpuTotal] += cpuStats[key]
+		}
+		cpusAvgStats[cpuName] = cpuStats
+	}
+
+	return cpusAvgStats, nil
+}
+
+// getCpuStatsInterval returns the % CPU utilization between 2 samples.
+// Time interval between the 2 samples is given in seconds.
+func getCpuStatsInterval(interval int64) (cpusAvgStats CpusAvgStats, err error) {
+	return getCpuStatsOver(time.Duration(interval) * time.Second)
+}
+
+// getCpuStatsOver returns the % CPU utilization between 2 samples taken d
+// apart.
+func getCpuStatsOver(d time.Duration) (cpusAvgStats CpusAvgStats, err error) {
+	return sampleOver(d, getCpuRawStats, getCpuAvgStats)
+}
+
+// getCpuStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n % CPU utilizations between them.
+func getCpuStatsSampleN(n int, interval time.Duration) (series []CpusAvgStats, err error) {
+	return sampleN(n, interval, getCpuRawStats, getCpuAvgStats)
+}
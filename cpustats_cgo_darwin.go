@@ -0,0 +1,64 @@
+// +build darwin
+
+package sysstats
+
+/*
+#include <mach/mach_host.h>
+#include <mach/mach_init.h>
+#include <mach/processor_info.h>
+#include <mach/vm_map.h>
+
+// cpu_load_info gets the ticks of every CPU. The array is allocated by the
+// kernel and must be freed with free_cpu_load_info.
+static kern_return_t cpu_load_info(natural_t *count, processor_cpu_load_info_t *info, mach_msg_type_number_t *info_count) {
+	return host_processor_info(mach_host_self(), PROCESSOR_CPU_LOAD_INFO, count,
+		(processor_info_array_t *)info, info_count);
+}
+
+static void free_cpu_load_info(processor_cpu_load_info_t info, mach_msg_type_number_t info_count) {
+	vm_deallocate(mach_task_self(), (vm_address_t)info, info_count * sizeof(integer_t));
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"strconv"
+	"unsafe"
+)
+
+// getCpuRawStats gets the CPU raw stats of an OSX system with
+// host_processor_info(PROCESSOR_CPU_LOAD_INFO). The stats of all the CPUs
+// (cpu) are the sum of the stats of every CPU, like in linux.
+func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
+	var count C.natural_t
+	var info C.processor_cpu_load_info_t
+	var infoCount C.mach_msg_type_number_t
+	ret := C.cpu_load_info(&count, &info, &infoCount)
+	if ret != C.KERN_SUCCESS {
+		return nil, errors.New("host_processor_info failed with error " + strconv.Itoa(int(ret)))
+	}
+	defer C.free_cpu_load_info(info, infoCount)
+
+	loads := unsafe.Slice((*C.struct_processor_cpu_load_info)(unsafe.Pointer(info)), int(count))
+
+	cpusRawStats = make(CpusRawStats, len(loads)+1)
+	allRawStats := CpuRawStats{}
+	for i, load := range loads {
+		rawStats := CpuRawStats{
+			CpuUser:   uint64(load.cpu_ticks[C.CPU_STATE_USER]),
+			CpuNice:   uint64(load.cpu_ticks[C.CPU_STATE_NICE]),
+			CpuSystem: uint64(load.cpu_ticks[C.CPU_STATE_SYSTEM]),
+			CpuIdle:   uint64(load.cpu_ticks[C.CPU_STATE_IDLE]),
+		}
+		rawStats[CpuTotal] = rawStats[CpuUser] + rawStats[CpuNice] + rawStats[CpuSystem] + rawStats[CpuIdle]
+		cpusRawStats[`cpu`+strconv.Itoa(i)] = rawStats
+
+		for key, value := range rawStats {
+			allRawStats[key] += value
+		}
+	}
+	cpusRawStats[`cpu`] = allRawStats
+
+	return cpusRawStats, nil
+}
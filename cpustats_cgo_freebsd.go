@@ -0,0 +1,61 @@
+// +build freebsd
+
+package sysstats
+
+/*
+#include <sys/types.h>
+#include <sys/resource.h>
+#include <sys/sysctl.h>
+#include <stdlib.h>
+
+// cp_times gets the ticks of every CPU (kern.cp_times), CPUSTATES longs per
+// CPU. The array is allocated with malloc and must be freed.
+static int cp_times(long **times, size_t *len) {
+	*len = 0;
+	if (sysctlbyname("kern.cp_times", NULL, len, NULL, 0) != 0) {
+		return -1;
+	}
+	*times = malloc(*len);
+	if (*times == NULL) {
+		return -1;
+	}
+	if (sysctlbyname("kern.cp_times", *times, len, NULL, 0) != 0) {
+		free(*times);
+		return -1;
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"strconv"
+	"unsafe"
+)
+
+// getCpuRawStats gets the CPU raw stats of a FreeBSD system from the sysctl
+// kern.cp_times, which has the states of kern.cp_time for every CPU.
+func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
+	var times *C.long
+	var length C.size_t
+	if C.cp_times(&times, &length) != 0 {
+		return nil, errors.New("Couldn't get the sysctl kern.cp_times")
+	}
+	defer C.free(unsafe.Pointer(times))
+
+	ticks := unsafe.Slice(times, int(length)/int(unsafe.Sizeof(*times)))
+	if len(ticks)%C.CPUSTATES != 0 {
+		return nil, errors.New("The sysctl kern.cp_times doesn't have " + strconv.Itoa(C.CPUSTATES) + " states per CPU")
+	}
+
+	cpus := make([][]uint64, len(ticks)/C.CPUSTATES)
+	for i := range cpus {
+		cpus[i] = make([]uint64, C.CPUSTATES)
+		for j := range cpus[i] {
+			cpus[i][j] = uint64(ticks[i*C.CPUSTATES+j])
+		}
+	}
+
+	return cpuRawStatsOf(cpus), nil
+}
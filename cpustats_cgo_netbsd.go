@@ -0,0 +1,58 @@
+// +build netbsd
+
+package sysstats
+
+/*
+#include <sys/types.h>
+#include <sys/sched.h>
+#include <sys/sysctl.h>
+#include <stdlib.h>
+
+// cp_time gets the ticks of every CPU. kern.cp_time returns the ticks of
+// every CPU, instead of their sum, when the buffer fits all of them. The
+// array is allocated with malloc and must be freed.
+static int cp_time(uint64_t **times, int *cpus) {
+	size_t len = sizeof(*cpus);
+	if (sysctlbyname("hw.ncpu", cpus, &len, NULL, 0) != 0) {
+		return -1;
+	}
+	len = sizeof(uint64_t) * CPUSTATES * *cpus;
+	*times = malloc(len);
+	if (*times == NULL) {
+		return -1;
+	}
+	if (sysctlbyname("kern.cp_time", *times, &len, NULL, 0) != 0) {
+		free(*times);
+		return -1;
+	}
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"os"
+	"unsafe"
+)
+
+// getCpuRawStats gets the CPU raw stats of a NetBSD system from the sysctl
+// kern.cp_time, one CPU after the other.
+func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
+	var times *C.uint64_t
+	var count C.int
+	if ret, err := C.cp_time(&times, &count); ret != 0 {
+		return nil, os.NewSyscallError("sysctl kern.cp_time", err)
+	}
+	defer C.free(unsafe.Pointer(times))
+
+	ticks := unsafe.Slice(times, int(count)*C.CPUSTATES)
+	cpus := make([][]uint64, int(count))
+	for i := range cpus {
+		cpus[i] = make([]uint64, C.CPUSTATES)
+		for j := range cpus[i] {
+			cpus[i][j] = uint64(ticks[i*C.CPUSTATES+j])
+		}
+	}
+
+	return cpuRawStatsOf(cpus), nil
+}
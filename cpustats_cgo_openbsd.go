@@ -0,0 +1,72 @@
+// +build openbsd
+
+package sysstats
+
+/*
+#include <sys/types.h>
+#include <sys/sched.h>
+#include <sys/sysctl.h>
+
+// ncpu gets the # of CPUs (hw.ncpu).
+static int ncpu(int *count) {
+	int mib[2] = {CTL_HW, HW_NCPU};
+	size_t len = sizeof(*count);
+	return sysctl(mib, 2, count, &len, NULL, 0);
+}
+
+// cp_time2 gets the ticks of a CPU (kern.cp_time2) in the order of
+// kern.cp_time of the other BSDs: user, nice, sys, intr and idle. The
+// spinning time is added to the system time.
+static int cp_time2(int cpu, uint64_t states[5]) {
+	int mib[3] = {CTL_KERN, KERN_CPTIME2, cpu};
+	uint64_t times[CPUSTATES];
+	size_t len = sizeof(times);
+	if (sysctl(mib, 3, times, &len, NULL, 0) != 0) {
+		return -1;
+	}
+	states[0] = times[CP_USER];
+	states[1] = times[CP_NICE];
+	states[2] = times[CP_SYS];
+#ifdef CP_SPIN
+	states[2] += times[CP_SPIN];
+#endif
+	states[3] = times[CP_INTR];
+	states[4] = times[CP_IDLE];
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"os"
+	"syscall"
+)
+
+// getCpuRawStats gets the CPU raw stats of an OpenBSD system from the sysctl
+// kern.cp_time2 of every CPU.
+func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
+	var count C.int
+	if ret, err := C.ncpu(&count); ret != 0 {
+		return nil, os.NewSyscallError("sysctl hw.ncpu", err)
+	}
+
+	cpus := make([][]uint64, int(count))
+	for i := range cpus {
+		var states [5]C.uint64_t
+		ret, err := C.cp_time2(C.int(i), &states[0])
+		if ret != 0 {
+			// The disabled CPUs (hw.smt=0) can't be read
+			if err == syscall.ENODEV {
+				cpus[i] = make([]uint64, len(states))
+				continue
+			}
+			return nil, os.NewSyscallError("sysctl kern.cp_time2", err)
+		}
+		cpus[i] = make([]uint64, len(states))
+		for j, state := range states {
+			cpus[i][j] = uint64(state)
+		}
+	}
+
+	return cpuRawStatsOf(cpus), nil
+}
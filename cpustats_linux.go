@@ -5,8 +5,6 @@ package sysstats
 import (
 	"bufio"
 	"errors"
-	"fmt"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -32,9 +30,13 @@ import (
 //               (since 2.6.33).
 //   Total     - Total time.
 // Note: CPU time is measured in units of USER_HZ (1/100ths of a second on most
-// architectures)
+// architectures). Since 2.6.24 User and Nice already include Guest and
+// GuestNice, so Total only counts the guest time once.
 type CpuRawStats map[string]uint64
 
+// cpuRawStatsUnit is the unit of the values of CpuRawStats.
+const cpuRawStatsUnit = UnitJiffies
+
 // CpuAvgStats represents *one* CPU statistics of a linux system.
 //
 // Map keys:
@@ -52,7 +54,9 @@ type CpuRawStats map[string]uint64
 //   GuestNice - % of CPU time spent running a niced guest (virtual Cpu for guest
 //               operating systems under the control of the Linux kernel)
 //               (since 2.6.33).
-//   Total     - Total time.
+//   Total     - % of CPU time not spent idle or waiting for I/O.
+// Note: User and Nice don't include the guest time, so all the keys but Total
+// add up to 100%.
 type CpuAvgStats map[string]float64
 
 // CpusRawStats represents *all* the CPU raw statistics of a linux system.
@@ -70,7 +74,7 @@ type CpusAvgStats map[string]CpuAvgStats
 // getCpuRawStats gets the CPU raw stats of a linux system from the
 // file /proc/stat
 func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
-	file, err := os.Open("/proc/stat")
+	file, err := openStatsFile("/proc/stat")
 	if err != nil {
 		return nil, err
 	}
@@ -109,39 +113,26 @@ func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
 //           Idle:1458880 Irq:806 Softirq:0 Guest:0]
 func parseCpuRawStats(stats string) (cpuName string, rawStats CpuRawStats,
 	err error) {
-	rawStats = CpuRawStats{}
+	// Sized for all the keys so the map doesn't grow while it's filled
+	rawStats = make(CpuRawStats, len(cpuStatKeys)+1)
 
 	fields := strings.Fields(stats)
+	if len(fields) < 2 {
+		return "", nil, errors.New("Couldn't parse CPU stats because there are no CPU times")
+	}
 	cpuName = fields[0]
 	for i := 1; i < len(fields); i++ {
 		stat, err := strconv.ParseUint(fields[i], 10, 64)
 		if err != nil {
 			return "", nil, err
 		}
-		rawStats[`total`] += stat
-		switch i {
-		case 1:
-			rawStats[`user`] = stat
-		case 2:
-			rawStats[`nice`] = stat
-		case 3:
-			rawStats[`system`] = stat
-		case 4:
-			rawStats[`idle`] = stat
-		case 5:
-			rawStats[`iowait`] = stat
-		case 6:
-			rawStats[`irq`] = stat
-		case 7:
-			rawStats[`softirq`] = stat
-		case 8:
-			rawStats[`steal`] = stat
-		case 9:
-			rawStats[`guest`] = stat
-		case 10:
-			rawStats[`guestnice`] = stat
+		rawStats[CpuTotal] += stat
+		if i <= len(cpuStatKeys) {
+			rawStats[cpuStatKeys[i-1]] = stat
 		}
 	}
+	// user and nice already include guest and guest_nice
+	rawStats[CpuTotal] -= rawStats[CpuGuest] + rawStats[CpuGuestNice]
 
 	return cpuName, rawStats, nil
 }
@@ -165,54 +156,73 @@ func getCpuAvgStats(firstSample CpusRawStats, secondSample CpusRawStats) (cpusAv
 			return nil, errors.New("The key " + cpuName + " doesn't exist in the first sample of CpusRawStats")
 		}
 
-		cpuStats := CpuAvgStats{}
-		timeDelta := float64(secondRawStats[`total`] - firstRawStats[`total`])
-		// Calculate average between the two samples
-		for key, secondValue := range secondRawStats {
-			// Don't calculate average if the key is 'Total'
-			if key == `Total` {
-				continue
-			}
-			avg := float64(secondValue-firstRawStats[key]) * 100.00 / timeDelta
-			avgStr := fmt.Sprintf("%3.2f", avg)
-			cpuStats[key], err = strconv.ParseFloat(avgStr, 64)
-			if err != nil {
-				return nil, err
-			}
+		delta := Delta(excludeGuest(firstRawStats), excludeGuest(secondRawStats))
+		cpusAvgStats[cpuName] = cpuPercent(delta)
+	}
 
+	return cpusAvgStats, nil
+}
+
+// cpuBusyKeys are the keys of the CPU time not spent idle or waiting for I/O.
+var cpuBusyKeys = []string{CpuUser, CpuNice, CpuSystem, CpuIrq, CpuSoftirq, CpuSteal, CpuGuest, CpuGuestNice}
+
+// excludeGuest returns a copy of the CPU raw stats where user and nice don't
+// include the guest time (they do since 2.6.24).
+func excludeGuest(rawStats CpuRawStats) CpuRawStats {
+	times := CpuRawStats{}
+	for key, value := range rawStats {
+		times[key] = value
+	}
+	for key, guestKey := range map[string]string{CpuUser: CpuGuest, CpuNice: CpuGuestNice} {
+		if times[key] >= times[guestKey] {
+			times[key] -= times[guestKey]
+		} else {
+			times[key] = 0
 		}
-		cpuTotal := 100.00 - cpuStats[`idle`]
-		cpuTotalStr := fmt.Sprintf("%3.2f", cpuTotal)
-		cpuStats[`total`], err = strconv.ParseFloat(cpuTotalStr, 64)
-		if err != nil {
-			return nil, err
+	}
+
+	return times
+}
+
+// cpuPercent converts the CPU times (without double counted guest time) to %
+// of their total time. The total % is the sum of the busy components.
+func cpuPercent(times CpuRawStats) (cpuStats CpuAvgStats) {
+	cpuStats = CpuAvgStats{}
+
+	total := float64(times[CpuTotal])
+	for key, value := range times {
+		if key == CpuTotal {
+			continue
+		}
+		if total > 0 {
+			cpuStats[key] = float64(value) * 100.00 / total
+		} else {
+			cpuStats[key] = 0
 		}
+	}
 
-		cpusAvgStats[cpuName] = cpuStats
+	cpuStats[CpuTotal] = 0
+	for _, key := range cpuBusyKeys {
+		cpuStats[CpuTotal] += cpuStats[key]
 	}
 
-	return cpusAvgStats, nil
+	return cpuStats
 }
 
 // getCpuStatsInterval returns the % CPU utilization between 2 samples.
 // Time interval between the 2 samples is given in seconds.
 func getCpuStatsInterval(interval int64) (cpusAvgStats CpusAvgStats, err error) {
-	firstSample, err := getCpuRawStats()
-	if err != nil {
-		return nil, err
-	}
-
-	time.Sleep(time.Duration(interval) * time.Second)
-
-	secondSample, err := getCpuRawStats()
-	if err != nil {
-		return nil, err
-	}
+	return getCpuStatsOver(time.Duration(interval) * time.Second)
+}
 
-	cpusAvgStats, err = getCpuAvgStats(firstSample, secondSample)
-	if err != nil {
-		return nil, err
-	}
+// getCpuStatsOver returns the % CPU utilization between 2 samples taken d
+// apart.
+func getCpuStatsOver(d time.Duration) (cpusAvgStats CpusAvgStats, err error) {
+	return sampleOver(d, getCpuRawStats, getCpuAvgStats)
+}
 
-	return cpusAvgStats, nil
+// getCpuStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n % CPU utilizations between them.
+func getCpuStatsSampleN(n int, interval time.Duration) (series []CpusAvgStats, err error) {
+	return sampleN(n, interval, getCpuRawStats, getCpuAvgStats)
 }
@@ -0,0 +1,37 @@
+// +build linux
+
+package sysstats
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseCpuRawStats(f *testing.F) {
+	for _, seed := range []string{
+		"cpu  2255110 3126 1209345 264715843 129822 0 10923 0 0 0",
+		"cpu0 1106112 1583 606236 132377561 65208 0 8457 0 0 0",
+		"cpu  1396254 7702 349913 52829706 21348 0 6173 13447 0 0",
+		"cpu 294 0 309 10612",
+		"cpu 1 2 3 4 5 6 7 8 9 10 11 12",
+		"cpu",
+		"",
+		"cpu 18446744073709551615 1",
+		"cpu -1 x",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		cpuName, rawStats, err := parseCpuRawStats(line)
+		if err != nil {
+			return
+		}
+		if cpuName == "" || strings.ContainsAny(cpuName, " \t") {
+			t.Errorf("parseCpuRawStats(%q) name = %q", line, cpuName)
+		}
+		if rawStats == nil {
+			t.Errorf("parseCpuRawStats(%q) returned nil stats without error", line)
+		}
+	})
+}
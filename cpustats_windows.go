@@ -0,0 +1,161 @@
+// +build windows
+
+package sysstats
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"time"
+	"unsafe"
+)
+
+// CpuRawStats represents *one* CPU raw statistics of a Windows system.
+//
+// Map keys:
+//   User      - Time spent in user mode.
+//   System    - Time spent in kernel mode, without the idle, interrupt and
+//               DPC time.
+//   Idle      - Time spent idle.
+//   Irq       - Time servicing hardware interrupts.
+//   Softirq   - Time servicing deferred procedure calls (DPCs).
+//   Total     - Total time.
+// Note: CPU time is measured in units of 100 nanoseconds.
+type CpuRawStats map[string]uint64
+
+// cpuRawStatsUnit is the unit of the values of CpuRawStats.
+const cpuRawStatsUnit = Unit100Nanoseconds
+
+// CpuAvgStats represents *one* CPU statistics of a Windows system.
+//
+// Map keys:
+//   User      - % of CPU time spent in user mode.
+//   System    - % of CPU time spent in kernel mode.
+//   Idle      - % of CPU time spent idle.
+//   Irq       - % of CPU time servicing hardware interrupts.
+//   Softirq   - % of CPU time servicing deferred procedure calls (DPCs).
+//   Total     - % of CPU time not spent idle.
+type CpuAvgStats map[string]float64
+
+// CpusRawStats represents *all* the CPU raw statistics of a Windows system.
+//
+// Map keys:
+//   Name - Name of the CPU (cpu for all of them, cpu0, cpu1,...).
+type CpusRawStats map[string]CpuRawStats
+
+// CpusAvgStats represents *all* the CPU statistics of a Windows system.
+//
+// Map keys:
+//   Name - Name of the CPU (cpu for all of them, cpu0, cpu1,...).
+type CpusAvgStats map[string]CpuAvgStats
+
+// cpuBusyKeys are the keys of the CPU time not spent idle.
+var cpuBusyKeys = []string{CpuUser, CpuSystem, CpuIrq, CpuSoftirq}
+
+// systemProcessorPerformanceInformation is the class of
+// NtQuerySystemInformation that returns the times of every CPU.
+const systemProcessorPerformanceInformation = 8
+
+// processorPerformance is SYSTEM_PROCESSOR_PERFORMANCE_INFORMATION. The
+// kernel time includes the idle, DPC and interrupt time.
+type processorPerformance struct {
+	IdleTime       int64
+	KernelTime     int64
+	UserTime       int64
+	DpcTime        int64
+	InterruptTime  int64
+	InterruptCount uint32
+}
+
+// getCpuRawStats gets the CPU raw stats of a Windows system with
+// NtQuerySystemInformation(SystemProcessorPerformanceInformation). The stats
+// of all the CPUs (cpu) are the sum of the stats of every CPU, like in linux.
+// Only the CPUs of the first processor group (up to 64) are reported.
+func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
+	performances := make([]processorPerformance, runtime.NumCPU())
+	var length uint32
+	status, _, _ := procNtQuerySystemInformation.Call(systemProcessorPerformanceInformation,
+		uintptr(unsafe.Pointer(&performances[0])),
+		uintptr(len(performances))*unsafe.Sizeof(performances[0]),
+		uintptr(unsafe.Pointer(&length)))
+	if status != 0 {
+		return nil, errors.New("NtQuerySystemInformation failed with status " + strconv.FormatUint(uint64(status), 16))
+	}
+	performances = performances[:int(uintptr(length)/unsafe.Sizeof(performances[0]))]
+
+	cpusRawStats = make(CpusRawStats, len(performances)+1)
+	allRawStats := CpuRawStats{}
+	for i, performance := range performances {
+		rawStats := CpuRawStats{
+			CpuUser:    uint64(performance.UserTime),
+			CpuIdle:    uint64(performance.IdleTime),
+			CpuIrq:     uint64(performance.InterruptTime),
+			CpuSoftirq: uint64(performance.DpcTime),
+		}
+		kernel := uint64(performance.KernelTime)
+		if busy := rawStats[CpuIdle] + rawStats[CpuIrq] + rawStats[CpuSoftirq]; kernel > busy {
+			rawStats[CpuSystem] = kernel - busy
+		}
+		rawStats[CpuTotal] = rawStats[CpuUser] + rawStats[CpuSystem] + rawStats[CpuIdle] + rawStats[CpuIrq] + rawStats[CpuSoftirq]
+		cpusRawStats[`cpu`+strconv.Itoa(i)] = rawStats
+
+		for key, value := range rawStats {
+			allRawStats[key] += value
+		}
+	}
+	cpusRawStats[`cpu`] = allRawStats
+
+	return cpusRawStats, nil
+}
+
+// getCpuAvgStats calculates average between 2 CpusRawStats samples and returns
+// the % CPU usage
+func getCpuAvgStats(firstSample CpusRawStats, secondSample CpusRawStats) (cpusAvgStats CpusAvgStats, err error) {
+	cpusAvgStats = CpusAvgStats{}
+
+	for cpuName, secondRawStats := range secondSample {
+		firstRawStats, ok := firstSample[cpuName]
+		if !ok {
+			return nil, errors.New("The key " + cpuName + " doesn't exist in the first sample of CpusRawStats")
+		}
+
+		delta := Delta(firstRawStats, secondRawStats)
+		cpuStats := CpuAvgStats{}
+		total := float64(delta[CpuTotal])
+		for key, value := range delta {
+			if key == CpuTotal {
+				continue
+			}
+			if total > 0 {
+				cpuStats[key] = float64(value) * 100.00 / total
+			} else {
+				cpuStats[key] = 0
+			}
+		}
+		cpuStats[CpuTotal] = 0
+		for _, key := range cpuBusyKeys {
+			cpuStats[CpuTotal] += cpuStats[key]
+		}
+		cpusAvgStats[cpuName] = cpuStats
+	}
+
+	return cpusAvgStats, nil
+}
+
+// getCpuStatsInterval returns the % CPU utilization between 2 samples.
+// Time interval between the 2 samples is given in seconds.
+func getCpuStatsInterval(interval int64) (cpusAvgStats CpusAvgStats, err error) {
+	return getCpuStatsOver(time.Duration(interval) * time.Second)
+}
+
+// getCpuStatsOver returns the % CPU utilization between 2 samples taken d
+// apart.
+func getCpuStatsOver(d time.Duration) (cpusAvgStats CpusAvgStats, err error) {
+	return sampleOver(d, getCpuRawStats, getCpuAvgStats)
+}
+
+// getCpuStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n % CPU utilizations between them.
+func getCpuStatsSampleN(n int, interval time.Duration) (series []CpusAvgStats, err error) {
+	return sampleN(n, interval, getCpuRawStats, getCpuAvgStats)
+}
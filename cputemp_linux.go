@@ -0,0 +1,148 @@
+// +build linux
+
+package sysstats
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hwmonRoot is the directory of the hardware monitoring devices.
+const hwmonRoot = "/sys/class/hwmon"
+
+// CpuTemperature represents the temperatures of *one* logical CPU, which are
+// the ones of its physical core and package.
+type CpuTemperature struct {
+	Core     float64 `json:"core" unit:"°C"`     // Temperature of the core in °C (0 if the sensor doesn't report it)
+	Package  float64 `json:"package" unit:"°C"`  // Temperature of the package in °C (0 if the sensor doesn't report it)
+	Critical float64 `json:"critical" unit:"°C"` // Critical temperature of the core (or package) in °C
+}
+
+// CpusTemperature represents the temperatures of *all* the CPUs of a linux
+// system.
+//
+// Map keys:
+//   Name - Name of the CPU (as it is on /proc/stat: cpu0, cpu1,...).
+type CpusTemperature map[string]CpuTemperature
+
+// sensorTemperatures are the temperatures read from a hwmon device, by
+// package and by core of the package.
+type sensorTemperatures struct {
+	packages map[int]CpuTemperature
+	cores    map[int]map[int]CpuTemperature
+}
+
+// getCpusTemperature gets the temperatures of the cores and packages from the
+// coretemp (Intel) and k10temp (AMD) hwmon sensors and maps them to the CPUs
+// with the topology.
+func getCpusTemperature() (cpusTemperature CpusTemperature, err error) {
+	topology, err := getTopology()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs, err := filepath.Glob(filepath.Join(hwmonRoot, "hwmon*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	sensors := sensorTemperatures{packages: map[int]CpuTemperature{}, cores: map[int]map[int]CpuTemperature{}}
+	amdPackage := 0
+	for _, dir := range dirs {
+		switch readSysfsString(filepath.Join(dir, "name")) {
+		case "coretemp":
+			readCoretemp(dir, sensors)
+		case "k10temp", "zenpower":
+			// One sensor per package, without per core temperatures
+			temperature, critical := readTemperature(dir, "Tdie")
+			if temperature == 0 {
+				temperature, critical = readTemperature(dir, "Tctl")
+			}
+			sensors.packages[amdPackage] = CpuTemperature{Package: temperature, Critical: critical}
+			amdPackage++
+		}
+	}
+
+	cpusTemperature = CpusTemperature{}
+	for cpuName, cpuTopology := range topology {
+		packageTemperature, okPackage := sensors.packages[cpuTopology.Package]
+		coreTemperature, okCore := sensors.cores[cpuTopology.Package][cpuTopology.Core]
+		if !okPackage && !okCore {
+			continue
+		}
+		cpuTemperature := CpuTemperature{}
+		cpuTemperature.Package = packageTemperature.Package
+		cpuTemperature.Core = coreTemperature.Core
+		cpuTemperature.Critical = coreTemperature.Critical
+		if cpuTemperature.Critical == 0 {
+			cpuTemperature.Critical = packageTemperature.Critical
+		}
+		cpusTemperature[cpuName] = cpuTemperature
+	}
+
+	return cpusTemperature, nil
+}
+
+// readCoretemp reads the temperatures of a coretemp hwmon device. The labels
+// of its sensors are "Package id N" and "Core N", where N is the
+// physical_package_id or the core_id of the topology.
+func readCoretemp(dir string, sensors sensorTemperatures) {
+	labels, _ := filepath.Glob(filepath.Join(dir, "temp*_label"))
+
+	packageId := -1
+	cores := map[int]CpuTemperature{}
+	for _, labelPath := range labels {
+		label := readSysfsString(labelPath)
+		prefix := strings.TrimSuffix(labelPath, "_label")
+		temperature := readMillidegrees(prefix + "_input")
+		critical := readMillidegrees(prefix + "_crit")
+
+		if strings.HasPrefix(label, "Package id ") {
+			id, err := strconv.Atoi(strings.TrimPrefix(label, "Package id "))
+			if err == nil {
+				packageId = id
+				sensors.packages[id] = CpuTemperature{Package: temperature, Critical: critical}
+			}
+		} else if strings.HasPrefix(label, "Core ") {
+			id, err := strconv.Atoi(strings.TrimPrefix(label, "Core "))
+			if err == nil {
+				cores[id] = CpuTemperature{Core: temperature, Critical: critical}
+			}
+		}
+	}
+
+	if packageId < 0 {
+		// Old kernels don't report the package, there is one device per package
+		packageId = len(sensors.cores)
+	}
+	sensors.cores[packageId] = cores
+}
+
+// readTemperature returns the temperature and the critical temperature of
+// the sensor of a hwmon device with the given label.
+func readTemperature(dir string, label string) (temperature float64, critical float64) {
+	labels, _ := filepath.Glob(filepath.Join(dir, "temp*_label"))
+	for _, labelPath := range labels {
+		if readSysfsString(labelPath) != label {
+			continue
+		}
+		prefix := strings.TrimSuffix(labelPath, "_label")
+		return readMillidegrees(prefix + "_input"), readMillidegrees(prefix + "_crit")
+	}
+
+	return 0, 0
+}
+
+// readMillidegrees reads a temperature of hwmon (in millidegrees Celsius)
+// and returns it in degrees Celsius, or 0 if it can't be read.
+func readMillidegrees(path string) float64 {
+	value, err := strconv.ParseInt(readSysfsString(path), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return float64(value) / 1000
+}
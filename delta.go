@@ -0,0 +1,124 @@
+package sysstats
+
+import (
+	"context"
+	"time"
+)
+
+// Number is the constraint of the values of the counters Delta and RateOver
+// work with.
+type Number interface {
+	~int | ~int32 | ~int64 | ~uint | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// Delta returns the difference (b - a) of every counter of b. Counters that
+// went backwards (reset or wrapped) have a delta of 0.
+func Delta[M ~map[K]V, K comparable, V Number](a M, b M) M {
+	delta := make(M, len(b))
+	for key, value := range b {
+		if previous := a[key]; value >= previous {
+			delta[key] = value - previous
+		} else {
+			delta[key] = 0
+		}
+	}
+
+	return delta
+}
+
+// RateOver returns the per second rate of every counter of b since a, where
+// d is the time elapsed between the 2 samples.
+func RateOver[M ~map[K]V, K comparable, V Number](a M, b M, d time.Duration) map[K]float64 {
+	rates := make(map[K]float64, len(b))
+	seconds := d.Seconds()
+	if seconds <= 0 {
+		return rates
+	}
+
+	for key, value := range Delta(a, b) {
+		rates[key] = float64(value) / seconds
+	}
+
+	return rates
+}
+
+// maxUnixSeconds is the largest sample time taken for Unix time in seconds
+// instead of nanoseconds (1e12 seconds is in the year 33658, 1e12
+// nanoseconds in 1970).
+const maxUnixSeconds = 1e12
+
+// sampleTimeNano returns the time of a raw sample (DiskRawStats.SampleTime,
+// ProcRawStats.Time and the time of IfaceRawStats) in Unix nanoseconds. The
+// versions of the package before sub-second sampling stored them in Unix
+// seconds under the same JSON names, so the raw samples saved by them can
+// still be compared with new ones.
+func sampleTimeNano(t int64) int64 {
+	if t > 0 && t < maxUnixSeconds {
+		return t * int64(time.Second)
+	}
+
+	return t
+}
+
+// sampleOver takes 2 raw samples d apart and returns the average between
+// them.
+func sampleOver[R any, A any](d time.Duration, raw func() (R, error), avg func(R, R) (A, error)) (a A, err error) {
+	return sampleOverContext(context.Background(), d, raw, avg)
+}
+
+// sampleOverContext is like sampleOver but stops waiting for the second
+// sample, and returns ctx.Err(), as soon as the context is done.
+func sampleOverContext[R any, A any](ctx context.Context, d time.Duration, raw func() (R, error), avg func(R, R) (A, error)) (a A, err error) {
+	if err = ctx.Err(); err != nil {
+		return a, err
+	}
+
+	firstSample, err := raw()
+	if err != nil {
+		return a, err
+	}
+
+	timer := time.NewTimer(d)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return a, ctx.Err()
+	case <-timer.C:
+	}
+
+	secondSample, err := raw()
+	if err != nil {
+		return a, err
+	}
+
+	return avg(firstSample, secondSample)
+}
+
+// sampleN takes n+1 raw samples, interval apart, and returns the n averages
+// between them.
+func sampleN[R any, A any](n int, interval time.Duration, raw func() (R, error), avg func(R, R) (A, error)) (series []A, err error) {
+	series = make([]A, 0, n)
+
+	previousSample, err := raw()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < n; i++ {
+		time.Sleep(interval)
+
+		sample, err := raw()
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := avg(previousSample, sample)
+		if err != nil {
+			return nil, err
+		}
+		series = append(series, a)
+		previousSample = sample
+	}
+
+	return series, nil
+}
@@ -0,0 +1,37 @@
+package sysstats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampleTimeNano(t *testing.T) {
+	now := time.Unix(1700000000, 500)
+	for _, test := range []struct {
+		t    int64
+		want int64
+	}{
+		{now.UnixNano(), now.UnixNano()},
+		{now.Unix(), now.Unix() * int64(time.Second)},
+		{0, 0},
+	} {
+		if got := sampleTimeNano(test.t); got != test.want {
+			t.Errorf("sampleTimeNano(%d) = %d, want %d", test.t, got, test.want)
+		}
+	}
+}
+
+func TestAvgStatsOfSecondsSamples(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	// A sample of an older version (time in seconds) and a new one
+	first := ProcRawStats{Processes: 100, Time: now.Unix()}
+	second := ProcRawStats{Processes: 120, Time: now.Add(10 * time.Second).UnixNano()}
+
+	procAvgStats, err := getProcAvgStats(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if procAvgStats.NewProcs != 2 {
+		t.Errorf("NewProcs = %v, want 2 forks per second", procAvgStats.NewProcs)
+	}
+}
@@ -0,0 +1,270 @@
+package sysstats
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DerivedMetric represents a metric calculated from other metrics with an
+// arithmetic expression, e.g.:
+//   net.eth0.rxbytes*8/1e6 as rx_mbps
+//   mem.memused/mem.memtotal*100 as mem_usedper
+// The expressions support numbers, metric names, + - * / and parentheses.
+// The names with characters other than letters, digits, '.' and '_' (e.g.
+// the mount points of the disk usage) are written between braces:
+//   {diskusage./var.used}/1024 as var_used_mb
+type DerivedMetric struct {
+	Name       string // Name of the derived metric
+	Expression string // Expression it's calculated with
+	expr       derivedExpr
+}
+
+// derivedExpr is a node of the expression of a derived metric.
+type derivedExpr interface {
+	eval(metrics map[string]float64) (float64, error)
+}
+
+// derivedNumber is a constant of an expression.
+type derivedNumber float64
+
+func (n derivedNumber) eval(metrics map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+// derivedName is a metric referred to by an expression.
+type derivedName string
+
+func (n derivedName) eval(metrics map[string]float64) (float64, error) {
+	value, ok := metrics[string(n)]
+	if !ok {
+		return 0, errors.New("The metric " + string(n) + " doesn't exist")
+	}
+
+	return value, nil
+}
+
+// derivedOp is an arithmetic operation of an expression. Unary minus has no
+// left operand.
+type derivedOp struct {
+	op    byte
+	left  derivedExpr
+	right derivedExpr
+}
+
+func (o derivedOp) eval(metrics map[string]float64) (float64, error) {
+	right, err := o.right.eval(metrics)
+	if err != nil {
+		return 0, err
+	}
+	if o.left == nil {
+		return -right, nil
+	}
+	left, err := o.left.eval(metrics)
+	if err != nil {
+		return 0, err
+	}
+
+	switch o.op {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	default:
+		if right == 0 {
+			return 0, errors.New("Division by zero")
+		}
+		return left / right, nil
+	}
+}
+
+// ParseDerivedMetric parses the definition of a derived metric, which is an
+// expression followed by "as" and the name of the metric.
+func ParseDerivedMetric(definition string) (derivedMetric DerivedMetric, err error) {
+	as := strings.LastIndex(definition, " as ")
+	if as < 0 {
+		return DerivedMetric{}, errors.New("Couldn't parse derived metric " + definition + ": it must end with \"as name\"")
+	}
+	derivedMetric.Expression = strings.TrimSpace(definition[:as])
+	derivedMetric.Name = strings.TrimSpace(definition[as+4:])
+	if derivedMetric.Name == "" || strings.ContainsAny(derivedMetric.Name, " \t") {
+		return DerivedMetric{}, errors.New("Couldn't parse derived metric " + definition + ": invalid name")
+	}
+
+	parser := &derivedParser{input: derivedMetric.Expression}
+	derivedMetric.expr, err = parser.parse()
+	if err != nil {
+		return DerivedMetric{}, errors.New("Couldn't parse derived metric " + definition + ": " + err.Error())
+	}
+
+	return derivedMetric, nil
+}
+
+// Eval calculates the derived metric from the metrics. It fails if a metric
+// of the expression is missing or the result isn't a finite number (e.g. a
+// division by zero).
+func (d DerivedMetric) Eval(metrics map[string]float64) (float64, error) {
+	if d.expr == nil {
+		return 0, errors.New("The derived metric " + d.Name + " wasn't parsed with ParseDerivedMetric")
+	}
+	value, err := d.expr.eval(metrics)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New("The derived metric " + d.Name + " isn't a finite number")
+	}
+
+	return value, nil
+}
+
+// String returns the definition of the derived metric.
+func (d DerivedMetric) String() string {
+	return d.Expression + " as " + d.Name
+}
+
+// derivedParser is a recursive descent parser of the expressions:
+//   expr   = term { ("+" | "-") term }
+//   term   = factor { ("*" | "/") factor }
+//   factor = "-" factor | "(" expr ")" | number | name | "{" name "}"
+type derivedParser struct {
+	input string
+	pos   int
+}
+
+// parse parses the whole input.
+func (p *derivedParser) parse() (expr derivedExpr, err error) {
+	expr, err = p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, errors.New("unexpected " + strconv.Quote(p.input[p.pos:]))
+	}
+
+	return expr, nil
+}
+
+func (p *derivedParser) expr() (expr derivedExpr, err error) {
+	expr, err = p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return expr, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		expr = derivedOp{op: op, left: expr, right: right}
+	}
+}
+
+func (p *derivedParser) term() (expr derivedExpr, err error) {
+	expr, err = p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return expr, nil
+		}
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		expr = derivedOp{op: op, left: expr, right: right}
+	}
+}
+
+func (p *derivedParser) factor() (expr derivedExpr, err error) {
+	switch c := p.peek(); {
+	case c == 0:
+		return nil, errors.New("unexpected end of the expression")
+	case c == '-':
+		p.pos++
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return derivedOp{op: '-', right: operand}, nil
+	case c == '(':
+		p.pos++
+		expr, err = p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return expr, nil
+	case c == '{':
+		end := strings.IndexByte(p.input[p.pos:], '}')
+		if end < 0 {
+			return nil, errors.New("missing }")
+		}
+		name := p.input[p.pos+1 : p.pos+end]
+		if name == "" {
+			return nil, errors.New("empty metric name between braces")
+		}
+		p.pos += end + 1
+		return derivedName(name), nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (isDerivedNumberChar(p.input[p.pos]) ||
+			(p.input[p.pos] == '+' || p.input[p.pos] == '-') && (p.input[p.pos-1] == 'e' || p.input[p.pos-1] == 'E')) {
+			p.pos++
+		}
+		number, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, errors.New("invalid number " + p.input[start:p.pos])
+		}
+		return derivedNumber(number), nil
+	case isDerivedNameChar(c):
+		start := p.pos
+		for p.pos < len(p.input) && isDerivedNameChar(p.input[p.pos]) {
+			p.pos++
+		}
+		return derivedName(p.input[start:p.pos]), nil
+	default:
+		return nil, errors.New("unexpected " + strconv.Quote(string(c)))
+	}
+}
+
+// peek skips the spaces and returns the next character, 0 at the end.
+func (p *derivedParser) peek() byte {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+
+	return p.input[p.pos]
+}
+
+func (p *derivedParser) skipSpaces() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// isDerivedNumberChar tells if c can be part of a number (1.5, 1e6).
+func isDerivedNumberChar(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E'
+}
+
+// isDerivedNameChar tells if c can be part of a metric name without braces.
+// '-' isn't included, as it would be ambiguous with the subtraction.
+func isDerivedNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_'
+}
@@ -0,0 +1,100 @@
+package sysstats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDerivedMetricEval(t *testing.T) {
+	metrics := map[string]float64{
+		`mem.memused`:         2,
+		`mem.memtotal`:        8,
+		`net.eth0.rxbytes`:    1e6,
+		`diskusage./var.used`: 2048,
+		`zero`:                0,
+	}
+	tests := []struct {
+		definition string
+		want       float64
+	}{
+		{`1 + 2 * 3 as precedence`, 7},
+		{`(1 + 2) * 3 as parentheses`, 9},
+		{`8 / 4 / 2 as left_associative`, 1},
+		{`10 - 4 - 3 as left_associative`, 3},
+		{`-2 * 3 as unary_minus`, -6},
+		{`2 * -3 as unary_minus`, -6},
+		{`--2 as double_minus`, 2},
+		{`-(1 + 2) as minus_parentheses`, -3},
+		{`1.5e3 + 1E-3 + .5 as numbers`, 1500.501},
+		{`mem.memused/mem.memtotal*100 as mem_usedper`, 25},
+		{`net.eth0.rxbytes*8/1e6 as rx_mbps`, 8},
+		{`{diskusage./var.used}/1024 as var_used_mb`, 2},
+		{`{mem.memused} - mem.memused as braces`, 0},
+		{"\tmem.memused\t* 2 as tabs", 4},
+	}
+	for _, test := range tests {
+		derivedMetric, err := ParseDerivedMetric(test.definition)
+		if err != nil {
+			t.Errorf("ParseDerivedMetric(%q) error = %v", test.definition, err)
+			continue
+		}
+		got, err := derivedMetric.Eval(metrics)
+		if err != nil {
+			t.Errorf("Eval(%q) error = %v", test.definition, err)
+			continue
+		}
+		if diff := got - test.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Eval(%q) = %v, want %v", test.definition, got, test.want)
+		}
+		if derivedMetric.String() != strings.TrimSpace(test.definition) {
+			t.Errorf("String() = %q, want %q", derivedMetric.String(), test.definition)
+		}
+	}
+}
+
+func TestDerivedMetricEvalErrors(t *testing.T) {
+	metrics := map[string]float64{`mem.memused`: 2, `zero`: 0}
+	for _, definition := range []string{
+		`1 / 0 as division_by_zero`,
+		`mem.memused / zero as division_by_zero`,
+		`mem.memused / (zero - 0) as division_by_zero`,
+		`mem.missing * 2 as missing_metric`,
+		`1e308 * 10 as overflow`,
+	} {
+		derivedMetric, err := ParseDerivedMetric(definition)
+		if err != nil {
+			t.Errorf("ParseDerivedMetric(%q) error = %v", definition, err)
+			continue
+		}
+		if got, err := derivedMetric.Eval(metrics); err == nil {
+			t.Errorf("Eval(%q) = %v, want an error", definition, got)
+		}
+	}
+
+	if _, err := (DerivedMetric{Name: `unparsed`, Expression: `1`}).Eval(metrics); err == nil {
+		t.Error("Eval() of an unparsed derived metric succeeded, want an error")
+	}
+}
+
+func TestParseDerivedMetricErrors(t *testing.T) {
+	for _, definition := range []string{
+		`mem.memused`,
+		`mem.memused as`,
+		`mem.memused as two names`,
+		` as empty`,
+		`1 + as missing_operand`,
+		`1 2 as missing_operator`,
+		`(1 + 2 as missing_parenthesis`,
+		`1 + 2) as extra_parenthesis`,
+		`{mem.memused as missing_brace`,
+		`{} as empty_braces`,
+		`1e as invalid_number`,
+		`1.2.3 as invalid_number`,
+		`1 % 2 as unknown_operator`,
+		`- as minus_alone`,
+	} {
+		if derivedMetric, err := ParseDerivedMetric(definition); err == nil {
+			t.Errorf("ParseDerivedMetric(%q) = %+v, want an error", definition, derivedMetric)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// +build linux
+
+package sysstats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DeviceIdentity represents the persistent identifiers of a block device,
+// which don't change across reboots like the kernel names (sda, nvme0n1,
+// dm-3) can.
+type DeviceIdentity struct {
+	Name     string   `json:"name"`               // Kernel name of the device
+	Wwid     string   `json:"wwid,omitempty"`     // World Wide Identifier (WWN, EUI, NGUID,...)
+	Serial   string   `json:"serial,omitempty"`   // Serial number of the device
+	DmName   string   `json:"dmname,omitempty"`   // Device mapper name (LVM volumes, LUKS,...)
+	DmUuid   string   `json:"dmuuid,omitempty"`   // Device mapper UUID
+	Ids      []string `json:"ids,omitempty"`      // Names in /dev/disk/by-id
+	Uuid     string   `json:"uuid,omitempty"`     // File system UUID (/dev/disk/by-uuid)
+	Label    string   `json:"label,omitempty"`    // File system label (/dev/disk/by-label)
+	PartUuid string   `json:"partuuid,omitempty"` // Partition UUID (/dev/disk/by-partuuid)
+}
+
+// StableName returns the most persistent identifier of the device: the WWID,
+// the device mapper name, the file system UUID, the serial number, the first
+// by-id name or, if it has none, the kernel name.
+func (d DeviceIdentity) StableName() string {
+	for _, name := range []string{d.Wwid, d.DmName, d.Uuid, d.Serial} {
+		if name != "" {
+			return name
+		}
+	}
+	if len(d.Ids) > 0 {
+		return d.Ids[0]
+	}
+
+	return d.Name
+}
+
+// getDevicesIdentity gets the persistent identifiers of all the block devices
+// (disks and partitions) from sysfs (/sys/class/block/[name]) and the udev
+// symlinks of /dev/disk. The file system UUIDs and labels are only known if
+// udev created the symlinks.
+func getDevicesIdentity() (devicesIdentity map[string]DeviceIdentity, err error) {
+	dirs, err := ioutil.ReadDir("/sys/class/block")
+	if err != nil {
+		return nil, err
+	}
+
+	devicesIdentity = make(map[string]DeviceIdentity, len(dirs))
+	for _, dir := range dirs {
+		name := dir.Name()
+		path := filepath.Join("/sys/class/block", name)
+		deviceIdentity := DeviceIdentity{Name: name}
+
+		deviceIdentity.Wwid = readSysfsString(filepath.Join(path, "wwid"))
+		if deviceIdentity.Wwid == "" {
+			deviceIdentity.Wwid = readSysfsString(filepath.Join(path, "device/wwid"))
+		}
+		deviceIdentity.Serial = readSysfsString(filepath.Join(path, "serial"))
+		if deviceIdentity.Serial == "" {
+			deviceIdentity.Serial = readSysfsString(filepath.Join(path, "device/serial"))
+		}
+		deviceIdentity.DmName = readSysfsString(filepath.Join(path, "dm/name"))
+		deviceIdentity.DmUuid = readSysfsString(filepath.Join(path, "dm/uuid"))
+
+		devicesIdentity[name] = deviceIdentity
+	}
+
+	links := map[string]func(deviceIdentity *DeviceIdentity, link string){
+		"by-id":       func(d *DeviceIdentity, link string) { d.Ids = append(d.Ids, link) },
+		"by-uuid":     func(d *DeviceIdentity, link string) { d.Uuid = link },
+		"by-label":    func(d *DeviceIdentity, link string) { d.Label = link },
+		"by-partuuid": func(d *DeviceIdentity, link string) { d.PartUuid = link },
+	}
+	for dir, set := range links {
+		entries, err := ioutil.ReadDir(filepath.Join("/dev/disk", dir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// No udev
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			target, err := filepath.EvalSymlinks(filepath.Join("/dev/disk", dir, entry.Name()))
+			if err != nil {
+				continue
+			}
+			deviceIdentity, ok := devicesIdentity[filepath.Base(target)]
+			if !ok {
+				continue
+			}
+			set(&deviceIdentity, entry.Name())
+			devicesIdentity[deviceIdentity.Name] = deviceIdentity
+		}
+	}
+	for name, deviceIdentity := range devicesIdentity {
+		sort.Strings(deviceIdentity.Ids)
+		devicesIdentity[name] = deviceIdentity
+	}
+
+	return devicesIdentity, nil
+}
@@ -0,0 +1,145 @@
+package sysstats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DiskFillProjection represents the projection of when a file system will be
+// full, from the linear regression of its used space over a window.
+type DiskFillProjection struct {
+	MountedOn   string    `json:"mountedon"`            // Mount point of the file system
+	FileSystem  string    `json:"filesystem"`           // File system
+	Time        time.Time `json:"time"`                 // Time of the last sample
+	Used        uint64    `json:"used" unit:"kB"`       // Used space in kilobytes
+	Available   uint64    `json:"available" unit:"kB"`  // Available space in kilobytes
+	Rate        float64   `json:"rate" unit:"kB/h"`     // Growth of the used space in kilobytes per hour
+	HoursToFull float64   `json:"hourstofull" unit:"h"` // Estimated hours until the file system is full, -1 if it isn't growing
+	Samples     int       `json:"samples"`              // # of samples of the regression
+	Filling     bool      `json:"filling"`              // The file system will be full within the horizon
+}
+
+// DiskFillPredictor estimates the hours until each file system is full from
+// the DiskUsage samples it's fed with, using a linear regression of the used
+// space over a rolling time window.
+type DiskFillPredictor struct {
+	Window  time.Duration // Time window of the regression (default 6 hours)
+	Horizon time.Duration // File systems that will be full within it are filling (default 24 hours)
+
+	mu      sync.Mutex
+	series  map[string][]diskFillPoint
+	filling map[string]bool
+}
+
+// diskFillPoint is a sample of the used space of a file system.
+type diskFillPoint struct {
+	time time.Time
+	used uint64
+}
+
+// Observe adds the disk usage observed at time t and returns the projections
+// of all the file systems, sorted by mount point, and the ones that started
+// filling since the previous call.
+func (p *DiskFillPredictor) Observe(t time.Time, diskUsageArr []DiskUsage) (projections []DiskFillProjection, filling []DiskFillProjection) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.series == nil {
+		p.series = map[string][]diskFillPoint{}
+		p.filling = map[string]bool{}
+	}
+	window, horizon := p.Window, p.Horizon
+	if window <= 0 {
+		window = 6 * time.Hour
+	}
+	if horizon <= 0 {
+		horizon = 24 * time.Hour
+	}
+
+	seen := map[string]bool{}
+	for _, diskUsage := range diskUsageArr {
+		mountedOn := diskUsage.MountedOn
+		seen[mountedOn] = true
+
+		points := append(p.series[mountedOn], diskFillPoint{time: t, used: diskUsage.Used})
+		for len(points) > 0 && t.Sub(points[0].time) > window {
+			points = points[1:]
+		}
+		p.series[mountedOn] = points
+
+		projection := DiskFillProjection{
+			MountedOn:   mountedOn,
+			FileSystem:  diskUsage.FileSystem,
+			Time:        t,
+			Used:        diskUsage.Used,
+			Available:   diskUsage.Available,
+			HoursToFull: -1,
+			Samples:     len(points),
+		}
+		projection.Rate = diskFillRate(points)
+		if projection.Rate > 0 {
+			projection.HoursToFull = float64(diskUsage.Available) / projection.Rate
+			projection.Filling = projection.HoursToFull <= horizon.Hours()
+		}
+		projections = append(projections, projection)
+
+		if projection.Filling && !p.filling[mountedOn] {
+			filling = append(filling, projection)
+		}
+		p.filling[mountedOn] = projection.Filling
+	}
+
+	// Forget the file systems that were unmounted
+	for mountedOn := range p.series {
+		if !seen[mountedOn] {
+			delete(p.series, mountedOn)
+			delete(p.filling, mountedOn)
+		}
+	}
+
+	sort.Slice(projections, func(i, j int) bool {
+		return projections[i].MountedOn < projections[j].MountedOn
+	})
+
+	return projections, filling
+}
+
+// diskFillRate returns the slope of the least squares regression of the used
+// space over time in kilobytes per hour, 0 if there aren't enough samples.
+func diskFillRate(points []diskFillPoint) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, point := range points {
+		x := point.time.Sub(points[0].time).Hours()
+		y := float64(point.used)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// ProjectDiskFill returns the projections of when the file systems will be
+// full from a series of snapshots (sorted by time), using the ones within
+// window of the last snapshot.
+func ProjectDiskFill(snapshots []Snapshot, window time.Duration) []DiskFillProjection {
+	predictor := &DiskFillPredictor{Window: window}
+
+	var projections []DiskFillProjection
+	for _, snapshot := range snapshots {
+		projections, _ = predictor.Observe(snapshot.Time, snapshot.DiskUsage)
+	}
+
+	return projections
+}
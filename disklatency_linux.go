@@ -0,0 +1,131 @@
+// +build linux
+
+package sysstats
+
+import (
+	"sort"
+	"time"
+)
+
+// defaultLatencyStep is the default time between the samples of
+// getDiskLatencyOver (10 Hz).
+const defaultLatencyStep = 100 * time.Millisecond
+
+// DiskLatency represents the estimated IO latency distribution of a disk.
+// The latency of every sub-interval is the mean latency of its IOs (ticks
+// spent reading and writing divided by the IOs completed) and it's weighted
+// by the # of IOs when the percentiles are calculated.
+type DiskLatency struct {
+	Name    string  `json:"name"`           // Disk name
+	IOs     uint64  `json:"ios"`            // # of IOs completed
+	Samples int     `json:"samples"`        // # of sub-intervals with IOs completed
+	Mean    float64 `json:"mean" unit:"ms"` // Mean latency in milliseconds
+	P50     float64 `json:"p50" unit:"ms"`  // Median latency in milliseconds
+	P95     float64 `json:"p95" unit:"ms"`  // 95th percentile of the latency in milliseconds
+	P99     float64 `json:"p99" unit:"ms"`  // 99th percentile of the latency in milliseconds
+	Max     float64 `json:"max" unit:"ms"`  // Max latency of a sub-interval in milliseconds
+}
+
+// latencySample is the mean latency of the IOs of a disk completed in a
+// sub-interval.
+type latencySample struct {
+	latency float64 // Milliseconds
+	ios     uint64
+}
+
+// diskLatencySamples returns the latency of the IOs of every disk completed
+// between 2 samples. The disks without IOs are skipped.
+func diskLatencySamples(firstSampleArr []DiskRawStats, secondSampleArr []DiskRawStats) (samples map[string]latencySample, err error) {
+	samples = map[string]latencySample{}
+
+	first := map[string]DiskRawStats{}
+	for _, firstSample := range firstSampleArr {
+		first[firstSample.Name] = firstSample
+	}
+	for _, secondSample := range secondSampleArr {
+		firstSample, ok := first[secondSample.Name]
+		if !ok {
+			continue
+		}
+		ios := (secondSample.ReadIOs + secondSample.WriteIOs) - (firstSample.ReadIOs + firstSample.WriteIOs)
+		ticks := (secondSample.ReadTicks + secondSample.WriteTicks) - (firstSample.ReadTicks + firstSample.WriteTicks)
+		if ios == 0 || int64(ios) < 0 || int64(ticks) < 0 {
+			continue
+		}
+		samples[secondSample.Name] = latencySample{latency: float64(ticks) / float64(ios), ios: ios}
+	}
+
+	return samples, nil
+}
+
+// getDiskLatencyOver samples /proc/diskstats every step (10 Hz if step is 0)
+// for d and returns the estimated IO latency distribution of every disk.
+func getDiskLatencyOver(d time.Duration, step time.Duration) (diskLatencyArr []DiskLatency, err error) {
+	if step <= 0 {
+		step = defaultLatencyStep
+	}
+	n := int(d / step)
+	if n < 1 {
+		n = 1
+	}
+
+	series, err := sampleN(n, step, getDiskRawStats, diskLatencySamples)
+	if err != nil {
+		return nil, err
+	}
+
+	disks := map[string][]latencySample{}
+	for _, samples := range series {
+		for name, sample := range samples {
+			disks[name] = append(disks[name], sample)
+		}
+	}
+
+	diskLatencyArr = make([]DiskLatency, 0, len(disks))
+	for name, samples := range disks {
+		diskLatencyArr = append(diskLatencyArr, diskLatency(name, samples))
+	}
+	sort.Slice(diskLatencyArr, func(i, j int) bool {
+		return diskLatencyArr[i].Name < diskLatencyArr[j].Name
+	})
+
+	return diskLatencyArr, nil
+}
+
+// diskLatency calculates the latency distribution of a disk from the samples
+// of its sub-intervals.
+func diskLatency(name string, samples []latencySample) (latency DiskLatency) {
+	latency = DiskLatency{Name: name, Samples: len(samples)}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].latency < samples[j].latency
+	})
+
+	var ticks float64
+	for _, sample := range samples {
+		latency.IOs += sample.ios
+		ticks += sample.latency * float64(sample.ios)
+	}
+	if latency.IOs == 0 {
+		return latency
+	}
+	latency.Mean = ticks / float64(latency.IOs)
+	latency.Max = samples[len(samples)-1].latency
+
+	percentile := func(p float64) float64 {
+		threshold := p * float64(latency.IOs)
+		var ios uint64
+		for _, sample := range samples {
+			ios += sample.ios
+			if float64(ios) >= threshold {
+				return sample.latency
+			}
+		}
+		return latency.Max
+	}
+	latency.P50 = percentile(0.50)
+	latency.P95 = percentile(0.95)
+	latency.P99 = percentile(0.99)
+
+	return latency
+}
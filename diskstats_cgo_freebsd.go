@@ -0,0 +1,117 @@
+// +build freebsd
+
+package sysstats
+
+/*
+#cgo LDFLAGS: -ldevstat
+#include <sys/types.h>
+#include <sys/devicestat.h>
+#include <devstat.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+// disk_stat is the subset of struct devstat the disk stats are made of,
+// with the times already converted to milliseconds.
+struct disk_stat {
+	char name[DEVSTAT_NAME_LEN + 16];
+	int unit;
+	uint64_t read_ops;
+	uint64_t read_bytes;
+	uint64_t read_ms;
+	uint64_t write_ops;
+	uint64_t write_bytes;
+	uint64_t write_ms;
+	uint64_t busy_ms;
+	uint64_t in_flight;
+};
+
+static uint64_t bintime_ms(struct bintime bt) {
+	return (uint64_t)bt.sec * 1000 + (((bt.frac >> 32) * 1000) >> 32);
+}
+
+// disk_stats gets the stats of all the devices with devstat_getdevs(3). The
+// array is allocated with malloc and must be freed.
+static int disk_stats(struct disk_stat **disks, int *count) {
+	struct statinfo stats;
+	memset(&stats, 0, sizeof(stats));
+	stats.dinfo = calloc(1, sizeof(struct devinfo));
+	if (stats.dinfo == NULL) {
+		return -1;
+	}
+	if (devstat_getdevs(NULL, &stats) == -1) {
+		free(stats.dinfo);
+		return -1;
+	}
+
+	int n = stats.dinfo->numdevs;
+	*disks = calloc(n > 0 ? n : 1, sizeof(struct disk_stat));
+	if (*disks == NULL) {
+		free(stats.dinfo->mem_ptr);
+		free(stats.dinfo);
+		return -1;
+	}
+	for (int i = 0; i < n; i++) {
+		struct devstat *dev = &stats.dinfo->devices[i];
+		struct disk_stat *disk = &(*disks)[i];
+		snprintf(disk->name, sizeof(disk->name), "%s%d", dev->device_name, dev->unit_number);
+		disk->unit = dev->unit_number;
+		disk->read_ops = dev->operations[DEVSTAT_READ];
+		disk->read_bytes = dev->bytes[DEVSTAT_READ];
+		disk->read_ms = bintime_ms(dev->duration[DEVSTAT_READ]);
+		disk->write_ops = dev->operations[DEVSTAT_WRITE];
+		disk->write_bytes = dev->bytes[DEVSTAT_WRITE];
+		disk->write_ms = bintime_ms(dev->duration[DEVSTAT_WRITE]);
+		disk->busy_ms = bintime_ms(dev->busy_time);
+		disk->in_flight = dev->start_count - dev->end_count;
+	}
+	*count = n;
+
+	free(stats.dinfo->mem_ptr);
+	free(stats.dinfo);
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"regexp"
+	"time"
+	"unsafe"
+)
+
+// getDiskRawStatsMatching gets the IO stats of the disks whose names match
+// the regexp (all of them if it's nil).
+func getDiskRawStatsMatching(disks *regexp.Regexp) (diskRawStatsArr []DiskRawStats, err error) {
+	var stats *C.struct_disk_stat
+	var count C.int
+	if C.disk_stats(&stats, &count) != 0 {
+		return nil, errors.New("Couldn't get the disk stats with devstat_getdevs: " + C.GoString(&C.devstat_errbuf[0]))
+	}
+	defer C.free(unsafe.Pointer(stats))
+
+	now := time.Now().UnixNano()
+	diskRawStatsArr = make([]DiskRawStats, 0, int(count))
+	for _, disk := range unsafe.Slice(stats, int(count)) {
+		name := C.GoString(&disk.name[0])
+		if disks != nil && !disks.MatchString(name) {
+			continue
+		}
+		diskRawStatsArr = append(diskRawStatsArr, DiskRawStats{
+			Minor:        int(disk.unit),
+			Name:         name,
+			ReadIOs:      uint64(disk.read_ops),
+			ReadSectors:  uint64(disk.read_bytes) / 512,
+			ReadTicks:    uint64(disk.read_ms),
+			WriteIOs:     uint64(disk.write_ops),
+			WriteSectors: uint64(disk.write_bytes) / 512,
+			WriteTicks:   uint64(disk.write_ms),
+			InFlight:     uint64(disk.in_flight),
+			IOTicks:      uint64(disk.busy_ms),
+			SampleTime:   now,
+		})
+	}
+
+	return diskRawStatsArr, nil
+}
@@ -3,10 +3,9 @@
 package sysstats
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
-	"os"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -14,44 +13,56 @@ import (
 
 // DiskRawStats represents the disk IO raw statistics of a linux system.
 type DiskRawStats struct {
-	Major        int    `json:"major"`        // Major number for the disk
-	Minor        int    `json:"minor"`        // Minor number for the disk
-	Name         string `json:"name"`         // Disk name
-	ReadIOs      uint64 `json:"readios"`      // # of reads completed since boot
-	ReadMerges   uint64 `json:"readmerges"`   // # of reads merged since boot
-	ReadSectors  uint64 `json:"readsectors"`  // # of sectors read since boot
-	ReadTicks    uint64 `json:"readticks"`    // # of milliseconds spent reading since boot
-	WriteIOs     uint64 `json:"writeios"`     // # of writes completed since boot
-	WriteMerges  uint64 `json:"writemerges"`  // # of writes merged since boot
-	WriteSectors uint64 `json:"writesectors"` // # of sectors written since boot
-	WriteTicks   uint64 `json:"writeticks"`   // # of milliseconds spent writing since boot
-	InFlight     uint64 `json:"inflight"`     // # of I/Os currently in progress
-	IOTicks      uint64 `json:"ioticks"`      // # of milliseconds spent doing I/Os since boot
-	TimeInQueue  uint64 `json:"timeinqueue"`  // Weighted # of milliseconds spent doing I/Os since boot
-	SampleTime   int64  `json:"sampletime"`   // Time when the sample was taken
+	Major         int    `json:"major"`                       // Major number for the disk
+	Minor         int    `json:"minor"`                       // Minor number for the disk
+	Name          string `json:"name"`                        // Disk name
+	ReadIOs       uint64 `json:"readios"`                     // # of reads completed since boot
+	ReadMerges    uint64 `json:"readmerges"`                  // # of reads merged since boot
+	ReadSectors   uint64 `json:"readsectors" unit:"sectors"`  // # of sectors read since boot
+	ReadTicks     uint64 `json:"readticks" unit:"ms"`         // # of milliseconds spent reading since boot
+	WriteIOs      uint64 `json:"writeios"`                    // # of writes completed since boot
+	WriteMerges   uint64 `json:"writemerges"`                 // # of writes merged since boot
+	WriteSectors  uint64 `json:"writesectors" unit:"sectors"` // # of sectors written since boot
+	WriteTicks    uint64 `json:"writeticks" unit:"ms"`        // # of milliseconds spent writing since boot
+	InFlight      uint64 `json:"inflight"`                    // # of I/Os currently in progress
+	IOTicks       uint64 `json:"ioticks" unit:"ms"`           // # of milliseconds spent doing I/Os since boot
+	TimeInQueue   uint64 `json:"timeinqueue" unit:"ms"`       // Weighted # of milliseconds spent doing I/Os since boot
+	SectorSize    uint64 `json:"sectorsize" unit:"bytes"`     // Size of the sectors in bytes (see SetSectorSize)
+	LogicalBlock  uint64 `json:"logicalblock" unit:"bytes"`   // Logical block size of the device in bytes
+	PhysicalBlock uint64 `json:"physicalblock" unit:"bytes"`  // Physical block size of the device in bytes
+	SampleTime    int64  `json:"sampletime" unit:"unixnano"`  // Time when the sample was taken (Unix time in nanoseconds)
 }
 
 // DiskAvgStats represents the average disk IO statistics (per second) of a
 // linux system.
 type DiskAvgStats struct {
-	Major       int     `json:"major"`       // Major number for the disk
-	Minor       int     `json:"minor"`       // Minor number for the disk
-	Name        string  `json:"name"`        // Disk name
-	ReadIOs     float64 `json:"readios"`     // # of reads completed per second
-	ReadMerges  float64 `json:"readmerges"`  // # of reads merged per second
-	ReadBytes   float64 `json:"readbytes"`   // # of bytes read per second
-	WriteIOs    float64 `json:"writeios"`    // # of writes completed per second
-	WriteMerges float64 `json:"writemerges"` // # of writes merged per second
-	WriteBytes  float64 `json:"writebytes"`  // # of bytes written per second
-	InFlight    uint64  `json:"inflight"`    // # of I/Os currently in progress
-	IOTicks     uint64  `json:"ioticks"`     // # of milliseconds spent doing I/Os
-	TimeInQueue uint64  `json:"timeinqueue"` // Weighted # of milliseconds spent doing I/Os
+	Major         int     `json:"major"`                      // Major number for the disk
+	Minor         int     `json:"minor"`                      // Minor number for the disk
+	Name          string  `json:"name"`                       // Disk name
+	ReadIOs       float64 `json:"readios" unit:"/s"`          // # of reads completed per second
+	ReadMerges    float64 `json:"readmerges" unit:"/s"`       // # of reads merged per second
+	ReadBytes     float64 `json:"readbytes" unit:"bytes/s"`   // # of bytes read per second
+	WriteIOs      float64 `json:"writeios" unit:"/s"`         // # of writes completed per second
+	WriteMerges   float64 `json:"writemerges" unit:"/s"`      // # of writes merged per second
+	WriteBytes    float64 `json:"writebytes" unit:"bytes/s"`  // # of bytes written per second
+	InFlight      uint64  `json:"inflight"`                   // # of I/Os currently in progress
+	IOTicks       uint64  `json:"ioticks" unit:"ms"`          // # of milliseconds spent doing I/Os
+	TimeInQueue   uint64  `json:"timeinqueue" unit:"ms"`      // Weighted # of milliseconds spent doing I/Os
+	LogicalBlock  uint64  `json:"logicalblock" unit:"bytes"`  // Logical block size of the device in bytes
+	PhysicalBlock uint64  `json:"physicalblock" unit:"bytes"` // Physical block size of the device in bytes
 }
 
 // getDiskRawStats gets the disk IO stats of a linux system from the
 // file /proc/diskstats
 func getDiskRawStats() (diskRawStatsArr []DiskRawStats, err error) {
-	file, err := os.Open("/proc/diskstats")
+	return getDiskRawStatsMatching(nil)
+}
+
+// getDiskRawStatsMatching gets the IO stats of the disks whose names match
+// the regexp (all of them if it's nil). The names are matched before parsing
+// the counters, so hosts with thousands of LUNs can be sampled cheaply.
+func getDiskRawStatsMatching(disks *regexp.Regexp) (diskRawStatsArr []DiskRawStats, err error) {
+	file, err := openStatsFile("/proc/diskstats")
 	if err != nil {
 		return nil, err
 	}
@@ -59,18 +70,29 @@ func getDiskRawStats() (diskRawStatsArr []DiskRawStats, err error) {
 
 	diskRawStatsArr = make([]DiskRawStats, 0, 5)
 
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
-	now := time.Now().Unix()
+	scanner, release := newStatsScanner(file)
+	defer release()
+	now := time.Now().UnixNano()
+	present := map[string]bool{}
 	for scanner.Scan() {
 		line := scanner.Text()
+		present[field(line, 2)] = true
+		if disks != nil && !disks.MatchString(field(line, 2)) {
+			continue
+		}
 		diskRawStats, err := parseDiskRawStats(line)
 		if err != nil {
 			return diskRawStatsArr, err
 		}
+		diskRawStats.SectorSize = sectorSize(diskRawStats.Name)
+		diskRawStats.LogicalBlock, diskRawStats.PhysicalBlock = getBlockSizes(diskRawStats.Name, diskRawStats.Major, diskRawStats.Minor)
 		diskRawStats.SampleTime = now
 		diskRawStatsArr = append(diskRawStatsArr, diskRawStats)
 	}
+	if err = scanner.Err(); err != nil {
+		return diskRawStatsArr, err
+	}
+	forgetBlockSizes(present)
 
 	return diskRawStatsArr, nil
 }
@@ -96,56 +118,34 @@ func parseDiskRawStats(stats string) (diskRawStats DiskRawStats, err error) {
 
 	fields := strings.Fields(stats)
 
-	// Check there are 14 fields
-	if len(fields) != 14 {
-		return diskRawStats, errors.New("Couldn't parse disk stats because there aren't 14 fields")
+	// Check there are at least 14 fields (4.18 added 4 discard fields and 5.5
+	// 2 flush fields, which are ignored)
+	if len(fields) < 14 {
+		return diskRawStats, errors.New("Couldn't parse disk stats because there are less than 14 fields")
 	}
 
 	// Parse fields
-	for i := 0; i < len(fields); i++ {
-		field := fields[i]
-		switch i {
-		case 0:
-			major, _ := strconv.ParseInt(field, 10, strconv.IntSize)
-			diskRawStats.Major = int(major)
-		case 1:
-			minor, _ := strconv.ParseInt(field, 10, strconv.IntSize)
-			diskRawStats.Minor = int(minor)
-		case 2:
-			diskRawStats.Name = fields[2]
-		case 3:
-			readIOs, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.ReadIOs = readIOs
-		case 4:
-			readMerges, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.ReadMerges = readMerges
-		case 5:
-			readSectors, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.ReadSectors = readSectors
-		case 6:
-			readTicks, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.ReadTicks = readTicks
-		case 7:
-			writeIOs, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.WriteIOs = writeIOs
-		case 8:
-			writeMerges, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.WriteMerges = writeMerges
-		case 9:
-			writeSectors, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.WriteSectors = writeSectors
-		case 10:
-			writeTicks, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.WriteTicks = writeTicks
-		case 11:
-			inFlight, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.InFlight = inFlight
-		case 12:
-			ioTicks, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.IOTicks = ioTicks
-		case 13:
-			timeInQueue, _ := strconv.ParseUint(field, 10, 64)
-			diskRawStats.TimeInQueue = timeInQueue
+	major, err := strconv.ParseInt(fields[0], 10, strconv.IntSize)
+	if err != nil {
+		return DiskRawStats{}, err
+	}
+	diskRawStats.Major = int(major)
+	minor, err := strconv.ParseInt(fields[1], 10, strconv.IntSize)
+	if err != nil {
+		return DiskRawStats{}, err
+	}
+	diskRawStats.Minor = int(minor)
+	diskRawStats.Name = fields[2]
+
+	values := []*uint64{
+		&diskRawStats.ReadIOs, &diskRawStats.ReadMerges, &diskRawStats.ReadSectors, &diskRawStats.ReadTicks,
+		&diskRawStats.WriteIOs, &diskRawStats.WriteMerges, &diskRawStats.WriteSectors, &diskRawStats.WriteTicks,
+		&diskRawStats.InFlight, &diskRawStats.IOTicks, &diskRawStats.TimeInQueue,
+	}
+	for i, value := range values {
+		*value, err = strconv.ParseUint(fields[i+3], 10, 64)
+		if err != nil {
+			return DiskRawStats{}, err
 		}
 	}
 
@@ -157,7 +157,7 @@ func parseDiskRawStats(stats string) (diskRawStats DiskRawStats, err error) {
 func diskAvgStats(firstSample DiskRawStats, secondSample DiskRawStats) (diskAvgStats DiskAvgStats, err error) {
 	diskAvgStats = DiskAvgStats{}
 
-	timeDelta := float64(secondSample.SampleTime - firstSample.SampleTime)
+	timeDelta := time.Duration(sampleTimeNano(secondSample.SampleTime) - sampleTimeNano(firstSample.SampleTime)).Seconds()
 
 	// Check the samples are from the same disk
 	if firstSample.Major != secondSample.Major ||
@@ -176,12 +176,14 @@ func diskAvgStats(firstSample DiskRawStats, secondSample DiskRawStats) (diskAvgS
 	// Calculate average between the 2 samples
 	diskAvgStats.ReadIOs = float64(secondSample.ReadIOs-firstSample.ReadIOs) / timeDelta
 	diskAvgStats.ReadMerges = float64(secondSample.ReadMerges-firstSample.ReadMerges) / timeDelta
-	diskAvgStats.ReadBytes = float64((secondSample.ReadSectors*512)-(firstSample.ReadSectors*512)) / timeDelta
+	diskAvgStats.ReadBytes = float64(secondSample.sectorBytes(secondSample.ReadSectors)-firstSample.sectorBytes(firstSample.ReadSectors)) / timeDelta
 	diskAvgStats.WriteIOs = float64(secondSample.WriteIOs-firstSample.WriteIOs) / timeDelta
 	diskAvgStats.WriteMerges = float64(secondSample.WriteMerges-firstSample.WriteMerges) / timeDelta
-	diskAvgStats.WriteBytes = float64((secondSample.WriteSectors*512)-(firstSample.WriteSectors*512)) / timeDelta
+	diskAvgStats.WriteBytes = float64(secondSample.sectorBytes(secondSample.WriteSectors)-firstSample.sectorBytes(firstSample.WriteSectors)) / timeDelta
 
 	diskAvgStats.InFlight = secondSample.InFlight
+	diskAvgStats.LogicalBlock = secondSample.LogicalBlock
+	diskAvgStats.PhysicalBlock = secondSample.PhysicalBlock
 	diskAvgStats.TimeInQueue = secondSample.TimeInQueue - firstSample.TimeInQueue
 
 	return diskAvgStats, nil
@@ -195,6 +197,7 @@ func getDiskAvgStats(firstSampleArr []DiskRawStats, secondSampleArr []DiskRawSta
 
 	for _, firstSample := range firstSampleArr {
 		diskName := firstSample.Name
+		found := false
 		for _, secondSample := range secondSampleArr {
 			if secondSample.Name == diskName {
 				diskAvgStats, err := diskAvgStats(firstSample, secondSample)
@@ -202,11 +205,15 @@ func getDiskAvgStats(firstSampleArr []DiskRawStats, secondSampleArr []DiskRawSta
 					return nil, err
 				}
 				diskAvgStatsArr = append(diskAvgStatsArr, diskAvgStats)
+				found = true
 				break
 			} else {
 				continue
 			}
 		}
+		if !found {
+			logDebug("skipped disk missing in the second sample", "disk", diskName)
+		}
 	}
 
 	return diskAvgStatsArr, nil
@@ -215,35 +222,16 @@ func getDiskAvgStats(firstSampleArr []DiskRawStats, secondSampleArr []DiskRawSta
 // getDiskStatsInterval returns the IO average between 2 samples.
 // Time interval between the 2 samples is given in seconds.
 func getDiskStatsInterval(interval int64) (diskAvgStatsArr []DiskAvgStats, err error) {
-	firstSampleArr, err := getDiskRawStats()
-	if err != nil {
-		return nil, err
-	}
-
-	diskAvgStatsArr = make([]DiskAvgStats, 0, len(firstSampleArr))
-
-	time.Sleep(time.Duration(interval) * time.Second)
-
-	secondSampleArr, err := getDiskRawStats()
-	if err != nil {
-		return nil, err
-	}
+	return getDiskStatsOver(time.Duration(interval) * time.Second)
+}
 
-	for _, firstSample := range firstSampleArr {
-		diskName := firstSample.Name
-		for _, secondSample := range secondSampleArr {
-			if secondSample.Name == diskName {
-				diskAvgStats, err := diskAvgStats(firstSample, secondSample)
-				if err != nil {
-					return nil, err
-				}
-				diskAvgStatsArr = append(diskAvgStatsArr, diskAvgStats)
-				break
-			} else {
-				continue
-			}
-		}
-	}
+// getDiskStatsOver returns the IO average between 2 samples taken d apart.
+func getDiskStatsOver(d time.Duration) (diskAvgStatsArr []DiskAvgStats, err error) {
+	return sampleOver(d, getDiskRawStats, getDiskAvgStats)
+}
 
-	return diskAvgStatsArr, nil
+// getDiskStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n IO averages between them.
+func getDiskStatsSampleN(n int, interval time.Duration) (series [][]DiskAvgStats, err error) {
+	return sampleN(n, interval, getDiskRawStats, getDiskAvgStats)
 }
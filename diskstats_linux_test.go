@@ -0,0 +1,99 @@
+// +build linux
+
+package sysstats
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func FuzzParseDiskRawStats(f *testing.F) {
+	for _, seed := range []string{
+		"   8       0 sda 1257332 13472 73140514 8834106 4170915 2981541 181302392 47853613 0 6180284 56681458",
+		" 202      80 xvdf 4190 32 251138 2416 98123 117345 18290608 610148 0 70780 542020 2271 0 1164052 404",
+		" 254       0 vda 10926 4080 1421050 5141 15054 27465 2244552 7538 0 2248 13155 14918 0 374056 473 43 0",
+		"   7       0 loop0 0 0 0 0 0 0 0 0 0 0 0",
+		"   8       0 sda 1 2 3",
+		"   8       0 sda 1 2 3 4 5 6 7 8 9 10 x",
+		"x y z 1 2 3 4 5 6 7 8 9 10 11",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		diskRawStats, err := parseDiskRawStats(line)
+		if err != nil {
+			if diskRawStats != (DiskRawStats{}) {
+				t.Errorf("parseDiskRawStats(%q) returned partial stats with error %v", line, err)
+			}
+			return
+		}
+		if diskRawStats.Name == "" {
+			t.Errorf("parseDiskRawStats(%q) has no name", line)
+		}
+	})
+}
+
+func TestDiskCountersSectorSize(t *testing.T) {
+	snapshot := Snapshot{Disk: []DiskRawStats{
+		{Name: `sda`, ReadSectors: 10, WriteSectors: 20, SectorSize: 4096},
+		{Name: `sdb`, ReadSectors: 10, WriteSectors: 20},
+	}}
+	counters := NewCounterRegistry().Collect(snapshot)
+
+	for name, want := range map[string]float64{
+		`disk.sda.readbytes`:  10 * 4096,
+		`disk.sda.writebytes`: 20 * 4096,
+		`disk.sdb.readbytes`:  10 * diskstatsSectorSize,
+		`disk.sdb.writebytes`: 20 * diskstatsSectorSize,
+	} {
+		if got := counters[name].Value; got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestBlockSizesCache(t *testing.T) {
+	statsFS := fstest.MapFS{
+		"proc/diskstats": {Data: []byte("   8       0 sda 1 2 3 4 5 6 7 8 9 10 11\n" +
+			"   7       0 loop0 0 0 0 0 0 0 0 0 0 0 0\n")},
+		"sys/block/sda/queue/logical_block_size":  {Data: []byte("512\n")},
+		"sys/block/sda/queue/physical_block_size": {Data: []byte("4096\n")},
+	}
+	SetStatsFS(statsFS)
+	defer func() {
+		SetStatsFS(nil)
+		sectorSizes.Lock()
+		sectorSizes.blocks = map[string]blockSizes{}
+		sectorSizes.Unlock()
+	}()
+
+	if _, err := getDiskRawStats(); err != nil {
+		t.Fatal(err)
+	}
+	sectorSizes.Lock()
+	loop, ok := sectorSizes.blocks[`loop0`]
+	sectorSizes.Unlock()
+	if !ok || loop.logical != 0 {
+		t.Errorf("block sizes of loop0 = %+v, %v, want the device without queue cached", loop, ok)
+	}
+
+	// A device replaced by another one with the same name is read again,
+	// and the ones that disappeared are forgotten
+	statsFS["proc/diskstats"] = &fstest.MapFile{Data: []byte("   8      16 sda 1 2 3 4 5 6 7 8 9 10 11\n")}
+	statsFS["sys/block/sda/queue/logical_block_size"] = &fstest.MapFile{Data: []byte("4096\n")}
+	diskRawStatsArr, err := getDiskRawStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diskRawStatsArr) != 1 || diskRawStatsArr[0].LogicalBlock != 4096 {
+		t.Errorf("getDiskRawStats() = %+v, want the block size of the new sda", diskRawStatsArr)
+	}
+	sectorSizes.Lock()
+	_, ok = sectorSizes.blocks[`loop0`]
+	sectorSizes.Unlock()
+	if ok {
+		t.Error("the block sizes of loop0 are still cached after it disappeared")
+	}
+}
@@ -0,0 +1,125 @@
+// +build !linux
+
+package sysstats
+
+import (
+	"fmt"
+	"time"
+)
+
+// DiskRawStats represents the disk IO raw statistics of a non-linux system.
+// They are only collected on FreeBSD (devstat), which doesn't count merged
+// IOs nor the weighted time in queue, so ReadMerges, WriteMerges and
+// TimeInQueue are always 0, and disks don't have a major number.
+type DiskRawStats struct {
+	Major        int    `json:"major"`                       // Always 0
+	Minor        int    `json:"minor"`                       // Unit number of the disk
+	Name         string `json:"name"`                        // Disk name (ada0, nvd0,...)
+	ReadIOs      uint64 `json:"readios"`                     // # of reads completed since boot
+	ReadMerges   uint64 `json:"readmerges"`                  // Always 0
+	ReadSectors  uint64 `json:"readsectors" unit:"sectors"`  // # of 512 bytes sectors read since boot
+	ReadTicks    uint64 `json:"readticks" unit:"ms"`         // # of milliseconds spent reading since boot
+	WriteIOs     uint64 `json:"writeios"`                    // # of writes completed since boot
+	WriteMerges  uint64 `json:"writemerges"`                 // Always 0
+	WriteSectors uint64 `json:"writesectors" unit:"sectors"` // # of 512 bytes sectors written since boot
+	WriteTicks   uint64 `json:"writeticks" unit:"ms"`        // # of milliseconds spent writing since boot
+	InFlight     uint64 `json:"inflight"`                    // # of I/Os currently in progress
+	IOTicks      uint64 `json:"ioticks" unit:"ms"`           // # of milliseconds the disk was busy since boot
+	TimeInQueue  uint64 `json:"timeinqueue" unit:"ms"`       // Always 0
+	SampleTime   int64  `json:"sampletime" unit:"unixnano"`  // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// DiskAvgStats represents the average disk IO statistics (per second) of a
+// non-linux system.
+type DiskAvgStats struct {
+	Major       int     `json:"major"`                     // Always 0
+	Minor       int     `json:"minor"`                     // Unit number of the disk
+	Name        string  `json:"name"`                      // Disk name
+	ReadIOs     float64 `json:"readios" unit:"/s"`         // # of reads completed per second
+	ReadMerges  float64 `json:"readmerges" unit:"/s"`      // Always 0
+	ReadBytes   float64 `json:"readbytes" unit:"bytes/s"`  // # of bytes read per second
+	WriteIOs    float64 `json:"writeios" unit:"/s"`        // # of writes completed per second
+	WriteMerges float64 `json:"writemerges" unit:"/s"`     // Always 0
+	WriteBytes  float64 `json:"writebytes" unit:"bytes/s"` // # of bytes written per second
+	InFlight    uint64  `json:"inflight"`                  // # of I/Os currently in progress
+	IOTicks     uint64  `json:"ioticks" unit:"ms"`         // # of milliseconds the disk was busy
+	TimeInQueue uint64  `json:"timeinqueue" unit:"ms"`     // Always 0
+}
+
+// getDiskRawStats gets the disk IO stats of the system (with devstat on
+// FreeBSD).
+func getDiskRawStats() (diskRawStatsArr []DiskRawStats, err error) {
+	return getDiskRawStatsMatching(nil)
+}
+
+// sectorBytes returns the sectors of the sample in bytes. The sectors of
+// devstat are always 512 bytes.
+func (d DiskRawStats) sectorBytes(sectors uint64) uint64 {
+	return sectors * 512
+}
+
+// diskAvgStats calculates the average between 2 DiskRawStats samples and returns
+// a DiskAvgStats variable with the number of IOs per second.
+func diskAvgStats(firstSample DiskRawStats, secondSample DiskRawStats) (diskAvgStats DiskAvgStats, err error) {
+	if firstSample.Minor != secondSample.Minor || firstSample.Name != secondSample.Name {
+		return DiskAvgStats{}, fmt.Errorf("The samples are from different disks: %d %s and %d %s",
+			firstSample.Minor, firstSample.Name, secondSample.Minor, secondSample.Name)
+	}
+
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+
+	diskAvgStats = DiskAvgStats{
+		Minor:      secondSample.Minor,
+		Name:       secondSample.Name,
+		ReadIOs:    float64(secondSample.ReadIOs-firstSample.ReadIOs) / timeDelta,
+		ReadBytes:  float64(secondSample.sectorBytes(secondSample.ReadSectors-firstSample.ReadSectors)) / timeDelta,
+		WriteIOs:   float64(secondSample.WriteIOs-firstSample.WriteIOs) / timeDelta,
+		WriteBytes: float64(secondSample.sectorBytes(secondSample.WriteSectors-firstSample.WriteSectors)) / timeDelta,
+		InFlight:   secondSample.InFlight,
+		IOTicks:    secondSample.IOTicks - firstSample.IOTicks,
+	}
+
+	return diskAvgStats, nil
+}
+
+// getDiskAvgStats calculates the average between 2 arrays of DiskRawStats
+// samples and returns an array of DiskAvgStats
+func getDiskAvgStats(firstSampleArr []DiskRawStats, secondSampleArr []DiskRawStats) (diskAvgStatsArr []DiskAvgStats, err error) {
+	secondSamples := make(map[string]DiskRawStats, len(secondSampleArr))
+	for _, secondSample := range secondSampleArr {
+		secondSamples[secondSample.Name] = secondSample
+	}
+
+	diskAvgStatsArr = make([]DiskAvgStats, 0, len(firstSampleArr))
+	for _, firstSample := range firstSampleArr {
+		secondSample, ok := secondSamples[firstSample.Name]
+		if !ok {
+			logDebug("skipped disk missing in the second sample", "disk", firstSample.Name)
+			continue
+		}
+		diskAvgStats, err := diskAvgStats(firstSample, secondSample)
+		if err != nil {
+			return nil, err
+		}
+		diskAvgStatsArr = append(diskAvgStatsArr, diskAvgStats)
+	}
+
+	return diskAvgStatsArr, nil
+}
+
+// getDiskStatsInterval returns the IO average between 2 samples.
+// Time interval between the 2 samples is given in seconds.
+func getDiskStatsInterval(interval int64) (diskAvgStatsArr []DiskAvgStats, err error) {
+	return getDiskStatsOver(time.Duration(interval) * time.Second)
+}
+
+// getDiskStatsOver returns the IO average between 2 samples taken d apart.
+func getDiskStatsOver(d time.Duration) (diskAvgStatsArr []DiskAvgStats, err error) {
+	return sampleOver(d, getDiskRawStats, getDiskAvgStats)
+}
+
+// getDiskStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n IO averages between them.
+func getDiskStatsSampleN(n int, interval time.Duration) (series [][]DiskAvgStats, err error) {
+	return sampleN(n, interval, getDiskRawStats, getDiskAvgStats)
+}
@@ -0,0 +1,12 @@
+// +build !linux,!freebsd
+
+package sysstats
+
+import (
+	"regexp"
+)
+
+// getDiskRawStatsMatching isn't supported outside linux and FreeBSD.
+func getDiskRawStatsMatching(disks *regexp.Regexp) (diskRawStatsArr []DiskRawStats, err error) {
+	return nil, ErrNotSupported
+}
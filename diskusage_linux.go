@@ -6,19 +6,19 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
-	"os/exec"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 // DiskUsage represents a file system disk space usage
 type DiskUsage struct {
 	FileSystem string `json:"filesystem"`
 	Type       string `json:"type"`
-	Total      uint64 `json:"total"`
-	Used       uint64 `json:"used"`
-	Available  uint64 `json:"available"`
-	UsedPer    uint64 `json:"usedper"`
+	Total      uint64 `json:"total" unit:"kB"`
+	Used       uint64 `json:"used" unit:"kB"`
+	Available  uint64 `json:"available" unit:"kB"`
+	UsedPer    uint64 `json:"usedper" unit:"%"`
 	MountedOn  string `json:"mountedon"`
 }
 
@@ -30,18 +30,17 @@ type DiskUsage struct {
 //   -P: uses the POSIX output format
 // It returns an array of DiskUsage elements (as many elements as file systems
 // has the OS)
+// When the external commands are disabled (see SetExecDisabled) the usage is
+// got with statfs instead (see getDiskUsageStatfs).
 func getDiskUsage() (diskUsageArr []DiskUsage, err error) {
-	diskUsageArr = make([]DiskUsage, 0, 5)
-
-	// Check df exists
-	df, err := exec.LookPath("df")
-	if err != nil {
-		return diskUsageArr, err
+	if !execAllowed() {
+		return getDiskUsageStatfs()
 	}
 
-	// Run df -kTP
-	out, err := exec.Command(df, "-kTP").Output()
+	diskUsageArr = make([]DiskUsage, 0, 5)
 
+	// Run df -kTP
+	out, err := runCommand("df", "-kTP")
 	if err != nil {
 		return diskUsageArr, err
 	}
@@ -63,6 +62,55 @@ func getDiskUsage() (diskUsageArr []DiskUsage, err error) {
 	return diskUsageArr, nil
 }
 
+// getDiskUsageStatfs gets the disk usage of the mount points of
+// /proc/self/mountinfo with the statfs syscall, the same way df does. The
+// pseudo file systems (proc, sysfs,...), which have no blocks, are skipped
+// and a mount point mounted over another one is reported once.
+func getDiskUsageStatfs() (diskUsageArr []DiskUsage, err error) {
+	mounts, err := getMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	diskUsageArr = make([]DiskUsage, 0, 5)
+	indexes := map[string]int{}
+	for _, mount := range mounts {
+		stat := syscall.Statfs_t{}
+		err := syscall.Statfs(mount.MountedOn, &stat)
+		if err != nil {
+			logDebug("skipped mount point", "mountedon", mount.MountedOn, "error", err)
+			continue
+		}
+		if stat.Blocks == 0 {
+			continue
+		}
+
+		blockSize := uint64(stat.Bsize)
+		diskUsage := DiskUsage{
+			FileSystem: mount.Source,
+			Type:       mount.Type,
+			Total:      stat.Blocks * blockSize / 1024,
+			Used:       (stat.Blocks - stat.Bfree) * blockSize / 1024,
+			Available:  stat.Bavail * blockSize / 1024,
+			MountedOn:  mount.MountedOn,
+		}
+		// df rounds the percentage up, relative to the space available to
+		// non-root users
+		if usable := diskUsage.Used + diskUsage.Available; usable > 0 {
+			diskUsage.UsedPer = (diskUsage.Used*100 + usable - 1) / usable
+		}
+
+		if i, ok := indexes[mount.MountedOn]; ok {
+			diskUsageArr[i] = diskUsage
+			continue
+		}
+		indexes[mount.MountedOn] = len(diskUsageArr)
+		diskUsageArr = append(diskUsageArr, diskUsage)
+	}
+
+	return diskUsageArr, nil
+}
+
 // parseDiskUsage parses the filesystem disk space usage reported by df.
 // The format of the usage sent as argument has the following format:
 //   Filesystem                      Type     1024-blocks      Used Available Capacity Mounted on
@@ -79,13 +127,13 @@ func parseDiskUsage(usage string) (diskUsage DiskUsage, err error) {
 
 	fields := strings.Fields(usage)
 
-	// Check there are 7 fields
-	if len(fields) != 7 {
-		return DiskUsage{}, errors.New("Couldn't parse disk usage because there aren't 7 fields")
+	// Check there are at least 7 fields (the mount point can have spaces)
+	if len(fields) < 7 {
+		return DiskUsage{}, errors.New("Couldn't parse disk usage because there are less than 7 fields")
 	}
 
 	// Parse fields
-	for i := 0; i < len(fields); i++ {
+	for i := 0; i < 7; i++ {
 		field := fields[i]
 		switch i {
 		case 0:
@@ -121,7 +169,7 @@ func parseDiskUsage(usage string) (diskUsage DiskUsage, err error) {
 			}
 			diskUsage.UsedPer = value
 		case 6:
-			diskUsage.MountedOn = field
+			diskUsage.MountedOn = strings.Join(fields[6:], " ")
 		}
 	}
 
@@ -0,0 +1,33 @@
+// +build linux
+
+package sysstats
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseDiskUsage(f *testing.F) {
+	for _, seed := range []string{
+		"/dev/vda1      ext4     25669860 3464612  20878728  15% /",
+		"tmpfs          tmpfs     3073700       0   3073700   0% /dev/shm",
+		"/dev/sdb1      vfat       523248    6216    517032   2% /media/My Disk",
+		"/dev/sda1 ext4 1 2 3 101% /",
+		"/dev/sda1 ext4 1 2 3 % /",
+		"/dev/sda1 ext4 1 2",
+		"Filesystem     Type     1K-blocks    Used Available Use% Mounted on",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		diskUsage, err := parseDiskUsage(line)
+		if err != nil {
+			return
+		}
+		if diskUsage.MountedOn == "" || strings.TrimSpace(diskUsage.MountedOn) != diskUsage.MountedOn {
+			t.Errorf("parseDiskUsage(%q) mount point = %q", line, diskUsage.MountedOn)
+		}
+	})
+}
@@ -0,0 +1,19 @@
+// +build !linux,!windows
+
+package sysstats
+
+// DiskUsage represents a file system disk space usage
+type DiskUsage struct {
+	FileSystem string `json:"filesystem"`
+	Type       string `json:"type"`
+	Total      uint64 `json:"total" unit:"kB"`
+	Used       uint64 `json:"used" unit:"kB"`
+	Available  uint64 `json:"available" unit:"kB"`
+	UsedPer    uint64 `json:"usedper" unit:"%"`
+	MountedOn  string `json:"mountedon"`
+}
+
+// getDiskUsage isn't implemented on this OS yet.
+func getDiskUsage() (diskUsageArr []DiskUsage, err error) {
+	return nil, ErrNotSupported
+}
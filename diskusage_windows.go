@@ -0,0 +1,79 @@
+// +build windows
+
+package sysstats
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// DiskUsage represents a file system disk space usage
+type DiskUsage struct {
+	FileSystem string `json:"filesystem"`
+	Type       string `json:"type"`
+	Total      uint64 `json:"total" unit:"kB"`
+	Used       uint64 `json:"used" unit:"kB"`
+	Available  uint64 `json:"available" unit:"kB"`
+	UsedPer    uint64 `json:"usedper" unit:"%"`
+	MountedOn  string `json:"mountedon"`
+}
+
+// driveFixed is the DRIVE_FIXED type of GetDriveTypeW (hard disks and SSDs).
+const driveFixed = 3
+
+// getDiskUsage gets the disk usage of the fixed drives of a Windows system
+// with GetDiskFreeSpaceExW. The sizes are in kilobytes, like df -k, and the
+// drives are both the file system and the mount point (C:\).
+func getDiskUsage() (diskUsageArr []DiskUsage, err error) {
+	buffer := make([]uint16, 256)
+	n, _, err := procGetLogicalDriveStringsW.Call(uintptr(len(buffer)), uintptr(unsafe.Pointer(&buffer[0])))
+	if n == 0 {
+		return nil, os.NewSyscallError("GetLogicalDriveStringsW", err)
+	}
+
+	diskUsageArr = make([]DiskUsage, 0, 5)
+	// The drives are NUL separated strings (C:\\\0D:\\\0\0)
+	start := 0
+	for i := 0; i < int(n); i++ {
+		if buffer[i] != 0 {
+			continue
+		}
+		drive := buffer[start : i+1]
+		start = i + 1
+
+		driveType, _, _ := procGetDriveTypeW.Call(uintptr(unsafe.Pointer(&drive[0])))
+		if driveType != driveFixed {
+			continue
+		}
+
+		var available, total, free uint64
+		ret, _, err := procGetDiskFreeSpaceExW.Call(uintptr(unsafe.Pointer(&drive[0])),
+			uintptr(unsafe.Pointer(&available)), uintptr(unsafe.Pointer(&total)), uintptr(unsafe.Pointer(&free)))
+		if ret == 0 {
+			logDebug("skipped drive", "drive", syscall.UTF16ToString(drive), "error", err)
+			continue
+		}
+
+		fsType := make([]uint16, 64)
+		procGetVolumeInformationW.Call(uintptr(unsafe.Pointer(&drive[0])), 0, 0, 0, 0, 0,
+			uintptr(unsafe.Pointer(&fsType[0])), uintptr(len(fsType)))
+
+		diskUsage := DiskUsage{
+			FileSystem: syscall.UTF16ToString(drive),
+			Type:       syscall.UTF16ToString(fsType),
+			Total:      total / 1024,
+			Used:       (total - free) / 1024,
+			Available:  available / 1024,
+			MountedOn:  syscall.UTF16ToString(drive),
+		}
+		// Rounded up like df, relative to the space available to the user
+		// (quotas)
+		if usable := diskUsage.Used + diskUsage.Available; usable > 0 {
+			diskUsage.UsedPer = (diskUsage.Used*100 + usable - 1) / usable
+		}
+		diskUsageArr = append(diskUsageArr, diskUsage)
+	}
+
+	return diskUsageArr, nil
+}
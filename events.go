@@ -0,0 +1,185 @@
+package sysstats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventType is the type of a discrete change of the system.
+type EventType string
+
+// Types of the events emitted by the Monitor.
+const (
+	EventIfaceUp      EventType = "iface.up"      // Network interface went up
+	EventIfaceDown    EventType = "iface.down"    // Network interface went down
+	EventIfaceAdded   EventType = "iface.added"   // New network interface
+	EventIfaceRemoved EventType = "iface.removed" // Network interface removed
+	EventDiskAdded    EventType = "disk.added"    // New disk
+	EventDiskRemoved  EventType = "disk.removed"  // Disk removed
+	EventFsMounted    EventType = "fs.mounted"    // File system mounted
+	EventFsUnmounted  EventType = "fs.unmounted"  // File system unmounted
+	EventFsReadOnly   EventType = "fs.readonly"   // File system remounted read-only
+	EventFsReadWrite  EventType = "fs.readwrite"  // File system remounted read-write
+	EventSwapOn       EventType = "swap.on"       // Swap device enabled
+	EventSwapOff      EventType = "swap.off"      // Swap device disabled
+	EventCpuOnline    EventType = "cpu.online"    // CPU brought online
+	EventCpuOffline   EventType = "cpu.offline"   // CPU taken offline
+	EventAnomaly      EventType = "anomaly"       // Anomalous value of a metric
+
+	EventProcessStarted EventType = "process.started" // Watched process started
+	EventProcessExited  EventType = "process.exited"  // Watched process exited
+	EventProcessGone    EventType = "process.gone"    // No watched process is running anymore
+
+	EventDiskFilling EventType = "disk.filling" // File system projected to be full within the horizon
+)
+
+// Event represents a discrete change of the system.
+type Event struct {
+	Type    EventType `json:"type"`              // Type of the change
+	Time    time.Time `json:"time"`              // Time the change was observed
+	Subject string    `json:"subject"`           // Interface, disk, mount point, swap device, CPU, metric or process name
+	Pid     int       `json:"pid,omitempty"`     // Process ID (process events only)
+	Anomaly *Anomaly  `json:"anomaly,omitempty"` // Anomaly details (EventAnomaly only)
+	// Projection details (EventDiskFilling only)
+	Projection *DiskFillProjection `json:"projection,omitempty"`
+}
+
+// SystemState represents the discrete state of the system the events are
+// derived from.
+type SystemState struct {
+	Ifaces map[string]bool `json:"ifaces"` // Network interfaces (true if up)
+	Disks  map[string]bool `json:"disks"`  // Disks
+	Mounts map[string]bool `json:"mounts"` // Mount points (true if read-only)
+	Swaps  map[string]bool `json:"swaps"`  // Swap devices
+	Cpus   map[string]bool `json:"cpus"`   // CPUs (true if online)
+}
+
+// stateEvents returns the events of the changes between 2 states.
+func stateEvents(previous SystemState, current SystemState, t time.Time) (events []Event) {
+	add := func(eventType EventType, subject string) {
+		events = append(events, Event{Type: eventType, Time: t, Subject: subject})
+	}
+
+	for iface, up := range current.Ifaces {
+		wasUp, ok := previous.Ifaces[iface]
+		switch {
+		case !ok:
+			add(EventIfaceAdded, iface)
+		case up && !wasUp:
+			add(EventIfaceUp, iface)
+		case !up && wasUp:
+			add(EventIfaceDown, iface)
+		}
+	}
+	for iface := range previous.Ifaces {
+		if _, ok := current.Ifaces[iface]; !ok {
+			add(EventIfaceRemoved, iface)
+		}
+	}
+
+	for disk := range current.Disks {
+		if !previous.Disks[disk] {
+			add(EventDiskAdded, disk)
+		}
+	}
+	for disk := range previous.Disks {
+		if !current.Disks[disk] {
+			add(EventDiskRemoved, disk)
+		}
+	}
+
+	for mount, readOnly := range current.Mounts {
+		wasReadOnly, ok := previous.Mounts[mount]
+		switch {
+		case !ok:
+			add(EventFsMounted, mount)
+		case readOnly && !wasReadOnly:
+			add(EventFsReadOnly, mount)
+		case !readOnly && wasReadOnly:
+			add(EventFsReadWrite, mount)
+		}
+	}
+	for mount := range previous.Mounts {
+		if _, ok := current.Mounts[mount]; !ok {
+			add(EventFsUnmounted, mount)
+		}
+	}
+
+	for swap := range current.Swaps {
+		if !previous.Swaps[swap] {
+			add(EventSwapOn, swap)
+		}
+	}
+	for swap := range previous.Swaps {
+		if !current.Swaps[swap] {
+			add(EventSwapOff, swap)
+		}
+	}
+
+	for cpu, online := range current.Cpus {
+		wasOnline, ok := previous.Cpus[cpu]
+		if online && (!ok || !wasOnline) {
+			add(EventCpuOnline, cpu)
+		} else if !online && (!ok || wasOnline) {
+			add(EventCpuOffline, cpu)
+		}
+	}
+	for cpu, wasOnline := range previous.Cpus {
+		if _, ok := current.Cpus[cpu]; !ok && wasOnline {
+			add(EventCpuOffline, cpu)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Type != events[j].Type {
+			return events[i].Type < events[j].Type
+		}
+		return events[i].Subject < events[j].Subject
+	})
+
+	return events
+}
+
+// EventBus delivers the events published to all its subscribers.
+type EventBus struct {
+	mu       sync.RWMutex
+	next     int
+	handlers map[int]func(Event)
+}
+
+// NewEventBus returns a new EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{handlers: map[int]func(Event){}}
+}
+
+// Subscribe registers a handler called (synchronously) with every event
+// published. It returns a function that removes the handler.
+func (b *EventBus) Subscribe(handler func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
+
+// Publish delivers the event to all the subscribers.
+func (b *EventBus) Publish(event Event) {
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
@@ -0,0 +1,160 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// getSystemState gets the discrete state of a linux system from the files
+// /sys/class/net/*/operstate, /proc/diskstats, /proc/mounts, /proc/swaps and
+// /sys/devices/system/cpu/{present,online}.
+func getSystemState() (state SystemState, err error) {
+	state = SystemState{
+		Ifaces: map[string]bool{},
+		Disks:  map[string]bool{},
+		Mounts: map[string]bool{},
+		Swaps:  map[string]bool{},
+		Cpus:   map[string]bool{},
+	}
+
+	// Network interfaces
+	ifaces, err := filepath.Glob("/sys/class/net/*/operstate")
+	if err != nil {
+		return SystemState{}, err
+	}
+	for _, operstateFile := range ifaces {
+		content, err := ioutil.ReadFile(operstateFile)
+		if err != nil {
+			// The interface disappeared
+			logDebug("skipped network interface", "file", operstateFile, "error", err)
+			continue
+		}
+		operstate := strings.TrimSpace(string(content))
+		// Interfaces without carrier detection (e.g. lo) report "unknown"
+		state.Ifaces[filepath.Base(filepath.Dir(operstateFile))] = operstate == "up" || operstate == "unknown"
+	}
+
+	// Disks
+	diskRawStatsArr, err := getDiskRawStats()
+	if err != nil {
+		return SystemState{}, err
+	}
+	for _, diskRawStats := range diskRawStatsArr {
+		state.Disks[diskRawStats.Name] = true
+	}
+
+	// Mount points
+	err = scanLines("/proc/mounts", func(line string) {
+		// device mountpoint fstype options dump pass
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return
+		}
+		readOnly := false
+		for _, option := range strings.Split(fields[3], ",") {
+			if option == "ro" {
+				readOnly = true
+			}
+		}
+		state.Mounts[fields[1]] = readOnly
+	})
+	if err != nil {
+		return SystemState{}, err
+	}
+
+	// Swap devices
+	header := true
+	err = scanLines("/proc/swaps", func(line string) {
+		if header {
+			header = false
+			return
+		}
+		if fields := strings.Fields(line); len(fields) > 0 {
+			state.Swaps[fields[0]] = true
+		}
+	})
+	if err != nil {
+		return SystemState{}, err
+	}
+
+	// CPUs
+	present, err := readCpuList("/sys/devices/system/cpu/present")
+	if err != nil {
+		return SystemState{}, err
+	}
+	online, err := readCpuList("/sys/devices/system/cpu/online")
+	if err != nil {
+		return SystemState{}, err
+	}
+	for _, cpu := range present {
+		state.Cpus["cpu"+strconv.Itoa(cpu)] = false
+	}
+	for _, cpu := range online {
+		state.Cpus["cpu"+strconv.Itoa(cpu)] = true
+	}
+
+	return state, nil
+}
+
+// scanLines calls fn with every line of the file.
+func scanLines(path string, fn func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// readCpuList reads a file with a list of CPUs in the kernel list format,
+// e.g. "0-3,5,7-8".
+func readCpuList(path string) (cpus []int, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCpuList(strings.TrimSpace(string(content)))
+}
+
+// parseCpuList parses a list of CPUs in the kernel list format, e.g.
+// "0-3,5,7-8".
+func parseCpuList(list string) (cpus []int, err error) {
+	cpus = make([]int, 0)
+	if list == "" {
+		return cpus, nil
+	}
+
+	for _, item := range strings.Split(list, ",") {
+		bounds := strings.SplitN(item, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	return cpus, nil
+}
@@ -0,0 +1,73 @@
+package sysstats
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// EWMA maintains the exponentially weighted moving average of a set of
+// metrics, giving load-average-style smoothing for any stat. The weight of a
+// value halves every HalfLife, regardless of how often the metrics are
+// updated.
+type EWMA struct {
+	mu       sync.Mutex
+	halfLife time.Duration
+	values   map[string]float64
+	updated  map[string]time.Time
+}
+
+// NewEWMA returns an EWMA with the given half-life.
+func NewEWMA(halfLife time.Duration) *EWMA {
+	return &EWMA{
+		halfLife: halfLife,
+		values:   map[string]float64{},
+		updated:  map[string]time.Time{},
+	}
+}
+
+// Update adds the metrics observed at time t to the moving averages. The
+// first value of a metric initializes its average.
+func (e *EWMA) Update(t time.Time, metrics map[string]float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for name, value := range metrics {
+		last, ok := e.updated[name]
+		if !ok || e.halfLife <= 0 {
+			e.values[name] = value
+			e.updated[name] = t
+			continue
+		}
+
+		elapsed := t.Sub(last)
+		if elapsed <= 0 {
+			continue
+		}
+		alpha := 1 - math.Exp(-math.Ln2*elapsed.Seconds()/e.halfLife.Seconds())
+		e.values[name] += alpha * (value - e.values[name])
+		e.updated[name] = t
+	}
+}
+
+// Value returns the moving average of the metric and whether it exists.
+func (e *EWMA) Value(name string) (value float64, ok bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	value, ok = e.values[name]
+	return value, ok
+}
+
+// Values returns a copy of the moving averages of all the metrics.
+func (e *EWMA) Values() map[string]float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	values := make(map[string]float64, len(e.values))
+	for name, value := range e.values {
+		values[name] = value
+	}
+
+	return values
+}
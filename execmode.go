@@ -0,0 +1,28 @@
+package sysstats
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrExecDisabled is returned when a collector would need to run an external
+// command (df, uname,...) and they are disabled.
+var ErrExecDisabled = errors.New("Running external commands is disabled")
+
+// execDisabled is set when the external commands are disabled at runtime.
+var execDisabled atomic.Bool
+
+// SetExecDisabled disables (or enables again) the external commands. When
+// they are disabled the collectors that have an alternative use it instead
+// (statfs instead of df, the uname syscall instead of uname,...), and the
+// rest return ErrExecDisabled. Building with the tag sysstats_noexec
+// disables them for good, for locked-down containers, seccomp profiles and
+// distroless images where exec fails.
+func SetExecDisabled(disabled bool) {
+	execDisabled.Store(disabled)
+}
+
+// execAllowed tells if the collectors can run external commands.
+func execAllowed() bool {
+	return !noExecBuild && !execDisabled.Load()
+}
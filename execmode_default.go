@@ -0,0 +1,6 @@
+// +build !sysstats_noexec
+
+package sysstats
+
+// noExecBuild is set when the package is built with the tag sysstats_noexec.
+const noExecBuild = false
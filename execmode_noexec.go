@@ -0,0 +1,7 @@
+// +build sysstats_noexec
+
+package sysstats
+
+// noExecBuild is set when the package is built with the tag sysstats_noexec,
+// which guarantees no external command is ever run.
+const noExecBuild = true
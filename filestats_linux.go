@@ -4,7 +4,6 @@ package sysstats
 
 import (
 	"errors"
-	"io/ioutil"
 	"strconv"
 	"strings"
 )
@@ -24,7 +23,7 @@ func getFileStats() (fileStats FileStats, err error) {
 	fileStats = FileStats{}
 
 	// Get file handler stats
-	content, err := ioutil.ReadFile("/proc/sys/fs/file-nr")
+	content, err := readStatsFile("/proc/sys/fs/file-nr")
 	if err != nil {
 		return FileStats{}, err
 	}
@@ -47,7 +46,7 @@ func getFileStats() (fileStats FileStats, err error) {
 	}
 
 	// Get the inode stats
-	content, err = ioutil.ReadFile("/proc/sys/fs/inode-nr")
+	content, err = readStatsFile("/proc/sys/fs/inode-nr")
 	if err != nil {
 		return FileStats{}, err
 	}
@@ -0,0 +1,117 @@
+// +build linux
+
+package sysstats
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// FirewallCounter represents a named nftables counter object.
+type FirewallCounter struct {
+	Family  string `json:"family"`             // Family of the table (ip, ip6, inet, arp, bridge, netdev)
+	Table   string `json:"table"`              // Table the counter belongs to
+	Name    string `json:"name"`               // Counter name
+	Packets uint64 `json:"packets"`            // # of packets counted
+	Bytes   uint64 `json:"bytes" unit:"bytes"` // # of bytes counted
+}
+
+// FirewallRawStats represents the nftables counters of a linux system.
+type FirewallRawStats struct {
+	// Counters by family/table/name (e.g. inet/filter/http)
+	Counters   map[string]FirewallCounter `json:"counters"`
+	SampleTime int64                      `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// FirewallCounterStats represents the rates of a named nftables counter
+// between 2 samples.
+type FirewallCounterStats struct {
+	Family  string  `json:"family"`                   // Family of the table
+	Table   string  `json:"table"`                    // Table the counter belongs to
+	Name    string  `json:"name"`                     // Counter name
+	Packets float64 `json:"packets" unit:"packets/s"` // # of packets counted per second
+	Bytes   float64 `json:"bytes" unit:"bytes/s"`     // # of bytes counted per second
+}
+
+// nftOutput is the output of nft -j list counters:
+//   {"nftables": [{"metainfo": {...}}, {"counter": {"family": "inet",
+//     "name": "http", "table": "filter", "handle": 2, "packets": 10,
+//     "bytes": 840}}]}
+type nftOutput struct {
+	Nftables []struct {
+		Counter *FirewallCounter `json:"counter"`
+	} `json:"nftables"`
+}
+
+// getFirewallRawStats gets the named counter objects of nftables running
+// nft -j list counters (it needs CAP_NET_ADMIN). The anonymous counters of
+// the rules aren't included: the rules to export must use named counters
+// (counter name "http").
+func getFirewallRawStats() (firewallRawStats FirewallRawStats, err error) {
+	out, err := runCommand("nft", "-j", "list", "counters")
+	if err != nil {
+		return FirewallRawStats{}, err
+	}
+
+	firewallRawStats, err = parseFirewallRawStats(out)
+	if err != nil {
+		return FirewallRawStats{}, err
+	}
+	firewallRawStats.SampleTime = time.Now().UnixNano()
+
+	return firewallRawStats, nil
+}
+
+// parseFirewallRawStats parses the output of nft -j list counters.
+func parseFirewallRawStats(out []byte) (firewallRawStats FirewallRawStats, err error) {
+	output := nftOutput{}
+	err = json.Unmarshal(out, &output)
+	if err != nil {
+		return FirewallRawStats{}, err
+	}
+
+	firewallRawStats = FirewallRawStats{Counters: map[string]FirewallCounter{}}
+	for _, object := range output.Nftables {
+		if object.Counter == nil {
+			continue
+		}
+		counter := *object.Counter
+		firewallRawStats.Counters[counter.Family+"/"+counter.Table+"/"+counter.Name] = counter
+	}
+
+	return firewallRawStats, nil
+}
+
+// getFirewallAvgStats calculates the rates of the nftables counters between
+// 2 samples. The counters that aren't in both samples, or that were reset
+// between them, are skipped.
+func getFirewallAvgStats(firstSample FirewallRawStats, secondSample FirewallRawStats) (firewallAvgStats map[string]FirewallCounterStats, err error) {
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	if timeDelta <= 0 {
+		return nil, errors.New("The second sample must be taken after the first one")
+	}
+
+	firewallAvgStats = map[string]FirewallCounterStats{}
+	for key, second := range secondSample.Counters {
+		first, ok := firstSample.Counters[key]
+		if !ok || second.Packets < first.Packets || second.Bytes < first.Bytes {
+			continue
+		}
+		firewallAvgStats[key] = FirewallCounterStats{
+			Family:  second.Family,
+			Table:   second.Table,
+			Name:    second.Name,
+			Packets: float64(second.Packets-first.Packets) / timeDelta,
+			Bytes:   float64(second.Bytes-first.Bytes) / timeDelta,
+		}
+	}
+
+	return firewallAvgStats, nil
+}
+
+// getFirewallStatsOver returns the rates of the nftables counters between 2
+// samples taken d apart.
+func getFirewallStatsOver(d time.Duration) (firewallAvgStats map[string]FirewallCounterStats, err error) {
+	return sampleOver(d, getFirewallRawStats, getFirewallAvgStats)
+}
@@ -0,0 +1,215 @@
+package sysstats
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// MinFsyncProbeInterval is the shortest time between the probes of a
+	// FsyncProbe, so it can't load the storage it measures.
+	MinFsyncProbeInterval = time.Second
+	// MaxFsyncProbeSize is the max # of bytes written by every probe.
+	MaxFsyncProbeSize = 1 << 20
+	// defaultFsyncProbeSize is the # of bytes written by every probe by
+	// default (a page).
+	defaultFsyncProbeSize = 4096
+	// defaultFsyncProbeWindow is the # of latencies kept per mount by
+	// default.
+	defaultFsyncProbeWindow = 100
+)
+
+// FsyncProbe periodically writes and fsyncs a small file on every mount to
+// measure the latency of the whole storage stack (file system, journal,
+// block layer and device), which the passive counters can't show. It's an
+// active probe: nothing is written unless Run or Probe are called.
+type FsyncProbe struct {
+	// Mounts are the directories the files are written to (e.g. the mount
+	// points of the file systems). They must be writable.
+	Mounts []string
+
+	// Interval is the time between the probes of Run, at least
+	// MinFsyncProbeInterval.
+	Interval time.Duration
+
+	// Size is the # of bytes written by every probe, 4KB by default and at
+	// most MaxFsyncProbeSize.
+	Size int
+
+	// Window is the # of latencies kept per mount to calculate the
+	// percentiles, 100 by default.
+	Window int
+
+	mu     sync.Mutex
+	mounts map[string]*fsyncSeries
+}
+
+// fsyncSeries are the last latencies of the probes of a mount.
+type fsyncSeries struct {
+	latencies []time.Duration
+	errors    uint64
+	lastError string
+}
+
+// FsyncLatency represents the latency distribution of the probes of a mount.
+type FsyncLatency struct {
+	Mount     string  `json:"mount"`          // Directory probed
+	Samples   int     `json:"samples"`        // # of latencies in the window
+	Errors    uint64  `json:"errors"`         // # of failed probes
+	LastError string  `json:"lasterror"`      // Error of the last failed probe
+	Mean      float64 `json:"mean" unit:"ms"` // Mean latency in milliseconds
+	P50       float64 `json:"p50" unit:"ms"`  // Median latency in milliseconds
+	P95       float64 `json:"p95" unit:"ms"`  // 95th percentile of the latency in milliseconds
+	P99       float64 `json:"p99" unit:"ms"`  // 99th percentile of the latency in milliseconds
+	Max       float64 `json:"max" unit:"ms"`  // Max latency in milliseconds
+}
+
+// Run probes the mounts every Interval until the context is cancelled, and
+// returns ctx.Err(). The probes that fail are counted in the errors of their
+// mount.
+func (p *FsyncProbe) Run(ctx context.Context) error {
+	if len(p.Mounts) == 0 {
+		return errors.New("The fsync probe needs at least one mount")
+	}
+	interval := p.Interval
+	if interval < MinFsyncProbeInterval {
+		interval = MinFsyncProbeInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.Probe()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe writes and fsyncs a file on every mount once and records the
+// latencies.
+func (p *FsyncProbe) Probe() {
+	size := p.Size
+	if size <= 0 {
+		size = defaultFsyncProbeSize
+	}
+	if size > MaxFsyncProbeSize {
+		size = MaxFsyncProbeSize
+	}
+	content := make([]byte, size)
+
+	for _, mount := range p.Mounts {
+		latency, err := fsyncLatency(mount, content)
+		p.record(mount, latency, err)
+	}
+}
+
+// fsyncLatency writes the content to a temporary file in the directory and
+// returns the time it took to write and fsync it. The file is removed.
+func fsyncLatency(dir string, content []byte) (latency time.Duration, err error) {
+	file, err := os.CreateTemp(dir, ".sysstats-fsync-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	start := time.Now()
+	if _, err = file.Write(content); err != nil {
+		return 0, err
+	}
+	if err = file.Sync(); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// record adds the latency of a probe of a mount to its window.
+func (p *FsyncProbe) record(mount string, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.mounts == nil {
+		p.mounts = map[string]*fsyncSeries{}
+	}
+	series, ok := p.mounts[mount]
+	if !ok {
+		series = &fsyncSeries{}
+		p.mounts[mount] = series
+	}
+
+	if err != nil {
+		series.errors++
+		series.lastError = err.Error()
+		logWarn("fsync probe failed", "mount", mount, "error", err)
+		return
+	}
+
+	window := p.Window
+	if window <= 0 {
+		window = defaultFsyncProbeWindow
+	}
+	series.latencies = append(series.latencies, latency)
+	if len(series.latencies) > window {
+		series.latencies = series.latencies[len(series.latencies)-window:]
+	}
+}
+
+// Latencies returns the latency distribution of the probes of every mount
+// probed so far.
+func (p *FsyncProbe) Latencies() (fsyncLatencyArr []FsyncLatency) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	fsyncLatencyArr = make([]FsyncLatency, 0, len(p.mounts))
+	for mount, series := range p.mounts {
+		fsyncLatencyArr = append(fsyncLatencyArr, series.latency(mount))
+	}
+	sort.Slice(fsyncLatencyArr, func(i, j int) bool {
+		return fsyncLatencyArr[i].Mount < fsyncLatencyArr[j].Mount
+	})
+
+	return fsyncLatencyArr
+}
+
+// latency calculates the latency distribution of the window of a mount.
+func (s *fsyncSeries) latency(mount string) (latency FsyncLatency) {
+	latency = FsyncLatency{Mount: mount, Samples: len(s.latencies), Errors: s.errors, LastError: s.lastError}
+	if len(s.latencies) == 0 {
+		return latency
+	}
+
+	milliseconds := make([]float64, 0, len(s.latencies))
+	var total float64
+	for _, d := range s.latencies {
+		ms := float64(d) / float64(time.Millisecond)
+		milliseconds = append(milliseconds, ms)
+		total += ms
+	}
+	sort.Float64s(milliseconds)
+
+	percentile := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(milliseconds)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return milliseconds[i]
+	}
+	latency.Mean = total / float64(len(milliseconds))
+	latency.P50 = percentile(0.50)
+	latency.P95 = percentile(0.95)
+	latency.P99 = percentile(0.99)
+	latency.Max = milliseconds[len(milliseconds)-1]
+
+	return latency
+}
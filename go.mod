@@ -0,0 +1,3 @@
+module github.com/rafacas/sysstats
+
+go 1.23
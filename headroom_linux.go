@@ -0,0 +1,149 @@
+// +build linux
+
+package sysstats
+
+import (
+	"sort"
+	"time"
+)
+
+// ResourceHeadroom represents the usage of a resource as a fraction of its
+// capacity.
+//
+// Resources and their units:
+//   cpu       - CPU time of all the CPUs in %, capacity 100.
+//   mem       - Memory in kilobytes (buffers and cache count as free).
+//   diskspace - Space of a file system in kilobytes (Name is the mount point).
+//   diskio    - Time a disk was busy in milliseconds (Name is the disk).
+//   net       - Busiest direction of an interface in bits per second, capacity
+//               is the link speed (Name is the interface).
+//   fds       - File handles.
+//   conntrack - Entries of the conntrack table.
+//   pids      - PIDs (every thread takes one).
+type ResourceHeadroom struct {
+	Resource string  `json:"resource"`       // Resource (cpu, mem, diskspace,...)
+	Name     string  `json:"name,omitempty"` // Device or mount point (if the resource has several)
+	Used     float64 `json:"used"`           // Usage of the resource
+	Capacity float64 `json:"capacity"`       // Capacity of the resource
+	Fraction float64 `json:"fraction"`       // Usage as a fraction of the capacity (0-1)
+}
+
+// headroomRawStats represents the counters the rates of the headroom are
+// calculated from.
+type headroomRawStats struct {
+	cpu  CpusRawStats
+	disk []DiskRawStats
+	net  NetRawStats
+}
+
+// getHeadroomRawStats gets the CPU, disk IO and network counters.
+func getHeadroomRawStats() (rawStats headroomRawStats, err error) {
+	rawStats = headroomRawStats{}
+	rawStats.cpu, err = getCpuRawStats()
+	if err != nil {
+		return headroomRawStats{}, err
+	}
+	rawStats.disk, err = getDiskRawStats()
+	if err != nil {
+		return headroomRawStats{}, err
+	}
+	rawStats.net, err = getNetRawStats()
+	if err != nil {
+		return headroomRawStats{}, err
+	}
+
+	return rawStats, nil
+}
+
+// getHeadroom calculates the usage of every resource: the rates (CPU, disk IO
+// and network) between 2 samples, the rest when it's called. The resources
+// whose capacity is unknown (e.g. the speed of virtual interfaces) are left
+// out.
+func getHeadroom(firstSample headroomRawStats, secondSample headroomRawStats) (headroom []ResourceHeadroom, err error) {
+	headroom = []ResourceHeadroom{}
+	add := func(resource string, name string, used float64, capacity float64) {
+		if capacity <= 0 {
+			return
+		}
+		headroom = append(headroom, ResourceHeadroom{
+			Resource: resource,
+			Name:     name,
+			Used:     used,
+			Capacity: capacity,
+			Fraction: used / capacity,
+		})
+	}
+
+	cpusAvgStats, err := getCpuAvgStats(firstSample.cpu, secondSample.cpu)
+	if err != nil {
+		return nil, err
+	}
+	add(`cpu`, "", cpusAvgStats[`cpu`][`total`], 100)
+
+	memStats, err := getMemStats()
+	if err != nil {
+		return nil, err
+	}
+	add(`mem`, "", float64(memStats[`memtotal`]-memStats[`realfree`]), float64(memStats[`memtotal`]))
+
+	diskUsageArr, err := getDiskUsage()
+	if err != nil {
+		return nil, err
+	}
+	for _, diskUsage := range diskUsageArr {
+		add(`diskspace`, diskUsage.MountedOn, float64(diskUsage.Used), float64(diskUsage.Used+diskUsage.Available))
+	}
+
+	for _, secondDisk := range secondSample.disk {
+		for _, firstDisk := range firstSample.disk {
+			if firstDisk.Name != secondDisk.Name {
+				continue
+			}
+			elapsed := time.Duration(secondDisk.SampleTime - firstDisk.SampleTime)
+			add(`diskio`, secondDisk.Name, float64(secondDisk.IOTicks-firstDisk.IOTicks),
+				float64(elapsed.Milliseconds()))
+			break
+		}
+	}
+
+	netAvgStats, err := getNetAvgStats(firstSample.net, secondSample.net)
+	if err != nil {
+		return nil, err
+	}
+	ifaceNames := make([]string, 0, len(netAvgStats))
+	for ifaceName := range netAvgStats {
+		ifaceNames = append(ifaceNames, ifaceName)
+	}
+	sort.Strings(ifaceNames)
+	for _, ifaceName := range ifaceNames {
+		ifaceAvgStats := netAvgStats[ifaceName]
+		busiest := ifaceAvgStats[`rxbytes`]
+		if ifaceAvgStats[`txbytes`] > busiest {
+			busiest = ifaceAvgStats[`txbytes`]
+		}
+		add(`net`, ifaceName, busiest*8, float64(secondSample.net[ifaceName][ifaceSpeedKey]))
+	}
+
+	fileStats, err := getFileStats()
+	if err != nil {
+		return nil, err
+	}
+	add(`fds`, "", float64(fileStats.FhAlloc), float64(fileStats.FhMax))
+
+	conntrackCount, conntrackMax := getConntrackUsage()
+	add(`conntrack`, "", float64(conntrackCount), float64(conntrackMax))
+
+	procLimits, err := getProcLimits()
+	if err != nil {
+		return nil, err
+	}
+	add(`pids`, "", float64(procLimits.Total), float64(procLimits.PidMax))
+
+	return headroom, nil
+}
+
+// getHeadroomOver returns the usage of every resource as a fraction of its
+// capacity, with the rates calculated between 2 samples taken d apart.
+func getHeadroomOver(d time.Duration) (headroom []ResourceHeadroom, err error) {
+	return sampleOver(d, getHeadroomRawStats, getHeadroom)
+}
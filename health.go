@@ -0,0 +1,97 @@
+package sysstats
+
+import (
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CollectorHealth represents the health of one of the collectors of the
+// snapshots, so a degraded stats pipeline can be told apart from a quiet
+// system.
+type CollectorHealth struct {
+	Collections uint64        `json:"collections"`        // # of collections
+	Errors      uint64        `json:"errors"`             // # of failed collections
+	Duration    time.Duration `json:"duration" unit:"ns"` // Duration of the last collection
+	Bytes       uint64        `json:"bytes" unit:"bytes"` // # of bytes parsed by the last collection
+	LastSuccess time.Time     `json:"lastsuccess"`        // Time of the last successful collection
+	LastError   string        `json:"lasterror"`          // Error of the last failed collection
+}
+
+// collectorsHealth is the health of all the collectors since the process
+// started.
+var collectorsHealth = struct {
+	sync.Mutex
+	collectors map[string]CollectorHealth
+}{collectors: map[string]CollectorHealth{}}
+
+// bytesParsed is the # of bytes read by the collectors. The bytes parsed by
+// one collection are approximate when several snapshots are collected
+// concurrently.
+var bytesParsed atomic.Uint64
+
+// countingFile counts the bytes read from a file in bytesParsed.
+type countingFile struct {
+	fs.File
+}
+
+// Read reads from the file and counts the bytes read.
+func (f countingFile) Read(p []byte) (n int, err error) {
+	n, err = f.File.Read(p)
+	bytesParsed.Add(uint64(n))
+	return n, err
+}
+
+// openStatsFile opens a file of the collectors (/proc/stat,...) for reading
+// from the stats file system (see SetStatsFS).
+func openStatsFile(path string) (countingFile, error) {
+	file, err := getStatsFS().Open(statsPath(path))
+	if err != nil {
+		return countingFile{}, err
+	}
+
+	return countingFile{file}, nil
+}
+
+// readStatsFile reads a whole file of the collectors (/proc/loadavg,...) from
+// the stats file system (see SetStatsFS).
+func readStatsFile(path string) ([]byte, error) {
+	content, err := fs.ReadFile(getStatsFS(), statsPath(path))
+	bytesParsed.Add(uint64(len(content)))
+
+	return content, err
+}
+
+// recordCollection updates the health of a collector after a collection that
+// started at start.
+func recordCollection(collector string, start time.Time, bytes uint64, err error) {
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+
+	health := collectorsHealth.collectors[collector]
+	health.Collections++
+	health.Duration = time.Since(start)
+	health.Bytes = bytes
+	if err != nil {
+		health.Errors++
+		health.LastError = err.Error()
+	} else {
+		health.LastSuccess = time.Now()
+	}
+	collectorsHealth.collectors[collector] = health
+}
+
+// getCollectorsHealth returns the health of the collectors that have run at
+// least once.
+func getCollectorsHealth() (health map[string]CollectorHealth) {
+	collectorsHealth.Lock()
+	defer collectorsHealth.Unlock()
+
+	health = make(map[string]CollectorHealth, len(collectorsHealth.collectors))
+	for collector, collectorHealth := range collectorsHealth.collectors {
+		health[collector] = collectorHealth
+	}
+
+	return health
+}
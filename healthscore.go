@@ -0,0 +1,164 @@
+package sysstats
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// HealthWeights represents the weights of the dimensions of the host health
+// score. A dimension with weight 0 is ignored.
+type HealthWeights struct {
+	Cpu       float64 `json:"cpu"`       // Weight of the CPU saturation
+	Mem       float64 `json:"mem"`       // Weight of the memory pressure
+	DiskFill  float64 `json:"diskfill"`  // Weight of the fullest file system
+	DiskIO    float64 `json:"diskio"`    // Weight of the busiest disk
+	NetErrors float64 `json:"neterrors"` // Weight of the network errors and drops
+}
+
+// DefaultHealthWeights are the weights used when none is given.
+var DefaultHealthWeights = HealthWeights{Cpu: 0.25, Mem: 0.25, DiskFill: 0.2, DiskIO: 0.15, NetErrors: 0.15}
+
+// Thresholds of the dimensions of the health score: a dimension is fully
+// healthy up to its ok value and fully unhealthy from its bad value.
+const (
+	healthCpuOk       = 70.0 // % of CPU time busy
+	healthCpuBad      = 100.0
+	healthMemOk       = 80.0 // % of memory used (buffers and cache count as free)
+	healthMemBad      = 98.0
+	healthDiskFillOk  = 80.0 // % of the fullest file system used
+	healthDiskFillBad = 98.0
+	healthDiskIOOk    = 60.0 // % of time the busiest disk was doing IO
+	healthDiskIOBad   = 100.0
+	healthNetErrOk    = 0.0 // % of the packets with errors or dropped
+	healthNetErrBad   = 1.0
+)
+
+// HostHealth represents the health of a host between 2 snapshots, from 0
+// (unhealthy) to 100 (healthy), with the score of every dimension so the
+// cause of a low score can be told at a glance.
+type HostHealth struct {
+	Score     float64 `json:"score"`     // Weighted mean of the dimensions (0-100)
+	Cpu       float64 `json:"cpu"`       // CPU saturation score (busy time and run queue)
+	Mem       float64 `json:"mem"`       // Memory pressure score
+	DiskFill  float64 `json:"diskfill"`  // Score of the fullest file system
+	DiskIO    float64 `json:"diskio"`    // Score of the busiest disk
+	NetErrors float64 `json:"neterrors"` // Network errors and drops score
+	Worst     string  `json:"worst"`     // Dimension with the lowest score (empty if all are 100)
+}
+
+// healthFraction scales value to 0 (up to ok) - 1 (from bad).
+func healthFraction(value float64, ok float64, bad float64) float64 {
+	if value <= ok {
+		return 0
+	}
+
+	return math.Min((value-ok)/(bad-ok), 1)
+}
+
+// HealthScore calculates the health of the host between 2 snapshots with
+// the given weights, or DefaultHealthWeights when they're all 0. It's
+// opinionated, meant for quick triage of a fleet: every dimension is 100
+// while it's below a sane threshold (e.g. 70% of CPU busy, 80% of a file
+// system used) and drops to 0 when the resource is exhausted. The CPU is also unhealthy when there are
+// more runnable tasks than CPUs.
+func HealthScore(first Snapshot, second Snapshot, weights HealthWeights) (hostHealth HostHealth, err error) {
+	comparison, err := compare(first, second)
+	if err != nil {
+		return HostHealth{}, err
+	}
+	if weights == (HealthWeights{}) {
+		weights = DefaultHealthWeights
+	}
+
+	// CPU: busy time, or the tasks waiting for a CPU (the run queue includes
+	// the running ones) up to 1 waiting per CPU
+	cpuBad := healthFraction(comparison.Cpu[`cpu`][CpuTotal], healthCpuOk, healthCpuBad)
+	if cpus := len(second.Cpu) - 1; cpus > 0 {
+		cpuBad = math.Max(cpuBad, healthFraction(float64(second.Proc.RunQueue)/float64(cpus), 1, 2))
+	}
+
+	memBad := 0.0
+	if memTotal := second.Mem[MemTotal]; memTotal > 0 {
+		memUsed := float64(memTotal-second.Mem[MemRealFree]) * 100.00 / float64(memTotal)
+		memBad = healthFraction(memUsed, healthMemOk, healthMemBad)
+	}
+
+	diskFillBad := 0.0
+	for _, diskUsage := range second.DiskUsage {
+		diskFillBad = math.Max(diskFillBad, healthFraction(float64(diskUsage.UsedPer), healthDiskFillOk, healthDiskFillBad))
+	}
+
+	diskIOBad := 0.0
+	for _, secondDisk := range second.Disk {
+		for _, firstDisk := range first.Disk {
+			if firstDisk.Name != secondDisk.Name {
+				continue
+			}
+			elapsed := time.Duration(secondDisk.SampleTime - firstDisk.SampleTime).Milliseconds()
+			if elapsed > 0 && secondDisk.IOTicks >= firstDisk.IOTicks {
+				util := float64(secondDisk.IOTicks-firstDisk.IOTicks) * 100.00 / float64(elapsed)
+				diskIOBad = math.Max(diskIOBad, healthFraction(util, healthDiskIOOk, healthDiskIOBad))
+			}
+			break
+		}
+	}
+
+	netPkts, netErrs := 0.0, 0.0
+	for _, ifaceAvgStats := range comparison.Net {
+		netPkts += ifaceAvgStats[IfaceRxPkts] + ifaceAvgStats[IfaceTxPkts]
+		netErrs += ifaceAvgStats[IfaceRxErrs] + ifaceAvgStats[IfaceRxDrop] +
+			ifaceAvgStats[IfaceTxErrs] + ifaceAvgStats[IfaceTxDrop]
+	}
+	netErrBad := 0.0
+	if netPkts > 0 {
+		netErrBad = healthFraction(netErrs*100.00/netPkts, healthNetErrOk, healthNetErrBad)
+	}
+
+	hostHealth = HostHealth{
+		Cpu:       100 * (1 - cpuBad),
+		Mem:       100 * (1 - memBad),
+		DiskFill:  100 * (1 - diskFillBad),
+		DiskIO:    100 * (1 - diskIOBad),
+		NetErrors: 100 * (1 - netErrBad),
+	}
+
+	dimensions := []struct {
+		name   string
+		weight float64
+		score  float64
+	}{
+		{`cpu`, weights.Cpu, hostHealth.Cpu},
+		{`mem`, weights.Mem, hostHealth.Mem},
+		{`diskfill`, weights.DiskFill, hostHealth.DiskFill},
+		{`diskio`, weights.DiskIO, hostHealth.DiskIO},
+		{`neterrors`, weights.NetErrors, hostHealth.NetErrors},
+	}
+	totalWeight := 0.0
+	worst := 100.0
+	for _, dimension := range dimensions {
+		if dimension.weight <= 0 {
+			continue
+		}
+		hostHealth.Score += dimension.weight * dimension.score
+		totalWeight += dimension.weight
+		if dimension.score < worst {
+			worst = dimension.score
+			hostHealth.Worst = dimension.name
+		}
+	}
+	if totalWeight <= 0 {
+		return HostHealth{}, errors.New("At least one of the weights of the health score must be positive")
+	}
+	hostHealth.Score /= totalWeight
+
+	return hostHealth, nil
+}
+
+// getHealthScoreOver returns the health of the host between 2 snapshots
+// taken d apart with the given weights.
+func getHealthScoreOver(d time.Duration, weights HealthWeights) (hostHealth HostHealth, err error) {
+	return sampleOver(d, getSnapshot, func(first Snapshot, second Snapshot) (HostHealth, error) {
+		return HealthScore(first, second, weights)
+	})
+}
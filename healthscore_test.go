@@ -0,0 +1,24 @@
+package sysstats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHealthScoreDefaultWeights(t *testing.T) {
+	first := Snapshot{Time: time.Unix(0, 0), DiskUsage: []DiskUsage{{MountedOn: `/`, UsedPer: 95}}}
+	second := first
+	second.Time = time.Unix(1, 0)
+
+	want, err := HealthScore(first, second, DefaultHealthWeights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := HealthScore(first, second, HealthWeights{})
+	if err != nil {
+		t.Fatalf("HealthScore() without weights error = %v", err)
+	}
+	if got.Score == 100 || got.Score != want.Score || got.Worst != `diskfill` {
+		t.Errorf("HealthScore() without weights = %+v, want %+v", got, want)
+	}
+}
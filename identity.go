@@ -0,0 +1,47 @@
+package sysstats
+
+import (
+	"os"
+)
+
+// Identity represents the identity of a host and the labels attached to every
+// snapshot, metric and point it exports.
+type Identity struct {
+	Hostname    string            `json:"hostname,omitempty"`    // Overrides the host name of the system
+	Role        string            `json:"role,omitempty"`        // Role of the host (e.g. db, web)
+	Environment string            `json:"environment,omitempty"` // Environment of the host (e.g. prod, staging)
+	Labels      map[string]string `json:"labels,omitempty"`      // Custom labels
+}
+
+// Host returns the host name of the identity, or the one of the system if it
+// isn't overridden.
+func (i *Identity) Host() string {
+	if i != nil && i.Hostname != "" {
+		return i.Hostname
+	}
+	host, _ := os.Hostname()
+
+	return host
+}
+
+// AllLabels returns the custom labels plus the host, role and environment
+// ones (the labels with the same key are overridden by them).
+func (i *Identity) AllLabels() map[string]string {
+	labels := map[string]string{}
+	if i == nil {
+		return labels
+	}
+
+	for key, value := range i.Labels {
+		labels[key] = value
+	}
+	labels[`host`] = i.Host()
+	if i.Role != "" {
+		labels[`role`] = i.Role
+	}
+	if i.Environment != "" {
+		labels[`environment`] = i.Environment
+	}
+
+	return labels
+}
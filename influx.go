@@ -0,0 +1,202 @@
+package sysstats
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InfluxEncoder encodes the stats in InfluxDB line protocol, so a collection
+// loop can write them straight to Telegraf or InfluxDB, e.g.:
+//   encoder := sysstats.InfluxEncoder{Tags: identity.AllLabels()}
+//   cpusAvgStats, err := sysstats.GetCpuStatsOver(10 * time.Second)
+//   ...
+//   conn.Write(encoder.Cpu(cpusAvgStats, time.Now()))
+// Every stat is a field of the measurement of its family (cpu, net, disk,
+// mem, load, proc) and the element it belongs to (CPU, interface, disk) is a
+// tag. All the values are written as floats.
+type InfluxEncoder struct {
+	Tags map[string]string // Tags of every line (e.g. host, see Identity.AllLabels)
+}
+
+// Cpu encodes the % CPU utilization as the measurement cpu with the tag
+// cpu=[name], e.g. cpu,cpu=cpu0 user=1.5,system=0.5,... 1700000000000000000
+func (e InfluxEncoder) Cpu(cpusAvgStats CpusAvgStats, t time.Time) []byte {
+	var buf bytes.Buffer
+	for _, cpuName := range sortedKeys(cpusAvgStats) {
+		e.encode(&buf, `cpu`, map[string]string{`cpu`: cpuName}, cpusAvgStats[cpuName], t)
+	}
+
+	return buf.Bytes()
+}
+
+// Net encodes the network traffic as the measurement net with the tag
+// iface=[name].
+func (e InfluxEncoder) Net(netAvgStats NetAvgStats, t time.Time) []byte {
+	var buf bytes.Buffer
+	for _, ifaceName := range sortedKeys(netAvgStats) {
+		e.encode(&buf, `net`, map[string]string{`iface`: ifaceName}, netAvgStats[ifaceName], t)
+	}
+
+	return buf.Bytes()
+}
+
+// Disk encodes the IO averages as the measurement disk with the tag
+// disk=[name].
+func (e InfluxEncoder) Disk(diskAvgStatsArr []DiskAvgStats, t time.Time) []byte {
+	var buf bytes.Buffer
+	for _, diskAvgStats := range diskAvgStatsArr {
+		e.encode(&buf, `disk`, nil, diskAvgStats, t)
+	}
+
+	return buf.Bytes()
+}
+
+// Mem encodes the memory stats as the measurement mem.
+func (e InfluxEncoder) Mem(memStats MemStats, t time.Time) []byte {
+	var buf bytes.Buffer
+	e.encode(&buf, `mem`, nil, memStats, t)
+
+	return buf.Bytes()
+}
+
+// LoadAvg encodes the load average as the measurement load.
+func (e InfluxEncoder) LoadAvg(loadAvg LoadAvg, t time.Time) []byte {
+	var buf bytes.Buffer
+	e.encode(&buf, `load`, nil, loadAvg, t)
+
+	return buf.Bytes()
+}
+
+// Proc encodes the processes stats as the measurement proc.
+func (e InfluxEncoder) Proc(procAvgStats ProcAvgStats, t time.Time) []byte {
+	var buf bytes.Buffer
+	e.encode(&buf, `proc`, nil, procAvgStats, t)
+
+	return buf.Bytes()
+}
+
+// encode writes a line of the measurement with the numeric values of the
+// stats (a map or a struct) as fields. The string values named name become
+// the tag [measurement]=[value].
+func (e InfluxEncoder) encode(buf *bytes.Buffer, measurement string, tags map[string]string, stats interface{}, t time.Time) {
+	allTags := map[string]string{}
+	for key, value := range e.Tags {
+		allTags[key] = value
+	}
+	for key, value := range tags {
+		allTags[key] = value
+	}
+
+	fields := map[string]float64{}
+	addInfluxFields(reflect.ValueOf(stats), measurement, fields, allTags)
+	writeInfluxLine(buf, measurement, allTags, fields, t)
+}
+
+// addInfluxFields adds the numeric values of a map or a struct to the fields,
+// with the JSON names of the struct fields as keys. The major and minor
+// numbers of the disks are left out, and the string field named name
+// becomes the tag [measurement]=[value].
+func addInfluxFields(v reflect.Value, measurement string, fields map[string]float64, tags map[string]string) {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if value, ok := influxFieldValue(iter.Value()); ok {
+				fields[iter.Key().String()] = value
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				addInfluxFields(v.Field(i), measurement, fields, tags)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			key, _, _ := strings.Cut(field.Tag.Get(`json`), `,`)
+			if key == "" {
+				key = field.Name
+			}
+			switch {
+			case key == `-` || key == `major` || key == `minor`:
+			case key == `name` && field.Type.Kind() == reflect.String:
+				if name := v.Field(i).String(); name != "" {
+					tags[measurement] = name
+				}
+			default:
+				if value, ok := influxFieldValue(v.Field(i)); ok {
+					fields[key] = value
+				}
+			}
+		}
+	}
+}
+
+// influxFieldValue returns the value of a numeric field as a float.
+func influxFieldValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
+
+// writeInfluxLine writes a line of the measurement with the tags and fields
+// sorted by key. The NaN and infinite values are skipped, as line protocol
+// can't represent them, and nothing is written without fields.
+func writeInfluxLine(buf *bytes.Buffer, measurement string, tags map[string]string, fields map[string]float64, t time.Time) {
+	written := 0
+	for _, key := range sortedKeys(fields) {
+		value := fields[key]
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		if written == 0 {
+			buf.WriteString(influxMeasurementEscape(measurement))
+			for _, tagKey := range sortedKeys(tags) {
+				buf.WriteString("," + influxEscape(tagKey) + "=" + influxEscape(tags[tagKey]))
+			}
+			buf.WriteString(" ")
+		} else {
+			buf.WriteString(",")
+		}
+		buf.WriteString(influxEscape(key) + "=" + strconv.FormatFloat(value, 'f', -1, 64))
+		written++
+	}
+	if written > 0 {
+		buf.WriteString(" " + strconv.FormatInt(t.UnixNano(), 10) + "\n")
+	}
+}
+
+// influxEscaper escapes the commas, spaces and equal signs of a line
+// protocol tag key, tag value or field key.
+var influxEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+// influxMeasurementEscaper escapes the commas and spaces of a line protocol
+// measurement, where the equal signs are literal.
+var influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+
+// influxEscape escapes a line protocol tag key, tag value or field key.
+func influxEscape(s string) string {
+	return influxEscaper.Replace(s)
+}
+
+// influxMeasurementEscape escapes a line protocol measurement.
+func influxMeasurementEscape(s string) string {
+	return influxMeasurementEscaper.Replace(s)
+}
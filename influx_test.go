@@ -0,0 +1,99 @@
+package sysstats
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfluxEncoderSkipsNaN(t *testing.T) {
+	encoder := InfluxEncoder{Tags: map[string]string{`host`: `web 1`}}
+	cpusAvgStats := CpusAvgStats{`cpu0`: CpuAvgStats{`user`: 1.5, `system`: math.NaN(), `idle`: math.Inf(1)}}
+
+	got := string(encoder.Cpu(cpusAvgStats, time.Unix(0, 1)))
+	want := "cpu,cpu=cpu0,host=web\\ 1 user=1.5 1\n"
+	if got != want {
+		t.Errorf("Cpu() = %q, want %q", got, want)
+	}
+
+	// A line without finite values is left out
+	cpusAvgStats = CpusAvgStats{`cpu0`: CpuAvgStats{`user`: math.NaN()}}
+	if got := encoder.Cpu(cpusAvgStats, time.Unix(0, 1)); len(got) != 0 {
+		t.Errorf("Cpu() = %q, want nothing", got)
+	}
+}
+
+func TestInfluxEncoderStructs(t *testing.T) {
+	var encoder InfluxEncoder
+
+	got := string(encoder.Proc(ProcAvgStats{NewProcs: 2, ProcStats: ProcStats{Running: 3}}, time.Unix(0, 1)))
+	if !strings.HasPrefix(got, "proc ") || !strings.Contains(got, "newprocs=2") || !strings.Contains(got, "running=3") {
+		t.Errorf("Proc() = %q, want the fields of ProcAvgStats and ProcStats", got)
+	}
+
+	got = string(encoder.Disk([]DiskAvgStats{{Major: 8, Name: `sda`, ReadIOs: 4}}, time.Unix(0, 1)))
+	if !strings.HasPrefix(got, "disk,disk=sda ") || strings.Contains(got, "major") || !strings.Contains(got, "readios=4") {
+		t.Errorf("Disk() = %q, want the tag disk=sda without the major number", got)
+	}
+}
+
+func TestInfluxSinkMatchesEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewInfluxSink(&buf)
+	snapshot := Snapshot{
+		Time:      time.Unix(0, 1),
+		Identity:  &Identity{Hostname: `web1`},
+		DiskUsage: []DiskUsage{{MountedOn: `/`, UsedPer: 40}},
+		Sinks:     []SinkStats{{Name: `influx`, Written: 2}},
+		Custom:    Metrics{`app.queue.len`: math.NaN()},
+	}
+	if err := sink.Write(snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"diskusage,host=web1,mount=/ ",
+		"sysstats,host=web1,sink=influx ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Write() = %q, want a line starting with %q", got, want)
+		}
+	}
+	if strings.Contains(got, "app") || strings.Contains(got, "NaN") {
+		t.Errorf("Write() = %q, want the NaN metric skipped", got)
+	}
+
+	// The CPU rates are written the same as by the encoder
+	first := Snapshot{Time: time.Unix(0, 1), Cpu: CpusRawStats{`cpu0`: CpuRawStats{CpuUser: 10, CpuIdle: 10, CpuTotal: 20}}}
+	second := Snapshot{Time: time.Unix(1, 1), Cpu: CpusRawStats{`cpu0`: CpuRawStats{CpuUser: 20, CpuIdle: 20, CpuTotal: 40}}}
+	comparison, err := compare(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := string(InfluxEncoder{}.Cpu(comparison.Cpu, second.Time))
+	sink = NewInfluxSink(&buf)
+	if err := sink.Write(first); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := sink.Write(second); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Write() = %q, want the line of the encoder %q", buf.String(), want)
+	}
+}
+
+func TestWriteInfluxLineEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	writeInfluxLine(&buf, `a=b c,d`, map[string]string{`k=1`: `v 1,2`}, map[string]float64{`f=x`: 1}, time.Unix(0, 1))
+
+	// The equal signs are only escaped in the tags and field keys
+	want := "a=b\\ c\\,d,k\\=1=v\\ 1\\,2 f\\=x=1 1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeInfluxLine() = %q, want %q", got, want)
+	}
+}
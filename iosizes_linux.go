@@ -0,0 +1,210 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ioSizeBuckets are the upper bounds (in bytes) of the buckets of the IO size
+// histograms. The IOs bigger than the last one go to an extra bucket.
+var ioSizeBuckets = []uint64{4096, 8192, 16384, 32768, 65536, 131072, 262144, 524288}
+
+// tracefsDirs are the mount points of tracefs, the second one being the
+// legacy path under debugfs.
+var tracefsDirs = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+
+// IOSizeBucket represents the # of IOs of a size range.
+type IOSizeBucket struct {
+	UpTo   uint64 `json:"upto" unit:"bytes"` // Max size of the IOs in bytes (0 for the last, unbounded, bucket)
+	Reads  uint64 `json:"reads"`             // # of reads completed
+	Writes uint64 `json:"writes"`            // # of writes completed
+}
+
+// DiskIOSizes represents the IO size distribution and the read/write mix of
+// a disk over an interval. Many small IOs point to a random workload, few
+// big ones to a sequential one.
+type DiskIOSizes struct {
+	Major      int            `json:"major"`                   // Major number for the disk
+	Minor      int            `json:"minor"`                   // Minor number for the disk
+	Name       string         `json:"name"`                    // Disk name (major:minor if unknown)
+	Reads      uint64         `json:"reads"`                   // # of reads completed
+	Writes     uint64         `json:"writes"`                  // # of writes completed
+	ReadBytes  uint64         `json:"readbytes" unit:"bytes"`  // # of bytes read
+	WriteBytes uint64         `json:"writebytes" unit:"bytes"` // # of bytes written
+	ReadPer    float64        `json:"readper" unit:"%"`        // % of the IOs that are reads
+	AvgSize    float64        `json:"avgsize" unit:"bytes"`    // Mean size of the IOs in bytes
+	Buckets    []IOSizeBucket `json:"buckets"`                 // IO size histogram
+}
+
+// getDiskIOSizesOver traces the requests completed by the block devices
+// (block:block_rq_complete tracepoint) for d and returns the IO size
+// histogram of every disk. It needs tracefs (root) and creates its own
+// tracing instance, so it doesn't disturb other tracers. The events lost
+// because the trace buffer filled up are returned as the # of overruns.
+func getDiskIOSizesOver(d time.Duration) (diskIOSizesArr []DiskIOSizes, overruns uint64, err error) {
+	tracefs := ""
+	for _, dir := range tracefsDirs {
+		if _, err := os.Stat(filepath.Join(dir, "instances")); err == nil {
+			tracefs = dir
+			break
+		}
+	}
+	if tracefs == "" {
+		return nil, 0, errors.New("tracefs is not mounted")
+	}
+
+	instance := filepath.Join(tracefs, "instances", fmt.Sprintf("sysstats-%d", os.Getpid()))
+	if err = os.Mkdir(instance, 0755); err != nil {
+		return nil, 0, err
+	}
+	defer os.Remove(instance)
+
+	enable := filepath.Join(instance, "events", "block", "block_rq_complete", "enable")
+	if err = ioutil.WriteFile(enable, []byte("1"), 0644); err != nil {
+		return nil, 0, err
+	}
+	time.Sleep(d)
+	if err = ioutil.WriteFile(enable, []byte("0"), 0644); err != nil {
+		return nil, 0, err
+	}
+
+	disks := map[string]*DiskIOSizes{}
+	err = scanLines(filepath.Join(instance, "trace"), func(line string) {
+		major, minor, write, bytes, ok := parseBlockRqComplete(line)
+		if !ok {
+			return
+		}
+		key := strconv.Itoa(major) + ":" + strconv.Itoa(minor)
+		disk, ok := disks[key]
+		if !ok {
+			disk = newDiskIOSizes(major, minor)
+			disks[key] = disk
+		}
+		disk.add(write, bytes)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The stats of every CPU buffer have the format:
+	//   entries: 0
+	//   overrun: 0
+	//   ...
+	cpuStats, _ := filepath.Glob(filepath.Join(instance, "per_cpu", "cpu*", "stats"))
+	for _, path := range cpuStats {
+		scanLines(path, func(line string) {
+			if value, ok := strings.CutPrefix(line, "overrun: "); ok {
+				n, _ := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+				overruns += n
+			}
+		})
+	}
+
+	diskIOSizesArr = make([]DiskIOSizes, 0, len(disks))
+	for key, disk := range disks {
+		disk.Name = key
+		if device, err := filepath.EvalSymlinks(filepath.Join("/sys/dev/block", key)); err == nil {
+			disk.Name = filepath.Base(device)
+		}
+		if ios := disk.Reads + disk.Writes; ios > 0 {
+			disk.ReadPer = float64(disk.Reads) * 100.00 / float64(ios)
+			disk.AvgSize = float64(disk.ReadBytes+disk.WriteBytes) / float64(ios)
+		}
+		diskIOSizesArr = append(diskIOSizesArr, *disk)
+	}
+	sort.Slice(diskIOSizesArr, func(i, j int) bool {
+		return diskIOSizesArr[i].Name < diskIOSizesArr[j].Name
+	})
+
+	return diskIOSizesArr, overruns, nil
+}
+
+// newDiskIOSizes returns the empty histogram of a disk.
+func newDiskIOSizes(major int, minor int) *DiskIOSizes {
+	diskIOSizes := &DiskIOSizes{Major: major, Minor: minor}
+	diskIOSizes.Buckets = make([]IOSizeBucket, len(ioSizeBuckets)+1)
+	for i, upTo := range ioSizeBuckets {
+		diskIOSizes.Buckets[i].UpTo = upTo
+	}
+
+	return diskIOSizes
+}
+
+// add accounts an IO of the disk.
+func (d *DiskIOSizes) add(write bool, bytes uint64) {
+	i := sort.Search(len(ioSizeBuckets), func(i int) bool {
+		return bytes <= ioSizeBuckets[i]
+	})
+	if write {
+		d.Writes++
+		d.WriteBytes += bytes
+		d.Buckets[i].Writes++
+	} else {
+		d.Reads++
+		d.ReadBytes += bytes
+		d.Buckets[i].Reads++
+	}
+}
+
+// parseBlockRqComplete parses an event of the block_rq_complete tracepoint,
+// e.g.:
+//   <idle>-0  [002] d.h1.  1234.567890: block_rq_complete: 259,0 WS () 4317184 + 16 [0]
+// The size is in 512 bytes sectors whatever the sector size of the device.
+// The flushes, discards and the requests without data are skipped.
+func parseBlockRqComplete(line string) (major int, minor int, write bool, bytes uint64, ok bool) {
+	_, event, found := strings.Cut(line, "block_rq_complete: ")
+	if !found {
+		return 0, 0, false, 0, false
+	}
+	fields := strings.Fields(event)
+	if len(fields) < 5 {
+		return 0, 0, false, 0, false
+	}
+
+	majorStr, minorStr, found := strings.Cut(fields[0], ",")
+	if !found {
+		return 0, 0, false, 0, false
+	}
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, 0, false, 0, false
+	}
+	minor, err = strconv.Atoi(minorStr)
+	if err != nil {
+		return 0, 0, false, 0, false
+	}
+
+	// rwbs: R(ead), W(rite), D(iscard), F(lush) followed by modifiers
+	rwbs := strings.TrimPrefix(fields[1], "F")
+	switch {
+	case strings.HasPrefix(rwbs, "R"):
+		write = false
+	case strings.HasPrefix(rwbs, "W"):
+		write = true
+	default:
+		return 0, 0, false, 0, false
+	}
+
+	// The command, between parentheses, may contain spaces
+	for i := 2; i < len(fields)-1; i++ {
+		if fields[i] != "+" {
+			continue
+		}
+		sectors, err := strconv.ParseUint(fields[i+1], 10, 64)
+		if err != nil || sectors == 0 {
+			return 0, 0, false, 0, false
+		}
+		return major, minor, write, sectors * 512, true
+	}
+
+	return 0, 0, false, 0, false
+}
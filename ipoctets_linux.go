@@ -0,0 +1,104 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// IpOctetsRawStats represents the bytes counted at the IP layer and at the
+// network interfaces of a linux system.
+type IpOctetsRawStats struct {
+	InOctets     uint64 `json:"inoctets" unit:"bytes"`      // # of IPv4 bytes received since boot (IpExt InOctets)
+	OutOctets    uint64 `json:"outoctets" unit:"bytes"`     // # of IPv4 bytes sent since boot (IpExt OutOctets)
+	In6Octets    uint64 `json:"in6octets" unit:"bytes"`     // # of IPv6 bytes received since boot (Ip6InOctets)
+	Out6Octets   uint64 `json:"out6octets" unit:"bytes"`    // # of IPv6 bytes sent since boot (Ip6OutOctets)
+	IfaceRxBytes uint64 `json:"ifacerxbytes" unit:"bytes"`  // # of bytes received by all the interfaces since boot
+	IfaceTxBytes uint64 `json:"ifacetxbytes" unit:"bytes"`  // # of bytes transmitted by all the interfaces since boot
+	SampleTime   int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// IpOctetsStats represents the bytes per second counted at the IP layer and
+// at the network interfaces between 2 samples. The interfaces also count the
+// link layer headers and the non IP traffic (ARP, LLDP,...), so the gaps are
+// never 0; a gap that grows with the traffic means packets are dropped
+// between the 2 layers (XDP programs, the firewall in the ingress hook,
+// malformed IP headers,...).
+type IpOctetsStats struct {
+	InOctets     float64 `json:"inoctets" unit:"bytes/s"`     // # of IP bytes (v4 + v6) received per second
+	OutOctets    float64 `json:"outoctets" unit:"bytes/s"`    // # of IP bytes (v4 + v6) sent per second
+	IfaceRxBytes float64 `json:"ifacerxbytes" unit:"bytes/s"` // # of bytes received by the interfaces per second
+	IfaceTxBytes float64 `json:"ifacetxbytes" unit:"bytes/s"` // # of bytes transmitted by the interfaces per second
+	RxGap        float64 `json:"rxgap" unit:"bytes/s"`        // Bytes per second received by the interfaces but not by IP
+	TxGap        float64 `json:"txgap" unit:"bytes/s"`        // Bytes per second sent by IP but not transmitted by the interfaces
+}
+
+// getIpOctetsRawStats gets the IP layer bytes from the IpExt counters of the
+// file /proc/net/netstat (and the Ip6 ones of /proc/net/snmp6 when IPv6 is
+// enabled), and the bytes of all the network interfaces from /proc/net/dev.
+func getIpOctetsRawStats() (ipOctetsRawStats IpOctetsRawStats, err error) {
+	ipExt, err := readSnmpCounters("/proc/net/netstat", "IpExt")
+	if err != nil {
+		return IpOctetsRawStats{}, err
+	}
+	netRawStats, err := getNetRawStats()
+	if err != nil {
+		return IpOctetsRawStats{}, err
+	}
+
+	ipOctetsRawStats = IpOctetsRawStats{
+		InOctets:  ipExt[`InOctets`],
+		OutOctets: ipExt[`OutOctets`],
+	}
+	snmp6RawStats, err := getSnmp6RawStats()
+	if err != nil && !os.IsNotExist(err) {
+		return IpOctetsRawStats{}, err
+	}
+	ipOctetsRawStats.In6Octets = snmp6RawStats[`Ip6InOctets`]
+	ipOctetsRawStats.Out6Octets = snmp6RawStats[`Ip6OutOctets`]
+
+	for _, ifaceRawStats := range netRawStats {
+		ipOctetsRawStats.IfaceRxBytes += ifaceRawStats[IfaceRxBytes]
+		ipOctetsRawStats.IfaceTxBytes += ifaceRawStats[IfaceTxBytes]
+	}
+	ipOctetsRawStats.SampleTime = time.Now().UnixNano()
+
+	return ipOctetsRawStats, nil
+}
+
+// getIpOctetsStats calculates the IP and interface bytes per second between 2
+// samples and the gaps between them. The interfaces added or removed between
+// the samples make the gaps meaningless for that interval.
+func getIpOctetsStats(firstSample IpOctetsRawStats, secondSample IpOctetsRawStats) (ipOctetsStats IpOctetsStats, err error) {
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	if timeDelta <= 0 {
+		return IpOctetsStats{}, errors.New("The second sample must be taken after the first one")
+	}
+
+	delta := func(first uint64, second uint64) float64 {
+		if second < first {
+			return 0
+		}
+		return float64(second-first) / timeDelta
+	}
+
+	ipOctetsStats = IpOctetsStats{}
+	ipOctetsStats.InOctets = delta(firstSample.InOctets, secondSample.InOctets) +
+		delta(firstSample.In6Octets, secondSample.In6Octets)
+	ipOctetsStats.OutOctets = delta(firstSample.OutOctets, secondSample.OutOctets) +
+		delta(firstSample.Out6Octets, secondSample.Out6Octets)
+	ipOctetsStats.IfaceRxBytes = delta(firstSample.IfaceRxBytes, secondSample.IfaceRxBytes)
+	ipOctetsStats.IfaceTxBytes = delta(firstSample.IfaceTxBytes, secondSample.IfaceTxBytes)
+	ipOctetsStats.RxGap = ipOctetsStats.IfaceRxBytes - ipOctetsStats.InOctets
+	ipOctetsStats.TxGap = ipOctetsStats.OutOctets - ipOctetsStats.IfaceTxBytes
+
+	return ipOctetsStats, nil
+}
+
+// getIpOctetsStatsOver returns the IP and interface bytes per second between
+// 2 samples taken d apart.
+func getIpOctetsStatsOver(d time.Duration) (ipOctetsStats IpOctetsStats, err error) {
+	return sampleOver(d, getIpOctetsRawStats, getIpOctetsStats)
+}
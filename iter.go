@@ -0,0 +1,153 @@
+package sysstats
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+)
+
+// errInvalidInterval is returned by the functions sampling every interval
+// when it isn't greater than 0.
+var errInvalidInterval = errors.New("The sampling interval must be greater than 0")
+
+// CpuSamples returns an iterator over the % CPU utilization of every
+// interval, e.g.:
+//   for cpusAvgStats, err := range sampler.CpuSamples(ctx, time.Second) {
+//       ...
+//   }
+// The samples are taken with SampleCpu, so they share the previous sample
+// (and the warm-up) of the sampler: the first rates are the ones since the
+// previous call of SampleCpu, if any. Otherwise the baseline sample is taken
+// immediately and not yielded. The iteration stops when the context is
+// cancelled or when the loop breaks. The errors of the samples are yielded
+// and the iteration goes on, like the Watch functions skip them, so a
+// partial snapshot (see CollectorError) or a transient failure doesn't end
+// it; only the errors of the context and of an interval not greater than 0
+// (the only one yielded then) stop it.
+func (s *Sampler) CpuSamples(ctx context.Context, interval time.Duration) iter.Seq2[CpusAvgStats, error] {
+	return samplerSamples(ctx, interval, s.SampleCpu)
+}
+
+// NetSamples returns an iterator over the network traffic of every interval
+// taken with SampleNet (see CpuSamples).
+func (s *Sampler) NetSamples(ctx context.Context, interval time.Duration) iter.Seq2[NetAvgStats, error] {
+	return samplerSamples(ctx, interval, s.SampleNet)
+}
+
+// DiskSamples returns an iterator over the IO averages of every interval
+// taken with SampleDisk (see CpuSamples).
+func (s *Sampler) DiskSamples(ctx context.Context, interval time.Duration) iter.Seq2[[]DiskAvgStats, error] {
+	return samplerSamples(ctx, interval, s.SampleDisk)
+}
+
+// ProcSamples returns an iterator over the processes stats of every interval
+// taken with SampleProc (see CpuSamples).
+func (s *Sampler) ProcSamples(ctx context.Context, interval time.Duration) iter.Seq2[ProcAvgStats, error] {
+	return samplerSamples(ctx, interval, s.SampleProc)
+}
+
+// MemSamples returns an iterator over the memory stats taken every interval.
+// The memory stats are gauges, so the first ones are yielded immediately.
+func (s *Sampler) MemSamples(ctx context.Context, interval time.Duration) iter.Seq2[MemStats, error] {
+	return gauges(ctx, interval, getMemStats)
+}
+
+// Comparisons returns an iterator over the rate changes of the whole system
+// between the snapshots taken every interval with Sample (see CpuSamples).
+func (s *Sampler) Comparisons(ctx context.Context, interval time.Duration) iter.Seq2[Comparison, error] {
+	return samplerSamples(ctx, interval, s.Sample)
+}
+
+// samplerSamples returns an iterator over the rates returned by a sampling
+// method of a Sampler every interval. The call returning ErrWarmingUp (the
+// baseline) is made immediately and not yielded.
+func samplerSamples[A any](ctx context.Context, interval time.Duration, sample func() (A, error)) iter.Seq2[A, error] {
+	return func(yield func(A, error) bool) {
+		var zero A
+		if interval <= 0 {
+			yield(zero, errInvalidInterval)
+			return
+		}
+
+		a, err := sample()
+		if !errors.Is(err, ErrWarmingUp) {
+			if !yield(a, err) || stopsIteration(err) {
+				return
+			}
+		}
+
+		sampleLoop(ctx, interval, zero, sample, latest[A], func(a A, err error) bool {
+			// A failed baseline is taken again without yielding it
+			if errors.Is(err, ErrWarmingUp) {
+				return true
+			}
+			return yield(a, err) && !stopsIteration(err)
+		})
+	}
+}
+
+// sampleLoop takes a raw sample every interval and emits the average between
+// it and the previous one, or the error taking or averaging it, until the
+// context is cancelled or emit returns false.
+func sampleLoop[R any, A any](ctx context.Context, interval time.Duration, previousSample R, raw func() (R, error), avg func(R, R) (A, error), emit func(A, error) bool) {
+	var zero A
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		sample, err := raw()
+		if err != nil {
+			if !emit(zero, err) {
+				return
+			}
+			continue
+		}
+		a, err := avg(previousSample, sample)
+		previousSample = sample
+		if !emit(a, err) {
+			return
+		}
+	}
+}
+
+// stopsIteration tells if the error of a sample ends the iteration over the
+// samples: the context is done or the interval is invalid.
+func stopsIteration(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, errInvalidInterval)
+}
+
+// latest is the "average" of the gauges and of the rates already averaged,
+// which is the second sample.
+func latest[G any](_ G, g G) (G, error) {
+	return g, nil
+}
+
+// gauges returns an iterator over the samples taken every interval. The
+// first sample is taken immediately.
+func gauges[G any](ctx context.Context, interval time.Duration, get func() (G, error)) iter.Seq2[G, error] {
+	return func(yield func(G, error) bool) {
+		if interval <= 0 {
+			var zero G
+			yield(zero, errInvalidInterval)
+			return
+		}
+
+		g, err := get()
+		if !yield(g, err) || stopsIteration(err) {
+			return
+		}
+
+		sampleLoop(ctx, interval, g, get, latest[G], func(g G, err error) bool {
+			return yield(g, err) && !stopsIteration(err)
+		})
+	}
+}
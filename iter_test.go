@@ -0,0 +1,93 @@
+package sysstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSamplerSamplesInvalidInterval(t *testing.T) {
+	sampler := NewSampler()
+	for _, err := range sampler.CpuSamples(context.Background(), 0) {
+		if err != errInvalidInterval {
+			t.Errorf("CpuSamples(0) error = %v, want %v", err, errInvalidInterval)
+		}
+	}
+	for _, err := range sampler.MemSamples(context.Background(), -time.Second) {
+		if err != errInvalidInterval {
+			t.Errorf("MemSamples(-1s) error = %v, want %v", err, errInvalidInterval)
+		}
+	}
+}
+
+func TestSamplerSamplesBaseline(t *testing.T) {
+	n := 0
+	sample := func(warm bool) func() (int, error) {
+		return func() (int, error) {
+			n++
+			if n == 1 && !warm {
+				return 0, ErrWarmingUp
+			}
+			if n == 4 {
+				return 0, errors.New("failed sample")
+			}
+			return n, nil
+		}
+	}
+
+	// Without a previous sample the baseline isn't yielded, and the
+	// iteration goes on after an error
+	got, errs := collectSamples(samplerSamples(context.Background(), time.Millisecond, sample(false)), 4)
+	if len(got) != 3 || got[0] != 2 || got[1] != 3 || got[2] != 5 || errs != 1 {
+		t.Errorf("samples = %v and %d errors, want [2 3 5] and 1 error", got, errs)
+	}
+
+	// With a previous sample the first rates are yielded immediately
+	n = 0
+	got, errs = collectSamples(samplerSamples(context.Background(), time.Millisecond, sample(true)), 5)
+	if len(got) != 4 || got[0] != 1 || errs != 1 {
+		t.Errorf("samples = %v and %d errors, want [1 2 3 5] and 1 error", got, errs)
+	}
+}
+
+func TestSamplerSamplesStop(t *testing.T) {
+	n := 0
+	sample := func() (int, error) {
+		n++
+		switch n {
+		case 1:
+			return 0, ErrWarmingUp
+		case 2:
+			// A failed baseline is taken again
+			return 0, ErrWarmingUp
+		case 4:
+			return 0, &CollectorError{Collector: `mem`, Err: errors.New("failed collector")}
+		case 5:
+			return 0, context.DeadlineExceeded
+		}
+		return n, nil
+	}
+
+	got, errs := collectSamples(samplerSamples(context.Background(), time.Millisecond, sample), 10)
+	if len(got) != 1 || got[0] != 3 || errs != 2 {
+		t.Errorf("samples = %v and %d errors, want [3] and 2 errors", got, errs)
+	}
+}
+
+// collectSamples returns the values yielded by the iterator and the # of
+// errors, up to max of them.
+func collectSamples(samples func(func(int, error) bool), max int) (values []int, errs int) {
+	for value, err := range samples {
+		if err != nil {
+			errs++
+		} else {
+			values = append(values, value)
+		}
+		if len(values)+errs >= max {
+			break
+		}
+	}
+
+	return values, errs
+}
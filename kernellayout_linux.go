@@ -0,0 +1,127 @@
+// +build linux
+
+package sysstats
+
+import (
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// KernelLayout represents the layout of the files whose format changed
+// across kernel versions, as detected on the running kernel. The parsers only
+// require the documented minimum and ignore the unknown trailing fields.
+type KernelLayout struct {
+	DiskstatsFields int      `json:"diskstatsfields"` // # of fields of /proc/diskstats (14, 18 since 4.18, 20 since 5.5)
+	LoadavgFields   int      `json:"loadavgfields"`   // # of fields of /proc/loadavg
+	NetDevColumns   []string `json:"netdevcolumns"`   // Keys of the columns of /proc/net/dev
+}
+
+// defaultNetDevColumns are the keys of the columns of /proc/net/dev used
+// when its header can't be parsed.
+var defaultNetDevColumns = ifaceStatKeys
+
+// netDevColumnKeys maps the names of the columns of the header of
+// /proc/net/dev to the keys of IfaceRawStats (without the rx/tx prefix).
+var netDevColumnKeys = map[string]string{
+	"bytes":      `bytes`,
+	"packets":    `pkts`,
+	"errs":       `errs`,
+	"drop":       `drop`,
+	"fifo":       `fifo`,
+	"frame":      `frame`,
+	"compressed": `compr`,
+	"multicast":  `multi`,
+	"colls":      `colls`,
+	"carrier":    `carr`,
+}
+
+var kernelLayout struct {
+	sync.Mutex
+	fsys   *fs.FS
+	layout *KernelLayout
+}
+
+// getKernelLayout detects the layout of the files of the running kernel the
+// first time it's called, and again when the stats file system changes (see
+// SetStatsFS).
+func getKernelLayout() KernelLayout {
+	kernelLayout.Lock()
+	defer kernelLayout.Unlock()
+
+	fsys := statsFS.Load()
+	if kernelLayout.layout != nil && kernelLayout.fsys == fsys {
+		return *kernelLayout.layout
+	}
+
+	layout := KernelLayout{NetDevColumns: defaultNetDevColumns}
+
+	scanStatsLines("/proc/diskstats", func(line string) {
+		if layout.DiskstatsFields == 0 {
+			layout.DiskstatsFields = len(strings.Fields(line))
+		}
+	})
+
+	content, err := readStatsFile("/proc/loadavg")
+	if err == nil {
+		layout.LoadavgFields = len(strings.Fields(string(content)))
+	}
+
+	lines := 0
+	scanStatsLines("/proc/net/dev", func(line string) {
+		lines++
+		if lines == 2 {
+			if columns := parseNetDevHeader(line); columns != nil {
+				layout.NetDevColumns = columns
+			}
+		}
+	})
+
+	logDebug("detected kernel layout", "diskstats", layout.DiskstatsFields,
+		"loadavg", layout.LoadavgFields, "netdev", strings.Join(layout.NetDevColumns, ","))
+	kernelLayout.fsys = fsys
+	kernelLayout.layout = &layout
+
+	return layout
+}
+
+// scanStatsLines calls fn with every line of a file of the stats file system
+// (see SetStatsFS).
+func scanStatsLines(path string, fn func(line string)) error {
+	file, err := openStatsFile(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner, release := newStatsScanner(file)
+	defer release()
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// parseNetDevHeader returns the keys of the columns of /proc/net/dev from the
+// second line of its header:
+//   face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+// It returns nil if the header can't be parsed.
+func parseNetDevHeader(header string) (columns []string) {
+	parts := strings.Split(header, "|")
+	if len(parts) != 3 {
+		return nil
+	}
+
+	for i, prefix := range []string{`rx`, `tx`} {
+		for _, name := range strings.Fields(parts[i+1]) {
+			key, ok := netDevColumnKeys[name]
+			if !ok {
+				key = name
+			}
+			columns = append(columns, prefix+key)
+		}
+	}
+
+	return columns
+}
@@ -0,0 +1,110 @@
+package sysstats
+
+// Keys of the time the raw samples were taken (Unix time in nanoseconds), in
+// the maps that have one (IfaceRawStats, Snmp6RawStats).
+const StatTime = `time`
+
+// ifaceSpeedKey is the key of the link speed (in bits/s) in IfaceRawStats,
+// only if it's known. It isn't a counter, so it's not averaged.
+const ifaceSpeedKey = `speed`
+
+// Keys of CpuRawStats and CpuAvgStats.
+const (
+	CpuUser      = `user`
+	CpuNice      = `nice`
+	CpuSystem    = `system`
+	CpuIdle      = `idle`
+	CpuIowait    = `iowait`
+	CpuIrq       = `irq`
+	CpuSoftirq   = `softirq`
+	CpuSteal     = `steal`
+	CpuGuest     = `guest`
+	CpuGuestNice = `guestnice`
+	CpuTotal     = `total`
+)
+
+// Keys of IfaceRawStats and IfaceAvgStats.
+const (
+	IfaceRxBytes = `rxbytes`
+	IfaceRxPkts  = `rxpkts`
+	IfaceRxErrs  = `rxerrs`
+	IfaceRxDrop  = `rxdrop`
+	IfaceRxFifo  = `rxfifo`
+	IfaceRxFrame = `rxframe`
+	IfaceRxCompr = `rxcompr`
+	IfaceRxMulti = `rxmulti`
+	IfaceTxBytes = `txbytes`
+	IfaceTxPkts  = `txpkts`
+	IfaceTxErrs  = `txerrs`
+	IfaceTxDrop  = `txdrop`
+	IfaceTxFifo  = `txfifo`
+	IfaceTxColls = `txcolls`
+	IfaceTxCarr  = `txcarr`
+	IfaceTxCompr = `txcompr`
+)
+
+// Keys of IfaceAvgStats that aren't rates of the counters, only present when
+// the link speed of the interface is known.
+const (
+	IfaceRxUtil = `rxutil`
+	IfaceTxUtil = `txutil`
+)
+
+// Keys of MemStats.
+const (
+	MemUsed        = `memused`
+	MemFree        = `memfree`
+	MemTotal       = `memtotal`
+	MemBuffers     = `buffers`
+	MemCached      = `cached`
+	MemRealFree    = `realfree`
+	MemSwapUsed    = `swapused`
+	MemSwapFree    = `swapfree`
+	MemSwapTotal   = `swaptotal`
+	MemSwapCached  = `swapcached`
+	MemActive      = `active`
+	MemInactive    = `inactive`
+	MemSlab        = `slab`
+	MemDirty       = `dirty`
+	MemMapped      = `mapped`
+	MemWriteback   = `writeback`
+	MemCommittedAS = `committed_as`
+	MemCommitLimit = `commitlimit`
+)
+
+// cpuStatKeys are the keys of the CPU times in the order of /proc/stat.
+var cpuStatKeys = []string{
+	CpuUser, CpuNice, CpuSystem, CpuIdle, CpuIowait, CpuIrq, CpuSoftirq, CpuSteal, CpuGuest, CpuGuestNice,
+}
+
+// ifaceStatKeys are the keys of the network interface stats in the order of
+// /proc/net/dev.
+var ifaceStatKeys = []string{
+	IfaceRxBytes, IfaceRxPkts, IfaceRxErrs, IfaceRxDrop, IfaceRxFifo, IfaceRxFrame, IfaceRxCompr, IfaceRxMulti,
+	IfaceTxBytes, IfaceTxPkts, IfaceTxErrs, IfaceTxDrop, IfaceTxFifo, IfaceTxColls, IfaceTxCarr, IfaceTxCompr,
+}
+
+// memStatKeys are the keys of the memory stats.
+var memStatKeys = []string{
+	MemUsed, MemFree, MemTotal, MemBuffers, MemCached, MemRealFree, MemSwapUsed, MemSwapFree, MemSwapTotal,
+	MemSwapCached, MemActive, MemInactive, MemSlab, MemDirty, MemMapped, MemWriteback, MemCommittedAS,
+	MemCommitLimit,
+}
+
+// CpuStatKeys returns the keys of CpuRawStats and CpuAvgStats (Total last),
+// e.g. for the exporters to list the columns before the first sample.
+func CpuStatKeys() []string {
+	return append(append([]string{}, cpuStatKeys...), CpuTotal)
+}
+
+// IfaceStatKeys returns the keys of IfaceRawStats and IfaceAvgStats (without
+// the time of the raw samples). Older kernels may not report all of them.
+func IfaceStatKeys() []string {
+	return append([]string{}, ifaceStatKeys...)
+}
+
+// MemStatKeys returns the keys of MemStats. Older kernels may not report all
+// of them.
+func MemStatKeys() []string {
+	return append([]string{}, memStatKeys...)
+}
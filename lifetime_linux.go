@@ -0,0 +1,134 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DiskLifetime represents the total IO done by a disk since boot.
+type DiskLifetime struct {
+	Name         string  `json:"name"`                        // Disk name
+	ReadBytes    uint64  `json:"readbytes" unit:"bytes"`      // # of bytes read since boot
+	WriteBytes   uint64  `json:"writebytes" unit:"bytes"`     // # of bytes written since boot
+	ReadBytesH   string  `json:"readbyteshr"`                 // Bytes read since boot (human readable)
+	WriteBytesH  string  `json:"writebyteshr"`                // Bytes written since boot (human readable)
+	BytesPerHour float64 `json:"bytesperhour" unit:"bytes/h"` // Average # of bytes read+written per hour since boot
+}
+
+// IfaceLifetime represents the total traffic of a network interface since
+// boot.
+type IfaceLifetime struct {
+	Name         string  `json:"name"`                        // Network interface name
+	RxBytes      uint64  `json:"rxbytes" unit:"bytes"`        // # of bytes received since boot
+	TxBytes      uint64  `json:"txbytes" unit:"bytes"`        // # of bytes transmitted since boot
+	RxBytesH     string  `json:"rxbyteshr"`                   // Bytes received since boot (human readable)
+	TxBytesH     string  `json:"txbyteshr"`                   // Bytes transmitted since boot (human readable)
+	BytesPerHour float64 `json:"bytesperhour" unit:"bytes/h"` // Average # of bytes rx+tx per hour since boot
+}
+
+// getBootTime gets the time the system booted from the btime line of the
+// file /proc/stat
+func getBootTime() (bootTime time.Time, err error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+
+	re := regexp.MustCompile(`^btime\s+(\d+)`)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		stat := re.FindStringSubmatch(scanner.Text())
+		if stat == nil {
+			continue
+		}
+		btime, err := strconv.ParseInt(stat[1], 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(btime, 0), nil
+	}
+
+	return time.Time{}, errors.New("Couldn't find btime in /proc/stat")
+}
+
+// cpuSinceBoot interprets a CpusRawStats sample as the % CPU usage since boot.
+// As the counters in /proc/stat start at 0 when the system boots, the split
+// is the same as the average between a zeroed sample and the given one.
+func cpuSinceBoot(sample CpusRawStats) (cpusAvgStats CpusAvgStats, err error) {
+	cpusAvgStats = CpusAvgStats{}
+
+	for cpuName, rawStats := range sample {
+		if rawStats[CpuTotal] == 0 {
+			return nil, errors.New("The total time of " + cpuName + " is 0")
+		}
+		cpusAvgStats[cpuName] = cpuPercent(excludeGuest(rawStats))
+	}
+
+	return cpusAvgStats, nil
+}
+
+// diskLifetime returns the bytes read and written by every disk of the given
+// sample since boot.
+func diskLifetime(sample []DiskRawStats, uptime time.Duration) (diskLifetimeArr []DiskLifetime) {
+	diskLifetimeArr = make([]DiskLifetime, 0, len(sample))
+
+	for _, rawStats := range sample {
+		diskLifetime := DiskLifetime{}
+		diskLifetime.Name = rawStats.Name
+		diskLifetime.ReadBytes = rawStats.sectorBytes(rawStats.ReadSectors)
+		diskLifetime.WriteBytes = rawStats.sectorBytes(rawStats.WriteSectors)
+		diskLifetime.ReadBytesH = FormatBytes(diskLifetime.ReadBytes)
+		diskLifetime.WriteBytesH = FormatBytes(diskLifetime.WriteBytes)
+		if hours := uptime.Hours(); hours > 0 {
+			diskLifetime.BytesPerHour = float64(diskLifetime.ReadBytes+diskLifetime.WriteBytes) / hours
+		}
+		diskLifetimeArr = append(diskLifetimeArr, diskLifetime)
+	}
+
+	return diskLifetimeArr
+}
+
+// netLifetime returns the bytes received and transmitted by every network
+// interface of the given sample since boot. Interfaces are sorted by name.
+func netLifetime(sample NetRawStats, uptime time.Duration) (ifaceLifetimeArr []IfaceLifetime) {
+	ifaceLifetimeArr = make([]IfaceLifetime, 0, len(sample))
+
+	for ifaceName, rawStats := range sample {
+		ifaceLifetime := IfaceLifetime{}
+		ifaceLifetime.Name = ifaceName
+		ifaceLifetime.RxBytes = rawStats[IfaceRxBytes]
+		ifaceLifetime.TxBytes = rawStats[IfaceTxBytes]
+		ifaceLifetime.RxBytesH = FormatBytes(ifaceLifetime.RxBytes)
+		ifaceLifetime.TxBytesH = FormatBytes(ifaceLifetime.TxBytes)
+		if hours := uptime.Hours(); hours > 0 {
+			ifaceLifetime.BytesPerHour = float64(ifaceLifetime.RxBytes+ifaceLifetime.TxBytes) / hours
+		}
+		ifaceLifetimeArr = append(ifaceLifetimeArr, ifaceLifetime)
+	}
+
+	sort.Slice(ifaceLifetimeArr, func(i, j int) bool {
+		return ifaceLifetimeArr[i].Name < ifaceLifetimeArr[j].Name
+	})
+
+	return ifaceLifetimeArr
+}
+
+// uptimeDuration returns the system uptime as a time.Duration.
+func uptimeDuration() (uptime time.Duration, err error) {
+	seconds, err := getUptime()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,10 @@
+// +build freebsd openbsd netbsd darwin
+
+package sysstats
+
+// LoadAvg represents the load average of the system
+type LoadAvg struct {
+	Avg1  float64 `json:"avg1"`  // The average processor workload of the last minute
+	Avg5  float64 `json:"avg5"`  // The average processor workload of the last 5 minutes
+	Avg15 float64 `json:"avg15"` // The average processor workload of the last 15 minutes
+}
@@ -0,0 +1,29 @@
+// +build freebsd openbsd netbsd
+
+package sysstats
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"errors"
+)
+
+// getLoadAvg gets the load average of a BSD system with getloadavg(3),
+// which reads the sysctl vm.loadavg.
+func getLoadAvg() (loadAvg LoadAvg, err error) {
+	var loads [3]C.double
+	if C.getloadavg(&loads[0], 3) != 3 {
+		return LoadAvg{}, errors.New("Couldn't get the load average with getloadavg")
+	}
+
+	loadAvg = LoadAvg{
+		Avg1:  float64(loads[0]),
+		Avg5:  float64(loads[1]),
+		Avg15: float64(loads[2]),
+	}
+
+	return loadAvg, nil
+}
@@ -3,7 +3,7 @@
 package sysstats
 
 import (
-	"io/ioutil"
+	"errors"
 	"strconv"
 	"strings"
 )
@@ -18,7 +18,7 @@ type LoadAvg struct {
 // getLoadAvg gets the load average of a linux system from the
 // file /proc/loadavg.
 func getLoadAvg() (loadAvg LoadAvg, err error) {
-	file, err := ioutil.ReadFile("/proc/loadavg")
+	file, err := readStatsFile("/proc/loadavg")
 	if err != nil {
 		return LoadAvg{}, err
 	}
@@ -26,6 +26,9 @@ func getLoadAvg() (loadAvg LoadAvg, err error) {
 
 	loadAvg = LoadAvg{}
 	fields := strings.Fields(content)
+	if len(fields) < 3 {
+		return LoadAvg{}, errors.New("Error parsing file /proc/loadavg. It should have at least 3 fields")
+	}
 	loadAvg1, err := strconv.ParseFloat(fields[0], 64)
 	if err != nil {
 		return LoadAvg{}, err
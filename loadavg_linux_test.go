@@ -0,0 +1,33 @@
+// +build linux
+
+package sysstats
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func FuzzGetLoadAvg(f *testing.F) {
+	for _, seed := range []string{
+		"0.05 0.09 0.13 2/71 26663\n",
+		"0.00 0.01 0.05 1/222 29645\n",
+		"0.52 0.58 0.59 1/1173 6830 extra\n",
+		"0.05 0.09\n",
+		"0.05 0.09 0.13\n",
+		"0.05 0.09 0.13 2 71\n",
+		"x y z 2/71 1\n",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		SetStatsFS(fstest.MapFS{"proc/loadavg": {Data: []byte(content)}})
+		defer SetStatsFS(nil)
+
+		loadAvg, err := getLoadAvg()
+		if err != nil && loadAvg != (LoadAvg{}) {
+			t.Errorf("getLoadAvg() of %q returned %+v with error %v", content, loadAvg, err)
+		}
+	})
+}
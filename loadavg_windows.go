@@ -0,0 +1,16 @@
+// +build windows
+
+package sysstats
+
+// LoadAvg represents the load average of the system. Windows doesn't have
+// one, so it isn't collected.
+type LoadAvg struct {
+	Avg1  float64 `json:"avg1"`  // The average processor workload of the last minute
+	Avg5  float64 `json:"avg5"`  // The average processor workload of the last 5 minutes
+	Avg15 float64 `json:"avg15"` // The average processor workload of the last 15 minutes
+}
+
+// getLoadAvg isn't supported on windows.
+func getLoadAvg() (loadAvg LoadAvg, err error) {
+	return LoadAvg{}, ErrNotSupported
+}
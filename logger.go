@@ -0,0 +1,45 @@
+package sysstats
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// slowCollection is the duration above which a collection is reported as
+// slow to the logger.
+const slowCollection = 100 * time.Millisecond
+
+// logger receives the non-fatal issues found by the collectors.
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger the collectors report non-fatal issues to
+// (unparseable lines, skipped devices, slow reads,...), so operators can
+// debug why a metric is missing. Nothing is logged by default; a nil logger
+// disables the logging again.
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// logWarn logs a non-fatal issue that makes a metric be missing or wrong.
+func logWarn(msg string, args ...any) {
+	if l := logger.Load(); l != nil {
+		l.Warn(msg, args...)
+	}
+}
+
+// logDebug logs an expected condition that may explain a missing metric,
+// e.g. a device that disappeared between 2 samples.
+func logDebug(msg string, args ...any) {
+	if l := logger.Load(); l != nil {
+		l.Debug(msg, args...)
+	}
+}
+
+// logSlow logs the collectors that took longer than slowCollection since
+// start.
+func logSlow(collector string, start time.Time) {
+	if elapsed := time.Since(start); elapsed > slowCollection {
+		logWarn("slow collection", "collector", collector, "duration", elapsed)
+	}
+}
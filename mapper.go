@@ -0,0 +1,135 @@
+package sysstats
+
+import (
+	"regexp"
+	"sort"
+)
+
+// renameRule renames the metrics matching a regexp.
+type renameRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// MetricMapper renames, drops and labels the flattened metrics (see
+// Snapshot.Metrics and Comparison.Metrics) before they reach an exporter, so
+// the same collection serves different backends' naming conventions.
+type MetricMapper struct {
+	drops   []*regexp.Regexp
+	renames []renameRule
+	labels  map[string]string
+	derived []DerivedMetric
+}
+
+// NewMetricMapper returns a mapper that doesn't change the metrics.
+func NewMetricMapper() *MetricMapper {
+	return &MetricMapper{labels: map[string]string{}}
+}
+
+// Drop drops the metrics whose (original) name matches the regexp.
+func (m *MetricMapper) Drop(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	m.drops = append(m.drops, re)
+
+	return nil
+}
+
+// Rename renames the metrics matching the regexp with the replacement, that
+// can refer to the submatches ($1, ${name}), e.g.:
+//   Rename(`^cpu\.(\w+)\.(\w+)$`, "node_cpu_${2}_percent.${1}")
+// Rules are applied in the order they were added, each one to the result of
+// the previous one.
+func (m *MetricMapper) Rename(pattern string, replacement string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	m.renames = append(m.renames, renameRule{re: re, replacement: replacement})
+
+	return nil
+}
+
+// Derive adds a metric calculated from the other ones (see
+// ParseDerivedMetric), e.g.:
+//   Derive("net.eth0.rxbytes*8/1e6 as net.eth0.rxmbps")
+// The expressions refer to the original names of the metrics, and the
+// derived metrics are exported as they are named, without being dropped or
+// renamed.
+func (m *MetricMapper) Derive(definition string) error {
+	derivedMetric, err := ParseDerivedMetric(definition)
+	if err != nil {
+		return err
+	}
+	m.derived = append(m.derived, derivedMetric)
+
+	return nil
+}
+
+// Label adds a static label (e.g. role, datacenter) attached to every metric
+// by the exporters supporting labels or tags.
+func (m *MetricMapper) Label(key string, value string) {
+	m.labels[key] = value
+}
+
+// Labels returns a copy of the static labels.
+func (m *MetricMapper) Labels() map[string]string {
+	labels := make(map[string]string, len(m.labels))
+	for key, value := range m.labels {
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// Map returns the metrics renamed and without the dropped ones, plus the
+// derived ones. A derived metric that can't be calculated (e.g. the metrics
+// it refers to are missing in the first sample) is left out.
+func (m *MetricMapper) Map(metrics map[string]float64) map[string]float64 {
+	mapped := make(map[string]float64, len(metrics)+len(m.derived))
+
+	for name, value := range metrics {
+		if m.dropped(name) {
+			continue
+		}
+		for _, rule := range m.renames {
+			name = rule.re.ReplaceAllString(name, rule.replacement)
+		}
+		mapped[name] = value
+	}
+
+	for _, derivedMetric := range m.derived {
+		value, err := derivedMetric.Eval(metrics)
+		if err != nil {
+			logDebug("skipped derived metric", "metric", derivedMetric.Name, "error", err)
+			continue
+		}
+		mapped[derivedMetric.Name] = value
+	}
+
+	return mapped
+}
+
+// dropped returns whether the metric must be dropped.
+func (m *MetricMapper) dropped(name string) bool {
+	for _, re := range m.drops {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sortedKeys returns the keys of a map sorted alphabetically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,75 @@
+// +build darwin
+
+package sysstats
+
+/*
+#include <mach/mach_host.h>
+#include <mach/mach_init.h>
+#include <sys/sysctl.h>
+#include <sys/types.h>
+
+// vm_stats gets the virtual memory stats of the host.
+static kern_return_t vm_stats(vm_statistics64_data_t *stats) {
+	mach_msg_type_number_t count = HOST_VM_INFO64_COUNT;
+	return host_statistics64(mach_host_self(), HOST_VM_INFO64, (host_info64_t)stats, &count);
+}
+
+// mem_size gets the physical memory in bytes (hw.memsize).
+static int mem_size(uint64_t *size) {
+	size_t len = sizeof(*size);
+	return sysctlbyname("hw.memsize", size, &len, NULL, 0);
+}
+
+// swap_usage gets the swap usage (vm.swapusage).
+static int swap_usage(struct xsw_usage *usage) {
+	size_t len = sizeof(*usage);
+	return sysctlbyname("vm.swapusage", usage, &len, NULL, 0);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// getMemStats gets the memory stats of an OSX system with
+// host_statistics64(HOST_VM_INFO64) and the sysctls hw.memsize and
+// vm.swapusage.
+func getMemStats() (memStats MemStats, err error) {
+	var vmStats C.vm_statistics64_data_t
+	ret := C.vm_stats(&vmStats)
+	if ret != C.KERN_SUCCESS {
+		return nil, errors.New("host_statistics64 failed with error " + strconv.Itoa(int(ret)))
+	}
+
+	var memSize C.uint64_t
+	if ret, err := C.mem_size(&memSize); ret != 0 {
+		return nil, os.NewSyscallError("sysctl hw.memsize", err)
+	}
+
+	var swapUsage C.struct_xsw_usage
+	if ret, err := C.swap_usage(&swapUsage); ret != 0 {
+		return nil, os.NewSyscallError("sysctl vm.swapusage", err)
+	}
+
+	pageSizeKB := uint64(os.Getpagesize() / 1024)
+	pages := func(count C.natural_t) uint64 {
+		return uint64(count) * pageSizeKB
+	}
+
+	memStats = make(MemStats, 10)
+	memStats[MemTotal] = uint64(memSize) / 1024
+	memStats[MemFree] = pages(vmStats.free_count)
+	memStats[MemUsed] = memStats[MemTotal] - memStats[MemFree]
+	memStats[MemActive] = pages(vmStats.active_count)
+	memStats[MemInactive] = pages(vmStats.inactive_count)
+	memStats[`wired`] = pages(vmStats.wire_count)
+	memStats[MemRealFree] = memStats[MemFree] + memStats[MemInactive] + pages(vmStats.speculative_count)
+	memStats[MemSwapTotal] = uint64(swapUsage.xsu_total) / 1024
+	memStats[MemSwapUsed] = uint64(swapUsage.xsu_used) / 1024
+	memStats[MemSwapFree] = uint64(swapUsage.xsu_avail) / 1024
+
+	return memStats, nil
+}
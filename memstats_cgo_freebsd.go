@@ -0,0 +1,106 @@
+// +build freebsd
+
+package sysstats
+
+/*
+#cgo LDFLAGS: -lkvm
+#include <sys/types.h>
+#include <sys/sysctl.h>
+#include <fcntl.h>
+#include <kvm.h>
+#include <paths.h>
+#include <stdlib.h>
+
+// sysctl_uint gets an unsigned int sysctl (the vm.stats.vm page counters).
+static int sysctl_uint(const char *name, u_int *value) {
+	size_t len = sizeof(*value);
+	return sysctlbyname(name, value, &len, NULL, 0);
+}
+
+// sysctl_ulong gets an unsigned long sysctl (hw.physmem, vfs.bufspace).
+static int sysctl_ulong(const char *name, u_long *value) {
+	size_t len = sizeof(*value);
+	return sysctlbyname(name, value, &len, NULL, 0);
+}
+
+// swap_info gets the total and used swap pages of all the swap devices. It
+// doesn't need to read the kernel memory, so it works unprivileged.
+static int swap_info(int *total, int *used) {
+	struct kvm_swap swap;
+	kvm_t *kd = kvm_open(NULL, _PATH_DEVNULL, NULL, O_RDONLY, NULL);
+	if (kd == NULL) {
+		return -1;
+	}
+	int n = kvm_getswapinfo(kd, &swap, 1, 0);
+	kvm_close(kd);
+	if (n < 0) {
+		return -1;
+	}
+	*total = swap.ksw_total;
+	*used = swap.ksw_used;
+	return 0;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"os"
+	"unsafe"
+)
+
+// memPageCounters are the sysctls of the page counters and the keys of
+// MemStats they are stored in.
+var memPageCounters = map[string]string{
+	`vm.stats.vm.v_free_count`:     MemFree,
+	`vm.stats.vm.v_active_count`:   MemActive,
+	`vm.stats.vm.v_inactive_count`: MemInactive,
+	`vm.stats.vm.v_laundry_count`:  `laundry`,
+	`vm.stats.vm.v_wire_count`:     `wired`,
+}
+
+// getMemStats gets the memory stats of a FreeBSD system from the sysctls
+// hw.physmem, vfs.bufspace and vm.stats.vm.*, and the swap devices with
+// kvm_getswapinfo.
+func getMemStats() (memStats MemStats, err error) {
+	memStats = make(MemStats, len(memPageCounters)+7)
+
+	pageSizeKB := uint64(os.Getpagesize() / 1024)
+	for name, key := range memPageCounters {
+		var count C.u_int
+		cname := C.CString(name)
+		ret, err := C.sysctl_uint(cname, &count)
+		C.free(unsafe.Pointer(cname))
+		if ret != 0 {
+			// v_laundry_count doesn't exist before FreeBSD 12.0
+			if key == `laundry` {
+				continue
+			}
+			return nil, os.NewSyscallError("sysctl "+name, err)
+		}
+		memStats[key] = uint64(count) * pageSizeKB
+	}
+
+	for name, key := range map[string]string{`hw.physmem`: MemTotal, `vfs.bufspace`: MemBuffers} {
+		var bytes C.u_long
+		cname := C.CString(name)
+		ret, err := C.sysctl_ulong(cname, &bytes)
+		C.free(unsafe.Pointer(cname))
+		if ret != 0 {
+			return nil, os.NewSyscallError("sysctl "+name, err)
+		}
+		memStats[key] = uint64(bytes) / 1024
+	}
+	memStats[MemUsed] = memStats[MemTotal] - memStats[MemFree]
+	memStats[MemRealFree] = memStats[MemFree] + memStats[MemInactive]
+
+	var swapTotal, swapUsed C.int
+	if C.swap_info(&swapTotal, &swapUsed) != 0 {
+		return nil, errors.New("Couldn't get the swap info with kvm_getswapinfo")
+	}
+	memStats[MemSwapTotal] = uint64(swapTotal) * pageSizeKB
+	memStats[MemSwapUsed] = uint64(swapUsed) * pageSizeKB
+	memStats[MemSwapFree] = memStats[MemSwapTotal] - memStats[MemSwapUsed]
+
+	return memStats, nil
+}
@@ -0,0 +1,50 @@
+// +build netbsd
+
+package sysstats
+
+/*
+#include <sys/types.h>
+#include <sys/sysctl.h>
+#include <uvm/uvm_extern.h>
+
+// uvmexp2 gets the virtual memory stats (vm.uvmexp2).
+static int uvmexp2(struct uvmexp_sysctl *stats) {
+	int mib[2] = {CTL_VM, VM_UVMEXP2};
+	size_t len = sizeof(*stats);
+	return sysctl(mib, 2, stats, &len, NULL, 0);
+}
+*/
+import "C"
+
+import (
+	"os"
+)
+
+// getMemStats gets the memory stats of a NetBSD system from the sysctl
+// vm.uvmexp2.
+func getMemStats() (memStats MemStats, err error) {
+	var stats C.struct_uvmexp_sysctl
+	if ret, err := C.uvmexp2(&stats); ret != 0 {
+		return nil, os.NewSyscallError("sysctl vm.uvmexp2", err)
+	}
+
+	pageSizeKB := uint64(stats.pagesize) / 1024
+	pages := func(count C.int64_t) uint64 {
+		return uint64(count) * pageSizeKB
+	}
+
+	memStats = make(MemStats, 11)
+	memStats[MemTotal] = pages(stats.npages)
+	memStats[MemFree] = pages(stats.free)
+	memStats[MemUsed] = memStats[MemTotal] - memStats[MemFree]
+	memStats[MemActive] = pages(stats.active)
+	memStats[MemInactive] = pages(stats.inactive)
+	memStats[`wired`] = pages(stats.wired)
+	memStats[MemCached] = pages(stats.filepages)
+	memStats[MemRealFree] = memStats[MemFree] + memStats[MemInactive]
+	memStats[MemSwapTotal] = pages(stats.swpages)
+	memStats[MemSwapUsed] = pages(stats.swpginuse)
+	memStats[MemSwapFree] = memStats[MemSwapTotal] - memStats[MemSwapUsed]
+
+	return memStats, nil
+}
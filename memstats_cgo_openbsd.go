@@ -0,0 +1,49 @@
+// +build openbsd
+
+package sysstats
+
+/*
+#include <sys/types.h>
+#include <sys/sysctl.h>
+#include <uvm/uvmexp.h>
+
+// uvmexp gets the virtual memory stats (vm.uvmexp).
+static int uvmexp(struct uvmexp *stats) {
+	int mib[2] = {CTL_VM, VM_UVMEXP};
+	size_t len = sizeof(*stats);
+	return sysctl(mib, 2, stats, &len, NULL, 0);
+}
+*/
+import "C"
+
+import (
+	"os"
+)
+
+// getMemStats gets the memory stats of an OpenBSD system from the sysctl
+// vm.uvmexp.
+func getMemStats() (memStats MemStats, err error) {
+	var stats C.struct_uvmexp
+	if ret, err := C.uvmexp(&stats); ret != 0 {
+		return nil, os.NewSyscallError("sysctl vm.uvmexp", err)
+	}
+
+	pageSizeKB := uint64(stats.pagesize) / 1024
+	pages := func(count C.int) uint64 {
+		return uint64(count) * pageSizeKB
+	}
+
+	memStats = make(MemStats, 10)
+	memStats[MemTotal] = pages(stats.npages)
+	memStats[MemFree] = pages(stats.free)
+	memStats[MemUsed] = memStats[MemTotal] - memStats[MemFree]
+	memStats[MemActive] = pages(stats.active)
+	memStats[MemInactive] = pages(stats.inactive)
+	memStats[`wired`] = pages(stats.wired)
+	memStats[MemRealFree] = memStats[MemFree] + memStats[MemInactive]
+	memStats[MemSwapTotal] = pages(stats.swpages)
+	memStats[MemSwapUsed] = pages(stats.swpginuse)
+	memStats[MemSwapFree] = memStats[MemSwapTotal] - memStats[MemSwapUsed]
+
+	return memStats, nil
+}
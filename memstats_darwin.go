@@ -2,15 +2,18 @@
 
 package sysstats
 
-import (
-	"errors"
-	"runtime"
-)
-
-// MemStat represents the memory statistics on an OSX system
+// MemStats represents the memory statistics of an OSX system (in kilobytes).
+//
+// Map keys:
+//   memtotal  - Total physical memory (hw.memsize).
+//   memfree   - Free memory.
+//   memused   - Used memory (memtotal - memfree).
+//   realfree  - Memory available without swapping (free, inactive and
+//               speculative pages).
+//   active    - Memory recently used.
+//   inactive  - Memory not recently used, reclaimable.
+//   wired     - Memory that can't be paged out.
+//   swaptotal - Total swap space (vm.swapusage).
+//   swapused  - Used swap space.
+//   swapfree  - Free swap space.
 type MemStats map[string]uint64
-
-// getMemStats gets the memory stats of an OSX system
-func getMemStats() (memStats MemStats, err error) {
-	return nil, errors.New("getMemStats: " + runtime.GOOS + " not supported yet")
-}
@@ -0,0 +1,23 @@
+// +build freebsd
+
+package sysstats
+
+// MemStats represents the memory statistics of a FreeBSD system (in
+// kilobytes).
+//
+// Map keys:
+//   memtotal  - Total physical memory (hw.physmem).
+//   memfree   - Free memory.
+//   memused   - Used memory (memtotal - memfree).
+//   realfree  - Memory available without swapping (free and inactive
+//               pages).
+//   active    - Memory recently used.
+//   inactive  - Memory not recently used, reclaimable.
+//   laundry   - Dirty memory not recently used, waiting to be written to
+//               the swap (FreeBSD 12.0 onward).
+//   wired     - Memory that can't be paged out.
+//   buffers   - Memory used by the buffer cache (vfs.bufspace).
+//   swaptotal - Total swap space.
+//   swapused  - Used swap space.
+//   swapfree  - Free swap space.
+type MemStats map[string]uint64
@@ -4,8 +4,6 @@ package sysstats
 
 import (
 	"bufio"
-	"fmt"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -47,13 +45,13 @@ type MemStats map[string]uint64
 // getMemStats gets the memory stats of a linux system from the
 // file /proc/meminfo
 func getMemStats() (memStats MemStats, err error) {
-	file, err := os.Open("/proc/meminfo")
+	file, err := openStatsFile("/proc/meminfo")
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	memStats = MemStats{}
+	memStats = make(MemStats, len(memStatKeys))
 	re := regexp.MustCompile(`^((?:Mem|Swap)(?:Total|Free)|Buffers|Cached|` +
 		`SwapCached|Active|Inactive|Dirty|Writeback|Mapped|Slab|` +
 		`Commit(?:Limit|ted_AS)):\s*(\d+)`)
@@ -70,16 +68,16 @@ func getMemStats() (memStats MemStats, err error) {
 		key := stat[1]
 		value, err := strconv.ParseUint(stat[2], 10, 64)
 		if err != nil {
-			fmt.Println(err)
+			logWarn("unparseable meminfo line", "file", "/proc/meminfo", "line", line, "error", err)
 			continue
 		} else {
 			memStats[strings.ToLower(key)] = value
 		}
 	}
 
-	memStats[`memused`] = memStats[`memtotal`] - memStats[`memfree`]
-	memStats[`swapused`] = memStats[`swaptotal`] - memStats[`swapfree`]
-	memStats[`realfree`] = memStats[`memfree`] + memStats[`buffers`] + memStats[`cached`]
+	memStats[MemUsed] = memStats[MemTotal] - memStats[MemFree]
+	memStats[MemSwapUsed] = memStats[MemSwapTotal] - memStats[MemSwapFree]
+	memStats[MemRealFree] = memStats[MemFree] + memStats[MemBuffers] + memStats[MemCached]
 
 	return memStats, nil
 }
@@ -0,0 +1,20 @@
+// +build openbsd
+
+package sysstats
+
+// MemStats represents the memory statistics of an OpenBSD system (in
+// kilobytes).
+//
+// Map keys:
+//   memtotal  - Total physical memory.
+//   memfree   - Free memory.
+//   memused   - Used memory (memtotal - memfree).
+//   realfree  - Memory available without swapping (free and inactive
+//               pages).
+//   active    - Memory recently used.
+//   inactive  - Memory not recently used, reclaimable.
+//   wired     - Memory that can't be paged out.
+//   swaptotal - Total swap space.
+//   swapused  - Used swap space.
+//   swapfree  - Free swap space.
+type MemStats map[string]uint64
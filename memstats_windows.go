@@ -0,0 +1,68 @@
+// +build windows
+
+package sysstats
+
+import (
+	"os"
+	"unsafe"
+)
+
+// MemStats represents the memory statistics of a Windows system (in
+// kilobytes).
+//
+// Map keys:
+//   memtotal     - Total physical memory.
+//   memfree      - Physical memory available (free, zeroed and standby
+//                  pages).
+//   memused      - Used memory (memtotal - memfree).
+//   realfree     - Same as memfree, the standby pages are already counted.
+//   commitlimit  - Max memory that can be committed (physical memory plus
+//                  the page files).
+//   committed_as - Memory currently committed.
+//   swaptotal    - Total size of the page files (commitlimit - memtotal).
+//   swapused     - Committed memory beyond the physical memory.
+//   swapfree     - swaptotal - swapused.
+type MemStats map[string]uint64
+
+// memoryStatusEx is MEMORYSTATUSEX.
+type memoryStatusEx struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+// getMemStats gets the memory stats of a Windows system with
+// GlobalMemoryStatusEx.
+func getMemStats() (memStats MemStats, err error) {
+	status := memoryStatusEx{}
+	status.Length = uint32(unsafe.Sizeof(status))
+	ret, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status)))
+	if ret == 0 {
+		return nil, os.NewSyscallError("GlobalMemoryStatusEx", err)
+	}
+
+	memStats = make(MemStats, 9)
+	memStats[MemTotal] = status.TotalPhys / 1024
+	memStats[MemFree] = status.AvailPhys / 1024
+	memStats[MemUsed] = memStats[MemTotal] - memStats[MemFree]
+	memStats[MemRealFree] = memStats[MemFree]
+	memStats[MemCommitLimit] = status.TotalPageFile / 1024
+	memStats[MemCommittedAS] = (status.TotalPageFile - status.AvailPageFile) / 1024
+	if memStats[MemCommitLimit] > memStats[MemTotal] {
+		memStats[MemSwapTotal] = memStats[MemCommitLimit] - memStats[MemTotal]
+	}
+	if memStats[MemCommittedAS] > memStats[MemTotal] {
+		memStats[MemSwapUsed] = memStats[MemCommittedAS] - memStats[MemTotal]
+	}
+	if memStats[MemSwapTotal] > memStats[MemSwapUsed] {
+		memStats[MemSwapFree] = memStats[MemSwapTotal] - memStats[MemSwapUsed]
+	}
+
+	return memStats, nil
+}
@@ -0,0 +1,319 @@
+package sysstats
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// MetricInfo describes one of the metrics of Snapshot.Metrics and
+// Comparison.Metrics, or one of the counters of CounterRegistry, so the
+// exporters and the documentation can be generated from the package. The
+// element a metric belongs to (CPU, interface, disk, mount point,...) is a
+// placeholder of its name, e.g. cpu.<cpu>.user.
+type MetricInfo struct {
+	Name        string      `json:"name"`          // Name of the metric, with the element as a placeholder
+	Family      string      `json:"family"`        // Collector of the metric (see SnapshotCollectors)
+	Type        CounterType `json:"type"`          // gauge, or counter for the monotonic counters of CounterRegistry (see LookupCounterInfo)
+	Unit        string      `json:"unit"`          // Unit of the value (kB, %, bytes/s,...), empty for plain numbers
+	Description string      `json:"description"`   // What the metric measures
+	Platforms   []string    `json:"platforms"`     // OSes (GOOS) where it's available
+	Cgo         []string    `json:"cgo,omitempty"` // Platforms where it's only available when the package is built with cgo
+}
+
+// Platforms of the metrics.
+var (
+	allPlatforms  = []string{`darwin`, `freebsd`, `linux`, `netbsd`, `openbsd`, `windows`}
+	unixPlatforms = []string{`darwin`, `freebsd`, `linux`, `netbsd`, `openbsd`}
+	bsdPlatforms  = []string{`freebsd`, `netbsd`, `openbsd`}
+	linuxPlatform = []string{`linux`}
+)
+
+// cgoPlatforms are the platforms where the collectors of a family read the
+// stats through the C library, so they return errNoCgo when the package is
+// built without cgo (e.g. cross-compiled).
+var cgoPlatforms = map[string][]string{
+	`cpu`:     {`darwin`, `freebsd`, `netbsd`, `openbsd`},
+	`mem`:     {`darwin`, `freebsd`, `netbsd`, `openbsd`},
+	`loadavg`: bsdPlatforms,
+	`net`:     bsdPlatforms,
+	`disk`:    {`freebsd`},
+}
+
+// newMetricInfo returns the description of a metric, with the platforms
+// where its family needs cgo.
+func newMetricInfo(name string, family string, counterType CounterType, unit string, description string,
+	platforms []string) MetricInfo {
+	metric := MetricInfo{name, family, counterType, unit, description, platforms, nil}
+	for _, cgoPlatform := range cgoPlatforms[family] {
+		for _, platform := range platforms {
+			if platform == cgoPlatform {
+				metric.Cgo = append(metric.Cgo, platform)
+			}
+		}
+	}
+
+	return metric
+}
+
+// platforms returns the union of the given platforms, sorted.
+func platforms(platformsArr ...[]string) []string {
+	union := map[string]bool{}
+	for _, platforms := range platformsArr {
+		for _, platform := range platforms {
+			union[platform] = true
+		}
+	}
+
+	return sortedKeys(union)
+}
+
+// metricsInfo are the metrics of the snapshots and comparisons.
+var metricsInfo = buildMetricsInfo()
+
+// buildMetricsInfo lists the metrics of the snapshots (gauges) and of the
+// comparisons (rates).
+func buildMetricsInfo() []MetricInfo {
+	metrics := []MetricInfo{
+		// Gauges of the snapshots and rates of the comparisons
+		newMetricInfo(`load.avg1`, `loadavg`, CounterGauge, ``, `Load average of the last minute`, unixPlatforms),
+		newMetricInfo(`load.avg5`, `loadavg`, CounterGauge, ``, `Load average of the last 5 minutes`, unixPlatforms),
+		newMetricInfo(`load.avg15`, `loadavg`, CounterGauge, ``, `Load average of the last 15 minutes`, unixPlatforms),
+		newMetricInfo(`diskusage.<mount>.total`, `diskusage`, CounterGauge, `kB`, `Size of the file system`, []string{`linux`, `windows`}),
+		newMetricInfo(`diskusage.<mount>.used`, `diskusage`, CounterGauge, `kB`, `Space used in the file system`, []string{`linux`, `windows`}),
+		newMetricInfo(`diskusage.<mount>.available`, `diskusage`, CounterGauge, `kB`, `Space available to unprivileged users`, []string{`linux`, `windows`}),
+		newMetricInfo(`diskusage.<mount>.usedper`, `diskusage`, CounterGauge, `%`, `% of the file system used`, []string{`linux`, `windows`}),
+		newMetricInfo(`sock.used`, `sock`, CounterGauge, ``, `Sockets in use`, linuxPlatform),
+		newMetricInfo(`sock.tcpinuse`, `sock`, CounterGauge, ``, `TCP sockets in use`, linuxPlatform),
+		newMetricInfo(`sock.tcporphaned`, `sock`, CounterGauge, ``, `Orphaned TCP sockets`, linuxPlatform),
+		newMetricInfo(`sock.tcptimewait`, `sock`, CounterGauge, ``, `TCP sockets in TIME_WAIT`, linuxPlatform),
+		newMetricInfo(`sock.udpinuse`, `sock`, CounterGauge, ``, `UDP sockets in use`, linuxPlatform),
+		newMetricInfo(`sock.raw`, `sock`, CounterGauge, ``, `Raw sockets in use`, linuxPlatform),
+		newMetricInfo(`sock.ipfrag`, `sock`, CounterGauge, ``, `IP fragments waiting for reassembly`, linuxPlatform),
+		newMetricInfo(`file.fhalloc`, `file`, CounterGauge, ``, `File handles allocated`, linuxPlatform),
+		newMetricInfo(`file.fhfree`, `file`, CounterGauge, ``, `File handles allocated but unused`, linuxPlatform),
+		newMetricInfo(`file.fhmax`, `file`, CounterGauge, ``, `Max # of file handles`, linuxPlatform),
+		newMetricInfo(`file.inalloc`, `file`, CounterGauge, ``, `Inodes allocated`, linuxPlatform),
+		newMetricInfo(`file.infree`, `file`, CounterGauge, ``, `Inodes allocated but unused`, linuxPlatform),
+		newMetricInfo(`proc.running`, `proc`, CounterGauge, ``, `Processes running`, linuxPlatform),
+		newMetricInfo(`proc.blocked`, `proc`, CounterGauge, ``, `Processes blocked waiting for IO`, linuxPlatform),
+		newMetricInfo(`proc.runqueue`, `proc`, CounterGauge, ``, `Processes in the run queue`, linuxPlatform),
+		newMetricInfo(`proc.total`, `proc`, CounterGauge, ``, `Processes and threads`, linuxPlatform),
+		newMetricInfo(`proc.newprocs`, `proc`, CounterGauge, `/s`, `Processes created per second`, linuxPlatform),
+		newMetricInfo(`disk.<disk>.readios`, `disk`, CounterGauge, `/s`, `Reads completed per second`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.readmerges`, `disk`, CounterGauge, `/s`, `Reads merged per second`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.readbytes`, `disk`, CounterGauge, `bytes/s`, `Bytes read per second`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.writeios`, `disk`, CounterGauge, `/s`, `Writes completed per second`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.writemerges`, `disk`, CounterGauge, `/s`, `Writes merged per second`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.writebytes`, `disk`, CounterGauge, `bytes/s`, `Bytes written per second`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.inflight`, `disk`, CounterGauge, ``, `IOs in progress`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.ioticks`, `disk`, CounterGauge, `ms`, `Time spent doing IOs`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.timeinqueue`, `disk`, CounterGauge, `ms`, `Weighted time spent doing IOs`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`reachability.<target>.loss`, `reachability`, CounterGauge, `%`, `% of the probes without reply`, allPlatforms),
+		newMetricInfo(`reachability.<target>.rttmin`, `reachability`, CounterGauge, `ms`, `Min round trip time of the probes`, allPlatforms),
+		newMetricInfo(`reachability.<target>.rttavg`, `reachability`, CounterGauge, `ms`, `Mean round trip time of the probes`, allPlatforms),
+		newMetricInfo(`reachability.<target>.rttmax`, `reachability`, CounterGauge, `ms`, `Max round trip time of the probes`, allPlatforms),
+		newMetricInfo(`sysstats.skew`, `sysstats`, CounterGauge, `s`, `Time between the first and the last family of the snapshot sampled`, allPlatforms),
+		newMetricInfo(`sysstats.<collector>.collections`, `sysstats`, CounterGauge, ``, `Collections of the collector`, allPlatforms),
+		newMetricInfo(`sysstats.<collector>.errors`, `sysstats`, CounterGauge, ``, `Failed collections of the collector`, allPlatforms),
+		newMetricInfo(`sysstats.<collector>.duration`, `sysstats`, CounterGauge, `s`, `Duration of the last collection`, allPlatforms),
+		newMetricInfo(`sysstats.<collector>.bytes`, `sysstats`, CounterGauge, `bytes`, `Bytes parsed by the last collection`, allPlatforms),
+		newMetricInfo(`sysstats.<collector>.lastsuccess`, `sysstats`, CounterGauge, `unixtime`, `Time of the last successful collection (Unix time)`, allPlatforms),
+		newMetricInfo(`sysstats.sinks.<sink>.queued`, `sysstats`, CounterGauge, ``, `Snapshots waiting to be written to the sink of the monitor`, allPlatforms),
+		newMetricInfo(`sysstats.sinks.<sink>.written`, `sysstats`, CounterGauge, ``, `Snapshots written to the sink of the monitor`, allPlatforms),
+		newMetricInfo(`sysstats.sinks.<sink>.failed`, `sysstats`, CounterGauge, ``, `Snapshots the sink of the monitor failed to write`, allPlatforms),
+		newMetricInfo(`sysstats.sinks.<sink>.dropped`, `sysstats`, CounterGauge, ``, `Snapshots dropped because the queue of the sink was full`, allPlatforms),
+
+		// Monotonic counters of CounterRegistry
+		newMetricInfo(`proc.processes`, `proc`, CounterCounter, ``, `Processes created since boot`, linuxPlatform),
+		newMetricInfo(`disk.<disk>.readios`, `disk`, CounterCounter, ``, `Reads completed`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.readmerges`, `disk`, CounterCounter, ``, `Reads merged`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.readbytes`, `disk`, CounterCounter, `bytes`, `Bytes read`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.readticks`, `disk`, CounterCounter, `ms`, `Time spent reading`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.writeios`, `disk`, CounterCounter, ``, `Writes completed`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.writemerges`, `disk`, CounterCounter, ``, `Writes merged`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.writebytes`, `disk`, CounterCounter, `bytes`, `Bytes written`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.writeticks`, `disk`, CounterCounter, `ms`, `Time spent writing`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.ioticks`, `disk`, CounterCounter, `ms`, `Time spent doing IOs`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`disk.<disk>.timeinqueue`, `disk`, CounterCounter, `ms`, `Weighted time spent doing IOs`, []string{`freebsd`, `linux`}),
+		newMetricInfo(`sysstats.<collector>.collections`, `sysstats`, CounterCounter, ``, `Collections of the collector`, allPlatforms),
+		newMetricInfo(`sysstats.<collector>.errors`, `sysstats`, CounterCounter, ``, `Failed collections of the collector`, allPlatforms),
+		newMetricInfo(`sysstats.sinks.<sink>.written`, `sysstats`, CounterCounter, ``, `Snapshots written to the sink of the monitor`, allPlatforms),
+		newMetricInfo(`sysstats.sinks.<sink>.failed`, `sysstats`, CounterCounter, ``, `Snapshots the sink of the monitor failed to write`, allPlatforms),
+		newMetricInfo(`sysstats.sinks.<sink>.dropped`, `sysstats`, CounterCounter, ``, `Snapshots dropped because the queue of the sink was full`, allPlatforms),
+	}
+
+	cpuKeys := []struct {
+		key         string
+		description string
+		platforms   []string
+	}{
+		{CpuUser, `% of time spent in user mode`, allPlatforms},
+		{CpuNice, `% of time spent in user mode with low priority`, unixPlatforms},
+		{CpuSystem, `% of time spent in kernel mode`, allPlatforms},
+		{CpuIdle, `% of time spent idle`, allPlatforms},
+		{CpuIowait, `% of time spent idle waiting for IO`, linuxPlatform},
+		{CpuIrq, `% of time spent servicing interrupts`, platforms(linuxPlatform, bsdPlatforms, []string{`windows`})},
+		{CpuSoftirq, `% of time spent servicing softirqs (DPCs on windows)`, []string{`linux`, `windows`}},
+		{CpuSteal, `% of time stolen by the hypervisor`, linuxPlatform},
+		{CpuGuest, `% of time spent running guests`, linuxPlatform},
+		{CpuGuestNice, `% of time spent running guests with low priority`, linuxPlatform},
+		{CpuTotal, `% of time not idle`, allPlatforms},
+	}
+	for _, cpuKey := range cpuKeys {
+		metrics = append(metrics, newMetricInfo(`cpu.<cpu>.`+cpuKey.key, `cpu`, CounterGauge, `%`,
+			cpuKey.description, cpuKey.platforms))
+		// The counters are the raw time of the CPU, in jiffies (100ns ticks
+		// on windows)
+		description := `Time` + strings.TrimPrefix(cpuKey.description, `% of time`)
+		var unixCpuPlatforms []string
+		for _, platform := range cpuKey.platforms {
+			if platform == `windows` {
+				metrics = append(metrics, newMetricInfo(`cpu.<cpu>.`+cpuKey.key, `cpu`, CounterCounter, `100ns`,
+					description, []string{`windows`}))
+			} else {
+				unixCpuPlatforms = append(unixCpuPlatforms, platform)
+			}
+		}
+		metrics = append(metrics, newMetricInfo(`cpu.<cpu>.`+cpuKey.key, `cpu`, CounterCounter, `jiffies`,
+			description, unixCpuPlatforms))
+	}
+
+	netPlatforms := platforms(linuxPlatform, bsdPlatforms, []string{`windows`})
+	ifaceKeys := []struct {
+		key         string
+		unit        string
+		description string
+		platforms   []string
+	}{
+		{IfaceRxBytes, `bytes/s`, `Bytes received per second`, netPlatforms},
+		{IfaceRxPkts, `packets/s`, `Packets received per second`, netPlatforms},
+		{IfaceRxErrs, `/s`, `Receive errors per second`, netPlatforms},
+		{IfaceRxDrop, `/s`, `Received packets dropped per second`, netPlatforms},
+		{IfaceRxFifo, `/s`, `Receive FIFO errors per second`, linuxPlatform},
+		{IfaceRxFrame, `/s`, `Receive framing errors per second`, linuxPlatform},
+		{IfaceRxCompr, `/s`, `Compressed packets received per second`, linuxPlatform},
+		{IfaceRxMulti, `/s`, `Multicast packets received per second`, platforms(linuxPlatform, bsdPlatforms)},
+		{IfaceTxBytes, `bytes/s`, `Bytes sent per second`, netPlatforms},
+		{IfaceTxPkts, `packets/s`, `Packets sent per second`, netPlatforms},
+		{IfaceTxErrs, `/s`, `Transmit errors per second`, netPlatforms},
+		{IfaceTxDrop, `/s`, `Packets dropped while sending per second`, netPlatforms},
+		{IfaceTxFifo, `/s`, `Transmit FIFO errors per second`, linuxPlatform},
+		{IfaceTxColls, `/s`, `Collisions per second`, platforms(linuxPlatform, bsdPlatforms)},
+		{IfaceTxCarr, `/s`, `Carrier losses per second`, linuxPlatform},
+		{IfaceTxCompr, `/s`, `Compressed packets sent per second`, linuxPlatform},
+		{IfaceRxUtil, `%`, `% of the link speed used receiving (when the speed is known)`, netPlatforms},
+		{IfaceTxUtil, `%`, `% of the link speed used sending (when the speed is known)`, netPlatforms},
+	}
+	for _, ifaceKey := range ifaceKeys {
+		metrics = append(metrics, newMetricInfo(`net.<iface>.`+ifaceKey.key, `net`, CounterGauge, ifaceKey.unit,
+			ifaceKey.description, ifaceKey.platforms))
+		if ifaceKey.key == IfaceRxUtil || ifaceKey.key == IfaceTxUtil {
+			continue
+		}
+		metrics = append(metrics, newMetricInfo(`net.<iface>.`+ifaceKey.key, `net`, CounterCounter,
+			ifaceCounterUnits[ifaceKey.key], strings.TrimSuffix(ifaceKey.description, ` per second`), ifaceKey.platforms))
+	}
+
+	memKeys := []struct {
+		key         string
+		description string
+		platforms   []string
+	}{
+		{MemUsed, `Memory used`, allPlatforms},
+		{MemFree, `Memory free`, allPlatforms},
+		{MemTotal, `Total memory`, allPlatforms},
+		{MemBuffers, `Memory used by the block device buffers (the buffer cache on freebsd)`, []string{`freebsd`, `linux`}},
+		{MemCached, `Memory used by the page cache`, []string{`linux`, `netbsd`}},
+		{MemRealFree, `Memory available without swapping (free + reclaimable)`, allPlatforms},
+		{MemSwapUsed, `Swap used`, allPlatforms},
+		{MemSwapFree, `Swap free`, allPlatforms},
+		{MemSwapTotal, `Total swap`, allPlatforms},
+		{MemSwapCached, `Swap also in memory`, linuxPlatform},
+		{MemActive, `Memory used recently`, unixPlatforms},
+		{MemInactive, `Memory not used recently`, unixPlatforms},
+		{MemSlab, `Memory used by the kernel slabs`, linuxPlatform},
+		{MemDirty, `Memory waiting to be written to the disks`, linuxPlatform},
+		{MemMapped, `Memory mapped by the processes`, linuxPlatform},
+		{MemWriteback, `Memory being written to the disks`, linuxPlatform},
+		{MemCommittedAS, `Memory committed by the processes`, []string{`linux`, `windows`}},
+		{MemCommitLimit, `Max memory that can be committed`, []string{`linux`, `windows`}},
+		{`wired`, `Memory that can't be paged out`, platforms([]string{`darwin`}, bsdPlatforms)},
+		{`laundry`, `Dirty memory not used recently, waiting to be written to the swap`, []string{`freebsd`}},
+	}
+	for _, memKey := range memKeys {
+		metrics = append(metrics, newMetricInfo(`mem.`+memKey.key, `mem`, CounterGauge, `kB`,
+			memKey.description, memKey.platforms))
+	}
+
+	sort.SliceStable(metrics, func(i, j int) bool {
+		return metrics[i].Name < metrics[j].Name
+	})
+
+	return metrics
+}
+
+// MetricsInfo returns the description of all the metrics of the snapshots
+// (see Snapshot.Metrics) and comparisons (see Comparison.Metrics), and of
+// the counters of CounterRegistry, sorted by name.
+func MetricsInfo() []MetricInfo {
+	metrics := make([]MetricInfo, len(metricsInfo))
+	copy(metrics, metricsInfo)
+
+	return metrics
+}
+
+// LookupMetricInfo returns the description of a metric of Snapshot.Metrics
+// or Comparison.Metrics by its name, e.g. cpu.cpu0.user is described by
+// cpu.<cpu>.user.
+func LookupMetricInfo(name string) (MetricInfo, bool) {
+	return lookupMetricInfo(name, CounterGauge)
+}
+
+// LookupCounterInfo returns the description of a counter of CounterRegistry
+// by its name. The monotonic counters have their own description, e.g.
+// cpu.cpu0.user is the time of the CPU in jiffies instead of its % of use,
+// and the gauges share the description of the metrics.
+func LookupCounterInfo(name string) (MetricInfo, bool) {
+	if metric, ok := lookupMetricInfo(name, CounterCounter); ok {
+		return metric, true
+	}
+
+	return lookupMetricInfo(name, CounterGauge)
+}
+
+// lookupMetricInfo returns the description of a metric of the given type by
+// its name, preferring the one of the current platform when the unit
+// depends on it.
+func lookupMetricInfo(name string, counterType CounterType) (MetricInfo, bool) {
+	var found []MetricInfo
+	for _, metric := range metricsInfo {
+		if metric.Type == counterType && matchesMetricName(metric.Name, name) {
+			found = append(found, metric)
+		}
+	}
+	if len(found) == 0 {
+		return MetricInfo{}, false
+	}
+	for _, metric := range found {
+		for _, platform := range metric.Platforms {
+			if platform == runtime.GOOS {
+				return metric, true
+			}
+		}
+	}
+
+	return found[0], true
+}
+
+// matchesMetricName returns whether the name of a metric matches the name
+// of its description, with the element as a placeholder.
+func matchesMetricName(pattern string, name string) bool {
+	prefix, rest, found := strings.Cut(pattern, `<`)
+	if !found {
+		return pattern == name
+	}
+	_, suffix, _ := strings.Cut(rest, `>`)
+
+	return len(name) > len(prefix)+len(suffix) && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix)
+}
@@ -0,0 +1,143 @@
+// +build linux
+
+package sysstats
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// metadataSnapshots returns 2 snapshots of the files of testdata, a second
+// apart, with every family that testdata doesn't have filled in.
+func metadataSnapshots(t *testing.T) (first Snapshot, second Snapshot) {
+	useStatsFS(t, filepath.Join("testdata", "statsfs", "linux-6.18"))
+
+	first, err := collectSnapshot([]string{`loadavg`, `mem`, `cpu`, `net`, `disk`, `sock`}, deviceFilter{})
+	if err != nil {
+		t.Fatalf("collectSnapshot() error = %v", err)
+	}
+	first.Time = time.Unix(1700000000, 0)
+	first.DiskUsage = []DiskUsage{{MountedOn: `/`, Total: 100, Used: 40, Available: 60, UsedPer: 40}}
+	first.Reachability = []ReachabilityStats{{Name: `gateway`, Sent: 3, Received: 3, RttMin: 1, RttAvg: 2, RttMax: 3}}
+	first.Times = map[string]time.Time{`cpu`: first.Time, `mem`: first.Time}
+	first.Health = map[string]CollectorHealth{`cpu`: {Collections: 1, LastSuccess: first.Time}}
+	first.Sinks = []SinkStats{{Name: `influx`, Written: 1}}
+	for ifaceName, rawStats := range first.Net {
+		rawStats[ifaceSpeedKey] = 1000
+		first.Net[ifaceName] = rawStats
+	}
+
+	second = first
+	second.Time = first.Time.Add(time.Second)
+
+	return first, second
+}
+
+// metadataNames returns the names of the descriptions of the metrics
+// matched by names.
+func metadataNames(names []string, lookup func(string) (MetricInfo, bool)) map[string]bool {
+	described := map[string]bool{}
+	for _, name := range names {
+		if metric, ok := lookup(name); ok {
+			described[metric.Name+` `+string(metric.Type)] = true
+		}
+	}
+
+	return described
+}
+
+func TestMetricsInfoMatchesMetrics(t *testing.T) {
+	first, second := metadataSnapshots(t)
+	comparison, err := compare(first, second)
+	if err != nil {
+		t.Fatalf("compare() error = %v", err)
+	}
+
+	var names []string
+	for name := range first.Metrics() {
+		names = append(names, name)
+	}
+	for name := range comparison.Metrics() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		metric, ok := LookupMetricInfo(name)
+		if !ok {
+			t.Errorf("LookupMetricInfo(%q) not found", name)
+			continue
+		}
+		if !containsString(metric.Platforms, `linux`) {
+			t.Errorf("%s (%s) doesn't have linux in its platforms %v", name, metric.Name, metric.Platforms)
+		}
+	}
+
+	// Every gauge of linux is in the metrics
+	described := metadataNames(names, LookupMetricInfo)
+	for _, metric := range MetricsInfo() {
+		if metric.Type == CounterGauge && containsString(metric.Platforms, `linux`) &&
+			!described[metric.Name+` `+string(metric.Type)] {
+			t.Errorf("%s isn't in the metrics", metric.Name)
+		}
+	}
+}
+
+func TestMetricsInfoMatchesCounters(t *testing.T) {
+	snapshot, _ := metadataSnapshots(t)
+
+	counters := NewCounterRegistry().Collect(snapshot)
+	names := CounterNames(counters)
+	for _, name := range names {
+		metric, ok := LookupCounterInfo(name)
+		if !ok {
+			t.Errorf("LookupCounterInfo(%q) not found", name)
+			continue
+		}
+		if metric.Type != counters[name].Type || metric.Unit != counters[name].Unit {
+			t.Errorf("LookupCounterInfo(%q) = %s %q, want %s %q", name, metric.Type, metric.Unit,
+				counters[name].Type, counters[name].Unit)
+		}
+	}
+
+	// Every counter of linux is in the registry
+	described := metadataNames(names, LookupCounterInfo)
+	for _, metric := range MetricsInfo() {
+		if metric.Type == CounterCounter && containsString(metric.Platforms, `linux`) &&
+			!described[metric.Name+` `+string(metric.Type)] {
+			t.Errorf("%s isn't in the counters", metric.Name)
+		}
+	}
+}
+
+func TestMetricsInfoCgo(t *testing.T) {
+	metric, ok := LookupMetricInfo(`mem.memused`)
+	if !ok {
+		t.Fatal("LookupMetricInfo(mem.memused) not found")
+	}
+	want := []string{`darwin`, `freebsd`, `netbsd`, `openbsd`}
+	if len(metric.Cgo) != len(want) {
+		t.Fatalf("mem.memused Cgo = %v, want %v", metric.Cgo, want)
+	}
+	for i := range want {
+		if metric.Cgo[i] != want[i] {
+			t.Fatalf("mem.memused Cgo = %v, want %v", metric.Cgo, want)
+		}
+	}
+
+	if metric, _ := LookupMetricInfo(`sysstats.cpu.lastsuccess`); metric.Unit != UnitUnixTime {
+		t.Errorf("sysstats.cpu.lastsuccess unit = %q, want %q", metric.Unit, UnitUnixTime)
+	}
+}
+
+// containsString returns whether the string is in the slice.
+func containsString(arr []string, s string) bool {
+	for _, elem := range arr {
+		if elem == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package sysstats
+
+// Metrics returns the gauges of the snapshot flattened in a map where the
+// keys are the metric names, e.g.:
+//   mem.memused, load.avg1, sock.tcpinuse, file.fhalloc, proc.running,
+//   diskusage./var.usedper, reachability.gateway.rttavg,
+//   sysstats.cpu.duration
+func (s Snapshot) Metrics() map[string]float64 {
+	metrics := map[string]float64{}
+
+	metrics[`load.avg1`] = s.LoadAvg.Avg1
+	metrics[`load.avg5`] = s.LoadAvg.Avg5
+	metrics[`load.avg15`] = s.LoadAvg.Avg15
+
+	for key, value := range s.Mem {
+		metrics[`mem.`+key] = float64(value)
+	}
+
+	for _, diskUsage := range s.DiskUsage {
+		prefix := `diskusage.` + diskUsage.MountedOn + `.`
+		metrics[prefix+`total`] = float64(diskUsage.Total)
+		metrics[prefix+`used`] = float64(diskUsage.Used)
+		metrics[prefix+`available`] = float64(diskUsage.Available)
+		metrics[prefix+`usedper`] = float64(diskUsage.UsedPer)
+	}
+
+	metrics[`sock.used`] = float64(s.Sock.Used)
+	metrics[`sock.tcpinuse`] = float64(s.Sock.TcpInUse)
+	metrics[`sock.tcporphaned`] = float64(s.Sock.TcpOrphaned)
+	metrics[`sock.tcptimewait`] = float64(s.Sock.TcpTimeWait)
+	metrics[`sock.udpinuse`] = float64(s.Sock.UdpInUse)
+	metrics[`sock.raw`] = float64(s.Sock.Raw)
+	metrics[`sock.ipfrag`] = float64(s.Sock.IpFrag)
+
+	metrics[`file.fhalloc`] = float64(s.File.FhAlloc)
+	metrics[`file.fhfree`] = float64(s.File.FhFree)
+	metrics[`file.fhmax`] = float64(s.File.FhMax)
+	metrics[`file.inalloc`] = float64(s.File.InAlloc)
+	metrics[`file.infree`] = float64(s.File.InFree)
+
+	metrics[`proc.running`] = float64(s.Proc.Running)
+	metrics[`proc.blocked`] = float64(s.Proc.Blocked)
+	metrics[`proc.runqueue`] = float64(s.Proc.RunQueue)
+	metrics[`proc.total`] = float64(s.Proc.Total)
+
+	for _, reachability := range s.Reachability {
+		if reachability.Error != "" {
+			continue
+		}
+		prefix := `reachability.` + reachability.Name + `.`
+		metrics[prefix+`loss`] = reachability.Loss
+		if reachability.Received > 0 {
+			metrics[prefix+`rttmin`] = reachability.RttMin
+			metrics[prefix+`rttavg`] = reachability.RttAvg
+			metrics[prefix+`rttmax`] = reachability.RttMax
+		}
+	}
+
+	if len(s.Times) > 0 {
+		metrics[`sysstats.skew`] = s.Skew().Seconds()
+	}
+	for collector, health := range s.Health {
+		prefix := `sysstats.` + collector + `.`
+		metrics[prefix+`collections`] = float64(health.Collections)
+		metrics[prefix+`errors`] = float64(health.Errors)
+		metrics[prefix+`duration`] = health.Duration.Seconds()
+		metrics[prefix+`bytes`] = float64(health.Bytes)
+		if !health.LastSuccess.IsZero() {
+			metrics[prefix+`lastsuccess`] = float64(health.LastSuccess.Unix())
+		}
+	}
+	for _, sinkStats := range s.Sinks {
+		prefix := `sysstats.sinks.` + sinkStats.Name + `.`
+		metrics[prefix+`queued`] = float64(sinkStats.Queued)
+		metrics[prefix+`written`] = float64(sinkStats.Written)
+		metrics[prefix+`failed`] = float64(sinkStats.Failed)
+		metrics[prefix+`dropped`] = float64(sinkStats.Dropped)
+	}
+
+	for key, value := range s.Custom {
+		metrics[key] = value
+	}
+
+	return metrics
+}
+
+// Metrics returns the rates of the comparison flattened in a map where the
+// keys are the metric names, e.g.:
+//   cpu.cpu0.user, net.eth0.rxbytes, disk.sda.writeios, proc.newprocs
+// The memory deltas are not included as they are not rates.
+func (c Comparison) Metrics() map[string]float64 {
+	metrics := map[string]float64{}
+
+	for cpuName, cpuStats := range c.Cpu {
+		for key, value := range cpuStats {
+			metrics[`cpu.`+cpuName+`.`+key] = value
+		}
+	}
+
+	for ifaceName, ifaceStats := range c.Net {
+		for key, value := range ifaceStats {
+			metrics[`net.`+ifaceName+`.`+key] = value
+		}
+	}
+
+	for _, diskStats := range c.Disk {
+		prefix := `disk.` + diskStats.Name + `.`
+		metrics[prefix+`readios`] = diskStats.ReadIOs
+		metrics[prefix+`readmerges`] = diskStats.ReadMerges
+		metrics[prefix+`readbytes`] = diskStats.ReadBytes
+		metrics[prefix+`writeios`] = diskStats.WriteIOs
+		metrics[prefix+`writemerges`] = diskStats.WriteMerges
+		metrics[prefix+`writebytes`] = diskStats.WriteBytes
+		metrics[prefix+`inflight`] = float64(diskStats.InFlight)
+		metrics[prefix+`ioticks`] = float64(diskStats.IOTicks)
+		metrics[prefix+`timeinqueue`] = float64(diskStats.TimeInQueue)
+	}
+
+	metrics[`proc.newprocs`] = c.Proc.NewProcs
+
+	return metrics
+}
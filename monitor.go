@@ -0,0 +1,428 @@
+package sysstats
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy is what a Monitor does with a snapshot when the queue of a
+// sink is full.
+type OverflowPolicy string
+
+// Overflow policies.
+const (
+	// OverflowDropOldest drops the oldest snapshot of the queue, so a slow
+	// sink gets the latest snapshots and never delays the collection
+	OverflowDropOldest OverflowPolicy = `dropoldest`
+	// OverflowBlock waits until the sink takes a snapshot of the queue, so
+	// no snapshot is lost but a slow sink delays the collection
+	OverflowBlock OverflowPolicy = `block`
+)
+
+// DefaultSinkQueueSize is the # of snapshots queued per sink by default.
+const DefaultSinkQueueSize = 10
+
+// DefaultDrainTimeout is the max time a Monitor waits by default for its
+// sinks to write the snapshots queued when it's stopped.
+const DefaultDrainTimeout = 10 * time.Second
+
+// dropWarningInterval is the min time between the warnings of the snapshots
+// dropped by the queue of a sink.
+const dropWarningInterval = time.Minute
+
+// SinkStats represents the queue of a sink of a Monitor.
+type SinkStats struct {
+	Name    string `json:"name"`    // Name of the sink (see Monitor.SinkNames)
+	Queued  int    `json:"queued"`  // # of snapshots waiting to be written
+	Written uint64 `json:"written"` // # of snapshots written
+	Failed  uint64 `json:"failed"`  // # of snapshots the sink failed to write
+	Dropped uint64 `json:"dropped"` // # of snapshots dropped because the queue was full
+}
+
+// Monitor takes a snapshot of the system every interval and writes it to all
+// its sinks. Every sink has its own queue and goroutine, so a slow sink (e.g.
+// an HTTP endpoint) doesn't delay the collection nor the other sinks.
+type Monitor struct {
+	Interval time.Duration // Time between snapshots (default 1 minute)
+	Align    bool          // Align the snapshots to the wall clock boundaries of the interval
+	Jitter   time.Duration // Max random delay added to every snapshot
+	// Registry holds the collectors of the snapshots (DefaultRegistry by
+	// default), and Collectors are the ones collected (see
+	// SnapshotCollectors), none means all the enabled ones.
+	Registry   *Registry
+	Collectors []string
+	// Ifaces and Disks, if set, keep only the network interfaces and disks
+	// whose names match them.
+	Ifaces *regexp.Regexp
+	Disks  *regexp.Regexp
+	Sinks  []Sink // Outputs of the snapshots
+	// SinkNames are the names of the sinks in their stats (see SinkStats),
+	// by position in Sinks. The sinks without name are named by their type
+	// (statsd, influx, json, file, recorder, agent,...), and the repeated
+	// names get the suffix -2, -3,...
+	SinkNames []string
+	// QueueSize is the # of snapshots queued per sink (DefaultSinkQueueSize
+	// by default), and Overflow what's done when a queue is full
+	// (OverflowDropOldest by default). The state of the queues is attached
+	// to every snapshot (see Snapshot.Sinks).
+	QueueSize int
+	Overflow  OverflowPolicy
+	// DrainTimeout is the max time Run waits for the sinks to write the
+	// snapshots queued when the context is cancelled (DefaultDrainTimeout by
+	// default). The snapshots still queued then are dropped.
+	DrainTimeout time.Duration
+	// OnError is called when a sink fails to write a snapshot, or with a nil
+	// sink when the snapshot can't be taken or some of its collectors fail
+	// (the snapshot is still written without their families). Errors are
+	// ignored if it's nil. The calls are serialized, so it doesn't need to
+	// be safe for concurrent use, but a slow one delays the other sinks.
+	OnError func(sink Sink, err error)
+	// Detectors are fed with the gauges and rates of every snapshot (see
+	// Snapshot.Metrics and Comparison.Metrics) and the anomalies they find
+	// are reported to OnAnomaly.
+	Detectors []*AnomalyDetector
+	OnAnomaly func(anomaly Anomaly)
+	// Events, if set, receives the discrete changes of the system observed
+	// between snapshots (interfaces going up/down, disks appearing,...) and
+	// the anomalies found by the detectors.
+	Events *EventBus
+	// DiskFill, if set, is fed with the disk usage of every snapshot and the
+	// file systems projected to be full soon are published to Events.
+	DiskFill *DiskFillPredictor
+	// Identity, if set, is attached to every snapshot written to the sinks.
+	Identity *Identity
+
+	previous *Snapshot
+	state    *SystemState
+	mu       sync.Mutex
+	queues   []*sinkQueue
+	errorMu  sync.Mutex
+}
+
+// sinkQueue is the queue of the snapshots to be written to a sink.
+type sinkQueue struct {
+	name      string
+	sink      Sink
+	snapshots chan Snapshot
+	written   atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+	// abandoned is set when the drain times out, so the snapshots left are
+	// dropped instead of written
+	abandoned atomic.Bool
+	// droppedWarned is the # of dropped snapshots when the last warning was
+	// logged, at warnedAt (Unix time in nanoseconds)
+	droppedWarned atomic.Uint64
+	warnedAt      atomic.Int64
+}
+
+// Run takes and writes the snapshots until the context is cancelled. Errors
+// taking the snapshots or writing them don't stop the monitor, they are
+// reported to OnError. When the context is cancelled it waits up to
+// DrainTimeout for the sinks to write the snapshots already queued.
+func (m *Monitor) Run(ctx context.Context) error {
+	s := schedule{interval: m.Interval, align: m.Align, jitter: m.Jitter}
+
+	wg := m.startSinks()
+	defer m.drain(wg)
+
+	return s.run(ctx, func() {
+		registry := m.Registry
+		if registry == nil {
+			registry = defaultRegistry
+		}
+		snapshot, err := registry.collectSnapshot(m.Collectors, deviceFilter{ifaces: m.Ifaces, disks: m.Disks})
+		if err != nil {
+			m.error(nil, err)
+			if !isPartialSnapshot(err) {
+				return
+			}
+		}
+		snapshot.Identity = m.Identity
+		snapshot.Sinks = m.SinkStats()
+		m.write(ctx, snapshot)
+		m.detect(snapshot)
+		m.project(snapshot)
+		m.observe(snapshot)
+	})
+}
+
+// observe publishes the changes of the system state since the previous
+// snapshot.
+func (m *Monitor) observe(snapshot Snapshot) {
+	if m.Events == nil {
+		return
+	}
+
+	state, err := getSystemState()
+	if errors.Is(err, ErrNotSupported) {
+		return
+	}
+	if err != nil {
+		m.error(nil, err)
+		return
+	}
+	previous := m.state
+	m.state = &state
+	if previous == nil {
+		return
+	}
+
+	for _, event := range stateEvents(*previous, state, snapshot.Time) {
+		m.Events.Publish(event)
+	}
+}
+
+// project feeds the disk fill predictor with the disk usage of the snapshot.
+func (m *Monitor) project(snapshot Snapshot) {
+	if m.DiskFill == nil {
+		return
+	}
+
+	_, filling := m.DiskFill.Observe(snapshot.Time, snapshot.DiskUsage)
+	if m.Events == nil {
+		return
+	}
+	for _, projection := range filling {
+		projection := projection
+		m.Events.Publish(Event{
+			Type:       EventDiskFilling,
+			Time:       projection.Time,
+			Subject:    projection.MountedOn,
+			Projection: &projection,
+		})
+	}
+}
+
+// detect feeds the detectors with the metrics of the snapshot.
+func (m *Monitor) detect(snapshot Snapshot) {
+	previous := m.previous
+	m.previous = &snapshot
+	if len(m.Detectors) == 0 {
+		return
+	}
+
+	metrics := snapshot.Metrics()
+	if previous != nil {
+		comparison, err := compare(*previous, snapshot)
+		if err != nil {
+			m.error(nil, err)
+		} else {
+			for name, value := range comparison.Metrics() {
+				metrics[name] = value
+			}
+		}
+	}
+
+	for _, detector := range m.Detectors {
+		for _, anomaly := range detector.Observe(snapshot.Time, metrics) {
+			if m.OnAnomaly != nil {
+				m.OnAnomaly(anomaly)
+			}
+			if m.Events != nil {
+				anomaly := anomaly
+				m.Events.Publish(Event{
+					Type:    EventAnomaly,
+					Time:    anomaly.Time,
+					Subject: anomaly.Metric,
+					Anomaly: &anomaly,
+				})
+			}
+		}
+	}
+}
+
+// startSinks creates the queues of the sinks and starts their goroutines,
+// which write the snapshots queued until the queue is closed.
+func (m *Monitor) startSinks() *sync.WaitGroup {
+	size := m.QueueSize
+	if size <= 0 {
+		size = DefaultSinkQueueSize
+	}
+
+	wg := &sync.WaitGroup{}
+	queues := make([]*sinkQueue, 0, len(m.Sinks))
+	names := m.sinkNames()
+	for i, sink := range m.Sinks {
+		queue := &sinkQueue{name: names[i], sink: sink, snapshots: make(chan Snapshot, size)}
+		queues = append(queues, queue)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for snapshot := range queue.snapshots {
+				if queue.abandoned.Load() {
+					queue.dropped.Add(1)
+					continue
+				}
+				if err := queue.sink.Write(snapshot); err != nil {
+					queue.failed.Add(1)
+					m.error(queue.sink, err)
+					continue
+				}
+				queue.written.Add(1)
+			}
+		}()
+	}
+
+	m.mu.Lock()
+	m.queues = queues
+	m.mu.Unlock()
+
+	return wg
+}
+
+// drain closes the queues of the sinks and waits up to DrainTimeout for
+// their goroutines to write the snapshots queued. When it times out the
+// snapshots left are dropped, and the sinks still writing are left behind.
+func (m *Monitor) drain(wg *sync.WaitGroup) {
+	for _, queue := range m.queues {
+		close(queue.snapshots)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timeout := m.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		for _, queue := range m.queues {
+			queue.abandoned.Store(true)
+			if len(queue.snapshots) > 0 {
+				logWarn("timed out draining the queue of a sink", "sink", queue.name,
+					"queued", len(queue.snapshots))
+			}
+		}
+	}
+}
+
+// sinkNames returns the names of the sinks: the ones of SinkNames, or their
+// type, with a suffix if they are repeated.
+func (m *Monitor) sinkNames() []string {
+	names := make([]string, 0, len(m.Sinks))
+	used := map[string]int{}
+	for i, sink := range m.Sinks {
+		name := ""
+		if i < len(m.SinkNames) {
+			name = m.SinkNames[i]
+		}
+		if name == "" {
+			name = sinkType(sink)
+		}
+		used[name]++
+		if used[name] > 1 {
+			name += "-" + strconv.Itoa(used[name])
+		}
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// sinkType returns the name of the type of a sink in lowercase without the
+// Sink suffix, e.g. statsd for a *StatsDSink.
+func sinkType(sink Sink) string {
+	t := reflect.TypeOf(sink)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" || t.Name() == "Sink" {
+		return "sink"
+	}
+
+	return strings.ToLower(strings.TrimSuffix(t.Name(), "Sink"))
+}
+
+// write queues the snapshot to all the sinks. When the queue of a sink is
+// full the oldest snapshot is dropped, or with OverflowBlock it waits until
+// the sink takes one or the context is cancelled.
+func (m *Monitor) write(ctx context.Context, snapshot Snapshot) {
+	for _, queue := range m.queues {
+		if m.Overflow == OverflowBlock {
+			select {
+			case queue.snapshots <- snapshot:
+			case <-ctx.Done():
+				queue.dropped.Add(1)
+			}
+			continue
+		}
+
+		for queued := false; !queued; {
+			select {
+			case queue.snapshots <- snapshot:
+				queued = true
+			default:
+				select {
+				case <-queue.snapshots:
+					queue.dropped.Add(1)
+					queue.warnDropped()
+				default:
+				}
+			}
+		}
+	}
+}
+
+// warnDropped logs the snapshots dropped by the queue, at most once every
+// dropWarningInterval so a sink that stays slow doesn't flood the logs.
+func (q *sinkQueue) warnDropped() {
+	now := time.Now().UnixNano()
+	warnedAt := q.warnedAt.Load()
+	if warnedAt != 0 && now-warnedAt < int64(dropWarningInterval) {
+		return
+	}
+	if !q.warnedAt.CompareAndSwap(warnedAt, now) {
+		return
+	}
+
+	dropped := q.dropped.Load()
+	logWarn("dropped snapshots of a slow sink", "sink", q.name, "dropped", dropped-q.droppedWarned.Swap(dropped))
+}
+
+// SinkStats returns the state of the queues of the sinks while the monitor
+// is running.
+func (m *Monitor) SinkStats() []SinkStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sinkStatsArr := make([]SinkStats, 0, len(m.queues))
+	for _, queue := range m.queues {
+		sinkStatsArr = append(sinkStatsArr, SinkStats{
+			Name:    queue.name,
+			Queued:  len(queue.snapshots),
+			Written: queue.written.Load(),
+			Failed:  queue.failed.Load(),
+			Dropped: queue.dropped.Load(),
+		})
+	}
+
+	return sinkStatsArr
+}
+
+// error reports an error to OnError.
+func (m *Monitor) error(sink Sink, err error) {
+	if m.OnError == nil {
+		return
+	}
+
+	// The goroutines of the sinks report their errors concurrently
+	m.errorMu.Lock()
+	defer m.errorMu.Unlock()
+	m.OnError(sink, err)
+}
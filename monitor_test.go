@@ -0,0 +1,134 @@
+package sysstats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingSink is a sink whose writes block until release is closed.
+type blockingSink struct {
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSink) Write(snapshot Snapshot) error {
+	select {
+	case s.writing <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return nil
+}
+
+// testRegistry returns a registry with only a custom collector.
+func testRegistry(t *testing.T) *Registry {
+	r := NewRegistry()
+	for _, name := range r.Names() {
+		if err := r.Disable(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Register(testCollector{name: `app`, metrics: Metrics{`app.requests`: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestMonitorSinkNames(t *testing.T) {
+	m := &Monitor{
+		Sinks:     []Sink{NewJSONSink(nil), &StatsDSink{}, &StatsDSink{}, NewInfluxSink(nil)},
+		SinkNames: []string{``, ``, ``, `telegraf`},
+	}
+	got := strings.Join(m.sinkNames(), ",")
+	if want := `json,statsd,statsd-2,telegraf`; got != want {
+		t.Errorf("sinkNames() = %s, want %s", got, want)
+	}
+}
+
+func TestMonitorDrainTimeout(t *testing.T) {
+	sink := &blockingSink{writing: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(sink.release)
+	m := &Monitor{
+		Interval:     time.Millisecond,
+		Registry:     testRegistry(t),
+		Sinks:        []Sink{sink},
+		DrainTimeout: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+	<-sink.writing
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return after the drain timeout")
+	}
+}
+
+// failingSink is a sink whose writes always fail.
+type failingSink struct{}
+
+func (failingSink) Write(snapshot Snapshot) error { return errors.New("failing sink") }
+
+func TestMonitorOnErrorSerialized(t *testing.T) {
+	// The counter isn't synchronized: the race detector catches concurrent
+	// calls of OnError
+	errs := 0
+	done := make(chan struct{})
+	m := &Monitor{
+		Interval: time.Millisecond,
+		Registry: testRegistry(t),
+		Sinks:    []Sink{failingSink{}, failingSink{}, failingSink{}, failingSink{}},
+		OnError: func(sink Sink, err error) {
+			errs++
+			if errs == 20 {
+				close(done)
+			}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnError wasn't called for the failing sinks")
+	}
+}
+
+func TestSinkQueueWarnDropped(t *testing.T) {
+	var logs bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer SetLogger(nil)
+
+	queue := &sinkQueue{name: `statsd`}
+	for i := 0; i < 5; i++ {
+		queue.dropped.Add(1)
+		queue.warnDropped()
+	}
+	if got := strings.Count(logs.String(), "dropped snapshots"); got != 1 {
+		t.Errorf("%d warnings logged, want 1:\n%s", got, logs.String())
+	}
+
+	// The next warning counts the snapshots dropped since the last one
+	queue.warnedAt.Store(time.Now().Add(-dropWarningInterval).UnixNano())
+	queue.dropped.Add(1)
+	queue.warnDropped()
+	if !strings.Contains(logs.String(), "dropped=5") {
+		t.Errorf("logs = %s, want the 5 snapshots dropped since the first warning", logs.String())
+	}
+}
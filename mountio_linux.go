@@ -0,0 +1,140 @@
+// +build linux
+
+package sysstats
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MountInfo represents a mount point from the file /proc/self/mountinfo.
+type MountInfo struct {
+	MountedOn string `json:"mountedon"` // Mount point
+	Source    string `json:"source"`    // Mounted device or file system (e.g. /dev/sda1)
+	Type      string `json:"type"`      // File system type
+	Major     int    `json:"major"`     // Major number of the device
+	Minor     int    `json:"minor"`     // Minor number of the device
+}
+
+// MountDiskStats represents the IO stats of the disk a mount point is on.
+type MountDiskStats struct {
+	MountedOn string `json:"mountedon"` // Mount point
+	Source    string `json:"source"`    // Mounted device or file system
+	Type      string `json:"type"`      // File system type
+	DiskAvgStats
+}
+
+// getMounts gets the mount points of a linux system from the file
+// /proc/self/mountinfo, which has the following format:
+//   36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+// The optional fields (master:1) end at the "-" separator.
+func getMounts() (mounts []MountInfo, err error) {
+	err = scanLines("/proc/self/mountinfo", func(line string) {
+		fields := strings.Fields(line)
+		separator := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				separator = i
+				break
+			}
+		}
+		if len(fields) < 7 || separator < 0 || separator+2 >= len(fields) {
+			return
+		}
+		device := strings.Split(fields[2], ":")
+		if len(device) != 2 {
+			return
+		}
+		major, err := strconv.Atoi(device[0])
+		if err != nil {
+			return
+		}
+		minor, err := strconv.Atoi(device[1])
+		if err != nil {
+			return
+		}
+
+		mounts = append(mounts, MountInfo{
+			MountedOn: unescapeMountPath(fields[4]),
+			Source:    unescapeMountPath(fields[separator+2]),
+			Type:      fields[separator+1],
+			Major:     major,
+			Minor:     minor,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mounts, nil
+}
+
+// unescapeMountPath replaces the octal escapes of the spaces, tabs, newlines
+// and backslashes of the paths of /proc/self/mountinfo (e.g. \040).
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+
+	var unescaped strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if char, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				unescaped.WriteByte(byte(char))
+				i += 3
+				continue
+			}
+		}
+		unescaped.WriteByte(path[i])
+	}
+
+	return unescaped.String()
+}
+
+// getMountDiskStats joins the mount points with the IO stats of their disks
+// by device number. The mount points of devices without IO stats (tmpfs,
+// proc, btrfs subvolumes,...) are left out. A device mounted more than once
+// (e.g. bind mounts) is reported at every mount point.
+func getMountDiskStats(diskAvgStatsArr []DiskAvgStats) (mountDiskStatsArr []MountDiskStats, err error) {
+	mounts, err := getMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	disks := make(map[[2]int]DiskAvgStats, len(diskAvgStatsArr))
+	for _, diskAvgStats := range diskAvgStatsArr {
+		disks[[2]int{diskAvgStats.Major, diskAvgStats.Minor}] = diskAvgStats
+	}
+
+	mountDiskStatsArr = []MountDiskStats{}
+	for _, mount := range mounts {
+		diskAvgStats, ok := disks[[2]int{mount.Major, mount.Minor}]
+		if !ok {
+			continue
+		}
+		mountDiskStatsArr = append(mountDiskStatsArr, MountDiskStats{
+			MountedOn:    mount.MountedOn,
+			Source:       mount.Source,
+			Type:         mount.Type,
+			DiskAvgStats: diskAvgStats,
+		})
+	}
+	sort.Slice(mountDiskStatsArr, func(i, j int) bool {
+		return mountDiskStatsArr[i].MountedOn < mountDiskStatsArr[j].MountedOn
+	})
+
+	return mountDiskStatsArr, nil
+}
+
+// getMountDiskStatsOver returns the IO average of the disks of the mount
+// points between 2 samples taken d apart.
+func getMountDiskStatsOver(d time.Duration) (mountDiskStatsArr []MountDiskStats, err error) {
+	diskAvgStatsArr, err := getDiskStatsOver(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return getMountDiskStats(diskAvgStatsArr)
+}
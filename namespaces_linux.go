@@ -0,0 +1,110 @@
+// +build linux
+
+package sysstats
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// namespaceTypes are the types of the namespaces of the inventory, as they
+// are named in /proc/[pid]/ns.
+var namespaceTypes = []string{`pid`, `net`, `mnt`, `uts`, `ipc`, `user`, `cgroup`}
+
+// Namespace represents a namespace in use by the processes of a linux
+// system.
+type Namespace struct {
+	Type      string `json:"type"`      // Namespace type (pid, net, mnt, uts, ipc, user, cgroup)
+	Inode     uint64 `json:"inode"`     // Inode that identifies the namespace
+	Processes int    `json:"processes"` // # of processes in the namespace
+	Pid       int    `json:"pid"`       // Lowest PID in the namespace (e.g. the init of a container)
+	Name      string `json:"name"`      // Command name of the lowest PID
+	Host      bool   `json:"host"`      // It's the namespace of PID 1 (false if PID 1 can't be read)
+}
+
+// getNamespaces gets the distinct namespaces in use from the links
+// /proc/[pid]/ns/*, which have the following format:
+//   net:[4026531833]
+// The processes whose namespaces can't be read (processes of other users
+// without privileges, or processes that exited) are skipped. The namespaces
+// are sorted by type (in the order of namespaceTypes) and # of processes.
+func getNamespaces() (namespaces []Namespace, err error) {
+	pids, err := getPids()
+	if err != nil {
+		return nil, err
+	}
+
+	type namespaceKey struct {
+		nsType string
+		inode  uint64
+	}
+	found := map[namespaceKey]*Namespace{}
+	hostInodes := map[string]uint64{}
+	for _, pid := range pids {
+		dir := "/proc/" + strconv.Itoa(pid) + "/ns/"
+		for _, nsType := range namespaceTypes {
+			link, err := os.Readlink(dir + nsType)
+			if err != nil {
+				logDebug("skipped namespace", "pid", pid, "type", nsType, "error", err)
+				continue
+			}
+			inode, ok := parseNamespaceLink(link, nsType)
+			if !ok {
+				continue
+			}
+			if pid == 1 {
+				hostInodes[nsType] = inode
+			}
+
+			key := namespaceKey{nsType, inode}
+			namespace, ok := found[key]
+			if !ok {
+				namespace = &Namespace{Type: nsType, Inode: inode, Pid: pid}
+				found[key] = namespace
+			}
+			namespace.Processes++
+		}
+	}
+
+	order := map[string]int{}
+	for i, nsType := range namespaceTypes {
+		order[nsType] = i
+	}
+	namespaces = make([]Namespace, 0, len(found))
+	for _, namespace := range found {
+		namespace.Host = hostInodes[namespace.Type] == namespace.Inode
+		// The PIDs are sorted, so the first one found is the lowest
+		processRawStats, err := readProcessRawStats(namespace.Pid, 0)
+		if err == nil {
+			namespace.Name = processRawStats.Name
+		}
+		namespaces = append(namespaces, *namespace)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		if namespaces[i].Type != namespaces[j].Type {
+			return order[namespaces[i].Type] < order[namespaces[j].Type]
+		}
+		if namespaces[i].Processes != namespaces[j].Processes {
+			return namespaces[i].Processes > namespaces[j].Processes
+		}
+		return namespaces[i].Inode < namespaces[j].Inode
+	})
+
+	return namespaces, nil
+}
+
+// parseNamespaceLink parses the inode of a link of /proc/[pid]/ns.
+func parseNamespaceLink(link string, nsType string) (inode uint64, ok bool) {
+	prefix := nsType + ":["
+	if !strings.HasPrefix(link, prefix) || !strings.HasSuffix(link, "]") {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(link[len(prefix):len(link)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return inode, true
+}
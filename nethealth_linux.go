@@ -0,0 +1,123 @@
+// +build linux
+
+package sysstats
+
+import (
+	"strconv"
+	"time"
+)
+
+// IfaceHealth represents the error and drop rates of a network interface.
+type IfaceHealth struct {
+	RxErrs float64 `json:"rxerrs" unit:"/s"`        // # of errors that happend while receiving packets per second
+	RxDrop float64 `json:"rxdrop" unit:"packets/s"` // # of received packets that were dropped per second
+	TxErrs float64 `json:"txerrs" unit:"/s"`        // # of errors that happend while transmitting packets per second
+	TxDrop float64 `json:"txdrop" unit:"packets/s"` // # of transmitted packets that were dropped per second
+}
+
+// NetHealth represents a summary of the network health of a linux system,
+// meant for simple host dashboards.
+type NetHealth struct {
+	Ifaces           map[string]IfaceHealth `json:"ifaces"`                    // Error and drop rates per interface
+	RetransRatio     float64                `json:"retransratio" unit:"%"`     // % of TCP segments retransmitted
+	ListenOverflows  float64                `json:"listenoverflows" unit:"/s"` // # of times a listen queue overflowed per second
+	ListenDrops      float64                `json:"listendrops" unit:"/s"`     // # of SYNs dropped by listening sockets per second
+	ConntrackCount   uint64                 `json:"conntrackcount"`            // # of conntrack entries (0 if conntrack isn't loaded)
+	ConntrackMax     uint64                 `json:"conntrackmax"`              // Max # of conntrack entries
+	ConntrackUsedPer float64                `json:"conntrackusedper" unit:"%"` // % of the conntrack table used
+	Sock             SockStats              `json:"sock"`                      // Socket counts
+}
+
+// netHealthRawStats represents the counters a NetHealth is calculated from.
+type netHealthRawStats struct {
+	net    NetRawStats
+	tcp    map[string]uint64
+	tcpExt map[string]uint64
+}
+
+// getNetHealthRawStats gets the counters of the interfaces from the file
+// /proc/net/dev and the TCP ones from the files /proc/net/snmp and
+// /proc/net/netstat.
+func getNetHealthRawStats() (rawStats netHealthRawStats, err error) {
+	rawStats = netHealthRawStats{}
+	rawStats.net, err = getNetRawStats()
+	if err != nil {
+		return netHealthRawStats{}, err
+	}
+	rawStats.tcp, err = readSnmpCounters("/proc/net/snmp", "Tcp")
+	if err != nil {
+		return netHealthRawStats{}, err
+	}
+	rawStats.tcpExt, err = readSnmpCounters("/proc/net/netstat", "TcpExt")
+	if err != nil {
+		return netHealthRawStats{}, err
+	}
+
+	return rawStats, nil
+}
+
+// getNetHealthAvgStats calculates the network health between 2 samples. The
+// conntrack and socket stats are read when it's called.
+func getNetHealthAvgStats(firstSample netHealthRawStats, secondSample netHealthRawStats) (netHealth NetHealth, err error) {
+	netHealth = NetHealth{}
+
+	netAvgStats, err := getNetAvgStats(firstSample.net, secondSample.net)
+	if err != nil {
+		return NetHealth{}, err
+	}
+	netHealth.Ifaces = map[string]IfaceHealth{}
+	for ifaceName, ifaceAvgStats := range netAvgStats {
+		netHealth.Ifaces[ifaceName] = IfaceHealth{
+			RxErrs: ifaceAvgStats[`rxerrs`],
+			RxDrop: ifaceAvgStats[`rxdrop`],
+			TxErrs: ifaceAvgStats[`txerrs`],
+			TxDrop: ifaceAvgStats[`txdrop`],
+		}
+	}
+
+	outSegs := secondSample.tcp[`OutSegs`] - firstSample.tcp[`OutSegs`]
+	if outSegs > 0 {
+		retransSegs := secondSample.tcp[`RetransSegs`] - firstSample.tcp[`RetransSegs`]
+		netHealth.RetransRatio = float64(retransSegs) * 100.00 / float64(outSegs)
+	}
+
+	// Every sample of /proc/net/dev has the same time
+	var timeDelta float64
+	for ifaceName, secondRawStats := range secondSample.net {
+		timeDelta = time.Duration(secondRawStats[`time`] - firstSample.net[ifaceName][`time`]).Seconds()
+		break
+	}
+	if timeDelta > 0 {
+		netHealth.ListenOverflows = float64(secondSample.tcpExt[`ListenOverflows`]-firstSample.tcpExt[`ListenOverflows`]) / timeDelta
+		netHealth.ListenDrops = float64(secondSample.tcpExt[`ListenDrops`]-firstSample.tcpExt[`ListenDrops`]) / timeDelta
+	}
+
+	netHealth.ConntrackCount, netHealth.ConntrackMax = getConntrackUsage()
+	if netHealth.ConntrackMax > 0 {
+		netHealth.ConntrackUsedPer = float64(netHealth.ConntrackCount) * 100.00 / float64(netHealth.ConntrackMax)
+	}
+
+	netHealth.Sock, err = getSockStats()
+	if err != nil {
+		return NetHealth{}, err
+	}
+
+	return netHealth, nil
+}
+
+// getNetHealthOver returns the network health between 2 samples taken d
+// apart.
+func getNetHealthOver(d time.Duration) (netHealth NetHealth, err error) {
+	return sampleOver(d, getNetHealthRawStats, getNetHealthAvgStats)
+}
+
+// getConntrackUsage returns the # of entries of the conntrack table and its
+// size from the files /proc/sys/net/netfilter/nf_conntrack_count and
+// /proc/sys/net/netfilter/nf_conntrack_max. Both are 0 if the nf_conntrack
+// module isn't loaded.
+func getConntrackUsage() (count uint64, max uint64) {
+	count, _ = strconv.ParseUint(readSysfsString("/proc/sys/net/netfilter/nf_conntrack_count"), 10, 64)
+	max, _ = strconv.ParseUint(readSysfsString("/proc/sys/net/netfilter/nf_conntrack_max"), 10, 64)
+
+	return count, max
+}
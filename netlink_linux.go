@@ -0,0 +1,175 @@
+// +build linux
+
+package sysstats
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Generic netlink constants (linux/genetlink.h)
+const (
+	genlIdCtrl          = 0x10 // GENL_ID_CTRL
+	ctrlCmdGetFamily    = 3    // CTRL_CMD_GETFAMILY
+	ctrlAttrFamilyId    = 1    // CTRL_ATTR_FAMILY_ID
+	ctrlAttrFamilyName  = 2    // CTRL_ATTR_FAMILY_NAME
+	genlHeaderLen       = 4    // sizeof(struct genlmsghdr)
+	nlAttrTypeMask      = 0x3fff
+	nlAttrHeaderLen     = 4
+	nlAttrAlignmentMask = 3
+)
+
+// genlConn is a generic netlink socket. It only supports the request/reply
+// and dump commands the collectors need.
+type genlConn struct {
+	fd  int
+	seq uint32
+}
+
+// newGenlConn opens a generic netlink socket.
+func newGenlConn() (conn *genlConn, err error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_GENERIC)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+	if err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	return &genlConn{fd: fd}, nil
+}
+
+// Close closes the socket.
+func (c *genlConn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// familyId resolves the ID of a generic netlink family (ethtool,...).
+func (c *genlConn) familyId(name string) (id uint16, err error) {
+	replies, err := c.execute(genlIdCtrl, ctrlCmdGetFamily, 1, nlAttr(ctrlAttrFamilyName, nlString(name)))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, reply := range replies {
+		value, ok := parseNlAttrs(reply)[ctrlAttrFamilyId]
+		if ok && len(value) >= 2 {
+			return binary.NativeEndian.Uint16(value), nil
+		}
+	}
+
+	return 0, errors.New("Couldn't resolve the generic netlink family " + name)
+}
+
+// execute sends a command to a family and returns the attributes of the
+// replies (without the generic netlink header).
+func (c *genlConn) execute(family uint16, cmd uint8, version uint8, attrs []byte) (replies [][]byte, err error) {
+	return c.request(family, cmd, version, syscall.NLM_F_ACK, attrs)
+}
+
+// dump sends a dump command to a family and returns the attributes of all
+// the replies, which can span several messages.
+func (c *genlConn) dump(family uint16, cmd uint8, version uint8, attrs []byte) (replies [][]byte, err error) {
+	return c.request(family, cmd, version, syscall.NLM_F_DUMP, attrs)
+}
+
+// request sends a request with the given flags and reads the replies until
+// the ACK or the end of the dump.
+func (c *genlConn) request(family uint16, cmd uint8, version uint8, flags uint16, attrs []byte) (replies [][]byte, err error) {
+	c.seq++
+
+	msg := make([]byte, syscall.NLMSG_HDRLEN+genlHeaderLen+len(attrs))
+	binary.NativeEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:6], family)
+	binary.NativeEndian.PutUint16(msg[6:8], syscall.NLM_F_REQUEST|flags)
+	binary.NativeEndian.PutUint32(msg[8:12], c.seq)
+	msg[syscall.NLMSG_HDRLEN] = cmd
+	msg[syscall.NLMSG_HDRLEN+1] = version
+	copy(msg[syscall.NLMSG_HDRLEN+genlHeaderLen:], attrs)
+
+	err = syscall.Sendto(c.fd, msg, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+	if err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	buf := make([]byte, os.Getpagesize()*8)
+	for {
+		n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			if msg.Header.Seq != c.seq {
+				continue
+			}
+			switch msg.Header.Type {
+			case syscall.NLMSG_ERROR:
+				if len(msg.Data) < 4 {
+					return nil, errors.New("Truncated netlink error message")
+				}
+				errno := int32(binary.NativeEndian.Uint32(msg.Data[0:4]))
+				if errno == 0 {
+					// ACK
+					return replies, nil
+				}
+				return nil, syscall.Errno(-errno)
+			case syscall.NLMSG_DONE:
+				return replies, nil
+			default:
+				if len(msg.Data) < genlHeaderLen {
+					continue
+				}
+				// buf is reused by the next read
+				replies = append(replies, append([]byte(nil), msg.Data[genlHeaderLen:]...))
+			}
+		}
+	}
+}
+
+// nlAttr encodes a netlink attribute.
+func nlAttr(attrType uint16, data []byte) []byte {
+	length := nlAttrHeaderLen + len(data)
+	attr := make([]byte, (length+nlAttrAlignmentMask)&^nlAttrAlignmentMask)
+	binary.NativeEndian.PutUint16(attr[0:2], uint16(length))
+	binary.NativeEndian.PutUint16(attr[2:4], attrType)
+	copy(attr[nlAttrHeaderLen:], data)
+
+	return attr
+}
+
+// nlString encodes a string netlink attribute value (NUL terminated).
+func nlString(s string) []byte {
+	return append([]byte(s), 0)
+}
+
+// parseNlAttrs decodes the netlink attributes of a message, indexed by type.
+// Nested attributes are returned undecoded.
+func parseNlAttrs(b []byte) (attrs map[uint16][]byte) {
+	attrs = map[uint16][]byte{}
+
+	for len(b) >= nlAttrHeaderLen {
+		length := int(binary.NativeEndian.Uint16(b[0:2]))
+		if length < nlAttrHeaderLen || length > len(b) {
+			break
+		}
+		attrType := binary.NativeEndian.Uint16(b[2:4]) & nlAttrTypeMask
+		attrs[attrType] = b[nlAttrHeaderLen:length]
+
+		length = (length + nlAttrAlignmentMask) &^ nlAttrAlignmentMask
+		if length > len(b) {
+			break
+		}
+		b = b[length:]
+	}
+
+	return attrs
+}
@@ -0,0 +1,133 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// StableIfaceRawStats represents the raw statistics of a network interface
+// with the metadata that identifies it. Name and Alias are informative: the
+// interface is tracked by the key of NetStableRawStats.
+type StableIfaceRawStats struct {
+	Name    string        `json:"name"`    // Interface name when the sample was taken
+	Alias   string        `json:"alias"`   // Interface alias (ifalias), if set
+	Mac     string        `json:"mac"`     // MAC address
+	Ifindex int           `json:"ifindex"` // Interface index
+	Stats   IfaceRawStats `json:"stats"`   // Raw statistics
+}
+
+// StableIfaceAvgStats represents the statistics of a network interface
+// between 2 samples with the metadata of the second sample.
+type StableIfaceAvgStats struct {
+	Name    string        `json:"name"`    // Interface name in the second sample
+	Alias   string        `json:"alias"`   // Interface alias (ifalias), if set
+	Mac     string        `json:"mac"`     // MAC address
+	Ifindex int           `json:"ifindex"` // Interface index
+	Renamed bool          `json:"renamed"` // The name changed between the samples
+	Stats   IfaceAvgStats `json:"stats"`   // Statistics
+}
+
+// NetStableRawStats represents the raw statistics of all the network
+// interfaces by stable key.
+//
+// Map keys:
+//   mac:[address]   - the MAC address, when no other interface has it.
+//   ifindex:[index] - the interface index otherwise (loopback, VLANs and
+//                     bond slaves that share the MAC of their parent,...).
+type NetStableRawStats map[string]StableIfaceRawStats
+
+// NetStableAvgStats represents the statistics of all the network interfaces
+// by stable key (see NetStableRawStats).
+type NetStableAvgStats map[string]StableIfaceAvgStats
+
+// getNetStableRawStats gets the raw statistics of the network interfaces
+// keyed by their MAC address or index (from /sys/class/net/[iface]/address
+// and ifindex), so the averages survive renames and alias changes.
+func getNetStableRawStats() (netStableRawStats NetStableRawStats, err error) {
+	netRawStats, err := getNetRawStats()
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces := make([]StableIfaceRawStats, 0, len(netRawStats))
+	macs := map[string]int{}
+	for ifaceName, ifaceRawStats := range netRawStats {
+		dir := filepath.Join("/sys/class/net", ifaceName)
+		iface := StableIfaceRawStats{
+			Name:  ifaceName,
+			Alias: readSysfsString(filepath.Join(dir, "ifalias")),
+			Mac:   readSysfsString(filepath.Join(dir, "address")),
+			Stats: ifaceRawStats,
+		}
+		iface.Ifindex, err = strconv.Atoi(readSysfsString(filepath.Join(dir, "ifindex")))
+		if err != nil {
+			// The interface disappeared after reading /proc/net/dev
+			logDebug("skipped network interface without ifindex", "iface", ifaceName)
+			continue
+		}
+		if iface.Mac != "" && iface.Mac != "00:00:00:00:00:00" {
+			macs[iface.Mac]++
+		}
+		ifaces = append(ifaces, iface)
+	}
+
+	netStableRawStats = make(NetStableRawStats, len(ifaces))
+	for _, iface := range ifaces {
+		key := "ifindex:" + strconv.Itoa(iface.Ifindex)
+		if macs[iface.Mac] == 1 {
+			key = "mac:" + iface.Mac
+		}
+		netStableRawStats[key] = iface
+	}
+
+	return netStableRawStats, nil
+}
+
+// getNetStableAvgStats calculates the network traffic average between 2
+// NetStableRawStats samples. The interfaces that aren't in both samples are
+// skipped.
+func getNetStableAvgStats(firstSample NetStableRawStats, secondSample NetStableRawStats) (netStableAvgStats NetStableAvgStats, err error) {
+	firstNet, secondNet := NetRawStats{}, NetRawStats{}
+	for key, secondIface := range secondSample {
+		firstIface, ok := firstSample[key]
+		if !ok {
+			logDebug("skipped network interface missing in the first sample", "iface", key)
+			continue
+		}
+		firstNet[key] = firstIface.Stats
+		secondNet[key] = secondIface.Stats
+	}
+	if len(secondSample) > 0 && len(secondNet) == 0 {
+		return nil, errors.New("None of the network interfaces of the second sample is in the first one")
+	}
+
+	netAvgStats, err := getNetAvgStats(firstNet, secondNet)
+	if err != nil {
+		return nil, err
+	}
+
+	netStableAvgStats = make(NetStableAvgStats, len(netAvgStats))
+	for key, ifaceAvgStats := range netAvgStats {
+		secondIface := secondSample[key]
+		netStableAvgStats[key] = StableIfaceAvgStats{
+			Name:    secondIface.Name,
+			Alias:   secondIface.Alias,
+			Mac:     secondIface.Mac,
+			Ifindex: secondIface.Ifindex,
+			Renamed: secondIface.Name != firstSample[key].Name,
+			Stats:   ifaceAvgStats,
+		}
+	}
+
+	return netStableAvgStats, nil
+}
+
+// getNetStableStatsOver returns the network traffic average by stable key
+// between 2 samples taken d apart.
+func getNetStableStatsOver(d time.Duration) (netStableAvgStats NetStableAvgStats, err error) {
+	return sampleOver(d, getNetStableRawStats, getNetStableAvgStats)
+}
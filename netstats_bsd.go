@@ -0,0 +1,109 @@
+// +build freebsd openbsd netbsd darwin
+
+package sysstats
+
+import (
+	"errors"
+	"time"
+)
+
+// IfaceRawStats represents *one* network interface raw statistics of a
+// BSD system.
+//
+// Map keys:
+//   rxbytes -  # of bytes.
+//   rxpkts  -  # of packets.
+//   rxerrs  -  # of errors that happend while receiving packets.
+//   rxdrop  -  # of packets that were dropped.
+//   rxmulti -  # of multicast packets received.
+//   txbytes -  # of bytes transmitted.
+//   txpkts  -  # of packets transmitted.
+//   txerrs  -  # of errors that happend while transmitting packets.
+//   txdrop  -  # of packets that were dropped (always 0 on NetBSD).
+//   txcolls -  # of collisions that were detected.
+//   speed   -  Link speed in bits per second (0 if it's unknown).
+//   time    -  Time when the sample was taken (Unix time in nanoseconds).
+type IfaceRawStats map[string]uint64
+
+// IfaceAvgStats represents *one* network interface statistics of a BSD
+// system.
+//
+// Map keys:
+//   rxbytes -  # of bytes per second.
+//   rxpkts  -  # of packets per second.
+//   rxerrs  -  # of errors that happend while receiving packets per second.
+//   rxdrop  -  # of packets that were dropped per second.
+//   rxmulti -  # of multicast packets received per second.
+//   txbytes -  # of bytes transmitted per second.
+//   txpkts  -  # of packets transmitted per second.
+//   txerrs  -  # of errors that happend while transmitting packets per second.
+//   txdrop  -  # of packets that were dropped per second.
+//   txcolls -  # of collisions that were detected per second.
+//   rxutil  -  % of the link speed used receiving (only if the speed is known).
+//   txutil  -  % of the link speed used transmitting (only if the speed is known).
+type IfaceAvgStats map[string]float64
+
+// NetRawStats represents *all* the network interfaces raw statistics of a
+// BSD system.
+//
+// Map keys:
+//   Name - name of the network interface
+type NetRawStats map[string]IfaceRawStats
+
+// NetAvgStats represents *all* the network interfaces statistics of a
+// BSD system.
+//
+// Map keys:
+//   Name - name of the network interface
+type NetAvgStats map[string]IfaceAvgStats
+
+// getNetRawStats gets the network interfaces raw statistics of a BSD
+// system with getifaddrs(3).
+func getNetRawStats() (netRawStats NetRawStats, err error) {
+	return getNetRawStatsMatching(nil)
+}
+
+// getNetAvgStats calculates the network traffic average between 2 NetRawStats samples
+func getNetAvgStats(firstSample NetRawStats, secondSample NetRawStats) (netAvgStats NetAvgStats, err error) {
+	netAvgStats = NetAvgStats{}
+	for ifaceName, secondRawStats := range secondSample {
+		firstRawStats, ok := firstSample[ifaceName]
+		if !ok {
+			return nil, errors.New("The key " + ifaceName + " doesn't exist in the first sample of NetRawStats")
+		}
+
+		ifaceAvgStats := IfaceAvgStats{}
+		timeDelta := time.Duration(secondRawStats[StatTime] - firstRawStats[StatTime]).Seconds()
+		for key, secondValue := range secondRawStats {
+			if key == StatTime || key == ifaceSpeedKey {
+				continue
+			}
+			ifaceAvgStats[key] = float64(secondValue-firstRawStats[key]) / timeDelta
+		}
+		if speed := float64(secondRawStats[ifaceSpeedKey]); speed > 0 {
+			ifaceAvgStats[IfaceRxUtil] = ifaceAvgStats[IfaceRxBytes] * 8 * 100.00 / speed
+			ifaceAvgStats[IfaceTxUtil] = ifaceAvgStats[IfaceTxBytes] * 8 * 100.00 / speed
+		}
+		netAvgStats[ifaceName] = ifaceAvgStats
+	}
+
+	return netAvgStats, nil
+}
+
+// getNetStatsInterval returns the network traffic average between 2 samples.
+// Time interval between the 2 samples is given in seconds.
+func getNetStatsInterval(interval int64) (netAvgStats NetAvgStats, err error) {
+	return getNetStatsOver(time.Duration(interval) * time.Second)
+}
+
+// getNetStatsOver returns the network traffic average between 2 samples
+// taken d apart.
+func getNetStatsOver(d time.Duration) (netAvgStats NetAvgStats, err error) {
+	return sampleOver(d, getNetRawStats, getNetAvgStats)
+}
+
+// getNetStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n network traffic averages between them.
+func getNetStatsSampleN(n int, interval time.Duration) (series []NetAvgStats, err error) {
+	return sampleN(n, interval, getNetRawStats, getNetAvgStats)
+}
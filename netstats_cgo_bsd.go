@@ -0,0 +1,70 @@
+// +build freebsd openbsd netbsd
+
+package sysstats
+
+/*
+#include <sys/types.h>
+#include <sys/socket.h>
+#include <net/if.h>
+#include <ifaddrs.h>
+
+// if_oqdrops returns the # of packets dropped on output, which NetBSD
+// doesn't count.
+static uint64_t if_oqdrops(struct if_data *data) {
+#ifdef __NetBSD__
+	return 0;
+#else
+	return data->ifi_oqdrops;
+#endif
+}
+*/
+import "C"
+
+import (
+	"os"
+	"regexp"
+	"time"
+	"unsafe"
+)
+
+// getNetRawStatsMatching gets the raw statistics of the network interfaces
+// whose names match the regexp (all of them if it's nil). The counters are
+// in the if_data of the link level (AF_LINK) address of every interface.
+func getNetRawStatsMatching(ifaces *regexp.Regexp) (netRawStats NetRawStats, err error) {
+	var addrs *C.struct_ifaddrs
+	if ret, err := C.getifaddrs(&addrs); ret != 0 {
+		return nil, os.NewSyscallError("getifaddrs", err)
+	}
+	defer C.freeifaddrs(addrs)
+
+	netRawStats = NetRawStats{}
+
+	now := time.Now().UnixNano()
+	for addr := addrs; addr != nil; addr = addr.ifa_next {
+		if addr.ifa_addr == nil || addr.ifa_addr.sa_family != C.AF_LINK || addr.ifa_data == nil {
+			continue
+		}
+		ifaceName := C.GoString(addr.ifa_name)
+		if ifaces != nil && !ifaces.MatchString(ifaceName) {
+			continue
+		}
+
+		data := (*C.struct_if_data)(unsafe.Pointer(addr.ifa_data))
+		netRawStats[ifaceName] = IfaceRawStats{
+			IfaceRxBytes:  uint64(data.ifi_ibytes),
+			IfaceRxPkts:   uint64(data.ifi_ipackets),
+			IfaceRxErrs:   uint64(data.ifi_ierrors),
+			IfaceRxDrop:   uint64(data.ifi_iqdrops),
+			IfaceRxMulti:  uint64(data.ifi_imcasts),
+			IfaceTxBytes:  uint64(data.ifi_obytes),
+			IfaceTxPkts:   uint64(data.ifi_opackets),
+			IfaceTxErrs:   uint64(data.ifi_oerrors),
+			IfaceTxDrop:   uint64(C.if_oqdrops(data)),
+			IfaceTxColls:  uint64(data.ifi_collisions),
+			ifaceSpeedKey: uint64(data.ifi_baudrate),
+			StatTime:      uint64(now),
+		}
+	}
+
+	return netRawStats, nil
+}
@@ -3,9 +3,8 @@
 package sysstats
 
 import (
-	"bufio"
 	"errors"
-	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -32,6 +31,8 @@ import (
 //   txcolls -  # of collisions that were detected.
 //   txcarr  -  # of carrier errors that happend on transmitted packets.
 //   txcompr -  # of compressed packets transmitted.
+//   speed   -  Link speed in bits per second (only if it's known).
+//   time    -  Time when the sample was taken (Unix time in nanoseconds).
 type IfaceRawStats map[string]uint64
 
 // IfaceAvgStats represents *one* network interface statistics of a linux system.
@@ -53,6 +54,8 @@ type IfaceRawStats map[string]uint64
 //   txcolls -  # of collisions that were detected per second.
 //   txcarr  -  # of carrier errors that happend on transmitted packets per second.
 //   txcompr -  # of compressed packets transmitted per second.
+//   rxutil  -  % of the link speed used receiving (only if the speed is known).
+//   txutil  -  % of the link speed used transmitting (only if the speed is known).
 type IfaceAvgStats map[string]float64
 
 // NetRawStats represents *all* the network interfaces raw statistics of a linux system.
@@ -70,7 +73,15 @@ type NetAvgStats map[string]IfaceAvgStats
 // getNetRawStats gets the network interfaces raw statistics of a linux system from the
 // file /proc/net/dev
 func getNetRawStats() (netRawStats NetRawStats, err error) {
-	file, err := os.Open("/proc/net/dev")
+	return getNetRawStatsMatching(nil)
+}
+
+// getNetRawStatsMatching gets the raw statistics of the network interfaces
+// whose names match the regexp (all of them if it's nil). The names are
+// matched before parsing the counters, so hosts with thousands of
+// interfaces (veth,...) can be sampled cheaply.
+func getNetRawStatsMatching(ifaces *regexp.Regexp) (netRawStats NetRawStats, err error) {
+	file, err := openStatsFile("/proc/net/dev")
 	if err != nil {
 		return nil, err
 	}
@@ -78,25 +89,34 @@ func getNetRawStats() (netRawStats NetRawStats, err error) {
 
 	netRawStats = NetRawStats{}
 
-	re := regexp.MustCompile(`^\s*(.+?):\s*(.*)`)
+	columns := getKernelLayout().NetDevColumns
 
-	scanner := bufio.NewScanner(file)
-	scanner.Split(bufio.ScanLines)
-	now := time.Now().Unix()
+	scanner, release := newStatsScanner(file)
+	defer release()
+	now := time.Now().UnixNano()
 	for scanner.Scan() {
 		line := scanner.Text()
-		stats := re.FindString(line)
-		if stats == "" {
-			// No match
+		// The 2 header lines don't have a colon
+		colon := strings.IndexByte(line, ':')
+		if colon < 0 {
+			continue
+		}
+		if ifaces != nil && !ifaces.MatchString(strings.TrimSpace(line[:colon])) {
 			continue
 		}
-		ifaceName, rawStats, err := parseIfaceRawStats(stats)
+		ifaceName, rawStats, err := parseIfaceRawStats(line, columns)
 		if err != nil {
 			return nil, err
 		}
-		rawStats[`time`] = uint64(now)
+		if speed := getIfaceSpeed(ifaceName); speed > 0 {
+			rawStats[ifaceSpeedKey] = uint64(speed)
+		}
+		rawStats[StatTime] = uint64(now)
 		netRawStats[ifaceName] = rawStats
 	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
 
 	return netRawStats, nil
 }
@@ -105,6 +125,8 @@ func getNetRawStats() (netRawStats NetRawStats, err error) {
 // It has the follogin format:
 //  eth0:  178331 2395 0 0 0 0 0 0 257286 1876 0 0 0 0 0 0
 //    lo:  166927  259 0 0 0 0 0 0 166927  259 0 0 0 0 0 0
+// The columns are the keys of the stats in the order they are in the file
+// (see KernelLayout), the fields beyond them are ignored.
 // It returns:
 //   - ifaceName, that is the name of the interface (lo, eth0,...)
 //   - rawStats with the following format:
@@ -115,58 +137,28 @@ func getNetRawStats() (netRawStats NetRawStats, err error) {
 //                    txbytes:0 txcolls:0 txcompr:0 rxfifo:0 txpkts:0 txerrs:0
 //                    txcarr:0 rxbytes:0 rxcompr:0 txdrop:0]
 //          ]
-func parseIfaceRawStats(stats string) (ifaceName string, rawStats IfaceRawStats,
+func parseIfaceRawStats(stats string, columns []string) (ifaceName string, rawStats IfaceRawStats,
 	err error) {
 
-	rawStats = IfaceRawStats{}
+	// Sized for all the columns, the speed and the time so the map doesn't
+	// grow
+	rawStats = make(IfaceRawStats, len(columns)+2)
 
-	fields := strings.Fields(stats)
-	ifaceName = fields[0]
-	// Trim the trailing ':'
-	if last := len(ifaceName) - 1; last >= 0 && ifaceName[last] == ':' {
-		ifaceName = ifaceName[:last]
+	// The name and the first counter aren't separated by spaces when the
+	// counter is big (eth0:1234567890)
+	colon := strings.Index(stats, ":")
+	if colon < 0 {
+		return "", nil, errors.New("Couldn't parse network stats because the interface name is missing")
 	}
+	ifaceName = strings.TrimSpace(stats[:colon])
 
-	for i := 1; i < len(fields); i++ {
+	fields := strings.Fields(stats[colon+1:])
+	for i := 0; i < len(fields) && i < len(columns); i++ {
 		stat, err := strconv.ParseUint(fields[i], 10, 64)
 		if err != nil {
 			return "", nil, err
 		}
-
-		switch i {
-		case 1:
-			rawStats[`rxbytes`] = stat
-		case 2:
-			rawStats[`rxpkts`] = stat
-		case 3:
-			rawStats[`rxerrs`] = stat
-		case 4:
-			rawStats[`rxdrop`] = stat
-		case 5:
-			rawStats[`rxfifo`] = stat
-		case 6:
-			rawStats[`rxframe`] = stat
-		case 7:
-			rawStats[`rxcompr`] = stat
-		case 8:
-			rawStats[`rxmulti`] = stat
-		case 9:
-			rawStats[`txbytes`] = stat
-		case 10:
-			rawStats[`txpkts`] = stat
-		case 11:
-			rawStats[`txerrs`] = stat
-		case 12:
-			rawStats[`txdrop`] = stat
-		case 13:
-			rawStats[`txfifo`] = stat
-		case 14:
-			rawStats[`txcolls`] = stat
-		case 15:
-			rawStats[`txcarr`] = stat
-		case 16:
-			rawStats[`txcompr`] = stat
-		}
+		rawStats[columns[i]] = stat
 	}
 
 	return ifaceName, rawStats, nil
@@ -182,39 +174,56 @@ func getNetAvgStats(firstSample NetRawStats, secondSample NetRawStats) (netAvgSt
 		}
 
 		ifaceAvgStats := IfaceAvgStats{}
-		timeDelta := float64(secondRawStats[`time`] - firstRawStats[`time`])
+		timeDelta := time.Duration(sampleTimeNano(int64(secondRawStats[StatTime])) - sampleTimeNano(int64(firstRawStats[StatTime]))).Seconds()
 		for key, secondValue := range secondRawStats {
-			if key == `time` {
+			if key == StatTime || key == ifaceSpeedKey {
 				continue
 			}
 			avg := float64(secondValue-firstRawStats[key]) / timeDelta
 			ifaceAvgStats[key] = avg
 		}
+		// The speed when the second sample was taken, so the samples can be
+		// compared on other hosts (see Snapshot)
+		if speed := float64(secondRawStats[ifaceSpeedKey]); speed > 0 {
+			ifaceAvgStats[IfaceRxUtil] = ifaceAvgStats[IfaceRxBytes] * 8 * 100.00 / speed
+			ifaceAvgStats[IfaceTxUtil] = ifaceAvgStats[IfaceTxBytes] * 8 * 100.00 / speed
+		}
 		netAvgStats[ifaceName] = ifaceAvgStats
 	}
 
 	return netAvgStats, nil
 }
 
-// getNetAvgStatsInterval returns the network traffic average between 2 samples.
-// Time interval between the 2 samples is given in seconds.
-func getNetStatsInterval(interval int64) (netAvgStats NetAvgStats, err error) {
-	firstSample, err := getNetRawStats()
+// getIfaceSpeed returns the link speed of a network interface in bits per
+// second from the file /sys/class/net/[iface]/speed (in Mbits/s), or 0 if
+// it's unknown (virtual interfaces, links down,...).
+func getIfaceSpeed(ifaceName string) float64 {
+	content, err := readStatsFile(filepath.Join("/sys/class/net", ifaceName, "speed"))
 	if err != nil {
-		return nil, err
+		return 0
+	}
+	speed, err := strconv.ParseFloat(strings.TrimSpace(string(content)), 64)
+	if err != nil || speed <= 0 {
+		return 0
 	}
 
-	time.Sleep(time.Duration(interval) * time.Second)
+	return speed * 1000000
+}
 
-	secondSample, err := getNetRawStats()
-	if err != nil {
-		return nil, err
-	}
+// getNetAvgStatsInterval returns the network traffic average between 2 samples.
+// Time interval between the 2 samples is given in seconds.
+func getNetStatsInterval(interval int64) (netAvgStats NetAvgStats, err error) {
+	return getNetStatsOver(time.Duration(interval) * time.Second)
+}
 
-	netAvgStats, err = getNetAvgStats(firstSample, secondSample)
-	if err != nil {
-		return nil, err
-	}
+// getNetStatsOver returns the network traffic average between 2 samples
+// taken d apart.
+func getNetStatsOver(d time.Duration) (netAvgStats NetAvgStats, err error) {
+	return sampleOver(d, getNetRawStats, getNetAvgStats)
+}
 
-	return netAvgStats, nil
+// getNetStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n network traffic averages between them.
+func getNetStatsSampleN(n int, interval time.Duration) (series []NetAvgStats, err error) {
+	return sampleN(n, interval, getNetRawStats, getNetAvgStats)
 }
@@ -0,0 +1,85 @@
+// +build linux
+
+package sysstats
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func FuzzParseIfaceRawStats(f *testing.F) {
+	for _, seed := range []string{
+		"  eth0:14239830741 37062651    0 1081    0     0          0         0 3528212651 11720893    0    0    0     0       0          0",
+		"    lo: 76331190    6432    0    0    0     0          0         0 76331190    6432    0    0    0     0       0          0",
+		"wlp2s0: 1962874377 1652210    0    0    0     0          0         0 161340543  862434    0    0    0     0       0          0",
+		"eth0: 1 2",
+		"eth0:",
+		":1 2 3",
+		"eth0 1 2 3",
+		"eth0: 1 x 3",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		_, rawStats, err := parseIfaceRawStats(line, defaultNetDevColumns)
+		if err != nil {
+			return
+		}
+		if len(rawStats) > len(defaultNetDevColumns) {
+			t.Errorf("parseIfaceRawStats(%q) has %d stats, more than the %d columns", line, len(rawStats), len(defaultNetDevColumns))
+		}
+	})
+}
+
+func TestNetRawStatsSpeed(t *testing.T) {
+	netDev := "Inter-|   Receive                                                |  Transmit\n" +
+		" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n" +
+		"  eth0: 1000 10 0 0 0 0 0 0 2000 20 0 0 0 0 0 0\n" +
+		"    lo: 1000 10 0 0 0 0 0 0 1000 10 0 0 0 0 0 0\n"
+	SetStatsFS(fstest.MapFS{
+		"proc/net/dev":             {Data: []byte(netDev)},
+		"sys/class/net/eth0/speed": {Data: []byte("1000\n")},
+		"sys/class/net/lo/speed":   {Data: []byte("-1\n")},
+	})
+	defer SetStatsFS(nil)
+
+	firstSample, err := getNetRawStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if speed := firstSample["eth0"][ifaceSpeedKey]; speed != 1000000000 {
+		t.Errorf("eth0 speed = %d, want 1000000000", speed)
+	}
+	if _, ok := firstSample["lo"][ifaceSpeedKey]; ok {
+		t.Error("lo has a speed")
+	}
+
+	// The speed of the sample is used, not the one of the host comparing them
+	SetStatsFS(fstest.MapFS{})
+	secondSample := NetRawStats{}
+	for ifaceName, rawStats := range firstSample {
+		secondRawStats := IfaceRawStats{}
+		for key, value := range rawStats {
+			secondRawStats[key] = value
+		}
+		secondRawStats[IfaceRxBytes] += 12500000
+		secondRawStats[StatTime] += uint64(time.Second)
+		secondSample[ifaceName] = secondRawStats
+	}
+	netAvgStats, err := getNetAvgStats(firstSample, secondSample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if util := netAvgStats["eth0"][IfaceRxUtil]; util != 10 {
+		t.Errorf("eth0 rxutil = %v, want 10", util)
+	}
+	if _, ok := netAvgStats["eth0"][ifaceSpeedKey]; ok {
+		t.Error("the speed was averaged")
+	}
+	if _, ok := netAvgStats["lo"][IfaceRxUtil]; ok {
+		t.Error("lo has a utilization without speed")
+	}
+}
@@ -0,0 +1,205 @@
+// +build windows
+
+package sysstats
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// IfaceRawStats represents *one* network interface raw statistics of a
+// Windows system.
+//
+// Map keys:
+//   rxbytes -  # of bytes.
+//   rxpkts  -  # of packets (unicast and non unicast).
+//   rxerrs  -  # of errors that happend while receiving packets.
+//   rxdrop  -  # of packets that were discarded.
+//   txbytes -  # of bytes transmitted.
+//   txpkts  -  # of packets transmitted (unicast and non unicast).
+//   txerrs  -  # of errors that happend while transmitting packets.
+//   txdrop  -  # of packets that were discarded.
+//   speed   -  Receive link speed in bits per second (0 if it's unknown).
+//   time    -  Time when the sample was taken (Unix time in nanoseconds).
+type IfaceRawStats map[string]uint64
+
+// IfaceAvgStats represents *one* network interface statistics of a Windows
+// system.
+//
+// Map keys:
+//   rxbytes -  # of bytes per second.
+//   rxpkts  -  # of packets per second.
+//   rxerrs  -  # of errors that happend while receiving packets per second.
+//   rxdrop  -  # of packets that were discarded per second.
+//   txbytes -  # of bytes transmitted per second.
+//   txpkts  -  # of packets transmitted per second.
+//   txerrs  -  # of errors that happend while transmitting packets per second.
+//   txdrop  -  # of packets that were discarded per second.
+//   rxutil  -  % of the link speed used receiving (only if the speed is known).
+//   txutil  -  % of the link speed used transmitting (only if the speed is known).
+type IfaceAvgStats map[string]float64
+
+// NetRawStats represents *all* the network interfaces raw statistics of a
+// Windows system.
+//
+// Map keys:
+//   Name - alias of the network interface (Ethernet, Wi-Fi,...)
+type NetRawStats map[string]IfaceRawStats
+
+// NetAvgStats represents *all* the network interfaces statistics of a
+// Windows system.
+//
+// Map keys:
+//   Name - alias of the network interface (Ethernet, Wi-Fi,...)
+type NetAvgStats map[string]IfaceAvgStats
+
+// mibIfRow2 is MIB_IF_ROW2 (the counters of an interface).
+type mibIfRow2 struct {
+	InterfaceLuid               uint64
+	InterfaceIndex              uint32
+	InterfaceGuid               [16]byte
+	Alias                       [257]uint16
+	Description                 [257]uint16
+	PhysicalAddressLength       uint32
+	PhysicalAddress             [32]byte
+	PermanentPhysicalAddress    [32]byte
+	Mtu                         uint32
+	Type                        uint32
+	TunnelType                  uint32
+	MediaType                   uint32
+	PhysicalMediumType          uint32
+	AccessType                  uint32
+	DirectionType               uint32
+	InterfaceAndOperStatusFlags uint8
+	OperStatus                  uint32
+	AdminStatus                 uint32
+	MediaConnectState           uint32
+	NetworkGuid                 [16]byte
+	ConnectionType              uint32
+	TransmitLinkSpeed           uint64
+	ReceiveLinkSpeed            uint64
+	InOctets                    uint64
+	InUcastPkts                 uint64
+	InNUcastPkts                uint64
+	InDiscards                  uint64
+	InErrors                    uint64
+	InUnknownProtos             uint64
+	InUcastOctets               uint64
+	InMulticastOctets           uint64
+	InBroadcastOctets           uint64
+	OutOctets                   uint64
+	OutUcastPkts                uint64
+	OutNUcastPkts               uint64
+	OutDiscards                 uint64
+	OutErrors                   uint64
+	OutUcastOctets              uint64
+	OutMulticastOctets          uint64
+	OutBroadcastOctets          uint64
+	OutQLen                     uint64
+}
+
+// ifTypeSoftwareLoopback is the IF_TYPE_SOFTWARE_LOOPBACK interface type.
+const ifTypeSoftwareLoopback = 24
+
+// getNetRawStats gets the network interfaces raw statistics of a Windows
+// system with GetIfTable2.
+func getNetRawStats() (netRawStats NetRawStats, err error) {
+	return getNetRawStatsMatching(nil)
+}
+
+// getNetRawStatsMatching gets the raw statistics of the network interfaces
+// whose aliases match the regexp (all of them if it's nil). Windows lists
+// several filter and miniport interfaces per adapter with the same counters,
+// so only the hardware interfaces and the loopback are reported.
+func getNetRawStatsMatching(ifaces *regexp.Regexp) (netRawStats NetRawStats, err error) {
+	var table unsafe.Pointer
+	ret, _, _ := procGetIfTable2.Call(uintptr(unsafe.Pointer(&table)))
+	if ret != 0 {
+		return nil, errors.New("GetIfTable2 failed with error " + strconv.FormatUint(uint64(ret), 10))
+	}
+	defer procFreeMibTable.Call(uintptr(table))
+
+	// MIB_IF_TABLE2 is the # of rows followed by the rows (8 bytes aligned)
+	count := *(*uint32)(table)
+	rows := unsafe.Slice((*mibIfRow2)(unsafe.Add(table, 8)), int(count))
+
+	netRawStats = NetRawStats{}
+
+	now := time.Now().UnixNano()
+	for i := range rows {
+		row := &rows[i]
+		// HardwareInterface is the first bit of the flags
+		if row.InterfaceAndOperStatusFlags&1 == 0 && row.Type != ifTypeSoftwareLoopback {
+			continue
+		}
+		ifaceName := syscall.UTF16ToString(row.Alias[:])
+		if ifaces != nil && !ifaces.MatchString(ifaceName) {
+			continue
+		}
+
+		netRawStats[ifaceName] = IfaceRawStats{
+			IfaceRxBytes:  row.InOctets,
+			IfaceRxPkts:   row.InUcastPkts + row.InNUcastPkts,
+			IfaceRxErrs:   row.InErrors,
+			IfaceRxDrop:   row.InDiscards,
+			IfaceTxBytes:  row.OutOctets,
+			IfaceTxPkts:   row.OutUcastPkts + row.OutNUcastPkts,
+			IfaceTxErrs:   row.OutErrors,
+			IfaceTxDrop:   row.OutDiscards,
+			ifaceSpeedKey: row.ReceiveLinkSpeed,
+			StatTime:      uint64(now),
+		}
+	}
+
+	return netRawStats, nil
+}
+
+// getNetAvgStats calculates the network traffic average between 2 NetRawStats samples
+func getNetAvgStats(firstSample NetRawStats, secondSample NetRawStats) (netAvgStats NetAvgStats, err error) {
+	netAvgStats = NetAvgStats{}
+	for ifaceName, secondRawStats := range secondSample {
+		firstRawStats, ok := firstSample[ifaceName]
+		if !ok {
+			return nil, errors.New("The key " + ifaceName + " doesn't exist in the first sample of NetRawStats")
+		}
+
+		ifaceAvgStats := IfaceAvgStats{}
+		timeDelta := time.Duration(secondRawStats[StatTime] - firstRawStats[StatTime]).Seconds()
+		for key, secondValue := range secondRawStats {
+			if key == StatTime || key == ifaceSpeedKey {
+				continue
+			}
+			ifaceAvgStats[key] = float64(secondValue-firstRawStats[key]) / timeDelta
+		}
+		// The loopback reports a fake speed
+		if speed := float64(secondRawStats[ifaceSpeedKey]); speed > 0 && speed != float64(^uint64(0)) {
+			ifaceAvgStats[IfaceRxUtil] = ifaceAvgStats[IfaceRxBytes] * 8 * 100.00 / speed
+			ifaceAvgStats[IfaceTxUtil] = ifaceAvgStats[IfaceTxBytes] * 8 * 100.00 / speed
+		}
+		netAvgStats[ifaceName] = ifaceAvgStats
+	}
+
+	return netAvgStats, nil
+}
+
+// getNetStatsInterval returns the network traffic average between 2 samples.
+// Time interval between the 2 samples is given in seconds.
+func getNetStatsInterval(interval int64) (netAvgStats NetAvgStats, err error) {
+	return getNetStatsOver(time.Duration(interval) * time.Second)
+}
+
+// getNetStatsOver returns the network traffic average between 2 samples
+// taken d apart.
+func getNetStatsOver(d time.Duration) (netAvgStats NetAvgStats, err error) {
+	return sampleOver(d, getNetRawStats, getNetAvgStats)
+}
+
+// getNetStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n network traffic averages between them.
+func getNetStatsSampleN(n int, interval time.Duration) (series []NetAvgStats, err error) {
+	return sampleN(n, interval, getNetRawStats, getNetAvgStats)
+}
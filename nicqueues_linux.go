@@ -0,0 +1,342 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Ethtool constants (linux/ethtool.h and linux/ethtool_netlink.h)
+const (
+	ethtoolMsgRingsGet    = 15 // ETHTOOL_MSG_RINGS_GET
+	ethtoolARingsHeader   = 1  // ETHTOOL_A_RINGS_HEADER
+	ethtoolAHeaderDevName = 2  // ETHTOOL_A_HEADER_DEV_NAME
+	siocEthtool           = 0x8946
+	ethtoolGSet           = 0x01
+	ethtoolGStrings       = 0x1b
+	ethtoolGStats         = 0x1d
+	ethtoolGSsetInfo      = 0x37
+	ethSsStats            = 1
+	ethGStringLen         = 32
+	ethtoolStatsAttempts  = 3
+)
+
+// NicRings represents the sizes of the RX/TX rings of a NIC.
+type NicRings struct {
+	RxMax      uint32 `json:"rxmax"`      // Max # of RX ring entries
+	RxMiniMax  uint32 `json:"rxminimax"`  // Max # of RX mini ring entries
+	RxJumboMax uint32 `json:"rxjumbomax"` // Max # of RX jumbo ring entries
+	TxMax      uint32 `json:"txmax"`      // Max # of TX ring entries
+	Rx         uint32 `json:"rx"`         // # of RX ring entries
+	RxMini     uint32 `json:"rxmini"`     // # of RX mini ring entries
+	RxJumbo    uint32 `json:"rxjumbo"`    // # of RX jumbo ring entries
+	Tx         uint32 `json:"tx"`         // # of TX ring entries
+}
+
+// NicQueue represents the configuration and raw drop counter of one queue
+// of a NIC.
+type NicQueue struct {
+	Cpus    string `json:"cpus"`    // CPU mask of rps_cpus (RX) or xps_cpus (TX)
+	FlowCnt uint64 `json:"flowcnt"` // # of RFS flow table entries (RX only)
+	Drops   uint64 `json:"drops"`   // # of packets dropped (if the driver reports it)
+}
+
+// NicRawStats represents the rings and queues raw stats of a NIC.
+//
+// Queues map keys:
+//   Name - Name of the queue (as it is on /sys/class/net/<iface>/queues:
+//          rx-0, tx-0,...).
+type NicRawStats struct {
+	Name       string              `json:"name"`                       // Name of the interface
+	Rings      *NicRings           `json:"rings"`                      // Ring sizes (nil if the driver doesn't support it)
+	Queues     map[string]NicQueue `json:"queues"`                     // Queues
+	SampleTime int64               `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// NicAvgStats represents the drops per second of every queue of a NIC
+// between 2 samples.
+type NicAvgStats struct {
+	Name   string             `json:"name"`                    // Name of the interface
+	Rings  *NicRings          `json:"rings"`                   // Ring sizes of the second sample
+	Queues map[string]float64 `json:"queues" unit:"packets/s"` // # of packets dropped per second by queue
+}
+
+// nicQueueDropRe matches the names of the per-queue drop counters of the
+// driver stats (rx_queue_0_drops, tx0_dropped, rx-1.discards,...).
+var nicQueueDropRe = regexp.MustCompile(`^(rx|tx)[_-]?(?:queue[_-]?)?(\d+)[_.-].*(?:drop|discard)`)
+
+// getNicRawStats gets the rings (via ethtool netlink), the per-queue drop
+// counters (from the driver stats) and the RPS/XPS configuration (from
+// /sys/class/net/<iface>/queues) of a NIC.
+func getNicRawStats(iface string) (nicRawStats NicRawStats, err error) {
+	queuesDir := filepath.Join("/sys/class/net", iface, "queues")
+	entries, err := ioutil.ReadDir(queuesDir)
+	if err != nil {
+		return NicRawStats{}, err
+	}
+
+	nicRawStats = NicRawStats{}
+	nicRawStats.Name = iface
+	nicRawStats.Queues = map[string]NicQueue{}
+	for _, entry := range entries {
+		queue := NicQueue{}
+		switch {
+		case strings.HasPrefix(entry.Name(), "rx-"):
+			queue.Cpus = readSysfsString(filepath.Join(queuesDir, entry.Name(), "rps_cpus"))
+			queue.FlowCnt, _ = strconv.ParseUint(readSysfsString(filepath.Join(queuesDir, entry.Name(), "rps_flow_cnt")), 10, 64)
+		case strings.HasPrefix(entry.Name(), "tx-"):
+			queue.Cpus = readSysfsString(filepath.Join(queuesDir, entry.Name(), "xps_cpus"))
+		default:
+			continue
+		}
+		nicRawStats.Queues[entry.Name()] = queue
+	}
+
+	nicRawStats.Rings, err = getNicRings(iface)
+	if err != nil {
+		logDebug("couldn't get the rings of the interface", "iface", iface, "error", err)
+	}
+
+	driverStats, err := getNicDriverStats(iface)
+	if err != nil {
+		logDebug("couldn't get the driver stats of the interface", "iface", iface, "error", err)
+	}
+	for name, value := range driverStats {
+		match := nicQueueDropRe.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		queueName := match[1] + "-" + match[2]
+		queue, ok := nicRawStats.Queues[queueName]
+		if !ok {
+			continue
+		}
+		queue.Drops += value
+		nicRawStats.Queues[queueName] = queue
+	}
+	nicRawStats.SampleTime = time.Now().UnixNano()
+
+	return nicRawStats, nil
+}
+
+// getNicAvgStats calculates the drops per second of every queue of a NIC
+// between 2 samples.
+func getNicAvgStats(firstSample NicRawStats, secondSample NicRawStats) (nicAvgStats NicAvgStats, err error) {
+	if firstSample.Name != secondSample.Name {
+		return NicAvgStats{}, errors.New("The samples are from different interfaces")
+	}
+
+	nicAvgStats = NicAvgStats{}
+	nicAvgStats.Name = secondSample.Name
+	nicAvgStats.Rings = secondSample.Rings
+	nicAvgStats.Queues = map[string]float64{}
+
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	for queueName, secondQueue := range secondSample.Queues {
+		firstQueue, ok := firstSample.Queues[queueName]
+		if !ok || timeDelta <= 0 || secondQueue.Drops < firstQueue.Drops {
+			nicAvgStats.Queues[queueName] = 0
+			continue
+		}
+		nicAvgStats.Queues[queueName] = float64(secondQueue.Drops-firstQueue.Drops) / timeDelta
+	}
+
+	return nicAvgStats, nil
+}
+
+// getNicStatsOver returns the drops per second of every queue of a NIC
+// between 2 samples taken d apart.
+func getNicStatsOver(iface string, d time.Duration) (nicAvgStats NicAvgStats, err error) {
+	return sampleOver(d, func() (NicRawStats, error) {
+		return getNicRawStats(iface)
+	}, getNicAvgStats)
+}
+
+// ethtoolFamily caches the ID of the ethtool generic netlink family. The
+// family is built into the kernel, so its ID doesn't change once resolved.
+var ethtoolFamily = struct {
+	sync.Mutex
+	id uint16
+}{}
+
+// getEthtoolFamily returns the ID of the ethtool family, resolving it with
+// conn the first time.
+func getEthtoolFamily(conn *genlConn) (family uint16, err error) {
+	ethtoolFamily.Lock()
+	defer ethtoolFamily.Unlock()
+
+	if ethtoolFamily.id != 0 {
+		return ethtoolFamily.id, nil
+	}
+	family, err = conn.familyId("ethtool")
+	if err != nil {
+		return 0, err
+	}
+	ethtoolFamily.id = family
+
+	return family, nil
+}
+
+// getNicRings gets the ring sizes of a NIC with the ethtool netlink
+// interface (since 5.6).
+func getNicRings(iface string) (rings *NicRings, err error) {
+	conn, err := newGenlConn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	family, err := getEthtoolFamily(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	header := nlAttr(ethtoolAHeaderDevName, nlString(iface))
+	replies, err := conn.execute(family, ethtoolMsgRingsGet, 1, nlAttr(ethtoolARingsHeader|syscall.NLA_F_NESTED, header))
+	if err != nil {
+		return nil, err
+	}
+	if len(replies) == 0 {
+		return nil, errors.New("No reply to the ethtool rings request")
+	}
+
+	rings = &NicRings{}
+	values := map[uint16]*uint32{
+		2: &rings.RxMax,
+		3: &rings.RxMiniMax,
+		4: &rings.RxJumboMax,
+		5: &rings.TxMax,
+		6: &rings.Rx,
+		7: &rings.RxMini,
+		8: &rings.RxJumbo,
+		9: &rings.Tx,
+	}
+	for attrType, value := range parseNlAttrs(replies[0]) {
+		if field, ok := values[attrType]; ok && len(value) >= 4 {
+			*field = binary.NativeEndian.Uint32(value)
+		}
+	}
+
+	return rings, nil
+}
+
+// ethtoolIfreq is the struct ifreq of the SIOCETHTOOL ioctl.
+type ethtoolIfreq struct {
+	name [syscall.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [16]byte
+}
+
+// ethtoolIoctl runs an ethtool command (data starts with the command) on a
+// NIC.
+func ethtoolIoctl(fd int, iface string, data []byte) error {
+	req := ethtoolIfreq{data: unsafe.Pointer(&data[0])}
+	copy(req.name[:syscall.IFNAMSIZ-1], iface)
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), siocEthtool, uintptr(unsafe.Pointer(&req)))
+	if errno != 0 {
+		return os.NewSyscallError("ioctl", errno)
+	}
+
+	return nil
+}
+
+// getNicDriverStats gets the NIC-specific stats of the driver (the ones of
+// ethtool -S). The ethtool netlink interface doesn't expose them, so they
+// are read with the ethtool ioctl.
+func getNicDriverStats(iface string) (driverStats map[string]uint64, err error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(fd)
+
+	// The number of stats can change between the ioctls (the driver
+	// reconfigured the queues,...), so they are read again if the kernel
+	// returns a different count than the one the buffers were sized for.
+	for attempt := 0; attempt < ethtoolStatsAttempts; attempt++ {
+		driverStats, ok, err := readNicDriverStats(fd, iface)
+		if err != nil || ok {
+			return driverStats, err
+		}
+	}
+
+	return nil, errors.New("The number of driver stats of " + iface + " kept changing")
+}
+
+// readNicDriverStats reads the driver stats of a NIC with the
+// ETHTOOL_GSSET_INFO, ETHTOOL_GSTRINGS and ETHTOOL_GSTATS ioctls. ok is
+// false if the number of stats changed between them.
+func readNicDriverStats(fd int, iface string) (driverStats map[string]uint64, ok bool, err error) {
+	// struct ethtool_sset_info
+	ssetInfo := make([]byte, 20)
+	binary.NativeEndian.PutUint32(ssetInfo[0:4], ethtoolGSsetInfo)
+	binary.NativeEndian.PutUint64(ssetInfo[8:16], 1<<ethSsStats)
+	err = ethtoolIoctl(fd, iface, ssetInfo)
+	if err != nil {
+		return nil, false, err
+	}
+	if binary.NativeEndian.Uint64(ssetInfo[8:16]) == 0 {
+		return map[string]uint64{}, true, nil
+	}
+	n := int(binary.NativeEndian.Uint32(ssetInfo[16:20]))
+
+	// struct ethtool_gstrings
+	strs := make([]byte, 12+n*ethGStringLen)
+	binary.NativeEndian.PutUint32(strs[0:4], ethtoolGStrings)
+	binary.NativeEndian.PutUint32(strs[4:8], ethSsStats)
+	binary.NativeEndian.PutUint32(strs[8:12], uint32(n))
+	err = ethtoolIoctl(fd, iface, strs)
+	if err != nil {
+		return nil, false, err
+	}
+	if int(binary.NativeEndian.Uint32(strs[8:12])) != n {
+		return nil, false, nil
+	}
+
+	// struct ethtool_stats
+	stats := make([]byte, 8+n*8)
+	binary.NativeEndian.PutUint32(stats[0:4], ethtoolGStats)
+	binary.NativeEndian.PutUint32(stats[4:8], uint32(n))
+	err = ethtoolIoctl(fd, iface, stats)
+	if err != nil {
+		return nil, false, err
+	}
+	if int(binary.NativeEndian.Uint32(stats[4:8])) != n {
+		return nil, false, nil
+	}
+
+	driverStats = map[string]uint64{}
+	for i := 0; i < n; i++ {
+		name := strs[12+i*ethGStringLen : 12+(i+1)*ethGStringLen]
+		if end := bytes.IndexByte(name, 0); end >= 0 {
+			name = name[:end]
+		}
+		driverStats[string(name)] = binary.NativeEndian.Uint64(stats[8+i*8:])
+	}
+
+	return driverStats, true, nil
+}
+
+// readSysfsString returns the trimmed content of a sysfs file, or an empty
+// string if it can't be read.
+func readSysfsString(path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
@@ -0,0 +1,32 @@
+// +build freebsd openbsd netbsd
+// +build !cgo
+
+package sysstats
+
+import (
+	"regexp"
+	"time"
+)
+
+// The BSD collectors read the stats through the C library, so the package
+// built without cgo (e.g. cross-compiled) returns errNoCgo for them.
+
+func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
+	return nil, errNoCgo
+}
+
+func getMemStats() (memStats MemStats, err error) {
+	return nil, errNoCgo
+}
+
+func getLoadAvg() (loadAvg LoadAvg, err error) {
+	return LoadAvg{}, errNoCgo
+}
+
+func getNetRawStatsMatching(ifaces *regexp.Regexp) (netRawStats NetRawStats, err error) {
+	return nil, errNoCgo
+}
+
+func getBootTime() (boottime time.Time, err error) {
+	return time.Time{}, errNoCgo
+}
@@ -0,0 +1,15 @@
+// +build !cgo
+
+package sysstats
+
+// The darwin CPU and memory collectors read the stats through the mach
+// host calls, so the package built without cgo (e.g. cross-compiled)
+// returns errNoCgo for them.
+
+func getCpuRawStats() (cpusRawStats CpusRawStats, err error) {
+	return nil, errNoCgo
+}
+
+func getMemStats() (memStats MemStats, err error) {
+	return nil, errNoCgo
+}
@@ -0,0 +1,11 @@
+// +build !cgo
+
+package sysstats
+
+import "regexp"
+
+// getDiskRawStatsMatching reads the devstat(9) stats through libdevstat, so
+// it returns errNoCgo when the package is built without cgo.
+func getDiskRawStatsMatching(disks *regexp.Regexp) (diskRawStatsArr []DiskRawStats, err error) {
+	return nil, errNoCgo
+}
@@ -0,0 +1,253 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProcessNumaStats represents where the memory of a process is resident
+// compared to the NUMA node it runs on.
+type ProcessNumaStats struct {
+	Pid     int            `json:"pid"`                    // Process ID
+	Name    string         `json:"name"`                   // Command name (comm)
+	Node    int            `json:"node"`                   // Node the process is bound to, or the one it last ran on if it isn't bound
+	Bound   bool           `json:"bound"`                  // The CPU affinity of the process is restricted to the node
+	Memory  map[int]uint64 `json:"memory" unit:"kB"`       // Resident memory by node in kilobytes
+	Local   uint64         `json:"local" unit:"kB"`        // Resident memory on the node of the process in kilobytes
+	Remote  uint64         `json:"remote" unit:"kB"`       // Resident memory on other nodes in kilobytes
+	CpuTime uint64         `json:"cputime" unit:"jiffies"` // CPU time (user + system) since the process started (USER_HZ)
+}
+
+// NumaNodeStats represents the processes placed on a NUMA node.
+type NumaNodeStats struct {
+	Node      int    `json:"node"`                   // NUMA node
+	Cpus      []int  `json:"cpus"`                   // CPUs of the node
+	Processes uint64 `json:"processes"`              // # of processes on the node (bound or last run there)
+	Bound     uint64 `json:"bound"`                  // # of processes bound to the node
+	Local     uint64 `json:"local" unit:"kB"`        // Resident memory of the processes on the node itself in kilobytes
+	Remote    uint64 `json:"remote" unit:"kB"`       // Resident memory of the processes on other nodes in kilobytes
+	CpuTime   uint64 `json:"cputime" unit:"jiffies"` // CPU time of the processes since they started (USER_HZ)
+}
+
+// NumaPlacement represents the placement of the processes of a linux system
+// on its NUMA nodes. The processes with memory on remote nodes cause
+// cross-node memory traffic.
+type NumaPlacement struct {
+	Nodes     []NumaNodeStats    `json:"nodes"`     // Nodes with CPUs
+	Processes []ProcessNumaStats `json:"processes"` // Processes with resident memory, most remote memory first
+}
+
+// getNumaPlacement gets the placement of the processes on the NUMA nodes
+// from /sys/devices/system/node and the files /proc/[pid]/stat,
+// /proc/[pid]/status and /proc/[pid]/numa_maps. The processes that can't be
+// read (they exit while they are read, or numa_maps needs the same
+// permissions as ptrace) and the kernel threads are left out.
+func getNumaPlacement() (numaPlacement NumaPlacement, err error) {
+	nodes, err := getNumaNodes()
+	if err != nil {
+		return NumaPlacement{}, err
+	}
+	nodeOf := map[int]int{}
+	nodeStats := map[int]*NumaNodeStats{}
+	numaPlacement = NumaPlacement{Nodes: make([]NumaNodeStats, 0, len(nodes)), Processes: []ProcessNumaStats{}}
+	for _, node := range nodes {
+		numaPlacement.Nodes = append(numaPlacement.Nodes, node)
+		nodeStats[node.Node] = &numaPlacement.Nodes[len(numaPlacement.Nodes)-1]
+		for _, cpu := range node.Cpus {
+			nodeOf[cpu] = node.Node
+		}
+	}
+
+	pids, err := getPids()
+	if err != nil {
+		return NumaPlacement{}, err
+	}
+	for _, pid := range pids {
+		processNumaStats, err := getProcessNumaStats(pid, nodeOf)
+		if err != nil {
+			logDebug("skipped process", "pid", pid, "error", err)
+			continue
+		}
+		if len(processNumaStats.Memory) == 0 {
+			// Kernel thread
+			continue
+		}
+		numaPlacement.Processes = append(numaPlacement.Processes, processNumaStats)
+
+		node, ok := nodeStats[processNumaStats.Node]
+		if !ok {
+			continue
+		}
+		node.Processes++
+		if processNumaStats.Bound {
+			node.Bound++
+		}
+		node.Local += processNumaStats.Local
+		node.Remote += processNumaStats.Remote
+		node.CpuTime += processNumaStats.CpuTime
+	}
+
+	sort.SliceStable(numaPlacement.Processes, func(i, j int) bool {
+		return numaPlacement.Processes[i].Remote > numaPlacement.Processes[j].Remote
+	})
+
+	return numaPlacement, nil
+}
+
+// getNumaNodes gets the NUMA nodes with CPUs from the directories
+// /sys/devices/system/node/node[N].
+func getNumaNodes() (nodes []NumaNodeStats, err error) {
+	files, err := ioutil.ReadDir("/sys/devices/system/node")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "node") {
+			continue
+		}
+		node, err := strconv.Atoi(strings.TrimPrefix(file.Name(), "node"))
+		if err != nil {
+			continue
+		}
+		cpus, err := readCpuList("/sys/devices/system/node/" + file.Name() + "/cpulist")
+		if err != nil {
+			return nil, err
+		}
+		if len(cpus) == 0 {
+			// Memory-only node
+			continue
+		}
+		nodes = append(nodes, NumaNodeStats{Node: node, Cpus: cpus})
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("Couldn't find any NUMA node with CPUs")
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Node < nodes[j].Node })
+
+	return nodes, nil
+}
+
+// getProcessNumaStats gets the resident memory by node of a process and the
+// node it's placed on, given the node of every CPU.
+func getProcessNumaStats(pid int, nodeOf map[int]int) (processNumaStats ProcessNumaStats, err error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+
+	stat, err := ioutil.ReadFile(dir + "/stat")
+	if err != nil {
+		return ProcessNumaStats{}, err
+	}
+	processRawStats, err := parseProcessRawStats(string(stat))
+	if err != nil {
+		return ProcessNumaStats{}, err
+	}
+	processNumaStats = ProcessNumaStats{
+		Pid:     pid,
+		Name:    processRawStats.Name,
+		Node:    -1,
+		CpuTime: processRawStats.Utime + processRawStats.Stime,
+	}
+
+	// Node of the CPUs the process can run on or, if they are in several
+	// nodes, of the CPU it last ran on (39th field of stat)
+	allowed, err := getProcessAllowedCpus(dir + "/status")
+	if err != nil {
+		return ProcessNumaStats{}, err
+	}
+	allowedNodes := map[int]bool{}
+	for _, cpu := range allowed {
+		if node, ok := nodeOf[cpu]; ok {
+			allowedNodes[node] = true
+		}
+	}
+	if len(allowedNodes) == 1 {
+		for node := range allowedNodes {
+			processNumaStats.Node = node
+		}
+		processNumaStats.Bound = true
+	} else {
+		fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+		if len(fields) > 36 {
+			if cpu, err := strconv.Atoi(fields[36]); err == nil {
+				if node, ok := nodeOf[cpu]; ok {
+					processNumaStats.Node = node
+				}
+			}
+		}
+	}
+
+	numaMaps, err := ioutil.ReadFile(dir + "/numa_maps")
+	if err != nil {
+		return ProcessNumaStats{}, err
+	}
+	processNumaStats.Memory = parseNumaMaps(string(numaMaps))
+	for node, memory := range processNumaStats.Memory {
+		if node == processNumaStats.Node {
+			processNumaStats.Local += memory
+		} else {
+			processNumaStats.Remote += memory
+		}
+	}
+
+	return processNumaStats, nil
+}
+
+// getProcessAllowedCpus gets the CPUs a process can run on from the
+// Cpus_allowed_list line of the file /proc/[pid]/status.
+func getProcessAllowedCpus(path string) (cpus []int, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "Cpus_allowed_list:") {
+			return parseCpuList(strings.TrimSpace(strings.TrimPrefix(line, "Cpus_allowed_list:")))
+		}
+	}
+
+	return nil, errors.New("Couldn't find Cpus_allowed_list in " + path)
+}
+
+// parseNumaMaps sums the resident memory by node (in kilobytes) of the
+// mappings of a process as they are in the file /proc/[pid]/numa_maps, which
+// has the following format (pages by node):
+//   55d0c4e2a000 default file=/usr/bin/bash mapped=48 N0=40 N1=8 kernelpagesize_kB=4
+//   7f3a2c000000 default anon=512 dirty=512 N1=512 kernelpagesize_kB=4
+func parseNumaMaps(numaMaps string) (memory map[int]uint64) {
+	memory = map[int]uint64{}
+
+	for _, line := range strings.Split(numaMaps, "\n") {
+		pageSizeKB := uint64(4)
+		pages := map[int]uint64{}
+		for _, field := range strings.Fields(line) {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(keyValue[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			if keyValue[0] == "kernelpagesize_kB" {
+				pageSizeKB = value
+				continue
+			}
+			if len(keyValue[0]) > 1 && keyValue[0][0] == 'N' {
+				if node, err := strconv.Atoi(keyValue[0][1:]); err == nil {
+					pages[node] = value
+				}
+			}
+		}
+		for node, count := range pages {
+			memory[node] += count * pageSizeKB
+		}
+	}
+
+	return memory
+}
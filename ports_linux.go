@@ -0,0 +1,206 @@
+// +build linux
+
+package sysstats
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Socket states of /proc/net/{tcp,udp}[6] (include/net/tcp_states.h)
+const (
+	tcpListen = "0A" // TCP_LISTEN
+	udpClose  = "07" // TCP_CLOSE (unconnected UDP socket)
+)
+
+// ListeningPort represents a TCP or UDP socket listening for connections or
+// datagrams.
+type ListeningPort struct {
+	Protocol string `json:"protocol"` // Protocol (tcp, tcp6, udp, udp6)
+	Address  string `json:"address"`  // Local address
+	Port     uint16 `json:"port"`     // Local port
+	Uid      uint32 `json:"uid"`      // UID of the owner of the socket
+	Inode    uint64 `json:"inode"`    // Inode of the socket
+	Backlog  uint64 `json:"backlog"`  // # of connections waiting to be accepted (tcp) or bytes waiting to be read (udp)
+	Pid      int    `json:"pid"`      // PID of the owner process (0 if it can't be resolved)
+	Process  string `json:"process"`  // Command name of the owner process
+}
+
+// getListeningPorts returns the TCP and UDP listening sockets of a linux
+// system from the files /proc/net/{tcp,tcp6,udp,udp6}. The owner process is
+// only resolved for the processes whose file descriptors are readable.
+func getListeningPorts() (listeningPorts []ListeningPort, err error) {
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		ports, err := parseListeningPorts(protocol)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// IPv6 disabled
+				continue
+			}
+			return nil, err
+		}
+		listeningPorts = append(listeningPorts, ports...)
+	}
+
+	owners := getSocketOwners()
+	for i := range listeningPorts {
+		pid, ok := owners[listeningPorts[i].Inode]
+		if !ok {
+			continue
+		}
+		listeningPorts[i].Pid = pid
+		processRawStats, err := readProcessRawStats(pid, 0)
+		if err == nil {
+			listeningPorts[i].Process = processRawStats.Name
+		}
+	}
+
+	sort.Slice(listeningPorts, func(i, j int) bool {
+		if listeningPorts[i].Protocol != listeningPorts[j].Protocol {
+			return listeningPorts[i].Protocol < listeningPorts[j].Protocol
+		}
+		if listeningPorts[i].Port != listeningPorts[j].Port {
+			return listeningPorts[i].Port < listeningPorts[j].Port
+		}
+		return listeningPorts[i].Address < listeningPorts[j].Address
+	})
+
+	return listeningPorts, nil
+}
+
+// parseListeningPorts parses the listening sockets of a protocol as they are
+// in the file /proc/net/<protocol>:
+//   sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+//    0: 0100007F:0035 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 662 1 ...
+func parseListeningPorts(protocol string) (listeningPorts []ListeningPort, err error) {
+	state := tcpListen
+	if strings.HasPrefix(protocol, "udp") {
+		state = udpClose
+	}
+
+	var parseErr error
+	err = scanLines("/proc/net/"+protocol, func(line string) {
+		if parseErr != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 10 || fields[0] == "sl" || fields[3] != state {
+			return
+		}
+
+		address, port, err := parseSocketAddress(fields[1])
+		if err != nil {
+			parseErr = err
+			return
+		}
+		if state == udpClose {
+			// Connected UDP sockets have a remote address
+			_, remotePort, err := parseSocketAddress(fields[2])
+			if err != nil {
+				parseErr = err
+				return
+			}
+			if remotePort != 0 {
+				return
+			}
+		}
+
+		listeningPort := ListeningPort{Protocol: protocol, Address: address.String(), Port: port}
+		queues := strings.Split(fields[4], ":")
+		if len(queues) == 2 {
+			listeningPort.Backlog, _ = strconv.ParseUint(queues[1], 16, 64)
+		}
+		uid, err := strconv.ParseUint(fields[7], 10, 32)
+		if err != nil {
+			parseErr = err
+			return
+		}
+		listeningPort.Uid = uint32(uid)
+		listeningPort.Inode, err = strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			parseErr = err
+			return
+		}
+
+		listeningPorts = append(listeningPorts, listeningPort)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if parseErr != nil {
+		return nil, parseErr
+	}
+
+	return listeningPorts, nil
+}
+
+// parseSocketAddress parses an address of /proc/net/{tcp,udp}[6]. The IP is
+// written as 32 bit words in host byte order and the port in hexadecimal:
+//   0100007F:0035
+//   00000000000000000000000001000000:0035
+func parseSocketAddress(s string) (ip net.IP, port uint16, err error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, 0, errors.New("Couldn't parse the socket address " + s)
+	}
+
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, errors.New("Couldn't parse the socket address " + s)
+	}
+	ip = make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+
+	portValue, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return ip, uint16(portValue), nil
+}
+
+// getSocketOwners maps the inodes of the sockets to the PIDs of the processes
+// that have them open, from the links of /proc/[pid]/fd.
+func getSocketOwners() (owners map[uint64]int) {
+	owners = map[uint64]int{}
+
+	pids, err := getPids()
+	if err != nil {
+		return owners
+	}
+	for _, pid := range pids {
+		fdDir := filepath.Join("/proc", strconv.Itoa(pid), "fd")
+		dir, err := os.Open(fdDir)
+		if err != nil {
+			// Not allowed or the process exited
+			continue
+		}
+		fds, _ := dir.Readdirnames(-1)
+		dir.Close()
+
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[inode]; !ok {
+				owners[inode] = pid
+			}
+		}
+	}
+
+	return owners
+}
@@ -0,0 +1,179 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// pressureResources are the resources PSI reports the stalls of.
+var pressureResources = []string{`cpu`, `memory`, `io`}
+
+// cgroupRoots are the mount points of the cgroup v2 hierarchy, the unified
+// one and the one of the hybrid setups (v1 controllers with a v2 hierarchy
+// for systemd).
+var cgroupRoots = []string{"/sys/fs/cgroup", "/sys/fs/cgroup/unified"}
+
+// PressureLine represents the share of time some (or all) the tasks
+// stalled on a resource.
+type PressureLine struct {
+	Avg10  float64 `json:"avg10" unit:"%"`  // % of time stalled in the last 10 seconds
+	Avg60  float64 `json:"avg60" unit:"%"`  // % of time stalled in the last 60 seconds
+	Avg300 float64 `json:"avg300" unit:"%"` // % of time stalled in the last 300 seconds
+	Total  uint64  `json:"total" unit:"us"` // Microseconds stalled since boot (or since the cgroup was created)
+}
+
+// ResourcePressure represents the stalls on a resource.
+type ResourcePressure struct {
+	Some PressureLine `json:"some"` // Some task stalled
+	Full PressureLine `json:"full"` // All the non-idle tasks stalled at the same time
+}
+
+// PressureStats represents the pressure stall information (PSI) of a linux
+// system or of one of its cgroups (Linux 4.20 onward). The resources whose
+// pressure isn't available (e.g. disabled with cgroup.pressure) are left
+// out.
+type PressureStats map[string]ResourcePressure
+
+// getPressureStats gets the pressure of the whole system from the files
+// /proc/pressure/{cpu,memory,io}.
+func getPressureStats() (pressureStats PressureStats, err error) {
+	pressureStats, err = readPressureStats("/proc/pressure")
+	if err != nil {
+		return nil, err
+	}
+	if len(pressureStats) == 0 {
+		return nil, errors.New("Couldn't find the pressure stall information (PSI) of the system")
+	}
+
+	return pressureStats, nil
+}
+
+// getCgroupPressureStats gets the pressure of the given cgroups v2 by path
+// relative to /sys/fs/cgroup (e.g. system.slice/nginx.service) from their
+// files {cpu,memory,io}.pressure. The paths can be patterns (e.g.
+// system.slice/*.service), but a path without wildcards that isn't a cgroup
+// is an error.
+func getCgroupPressureStats(cgroups []string) (cgroupsPressureStats map[string]PressureStats, err error) {
+	cgroupRoot, err := getCgroupRoot()
+	if err != nil {
+		return nil, err
+	}
+	cgroupsPressureStats = map[string]PressureStats{}
+
+	for _, cgroup := range cgroups {
+		// The cgroup.controllers file is in every cgroup v2, and only there
+		pattern := path.Join(cgroupRoot, path.Clean("/"+cgroup), "cgroup.controllers")
+		files, err := fs.Glob(getStatsFS(), statsPath(pattern))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 && !strings.ContainsAny(cgroup, "*?[") {
+			return nil, errors.New("Couldn't find the cgroup " + cgroup)
+		}
+
+		for _, file := range files {
+			dir := path.Dir("/" + file)
+			pressureStats, err := readPressureStats(dir)
+			if err != nil {
+				return nil, err
+			}
+			name := strings.TrimPrefix(strings.TrimPrefix(dir, cgroupRoot), "/")
+			if name == "" {
+				// Root cgroup
+				name = "/"
+			}
+			cgroupsPressureStats[name] = pressureStats
+		}
+	}
+
+	return cgroupsPressureStats, nil
+}
+
+// getCgroupRoot returns the mount point of the cgroup v2 hierarchy.
+func getCgroupRoot() (cgroupRoot string, err error) {
+	for _, cgroupRoot := range cgroupRoots {
+		if _, err := fs.Stat(getStatsFS(), statsPath(path.Join(cgroupRoot, "cgroup.controllers"))); err == nil {
+			return cgroupRoot, nil
+		}
+	}
+
+	return "", errors.New("Couldn't find the cgroup v2 hierarchy")
+}
+
+// readPressureStats reads the pressure files of a directory, which are
+// /proc/pressure/{cpu,memory,io} for the whole system and
+// {cpu,memory,io}.pressure for a cgroup.
+func readPressureStats(dir string) (pressureStats PressureStats, err error) {
+	pressureStats = make(PressureStats, len(pressureResources))
+
+	for _, resource := range pressureResources {
+		file := path.Join(dir, resource)
+		if dir != "/proc/pressure" {
+			file += ".pressure"
+		}
+		content, err := readStatsFile(file)
+		if err != nil {
+			// The pressure of a cgroup can be disabled (cgroup.pressure)
+			if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.EOPNOTSUPP) {
+				continue
+			}
+			return nil, err
+		}
+		resourcePressure, err := parsePressure(string(content))
+		if err != nil {
+			return nil, err
+		}
+		pressureStats[resource] = resourcePressure
+	}
+
+	return pressureStats, nil
+}
+
+// parsePressure parses a PSI file, which has the following format (full is
+// missing for the cpu of the whole system before Linux 5.13):
+//   some avg10=0.00 avg60=0.12 avg300=0.08 total=3178403
+//   full avg10=0.00 avg60=0.05 avg300=0.03 total=1709217
+func parsePressure(content string) (resourcePressure ResourcePressure, err error) {
+	lines := map[string]*PressureLine{
+		`some`: &resourcePressure.Some,
+		`full`: &resourcePressure.Full,
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pressureLine, ok := lines[fields[0]]
+		if !ok {
+			continue
+		}
+		for _, field := range fields[1:] {
+			keyValue := strings.SplitN(field, "=", 2)
+			if len(keyValue) != 2 {
+				return ResourcePressure{}, errors.New("Couldn't parse the pressure line " + line)
+			}
+			switch keyValue[0] {
+			case `avg10`:
+				pressureLine.Avg10, err = strconv.ParseFloat(keyValue[1], 64)
+			case `avg60`:
+				pressureLine.Avg60, err = strconv.ParseFloat(keyValue[1], 64)
+			case `avg300`:
+				pressureLine.Avg300, err = strconv.ParseFloat(keyValue[1], 64)
+			case `total`:
+				pressureLine.Total, err = strconv.ParseUint(keyValue[1], 10, 64)
+			}
+			if err != nil {
+				return ResourcePressure{}, err
+			}
+		}
+	}
+
+	return resourcePressure, nil
+}
@@ -0,0 +1,217 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcessRawStats represents the raw statistics of a process of a linux
+// system.
+type ProcessRawStats struct {
+	Pid        int    `json:"pid"`                        // Process ID
+	Name       string `json:"name"`                       // Command name (comm)
+	State      string `json:"state"`                      // State (R, S, D, Z, T,...)
+	Ppid       int    `json:"ppid"`                       // Parent process ID
+	MinFlt     uint64 `json:"minflt"`                     // # of minor faults since the process started
+	MajFlt     uint64 `json:"majflt"`                     // # of major faults since the process started
+	Utime      uint64 `json:"utime" unit:"jiffies"`       // Time spent in user mode (USER_HZ)
+	Stime      uint64 `json:"stime" unit:"jiffies"`       // Time spent in kernel mode (USER_HZ)
+	NumThreads uint64 `json:"numthreads"`                 // # of threads
+	StartTime  uint64 `json:"starttime" unit:"jiffies"`   // Time the process started after boot (USER_HZ)
+	VSize      uint64 `json:"vsize" unit:"bytes"`         // Virtual memory size in bytes
+	Rss        uint64 `json:"rss" unit:"kB"`              // Resident set size in kilobytes
+	CpuTotal   uint64 `json:"cputotal" unit:"jiffies"`    // Total CPU time of the system when the sample was taken (USER_HZ)
+	SampleTime int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// ProcessAvgStats represents the CPU usage of a process between 2 samples.
+// The percentages are relative to the total CPU time of the system (all the
+// CPUs), the same as the system CPU stats.
+type ProcessAvgStats struct {
+	Pid    int     `json:"pid"`              // Process ID
+	Name   string  `json:"name"`             // Command name (comm)
+	User   float64 `json:"user" unit:"%"`    // % of CPU time spent in user mode
+	System float64 `json:"system" unit:"%"`  // % of CPU time spent in kernel mode
+	Total  float64 `json:"total" unit:"%"`   // % of CPU time (user + system)
+	MinFlt float64 `json:"minflt" unit:"/s"` // # of minor faults per second
+	MajFlt float64 `json:"majflt" unit:"/s"` // # of major faults per second
+}
+
+// pageSizeKB is the size of a memory page in kilobytes.
+var pageSizeKB = uint64(os.Getpagesize() / 1024)
+
+// getProcessRawStats gets the raw stats of a process from the file
+// /proc/[pid]/stat.
+func getProcessRawStats(pid int) (processRawStats ProcessRawStats, err error) {
+	cpusRawStats, err := getCpuRawStats()
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+
+	return readProcessRawStats(pid, cpusRawStats[`cpu`][`total`])
+}
+
+// getAllProcessesRawStats gets the raw stats of all the processes of the
+// system, sharing the same total CPU time (see ProcWalker). The processes
+// that exit while they are read are skipped.
+func getAllProcessesRawStats() (processesRawStats map[int]ProcessRawStats, err error) {
+	walker := &ProcWalker{}
+
+	return walker.Walk()
+}
+
+// readProcessRawStats reads the raw stats of a process from the file
+// /proc/[pid]/stat, using the given total CPU time of the system.
+func readProcessRawStats(pid int, cpuTotal uint64) (processRawStats ProcessRawStats, err error) {
+	content, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+
+	processRawStats, err = parseProcessRawStats(string(content))
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+	processRawStats.CpuTotal = cpuTotal
+	processRawStats.SampleTime = time.Now().UnixNano()
+
+	return processRawStats, nil
+}
+
+// parseProcessRawStats parses the process stats as they are in the file
+// /proc/[pid]/stat, which has the following format:
+//   1234 (command name) S 1 1234 1234 0 -1 4194560 2061 0 0 0 12 5 0 0 20 0 1 0 8754 ...
+// The command name is between parentheses and can contain spaces and
+// parentheses itself, so the fields are counted from the last ')'.
+func parseProcessRawStats(stats string) (processRawStats ProcessRawStats, err error) {
+	processRawStats = ProcessRawStats{}
+
+	open := strings.Index(stats, "(")
+	close := strings.LastIndex(stats, ")")
+	if open < 0 || close < open {
+		return ProcessRawStats{}, errors.New("Couldn't parse process stats because the command name is missing")
+	}
+
+	processRawStats.Pid, err = strconv.Atoi(strings.TrimSpace(stats[:open]))
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+	processRawStats.Name = stats[open+1 : close]
+
+	// fields[0] is the state (3rd field of the file)
+	fields := strings.Fields(stats[close+1:])
+	if len(fields) < 22 {
+		return ProcessRawStats{}, errors.New("Couldn't parse process stats because there aren't enough fields")
+	}
+	processRawStats.State = fields[0]
+	processRawStats.Ppid, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+
+	values := map[int]*uint64{
+		7:  &processRawStats.MinFlt,
+		9:  &processRawStats.MajFlt,
+		11: &processRawStats.Utime,
+		12: &processRawStats.Stime,
+		17: &processRawStats.NumThreads,
+		19: &processRawStats.StartTime,
+		20: &processRawStats.VSize,
+		21: &processRawStats.Rss,
+	}
+	for i, value := range values {
+		*value, err = strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			// rss can be negative for kernel threads in some kernels
+			if i == 21 {
+				*value = 0
+				continue
+			}
+			return ProcessRawStats{}, err
+		}
+	}
+	processRawStats.Rss *= pageSizeKB
+
+	return processRawStats, nil
+}
+
+// getProcessAvgStats calculates the CPU usage of a process between 2 samples.
+func getProcessAvgStats(firstSample ProcessRawStats, secondSample ProcessRawStats) (processAvgStats ProcessAvgStats, err error) {
+	if firstSample.Pid != secondSample.Pid || firstSample.StartTime != secondSample.StartTime {
+		return ProcessAvgStats{}, errors.New("The samples are from different processes")
+	}
+
+	processAvgStats = ProcessAvgStats{}
+	processAvgStats.Pid = secondSample.Pid
+	processAvgStats.Name = secondSample.Name
+
+	cpuDelta := float64(secondSample.CpuTotal - firstSample.CpuTotal)
+	if cpuDelta > 0 {
+		processAvgStats.User = float64(secondSample.Utime-firstSample.Utime) * 100.00 / cpuDelta
+		processAvgStats.System = float64(secondSample.Stime-firstSample.Stime) * 100.00 / cpuDelta
+		processAvgStats.Total = processAvgStats.User + processAvgStats.System
+	}
+
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	if timeDelta > 0 {
+		processAvgStats.MinFlt = float64(secondSample.MinFlt-firstSample.MinFlt) / timeDelta
+		processAvgStats.MajFlt = float64(secondSample.MajFlt-firstSample.MajFlt) / timeDelta
+	}
+
+	return processAvgStats, nil
+}
+
+// getProcessCpuPercent returns the CPU usage of a process between 2 samples
+// taken d apart.
+func getProcessCpuPercent(pid int, d time.Duration) (processAvgStats ProcessAvgStats, err error) {
+	return sampleOver(d, func() (ProcessRawStats, error) {
+		return getProcessRawStats(pid)
+	}, getProcessAvgStats)
+}
+
+// getProcessesCpuPercent returns the CPU usage of several processes between 2
+// samples taken d apart. The processes that don't exist in both samples are
+// skipped.
+func getProcessesCpuPercent(pids []int, d time.Duration) (processesAvgStats map[int]ProcessAvgStats, err error) {
+	raw := func() (map[int]ProcessRawStats, error) {
+		cpusRawStats, err := getCpuRawStats()
+		if err != nil {
+			return nil, err
+		}
+		samples := map[int]ProcessRawStats{}
+		for _, pid := range pids {
+			processRawStats, err := readProcessRawStats(pid, cpusRawStats[`cpu`][`total`])
+			if err != nil {
+				logDebug("skipped process", "pid", pid, "error", err)
+				continue
+			}
+			samples[pid] = processRawStats
+		}
+		return samples, nil
+	}
+
+	avg := func(firstSamples map[int]ProcessRawStats, secondSamples map[int]ProcessRawStats) (map[int]ProcessAvgStats, error) {
+		processesAvgStats := map[int]ProcessAvgStats{}
+		for pid, secondSample := range secondSamples {
+			firstSample, ok := firstSamples[pid]
+			if !ok {
+				continue
+			}
+			processAvgStats, err := getProcessAvgStats(firstSample, secondSample)
+			if err != nil {
+				// The PID was reused by a new process
+				continue
+			}
+			processesAvgStats[pid] = processAvgStats
+		}
+		return processesAvgStats, nil
+	}
+
+	return sampleOver(d, raw, avg)
+}
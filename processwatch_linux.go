@@ -0,0 +1,227 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ProcessWatcher tracks the processes matching a name, a pattern and/or a
+// command line across samples, so a service can be monitored without
+// knowing its PIDs. All the filters that are set must match.
+type ProcessWatcher struct {
+	// Name is the exact command name (comm) of the processes.
+	Name string
+
+	// Pattern is matched against the command name (comm) of the processes.
+	Pattern *regexp.Regexp
+
+	// Cmdline is matched against the command line of the processes (the
+	// arguments are separated by spaces).
+	Cmdline *regexp.Regexp
+
+	// Events, if set, receives the process.started, process.exited and
+	// process.gone events.
+	Events *EventBus
+
+	// WarmUp, if set, makes the first call of Sample wait for it after
+	// taking the baseline and return the stats over it, instead of
+	// returning ErrWarmingUp.
+	WarmUp time.Duration
+
+	mu       sync.Mutex
+	previous map[int]ProcessRawStats
+	restarts int
+}
+
+// ProcessGroupStats represents the aggregated stats of the processes matched
+// by a ProcessWatcher.
+type ProcessGroupStats struct {
+	Time     time.Time `json:"time"`            // Time when the sample was taken
+	Pids     []int     `json:"pids"`            // PIDs of the matching processes
+	User     float64   `json:"user" unit:"%"`   // % of CPU time spent in user mode since the previous sample
+	System   float64   `json:"system" unit:"%"` // % of CPU time spent in kernel mode since the previous sample
+	Total    float64   `json:"total" unit:"%"`  // % of CPU time since the previous sample
+	Rss      uint64    `json:"rss" unit:"kB"`   // Resident set size in kilobytes
+	Threads  uint64    `json:"threads"`         // # of threads
+	Started  []int     `json:"started"`         // PIDs started since the previous sample
+	Exited   []int     `json:"exited"`          // PIDs exited since the previous sample
+	Restarts int       `json:"restarts"`        // # of restarts (PID changes) since the watcher was created
+}
+
+// Sample finds the matching processes and returns their aggregated stats
+// since the previous call. The first call only takes the baseline: it
+// returns the PIDs, memory and threads of the processes along with
+// ErrWarmingUp, as the CPU percentages and the processes started need a
+// previous sample (see WarmUp).
+func (w *ProcessWatcher) Sample() (processGroupStats ProcessGroupStats, err error) {
+	processGroupStats, err = w.sample()
+	if err == ErrWarmingUp && w.WarmUp > 0 {
+		time.Sleep(w.WarmUp)
+		return w.sample()
+	}
+
+	return processGroupStats, err
+}
+
+// sample finds the matching processes and returns their aggregated stats
+// since the previous sample.
+func (w *ProcessWatcher) sample() (processGroupStats ProcessGroupStats, err error) {
+	if w.Name == "" && w.Pattern == nil && w.Cmdline == nil {
+		return ProcessGroupStats{}, errors.New("The process watcher needs a name, a pattern or a command line")
+	}
+
+	processesRawStats, err := getAllProcessesRawStats()
+	if err != nil {
+		return ProcessGroupStats{}, err
+	}
+
+	current := map[int]ProcessRawStats{}
+	for pid, processRawStats := range processesRawStats {
+		if w.match(processRawStats) {
+			current[pid] = processRawStats
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	processGroupStats = ProcessGroupStats{}
+	processGroupStats.Time = time.Now()
+	for pid, processRawStats := range current {
+		processGroupStats.Pids = append(processGroupStats.Pids, pid)
+		processGroupStats.Rss += processRawStats.Rss
+		processGroupStats.Threads += processRawStats.NumThreads
+
+		if w.previous == nil {
+			continue
+		}
+		previous, ok := w.previous[pid]
+		if ok {
+			processAvgStats, err := getProcessAvgStats(previous, processRawStats)
+			if err == nil {
+				processGroupStats.User += processAvgStats.User
+				processGroupStats.System += processAvgStats.System
+				processGroupStats.Total += processAvgStats.Total
+				continue
+			}
+			// The PID was reused by a new process
+			processGroupStats.Exited = append(processGroupStats.Exited, pid)
+		}
+		processGroupStats.Started = append(processGroupStats.Started, pid)
+	}
+	for pid := range w.previous {
+		if _, ok := current[pid]; !ok {
+			processGroupStats.Exited = append(processGroupStats.Exited, pid)
+		}
+	}
+	sort.Ints(processGroupStats.Pids)
+	sort.Ints(processGroupStats.Started)
+	sort.Ints(processGroupStats.Exited)
+
+	restarts := len(processGroupStats.Started)
+	if len(processGroupStats.Exited) < restarts {
+		restarts = len(processGroupStats.Exited)
+	}
+	w.restarts += restarts
+	processGroupStats.Restarts = w.restarts
+
+	w.publish(processGroupStats, w.previous, current)
+	first := w.previous == nil
+	w.previous = current
+	if first {
+		return processGroupStats, ErrWarmingUp
+	}
+
+	return processGroupStats, nil
+}
+
+// match reports whether a process matches all the filters of the watcher.
+func (w *ProcessWatcher) match(processRawStats ProcessRawStats) bool {
+	if w.Name != "" && processRawStats.Name != w.Name {
+		return false
+	}
+	if w.Pattern != nil && !w.Pattern.MatchString(processRawStats.Name) {
+		return false
+	}
+	if w.Cmdline != nil {
+		cmdline, err := getProcessCmdline(processRawStats.Pid)
+		if err != nil || !w.Cmdline.MatchString(cmdline) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// publish publishes the events of the processes started and exited between
+// 2 samples.
+func (w *ProcessWatcher) publish(processGroupStats ProcessGroupStats, previous map[int]ProcessRawStats, current map[int]ProcessRawStats) {
+	if w.Events == nil {
+		return
+	}
+
+	for _, pid := range processGroupStats.Exited {
+		w.Events.Publish(Event{Type: EventProcessExited, Time: processGroupStats.Time, Subject: previous[pid].Name, Pid: pid})
+	}
+	for _, pid := range processGroupStats.Started {
+		w.Events.Publish(Event{Type: EventProcessStarted, Time: processGroupStats.Time, Subject: current[pid].Name, Pid: pid})
+	}
+	if len(previous) > 0 && len(current) == 0 {
+		subject := w.Name
+		if subject == "" {
+			for _, processRawStats := range previous {
+				subject = processRawStats.Name
+				break
+			}
+		}
+		w.Events.Publish(Event{Type: EventProcessGone, Time: processGroupStats.Time, Subject: subject})
+	}
+}
+
+// getPids returns the PIDs of all the processes of the system.
+func getPids() (pids []int, err error) {
+	dir, err := os.Open("/proc")
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		pid, err := strconv.Atoi(name)
+		if err != nil {
+			// Not a process
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+
+	return pids, nil
+}
+
+// getProcessCmdline returns the command line of a process from the file
+// /proc/[pid]/cmdline, with the arguments separated by spaces. It's empty for
+// kernel threads and zombies.
+func getProcessCmdline(pid int) (cmdline string, err error) {
+	content, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err != nil {
+		return "", err
+	}
+
+	content = bytes.TrimRight(content, "\x00")
+	return string(bytes.Replace(content, []byte{0}, []byte{' '}, -1)), nil
+}
@@ -0,0 +1,59 @@
+// +build linux
+
+package sysstats
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ProcLimits represents the usage of the PID and thread limits of the kernel.
+// Every thread takes a PID, so both limits are compared with the # of kernel
+// scheduling entities (ProcStats.Total).
+type ProcLimits struct {
+	Total          uint64  `json:"total"`                   // # of kernel scheduling entities (processes, threads)
+	PidMax         uint64  `json:"pidmax"`                  // Max PID (kernel.pid_max)
+	ThreadsMax     uint64  `json:"threadsmax"`              // Max # of threads (kernel.threads-max)
+	PidUsedPer     float64 `json:"pidusedper" unit:"%"`     // % of the PIDs used
+	ThreadsUsedPer float64 `json:"threadsusedper" unit:"%"` // % of the threads used
+}
+
+// getProcLimits gets the usage of the PID and thread limits from the files
+// /proc/sys/kernel/pid_max and /proc/sys/kernel/threads-max, and the # of
+// scheduling entities from /proc/loadavg.
+func getProcLimits() (procLimits ProcLimits, err error) {
+	procRawStats, err := getProcRawStats()
+	if err != nil {
+		return ProcLimits{}, err
+	}
+
+	procLimits = ProcLimits{}
+	procLimits.Total = procRawStats.Total
+
+	content, err := readStatsFile("/proc/sys/kernel/pid_max")
+	if err != nil {
+		return ProcLimits{}, err
+	}
+	procLimits.PidMax, err = strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return ProcLimits{}, err
+	}
+
+	content, err = readStatsFile("/proc/sys/kernel/threads-max")
+	if err != nil {
+		return ProcLimits{}, err
+	}
+	procLimits.ThreadsMax, err = strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return ProcLimits{}, err
+	}
+
+	if procLimits.PidMax > 0 {
+		procLimits.PidUsedPer = float64(procLimits.Total) * 100.00 / float64(procLimits.PidMax)
+	}
+	if procLimits.ThreadsMax > 0 {
+		procLimits.ThreadsUsedPer = float64(procLimits.Total) * 100.00 / float64(procLimits.ThreadsMax)
+	}
+
+	return procLimits, nil
+}
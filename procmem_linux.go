@@ -0,0 +1,72 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// ProcessMemory represents the memory breakdown of a process, in kilobytes.
+type ProcessMemory struct {
+	Pid     int    `json:"pid"`               // Process ID
+	Rss     uint64 `json:"rss" unit:"kB"`     // Resident set size (shared pages counted in full)
+	Pss     uint64 `json:"pss" unit:"kB"`     // Proportional set size (shared pages divided by # of sharers)
+	Uss     uint64 `json:"uss" unit:"kB"`     // Unique set size (private pages, freed if the process exits)
+	Shared  uint64 `json:"shared" unit:"kB"`  // Resident pages shared with other processes
+	Swap    uint64 `json:"swap" unit:"kB"`    // Swapped out anonymous memory
+	SwapPss uint64 `json:"swappss" unit:"kB"` // Proportional swap (since 4.3)
+}
+
+// getProcessMemoryDetail gets the memory breakdown of a process from the file
+// /proc/[pid]/smaps_rollup (since 4.14). On older kernels the mappings of
+// /proc/[pid]/smaps are added up.
+func getProcessMemoryDetail(pid int) (processMemory ProcessMemory, err error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	file, err := os.Open(dir + "/smaps_rollup")
+	if os.IsNotExist(err) {
+		file, err = os.Open(dir + "/smaps")
+	}
+	if err != nil {
+		return ProcessMemory{}, err
+	}
+	defer file.Close()
+
+	processMemory = ProcessMemory{Pid: pid}
+
+	re := regexp.MustCompile(`^(Rss|Pss|Shared_Clean|Shared_Dirty|Private_Clean|Private_Dirty|Swap|SwapPss):\s+(\d+) kB`)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		stat := re.FindStringSubmatch(scanner.Text())
+		if stat == nil {
+			continue
+		}
+		value, err := strconv.ParseUint(stat[2], 10, 64)
+		if err != nil {
+			return ProcessMemory{}, err
+		}
+		switch stat[1] {
+		case `Rss`:
+			processMemory.Rss += value
+		case `Pss`:
+			processMemory.Pss += value
+		case `Shared_Clean`, `Shared_Dirty`:
+			processMemory.Shared += value
+		case `Private_Clean`, `Private_Dirty`:
+			processMemory.Uss += value
+		case `Swap`:
+			processMemory.Swap += value
+		case `SwapPss`:
+			processMemory.SwapPss += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ProcessMemory{}, err
+	}
+
+	return processMemory, nil
+}
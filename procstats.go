@@ -0,0 +1,71 @@
+package sysstats
+
+import (
+	"time"
+)
+
+// ProcStats represents the processes statistics (NOT counted since boot)
+type ProcStats struct {
+	Running uint64 `json:"running"` // # of processes in runnable state (Linux 2.5.45 onward)
+	// # of processes blocked waiting for I/O to complete (Linux 2.5.45 onward)
+	Blocked uint64 `json:"blocked"`
+	// # of currently runnable kernel scheduling entities (processes, threads)
+	RunQueue uint64 `json:"runqueue"`
+	// # of kernel scheduling entities that currently exist on the system
+	Total uint64 `json:"total"`
+}
+
+// ProcRawStats represents the raw processes statistics
+type ProcRawStats struct {
+	Processes uint64 `json:"processes"` // # of forks since boot
+	ProcStats
+	Time int64 `json:"time" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// ProcAvgStats represents the processes statistics
+type ProcAvgStats struct {
+	NewProcs float64 `json:"newprocs" unit:"/s"` // # of forks per second
+	ProcStats
+}
+
+// getProcAvgStats calculates the average between 2 ProcRawStats samples.
+func getProcAvgStats(firstSample ProcRawStats, secondSample ProcRawStats) (procAvgStats ProcAvgStats, err error) {
+	procAvgStats = ProcAvgStats{}
+
+	timeDelta := time.Duration(sampleTimeNano(secondSample.Time) - sampleTimeNano(firstSample.Time)).Seconds()
+
+	// Calculate number of new processes created per second
+	if timeDelta > 0 {
+		avg := float64(secondSample.Processes-firstSample.Processes) / timeDelta
+		procAvgStats.NewProcs = avg
+	} else {
+		procAvgStats.NewProcs = 0
+	}
+
+	// The other values of procAvgStats will be taken from the second sample because
+	// they are "current" values (not counted since boot)
+	procAvgStats.Running = secondSample.Running
+	procAvgStats.Blocked = secondSample.Blocked
+	procAvgStats.RunQueue = secondSample.RunQueue
+	procAvgStats.Total = secondSample.Total
+
+	return procAvgStats, nil
+}
+
+// getProcStatsInterval returns the processes statistics between 2 samples.
+// Time interval between the 2 samples is given in seconds
+func getProcStatsInterval(interval int64) (procAvgStats ProcAvgStats, err error) {
+	return getProcStatsOver(time.Duration(interval) * time.Second)
+}
+
+// getProcStatsOver returns the processes statistics between 2 samples taken
+// d apart.
+func getProcStatsOver(d time.Duration) (procAvgStats ProcAvgStats, err error) {
+	return sampleOver(d, getProcRawStats, getProcAvgStats)
+}
+
+// getProcStatsSampleN takes n+1 consecutive samples, interval apart, and
+// returns the n processes stats averages between them.
+func getProcStatsSampleN(n int, interval time.Duration) (series []ProcAvgStats, err error) {
+	return sampleN(n, interval, getProcRawStats, getProcAvgStats)
+}
@@ -0,0 +1,217 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcessIoRawStats represents the raw IO stats of a process from the file
+// /proc/[pid]/io.
+type ProcessIoRawStats struct {
+	Rchar      uint64 `json:"rchar" unit:"bytes"`      // # of bytes read (including the page cache)
+	Wchar      uint64 `json:"wchar" unit:"bytes"`      // # of bytes written (including the page cache)
+	ReadBytes  uint64 `json:"readbytes" unit:"bytes"`  // # of bytes read from the storage layer
+	WriteBytes uint64 `json:"writebytes" unit:"bytes"` // # of bytes written to the storage layer
+}
+
+// ProcessTreeRawStats represents the raw stats of a process and all its
+// descendants.
+type ProcessTreeRawStats struct {
+	Root       int                       `json:"root"`                       // PID of the root process of the tree
+	Processes  map[int]ProcessRawStats   `json:"processes"`                  // Raw stats of the processes of the tree
+	Io         map[int]ProcessIoRawStats `json:"io"`                         // IO raw stats of the processes (if readable)
+	CpuTotal   uint64                    `json:"cputotal" unit:"jiffies"`    // Total CPU time of the system when the sample was taken (USER_HZ)
+	SampleTime int64                     `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// ProcessTreeAvgStats represents the aggregated stats of a process and all its
+// descendants between 2 samples. The CPU time and IO of the processes that
+// didn't exist in both samples is not counted.
+type ProcessTreeAvgStats struct {
+	Root       int     `json:"root"`                      // PID of the root process of the tree
+	Pids       []int   `json:"pids"`                      // PIDs of the processes of the tree
+	User       float64 `json:"user" unit:"%"`             // % of CPU time spent in user mode
+	System     float64 `json:"system" unit:"%"`           // % of CPU time spent in kernel mode
+	Total      float64 `json:"total" unit:"%"`            // % of CPU time (user + system)
+	Rss        uint64  `json:"rss" unit:"kB"`             // Resident set size in kilobytes
+	Threads    uint64  `json:"threads"`                   // # of threads
+	Rchar      float64 `json:"rchar" unit:"bytes/s"`      // # of bytes read per second (including the page cache)
+	Wchar      float64 `json:"wchar" unit:"bytes/s"`      // # of bytes written per second (including the page cache)
+	ReadBytes  float64 `json:"readbytes" unit:"bytes/s"`  // # of bytes read per second from the storage layer
+	WriteBytes float64 `json:"writebytes" unit:"bytes/s"` // # of bytes written per second to the storage layer
+}
+
+// readPidfile returns the PID written in a pidfile.
+func readPidfile(path string) (pid int, err error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return 0, errors.New("The pidfile " + path + " is empty")
+	}
+	pid, err = strconv.Atoi(fields[0])
+	if err != nil || pid <= 0 {
+		return 0, errors.New("The pidfile " + path + " doesn't contain a valid PID")
+	}
+
+	return pid, nil
+}
+
+// getProcessIoRawStats gets the IO raw stats of a process from the file
+// /proc/[pid]/io, which has the following format:
+//   rchar: 323934931
+//   wchar: 323929600
+//   syscr: 632687
+//   syscw: 632675
+//   read_bytes: 0
+//   write_bytes: 323932160
+//   cancelled_write_bytes: 0
+// Reading it requires the same permissions as ptrace.
+func getProcessIoRawStats(pid int) (processIoRawStats ProcessIoRawStats, err error) {
+	file, err := os.Open("/proc/" + strconv.Itoa(pid) + "/io")
+	if err != nil {
+		return ProcessIoRawStats{}, err
+	}
+	defer file.Close()
+
+	processIoRawStats = ProcessIoRawStats{}
+	values := map[string]*uint64{
+		`rchar`:       &processIoRawStats.Rchar,
+		`wchar`:       &processIoRawStats.Wchar,
+		`read_bytes`:  &processIoRawStats.ReadBytes,
+		`write_bytes`: &processIoRawStats.WriteBytes,
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, ok := values[strings.TrimSuffix(fields[0], ":")]
+		if !ok {
+			continue
+		}
+		*value, err = strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return ProcessIoRawStats{}, err
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return ProcessIoRawStats{}, err
+	}
+
+	return processIoRawStats, nil
+}
+
+// getProcessTreeRawStats gets the raw stats of a process and all its
+// descendants.
+func getProcessTreeRawStats(root int) (processTreeRawStats ProcessTreeRawStats, err error) {
+	processes, err := getAllProcessesRawStats()
+	if err != nil {
+		return ProcessTreeRawStats{}, err
+	}
+
+	children := map[int][]int{}
+	for pid, processRawStats := range processes {
+		children[processRawStats.Ppid] = append(children[processRawStats.Ppid], pid)
+	}
+	if _, ok := processes[root]; !ok {
+		return ProcessTreeRawStats{}, errors.New("The process " + strconv.Itoa(root) + " doesn't exist")
+	}
+
+	processTreeRawStats = ProcessTreeRawStats{}
+	processTreeRawStats.Root = root
+	processTreeRawStats.Processes = map[int]ProcessRawStats{}
+	processTreeRawStats.Io = map[int]ProcessIoRawStats{}
+	processTreeRawStats.CpuTotal = processes[root].CpuTotal
+	processTreeRawStats.SampleTime = time.Now().UnixNano()
+
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		processTreeRawStats.Processes[pid] = processes[pid]
+		processIoRawStats, err := getProcessIoRawStats(pid)
+		if err == nil {
+			processTreeRawStats.Io[pid] = processIoRawStats
+		}
+		queue = append(queue, children[pid]...)
+	}
+
+	return processTreeRawStats, nil
+}
+
+// getProcessTreeAvgStats calculates the aggregated stats of a process tree
+// between 2 samples.
+func getProcessTreeAvgStats(firstSample ProcessTreeRawStats, secondSample ProcessTreeRawStats) (processTreeAvgStats ProcessTreeAvgStats, err error) {
+	if firstSample.Root != secondSample.Root {
+		return ProcessTreeAvgStats{}, errors.New("The samples are from different process trees")
+	}
+
+	processTreeAvgStats = ProcessTreeAvgStats{}
+	processTreeAvgStats.Root = secondSample.Root
+
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	for pid, processRawStats := range secondSample.Processes {
+		processTreeAvgStats.Pids = append(processTreeAvgStats.Pids, pid)
+		processTreeAvgStats.Rss += processRawStats.Rss
+		processTreeAvgStats.Threads += processRawStats.NumThreads
+
+		previous, ok := firstSample.Processes[pid]
+		if !ok {
+			continue
+		}
+		processAvgStats, err := getProcessAvgStats(previous, processRawStats)
+		if err != nil {
+			// The PID was reused by a new process
+			continue
+		}
+		processTreeAvgStats.User += processAvgStats.User
+		processTreeAvgStats.System += processAvgStats.System
+		processTreeAvgStats.Total += processAvgStats.Total
+
+		firstIo, okFirst := firstSample.Io[pid]
+		secondIo, okSecond := secondSample.Io[pid]
+		if okFirst && okSecond && timeDelta > 0 {
+			processTreeAvgStats.Rchar += float64(secondIo.Rchar-firstIo.Rchar) / timeDelta
+			processTreeAvgStats.Wchar += float64(secondIo.Wchar-firstIo.Wchar) / timeDelta
+			processTreeAvgStats.ReadBytes += float64(secondIo.ReadBytes-firstIo.ReadBytes) / timeDelta
+			processTreeAvgStats.WriteBytes += float64(secondIo.WriteBytes-firstIo.WriteBytes) / timeDelta
+		}
+	}
+	sort.Ints(processTreeAvgStats.Pids)
+
+	return processTreeAvgStats, nil
+}
+
+// getProcessTreeStatsOver returns the aggregated stats of a process and all
+// its descendants between 2 samples taken d apart.
+func getProcessTreeStatsOver(root int, d time.Duration) (processTreeAvgStats ProcessTreeAvgStats, err error) {
+	return sampleOver(d, func() (ProcessTreeRawStats, error) {
+		return getProcessTreeRawStats(root)
+	}, getProcessTreeAvgStats)
+}
+
+// getPidfileTreeStatsOver returns the aggregated stats of the process whose
+// PID is in the pidfile and all its descendants between 2 samples taken d
+// apart.
+func getPidfileTreeStatsOver(path string, d time.Duration) (processTreeAvgStats ProcessTreeAvgStats, err error) {
+	pid, err := readPidfile(path)
+	if err != nil {
+		return ProcessTreeAvgStats{}, err
+	}
+
+	return getProcessTreeStatsOver(pid, d)
+}
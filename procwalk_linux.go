@@ -0,0 +1,114 @@
+// +build linux
+
+package sysstats
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultProcWalkWorkers is the # of workers of a ProcWalker when none is
+// given. Reading /proc/[pid]/stat is mostly kernel time, so a few workers
+// are enough and more would only make the agent show up in its own stats.
+const DefaultProcWalkWorkers = 4
+
+// procStatBufferSize is the size of the buffers /proc/[pid]/stat is read into,
+// bigger than the longest line the kernel writes (52 numbers and the command
+// name).
+const procStatBufferSize = 4096
+
+// ProcWalker reads the raw stats of all the processes of the system (or the
+// ones passing the filter) with a bounded pool of workers, each one reusing
+// its read buffer, so hosts with 10k+ processes can be scanned every sample.
+type ProcWalker struct {
+	Workers int                // # of concurrent readers (default DefaultProcWalkWorkers)
+	Filter  func(pid int) bool // Processes read, nil means all
+}
+
+// Walk returns the raw stats of the processes, sharing the same total CPU
+// time. The processes that exit while they are read are skipped.
+func (w *ProcWalker) Walk() (processesRawStats map[int]ProcessRawStats, err error) {
+	pids, err := getPids()
+	if err != nil {
+		return nil, err
+	}
+	cpusRawStats, err := getCpuRawStats()
+	if err != nil {
+		return nil, err
+	}
+	cpuTotal := cpusRawStats[`cpu`][CpuTotal]
+
+	if w.Filter != nil {
+		filtered := pids[:0]
+		for _, pid := range pids {
+			if w.Filter(pid) {
+				filtered = append(filtered, pid)
+			}
+		}
+		pids = filtered
+	}
+
+	workers := w.Workers
+	if workers <= 0 {
+		workers = DefaultProcWalkWorkers
+	}
+	if workers > len(pids) {
+		workers = len(pids)
+	}
+
+	processesRawStats = make(map[int]ProcessRawStats, len(pids))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan int, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buffer := make([]byte, procStatBufferSize)
+			for pid := range jobs {
+				processRawStats, err := readProcessStat(pid, buffer)
+				if err != nil {
+					// The process exited after listing it
+					continue
+				}
+				processRawStats.CpuTotal = cpuTotal
+				mu.Lock()
+				processesRawStats[pid] = processRawStats
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, pid := range pids {
+		jobs <- pid
+	}
+	close(jobs)
+	wg.Wait()
+
+	return processesRawStats, nil
+}
+
+// readProcessStat reads the raw stats of a process from the file
+// /proc/[pid]/stat into the given buffer.
+func readProcessStat(pid int, buffer []byte) (processRawStats ProcessRawStats, err error) {
+	file, err := os.Open("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+	defer file.Close()
+
+	// The kernel writes the whole line in one read
+	n, err := file.Read(buffer)
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+
+	processRawStats, err = parseProcessRawStats(string(buffer[:n]))
+	if err != nil {
+		return ProcessRawStats{}, err
+	}
+	processRawStats.SampleTime = time.Now().UnixNano()
+
+	return processRawStats, nil
+}
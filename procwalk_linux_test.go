@@ -0,0 +1,104 @@
+// +build linux
+
+package sysstats
+
+import (
+	"os"
+	"strconv"
+	"testing"
+)
+
+// naiveProcessScan reads the raw stats of every process one by one, the way
+// the processes were scanned before ProcWalker.
+func naiveProcessScan() (processesRawStats map[int]ProcessRawStats, err error) {
+	pids, err := getPids()
+	if err != nil {
+		return nil, err
+	}
+	cpusRawStats, err := getCpuRawStats()
+	if err != nil {
+		return nil, err
+	}
+
+	processesRawStats = make(map[int]ProcessRawStats, len(pids))
+	for _, pid := range pids {
+		processRawStats, err := readProcessRawStats(pid, cpusRawStats[`cpu`][CpuTotal])
+		if err != nil {
+			continue
+		}
+		processesRawStats[pid] = processRawStats
+	}
+
+	return processesRawStats, nil
+}
+
+func TestProcWalker(t *testing.T) {
+	self := os.Getpid()
+	walker := &ProcWalker{Workers: 2, Filter: func(pid int) bool { return pid == self }}
+
+	processesRawStats, err := walker.Walk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processesRawStats) != 1 {
+		t.Fatalf("Walk() returned %d processes, want only this one", len(processesRawStats))
+	}
+	if processRawStats := processesRawStats[self]; processRawStats.CpuTotal == 0 {
+		t.Errorf("Walk() = %+v, want the total CPU time", processRawStats)
+	}
+}
+
+func BenchmarkProcWalker(b *testing.B) {
+	walker := &ProcWalker{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := walker.Walk(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProcWalkerFiltered(b *testing.B) {
+	self := os.Getpid()
+	walker := &ProcWalker{Filter: func(pid int) bool { return pid == self }}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := walker.Walk(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNaiveProcessScan(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := naiveProcessScan(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadProcessStat(b *testing.B) {
+	pid := os.Getpid()
+	buffer := make([]byte, procStatBufferSize)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := readProcessStat(pid, buffer); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseProcessRawStats(b *testing.B) {
+	content, err := os.ReadFile("/proc/" + strconv.Itoa(os.Getpid()) + "/stat")
+	if err != nil {
+		b.Skip(err)
+	}
+	stat := string(content)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parseProcessRawStats(stat); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package sysstats
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultReachabilityCount is the # of probes sent to every target by
+	// default in every snapshot.
+	defaultReachabilityCount = 3
+	// defaultReachabilityTimeout is the time a probe waits for its reply by
+	// default.
+	defaultReachabilityTimeout = time.Second
+)
+
+// errProbeTimeout is returned by the probes that got no reply in time.
+var errProbeTimeout = errors.New("The probe timed out")
+
+// probeSetupError is returned by the probes that couldn't be sent at all
+// (the address doesn't resolve, no ICMP sockets,...), which isn't loss.
+type probeSetupError struct {
+	err error
+}
+
+func (e *probeSetupError) Error() string { return e.err.Error() }
+func (e *probeSetupError) Unwrap() error { return e.err }
+
+// ReachabilityTarget represents a host probed by the reachability collector
+// of the snapshots.
+type ReachabilityTarget struct {
+	Name    string        // Name of the target in the metrics (the address if it's empty)
+	Network string        // icmp (echo requests, needs net.ipv4.ping_group_range on linux) or tcp (connects)
+	Address string        // Host for icmp, host:port for tcp
+	Count   int           // # of probes per snapshot (3 by default)
+	Timeout time.Duration // Time a probe waits for its reply (1s by default)
+}
+
+// ReachabilityStats represents the round trip time and the packet loss of
+// the probes sent to a target.
+type ReachabilityStats struct {
+	Name     string  `json:"name"`             // Name of the target
+	Network  string  `json:"network"`          // icmp or tcp
+	Address  string  `json:"address"`          // Address probed
+	Sent     int     `json:"sent"`             // # of probes sent
+	Received int     `json:"received"`         // # of replies received in time
+	Loss     float64 `json:"loss" unit:"%"`    // % of probes without reply
+	RttMin   float64 `json:"rttmin" unit:"ms"` // Min round trip time in milliseconds
+	RttAvg   float64 `json:"rttavg" unit:"ms"` // Mean round trip time in milliseconds
+	RttMax   float64 `json:"rttmax" unit:"ms"` // Max round trip time in milliseconds
+	// Time when the last probe finished (Unix time in nanoseconds)
+	SampleTime int64 `json:"sampletime" unit:"unixnano"`
+	// Why the target couldn't be probed (the other stats are empty then)
+	Error string `json:"error,omitempty"`
+}
+
+// reachabilityProber probes the targets in the background, so the snapshots
+// don't wait for up to Count x Timeout.
+type reachabilityProber struct {
+	targets []ReachabilityTarget
+	running atomic.Bool                         // A round of probes is running
+	stats   atomic.Pointer[[]ReachabilityStats] // Stats of the last round of probes
+}
+
+// reachabilityProbes is the prober of the targets of the reachability
+// collector.
+var reachabilityProbes atomic.Pointer[reachabilityProber]
+
+// SetReachabilityTargets sets the hosts the snapshots probe, with ICMP echo
+// requests or TCP connects, to report the quality of the network of the
+// host (round trip time and loss) along the counters of its interfaces. The
+// reachability collector is active only when targets are set; none disables
+// it again. It returns an error, and keeps the previous targets, if any of
+// the targets is invalid.
+//
+// The probes run in the background: every snapshot has the stats of the
+// last round of probes finished and starts the next one, so the first
+// snapshots after setting the targets may have none.
+func SetReachabilityTargets(targets ...ReachabilityTarget) error {
+	for _, target := range targets {
+		if err := validateReachabilityTarget(target); err != nil {
+			return err
+		}
+	}
+	if len(targets) == 0 {
+		reachabilityProbes.Store(nil)
+		return nil
+	}
+
+	prober := &reachabilityProber{targets: append([]ReachabilityTarget(nil), targets...)}
+	reachabilityProbes.Store(prober)
+	prober.start()
+
+	return nil
+}
+
+// validateReachabilityTarget checks the network and the address of a target.
+func validateReachabilityTarget(target ReachabilityTarget) error {
+	switch target.Network {
+	case `icmp`:
+		if target.Address == "" {
+			return errors.New("The reachability target " + target.Name + " has no address")
+		}
+	case `tcp`:
+		if _, port, err := net.SplitHostPort(target.Address); err != nil || port == "" {
+			return errors.New("The address of the tcp reachability target " + target.Name +
+				" isn't host:port: " + target.Address)
+		}
+	default:
+		return errors.New("Unknown reachability network " + target.Network + " of " + target.Address)
+	}
+
+	return nil
+}
+
+// start runs a round of probes in the background unless one is already
+// running.
+func (p *reachabilityProber) start() {
+	if !p.running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer p.running.Store(false)
+		reachabilityStatsArr := probeTargets(p.targets)
+		p.stats.Store(&reachabilityStatsArr)
+	}()
+}
+
+// getReachabilityStats returns the stats of the last round of probes of the
+// targets set with SetReachabilityTargets, and starts the next one. It
+// returns no stats if there are no targets or no round has finished yet.
+func getReachabilityStats() (reachabilityStatsArr []ReachabilityStats, err error) {
+	prober := reachabilityProbes.Load()
+	if prober == nil {
+		return nil, nil
+	}
+
+	last := prober.stats.Load()
+	prober.start()
+	if last == nil {
+		return nil, nil
+	}
+
+	return append([]ReachabilityStats(nil), *last...), nil
+}
+
+// probeTargets probes all the targets concurrently.
+func probeTargets(targets []ReachabilityTarget) (reachabilityStatsArr []ReachabilityStats) {
+	reachabilityStatsArr = make([]ReachabilityStats, len(targets))
+	wg := sync.WaitGroup{}
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reachabilityStatsArr[i] = probeTarget(target)
+		}()
+	}
+	wg.Wait()
+
+	return reachabilityStatsArr
+}
+
+// probeTarget sends the probes of a target one after the other. The probes
+// without reply count as lost; the ones that couldn't be sent (see
+// probeSetupError) stop the probing and set the Error of the stats.
+func probeTarget(target ReachabilityTarget) (reachabilityStats ReachabilityStats) {
+	reachabilityStats = ReachabilityStats{Name: target.Name, Network: target.Network, Address: target.Address}
+	if reachabilityStats.Name == "" {
+		reachabilityStats.Name = target.Address
+	}
+
+	var probe func(seq int, timeout time.Duration) (time.Duration, error)
+	switch target.Network {
+	case `icmp`:
+		probe = func(seq int, timeout time.Duration) (time.Duration, error) {
+			return icmpRtt(target.Address, seq, timeout)
+		}
+	case `tcp`:
+		probe = func(_ int, timeout time.Duration) (time.Duration, error) {
+			return tcpRtt(target.Address, timeout)
+		}
+	default:
+		reachabilityStats.Error = "Unknown reachability network " + target.Network
+		return reachabilityStats
+	}
+	count := target.Count
+	if count <= 0 {
+		count = defaultReachabilityCount
+	}
+	timeout := target.Timeout
+	if timeout <= 0 {
+		timeout = defaultReachabilityTimeout
+	}
+
+	var total float64
+	for seq := 1; seq <= count; seq++ {
+		reachabilityStats.Sent++
+		rtt, err := probe(seq, timeout)
+		var setupErr *probeSetupError
+		if errors.As(err, &setupErr) {
+			logWarn("reachability target not probed", "target", reachabilityStats.Name, "error", err)
+			return ReachabilityStats{Name: reachabilityStats.Name, Network: target.Network,
+				Address: target.Address, SampleTime: time.Now().UnixNano(), Error: err.Error()}
+		}
+		if err != nil {
+			logDebug("reachability probe lost", "target", reachabilityStats.Name, "error", err)
+			continue
+		}
+		ms := float64(rtt) / float64(time.Millisecond)
+		if reachabilityStats.Received == 0 || ms < reachabilityStats.RttMin {
+			reachabilityStats.RttMin = ms
+		}
+		if ms > reachabilityStats.RttMax {
+			reachabilityStats.RttMax = ms
+		}
+		total += ms
+		reachabilityStats.Received++
+	}
+	reachabilityStats.Loss = float64(reachabilityStats.Sent-reachabilityStats.Received) * 100.00 /
+		float64(reachabilityStats.Sent)
+	if reachabilityStats.Received > 0 {
+		reachabilityStats.RttAvg = total / float64(reachabilityStats.Received)
+	}
+	reachabilityStats.SampleTime = time.Now().UnixNano()
+
+	return reachabilityStats
+}
+
+// tcpRtt returns the time it takes to connect to the address (host:port),
+// which is the round trip time of the SYN and the SYN-ACK. The host names
+// that don't resolve are a probeSetupError.
+func tcpRtt(address string, timeout time.Duration) (rtt time.Duration, err error) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return 0, &probeSetupError{err}
+	}
+	if err != nil {
+		return 0, err
+	}
+	rtt = time.Since(start)
+	conn.Close()
+
+	return rtt, nil
+}
@@ -0,0 +1,76 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// icmpRtt sends an ICMP echo request to the host and returns the time it
+// took to get the reply. It uses an unprivileged ICMP socket, so the group of
+// the process must be in the range of the sysctl net.ipv4.ping_group_range
+// (which also applies to IPv6). The host names that don't resolve and the
+// errors creating the socket are a probeSetupError.
+func icmpRtt(host string, seq int, timeout time.Duration) (rtt time.Duration, err error) {
+	addr, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		return 0, &probeSetupError{err}
+	}
+
+	family, proto := syscall.AF_INET, syscall.IPPROTO_ICMP
+	echoRequest, echoReply := byte(8), byte(0)
+	var sockaddr syscall.Sockaddr
+	if ip := addr.IP.To4(); ip != nil {
+		sockaddr4 := &syscall.SockaddrInet4{}
+		copy(sockaddr4.Addr[:], ip)
+		sockaddr = sockaddr4
+	} else {
+		family, proto = syscall.AF_INET6, syscall.IPPROTO_ICMPV6
+		echoRequest, echoReply = 128, 129
+		sockaddr6 := &syscall.SockaddrInet6{}
+		copy(sockaddr6.Addr[:], addr.IP.To16())
+		sockaddr = sockaddr6
+	}
+
+	fd, err := syscall.Socket(family, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return 0, &probeSetupError{os.NewSyscallError("socket", err)}
+	}
+	defer syscall.Close(fd)
+	tv := syscall.NsecToTimeval(int64(timeout))
+	if err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return 0, &probeSetupError{os.NewSyscallError("setsockopt", err)}
+	}
+
+	// Type, code, checksum, id and sequence, followed by the payload. The
+	// kernel sets the id and the checksum of the unprivileged sockets.
+	request := []byte{echoRequest, 0, 0, 0, 0, 0, byte(seq >> 8), byte(seq), 's', 'y', 's', 's', 't', 'a', 't', 's'}
+	start := time.Now()
+	if err = syscall.Sendto(fd, request, 0, sockaddr); err != nil {
+		return 0, os.NewSyscallError("sendto", err)
+	}
+
+	reply := make([]byte, 1500)
+	for time.Since(start) < timeout {
+		n, _, err := syscall.Recvfrom(fd, reply, 0)
+		if errors.Is(err, syscall.EINTR) {
+			continue
+		}
+		if errors.Is(err, syscall.EAGAIN) {
+			break
+		}
+		if err != nil {
+			return 0, os.NewSyscallError("recvfrom", err)
+		}
+		// The replies have no IP header
+		if n >= 8 && reply[0] == echoReply && reply[6] == byte(seq>>8) && reply[7] == byte(seq) {
+			return time.Since(start), nil
+		}
+	}
+
+	return 0, errProbeTimeout
+}
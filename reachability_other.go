@@ -0,0 +1,14 @@
+// +build !linux
+
+package sysstats
+
+import (
+	"errors"
+	"time"
+)
+
+// icmpRtt isn't supported outside linux, which is the only OS with
+// unprivileged ICMP sockets; the TCP probes can be used instead.
+func icmpRtt(host string, seq int, timeout time.Duration) (rtt time.Duration, err error) {
+	return 0, &probeSetupError{errors.New("The ICMP probes are only supported on linux")}
+}
@@ -0,0 +1,78 @@
+package sysstats
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSetReachabilityTargetsInvalid(t *testing.T) {
+	t.Cleanup(func() { SetReachabilityTargets() })
+
+	for _, target := range []ReachabilityTarget{
+		{Network: `udp`, Address: `192.0.2.1:53`},
+		{Network: `tcp`, Address: `192.0.2.1`},
+		{Network: `icmp`},
+	} {
+		if err := SetReachabilityTargets(target); err == nil {
+			t.Errorf("SetReachabilityTargets(%+v) succeeded, want an error", target)
+		}
+	}
+	if reachabilityProbes.Load() != nil {
+		t.Error("the invalid targets were set")
+	}
+}
+
+func TestReachabilityProbesInBackground(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	t.Cleanup(func() { SetReachabilityTargets() })
+	err = SetReachabilityTargets(ReachabilityTarget{Name: `local`, Network: `tcp`, Address: listener.Addr().String(), Count: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		reachabilityStatsArr, err := getReachabilityStats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reachabilityStatsArr) == 1 {
+			stats := reachabilityStatsArr[0]
+			if stats.Name != `local` || stats.Sent != 2 || stats.Received != 2 || stats.Loss != 0 || stats.Error != "" {
+				t.Errorf("stats = %+v, want 2 probes received", stats)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no round of probes finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProbeTargetSetupError(t *testing.T) {
+	stats := probeTarget(ReachabilityTarget{Network: `udp`, Address: `192.0.2.1:53`})
+	if stats.Error == "" || stats.Sent != 0 || stats.Loss != 0 {
+		t.Errorf("probeTarget() = %+v, want an error and no probes", stats)
+	}
+
+	_, err := tcpRtt(`host.invalid:80`, time.Second)
+	if _, ok := err.(*probeSetupError); !ok {
+		t.Errorf("tcpRtt() of an unresolvable host error = %v, want a probeSetupError", err)
+	}
+}
@@ -0,0 +1,95 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ReclaimRawStats represents the raw page reclaim counters of a linux
+// system.
+type ReclaimRawStats struct {
+	ScanKswapd  uint64 `json:"scankswapd" unit:"pages"`    // # of pages scanned by kswapd since boot
+	ScanDirect  uint64 `json:"scandirect" unit:"pages"`    // # of pages scanned by direct reclaim since boot
+	StealKswapd uint64 `json:"stealkswapd" unit:"pages"`   // # of pages reclaimed by kswapd since boot
+	StealDirect uint64 `json:"stealdirect" unit:"pages"`   // # of pages reclaimed by direct reclaim since boot
+	AllocStall  uint64 `json:"allocstall"`                 // # of allocations that entered direct reclaim since boot
+	SampleTime  int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// ReclaimStats represents the page reclaim activity of a linux system
+// between 2 samples. Direct reclaim stalls the allocating tasks, and it's
+// the early warning of memory pressure before swapping starts.
+type ReclaimStats struct {
+	ScanKswapd    float64 `json:"scankswapd" unit:"pages/s"`  // # of pages scanned by kswapd per second
+	ScanDirect    float64 `json:"scandirect" unit:"pages/s"`  // # of pages scanned by direct reclaim per second
+	StealKswapd   float64 `json:"stealkswapd" unit:"pages/s"` // # of pages reclaimed by kswapd per second
+	StealDirect   float64 `json:"stealdirect" unit:"pages/s"` // # of pages reclaimed by direct reclaim per second
+	AllocStall    float64 `json:"allocstall" unit:"/s"`       // # of allocations that entered direct reclaim per second
+	Efficiency    float64 `json:"efficiency" unit:"%"`        // % of the scanned pages that were reclaimed (100 if none was scanned)
+	DirectReclaim bool    `json:"directreclaim"`              // Direct reclaim happened between the samples
+}
+
+// getReclaimRawStats gets the reclaim counters from the file /proc/vmstat.
+// Older kernels report them per zone (pgscan_kswapd_normal,...), so the
+// counters with the same prefix are added up. pgscan_direct_throttle counts
+// throttling events, not pages, so it's left out.
+func getReclaimRawStats() (reclaimRawStats ReclaimRawStats, err error) {
+	vmstat, err := readVmstat()
+	if err != nil {
+		return ReclaimRawStats{}, err
+	}
+
+	reclaimRawStats = ReclaimRawStats{}
+	for name, value := range vmstat {
+		switch {
+		case name == `pgscan_direct_throttle`:
+			continue
+		case strings.HasPrefix(name, `pgscan_kswapd`):
+			reclaimRawStats.ScanKswapd += value
+		case strings.HasPrefix(name, `pgscan_direct`):
+			reclaimRawStats.ScanDirect += value
+		case strings.HasPrefix(name, `pgsteal_kswapd`):
+			reclaimRawStats.StealKswapd += value
+		case strings.HasPrefix(name, `pgsteal_direct`):
+			reclaimRawStats.StealDirect += value
+		case strings.HasPrefix(name, `allocstall`):
+			reclaimRawStats.AllocStall += value
+		}
+	}
+	reclaimRawStats.SampleTime = time.Now().UnixNano()
+
+	return reclaimRawStats, nil
+}
+
+// getReclaimStats calculates the reclaim rates and efficiency between 2
+// samples.
+func getReclaimStats(firstSample ReclaimRawStats, secondSample ReclaimRawStats) (reclaimStats ReclaimStats, err error) {
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	if timeDelta <= 0 {
+		return ReclaimStats{}, errors.New("The second sample must be taken after the first one")
+	}
+
+	reclaimStats = ReclaimStats{}
+	reclaimStats.ScanKswapd = float64(secondSample.ScanKswapd-firstSample.ScanKswapd) / timeDelta
+	reclaimStats.ScanDirect = float64(secondSample.ScanDirect-firstSample.ScanDirect) / timeDelta
+	reclaimStats.StealKswapd = float64(secondSample.StealKswapd-firstSample.StealKswapd) / timeDelta
+	reclaimStats.StealDirect = float64(secondSample.StealDirect-firstSample.StealDirect) / timeDelta
+	reclaimStats.AllocStall = float64(secondSample.AllocStall-firstSample.AllocStall) / timeDelta
+
+	reclaimStats.Efficiency = 100
+	if scanned := reclaimStats.ScanKswapd + reclaimStats.ScanDirect; scanned > 0 {
+		reclaimStats.Efficiency = (reclaimStats.StealKswapd + reclaimStats.StealDirect) * 100.00 / scanned
+	}
+	reclaimStats.DirectReclaim = reclaimStats.ScanDirect > 0 || reclaimStats.AllocStall > 0
+
+	return reclaimStats, nil
+}
+
+// getReclaimStatsOver returns the reclaim activity between 2 samples taken d
+// apart.
+func getReclaimStatsOver(d time.Duration) (reclaimStats ReclaimStats, err error) {
+	return sampleOver(d, getReclaimRawStats, getReclaimStats)
+}
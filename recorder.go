@@ -0,0 +1,255 @@
+package sysstats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// recordMagic is written at the beginning of every data file created by a
+// Recorder so the readers can reject files with another format.
+const recordMagic = "SYSSTATS"
+
+// recordHeaderSize is the size of the header of every record:
+//   - 8 bytes: time of the snapshot (Unix time in nanoseconds, big endian)
+//   - 4 bytes: length of the payload (big endian)
+const recordHeaderSize = 12
+
+// Recorder writes snapshots to an append-only data file, giving sar-like
+// historical data that can be replayed or queried by time range with
+// ReplayRecords and ReadRecords.
+type Recorder struct {
+	Identity *Identity // Identity attached to the snapshots written without one
+
+	file *os.File
+}
+
+// NewRecorder opens (or creates) the data file at path for appending
+// snapshots to it. A record torn by a crash at the end of an existing file
+// is truncated, so the new records aren't appended after it.
+func NewRecorder(path string) (recorder *Recorder, err error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		// New file: write the magic header
+		_, err = file.Write([]byte(recordMagic))
+	} else {
+		// Existing file: check it was created by a Recorder
+		err = checkRecordMagic(io.NewSectionReader(file, 0, int64(len(recordMagic))))
+		if err == nil {
+			err = truncateTornRecord(file, info.Size())
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Recorder{file: file}, nil
+}
+
+// truncateTornRecord truncates the data file of the given size after its last
+// complete record.
+func truncateTornRecord(file *os.File, size int64) error {
+	end := int64(len(recordMagic))
+	last, lastLength := int64(-1), int64(0)
+	header := make([]byte, recordHeaderSize)
+	for end+recordHeaderSize <= size {
+		_, err := file.ReadAt(header, end)
+		if err != nil {
+			return err
+		}
+		length := int64(binary.BigEndian.Uint32(header[8:12]))
+		if length == 0 || end+recordHeaderSize+length > size {
+			break
+		}
+		last, lastLength = end, length
+		end += recordHeaderSize + length
+	}
+
+	// A crash while the file grew can leave the payload of the last record
+	// zero filled instead of short
+	if last >= 0 {
+		payload := make([]byte, lastLength)
+		_, err := file.ReadAt(payload, last+recordHeaderSize)
+		if err != nil {
+			return err
+		}
+		if !json.Valid(payload) {
+			end = last
+		}
+	}
+
+	if end == size {
+		return nil
+	}
+	logWarn("truncated torn record", "file", file.Name(), "offset", end, "size", size)
+
+	return file.Truncate(end)
+}
+
+// Write appends the snapshot to the data file, with the Identity of the
+// recorder if it doesn't have one.
+func (r *Recorder) Write(snapshot Snapshot) error {
+	if snapshot.Identity == nil {
+		snapshot.Identity = r.Identity
+	}
+	payload, err := MarshalSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+
+	// Write the whole record at once so a crash doesn't leave half a header
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint64(record[0:8], uint64(snapshot.Time.UnixNano()))
+	binary.BigEndian.PutUint32(record[8:12], uint32(len(payload)))
+	record = append(record, payload...)
+
+	_, err = r.file.Write(record)
+	return err
+}
+
+// Run takes a snapshot every interval and appends it to the data file until
+// the context is cancelled. The snapshots are aligned to the wall clock
+// boundaries of the interval so records of different hosts can be matched.
+// The snapshots some collectors of which fail are recorded without their
+// families (the errors are logged).
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var recordErr error
+	s := schedule{interval: interval, align: true}
+	err := s.run(ctx, func() {
+		snapshot, err := getSnapshot()
+		if isPartialSnapshot(err) {
+			logWarn("partial snapshot", "failed", FailedCollectors(err), "error", err)
+			err = nil
+		}
+		if err == nil {
+			err = r.Write(snapshot)
+		}
+		if err != nil {
+			recordErr = err
+			cancel()
+		}
+	})
+	if recordErr != nil {
+		return recordErr
+	}
+
+	return err
+}
+
+// Close closes the data file.
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// checkRecordMagic checks the data read from r starts with the magic header.
+func checkRecordMagic(r io.Reader) error {
+	magic := make([]byte, len(recordMagic))
+	_, err := io.ReadFull(r, magic)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(magic, []byte(recordMagic)) {
+		return errors.New("The file is not a sysstats data file")
+	}
+
+	return nil
+}
+
+// ReplayRecords reads the data file at path and calls fn for every snapshot
+// taken between from and to (both included), in the order they were written.
+// A zero from or to means no limit.
+func ReplayRecords(path string, from time.Time, to time.Time, fn func(Snapshot) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	err = checkRecordMagic(reader)
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, recordHeaderSize)
+	for {
+		_, err = io.ReadFull(reader, header)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// A truncated record at the end of the file is ignored
+			if err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return err
+		}
+		sampleTime := time.Unix(0, int64(binary.BigEndian.Uint64(header[0:8])))
+		length := int(binary.BigEndian.Uint32(header[8:12]))
+
+		// Skip the records out of the time range without decoding them
+		if (!from.IsZero() && sampleTime.Before(from)) || (!to.IsZero() && sampleTime.After(to)) {
+			_, err = reader.Discard(length)
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			continue
+		}
+
+		payload := make([]byte, length)
+		_, err = io.ReadFull(reader, payload)
+		if err != nil {
+			if err == io.ErrUnexpectedEOF || err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		snapshot, err := UnmarshalSnapshot(payload)
+		if err != nil {
+			return err
+		}
+		err = fn(snapshot)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ReadRecords returns the snapshots of the data file at path taken between
+// from and to (both included). A zero from or to means no limit.
+func ReadRecords(path string, from time.Time, to time.Time) (snapshots []Snapshot, err error) {
+	snapshots = make([]Snapshot, 0)
+
+	err = ReplayRecords(path, from, to, func(snapshot Snapshot) error {
+		snapshots = append(snapshots, snapshot)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
@@ -0,0 +1,77 @@
+package sysstats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRecorderTruncatesTornRecord(t *testing.T) {
+	for name, torn := range map[string][]byte{
+		"short header":   {0, 0, 0},
+		"short payload":  {0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 100, '{', '"'},
+		"zero filled":    make([]byte, 64),
+		"invalid record": {0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 4, '{', '"', 0, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data")
+			start := time.Unix(1700000000, 0)
+
+			recorder, err := NewRecorder(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := recorder.Write(Snapshot{Time: start}); err != nil {
+				t.Fatal(err)
+			}
+			recorder.Close()
+
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write(torn)
+			file.Close()
+
+			recorder, err = NewRecorder(path)
+			if err != nil {
+				t.Fatalf("NewRecorder() error = %v", err)
+			}
+			if err := recorder.Write(Snapshot{Time: start.Add(time.Minute)}); err != nil {
+				t.Fatal(err)
+			}
+			recorder.Close()
+
+			snapshots, err := ReadRecords(path, time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("ReadRecords() error = %v", err)
+			}
+			if len(snapshots) != 2 || !snapshots[1].Time.Equal(start.Add(time.Minute)) {
+				t.Errorf("ReadRecords() = %d snapshots, want the one before the torn record and the new one", len(snapshots))
+			}
+		})
+	}
+}
+
+func TestRecorderWriteIdentity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Identity = &Identity{Hostname: "web1"}
+	start := time.Unix(1700000000, 0)
+	if err := recorder.Write(Snapshot{Time: start}); err != nil {
+		t.Fatal(err)
+	}
+	recorder.Close()
+
+	snapshots, err := ReadRecords(path, start, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Identity.Host() != "web1" {
+		t.Errorf("ReadRecords() = %+v, want a snapshot with the identity of the recorder", snapshots)
+	}
+}
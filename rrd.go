@@ -0,0 +1,233 @@
+package sysstats
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RRDArchive defines one resolution of a round-robin store: every Step the
+// values added to the store are averaged into one point, and only the last
+// Size points are kept.
+type RRDArchive struct {
+	Step time.Duration `json:"step" unit:"ns"` // Resolution of the archive
+	Size int           `json:"size"`           // # of points kept
+}
+
+// DefaultRRDArchives keeps 1 hour of data at 1 second resolution, 1 day at 1
+// minute resolution and 30 days at 1 hour resolution.
+var DefaultRRDArchives = []RRDArchive{
+	{Step: time.Second, Size: 3600},
+	{Step: time.Minute, Size: 1440},
+	{Step: time.Hour, Size: 720},
+}
+
+// RRDPoint represents one consolidated point of a round-robin archive.
+type RRDPoint struct {
+	Time   time.Time          `json:"time"`   // Start of the step
+	Values map[string]float64 `json:"values"` // Average of every metric during the step
+}
+
+// rrdArchive is the state of one archive of the store.
+type rrdArchive struct {
+	RRDArchive
+	Points  []RRDPoint         `json:"points"`  // Ring of points
+	Head    int                `json:"head"`    // Position of the next point in the ring
+	Current time.Time          `json:"current"` // Start of the step being consolidated
+	Sums    map[string]float64 `json:"sums"`    // Sums of the step being consolidated
+	Counts  map[string]int     `json:"counts"`  // # of values of the step being consolidated
+}
+
+// RRD is a fixed size round-robin store, like rrdtool, that downsamples the
+// older data into coarser resolutions. Its size is bounded by the number of
+// points of its archives so it is suitable for embedded/edge devices.
+type RRD struct {
+	mu       sync.Mutex
+	path     string
+	archives []*rrdArchive
+}
+
+// OpenRRD opens the round-robin store persisted at path. If the file doesn't
+// exist a new store is created with the given archives (DefaultRRDArchives if
+// none is given). If it exists and archives are given, they must be the ones
+// of the store. The store is written to disk by Flush.
+func OpenRRD(path string, archives ...RRDArchive) (rrd *RRD, err error) {
+	rrd = &RRD{path: path}
+
+	content, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(content, &rrd.archives)
+		if err != nil {
+			return nil, err
+		}
+		if len(archives) > 0 && len(archives) != len(rrd.archives) {
+			return nil, errors.New("The archives of the RRD " + path + " differ from the ones requested")
+		}
+		for i, archive := range rrd.archives {
+			if archive == nil {
+				return nil, errors.New("The RRD " + path + " is corrupted")
+			}
+			err = archive.validate()
+			if err != nil {
+				return nil, errors.New("The RRD " + path + " is corrupted: " + err.Error())
+			}
+			if len(archives) > 0 && archive.RRDArchive != archives[i] {
+				return nil, errors.New("The archives of the RRD " + path + " differ from the ones requested")
+			}
+		}
+		return rrd, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if len(archives) == 0 {
+		archives = DefaultRRDArchives
+	}
+	for _, archive := range archives {
+		if archive.Step <= 0 || archive.Size <= 0 {
+			return nil, errors.New("The step and size of the RRD archives must be greater than 0")
+		}
+		rrd.archives = append(rrd.archives, &rrdArchive{
+			RRDArchive: archive,
+			Points:     make([]RRDPoint, 0, archive.Size),
+		})
+	}
+
+	return rrd, nil
+}
+
+// validate checks an archive loaded from disk, so a corrupted store fails to
+// open instead of panicking later.
+func (archive *rrdArchive) validate() error {
+	if archive.Step <= 0 || archive.Size <= 0 {
+		return errors.New("the step and size of the archives must be greater than 0")
+	}
+	if len(archive.Points) > archive.Size {
+		return errors.New("an archive has more points than its size")
+	}
+	// The ring is filled in order until it's full
+	if (len(archive.Points) < archive.Size && archive.Head != len(archive.Points)) ||
+		archive.Head < 0 || archive.Head >= archive.Size {
+		return errors.New("the head of an archive is out of range")
+	}
+	for name := range archive.Sums {
+		if archive.Counts[name] <= 0 {
+			return errors.New("the step being consolidated has a sum without count")
+		}
+	}
+
+	return nil
+}
+
+// Add adds the metrics taken at time t to every archive of the store.
+func (rrd *RRD) Add(t time.Time, metrics map[string]float64) {
+	rrd.mu.Lock()
+	defer rrd.mu.Unlock()
+
+	for _, archive := range rrd.archives {
+		step := t.Truncate(archive.Step)
+		if step.Before(archive.Current) {
+			// Out of order value for an already consolidated step
+			continue
+		}
+		if !step.Equal(archive.Current) {
+			archive.consolidate()
+			archive.Current = step
+		}
+		if archive.Sums == nil {
+			archive.Sums = map[string]float64{}
+			archive.Counts = map[string]int{}
+		}
+		for name, value := range metrics {
+			archive.Sums[name] += value
+			archive.Counts[name]++
+		}
+	}
+}
+
+// consolidate averages the values of the current step into a new point of
+// the ring, overwriting the oldest one when the ring is full.
+func (archive *rrdArchive) consolidate() {
+	if len(archive.Sums) == 0 {
+		return
+	}
+
+	point := RRDPoint{Time: archive.Current, Values: map[string]float64{}}
+	for name, sum := range archive.Sums {
+		point.Values[name] = sum / float64(archive.Counts[name])
+	}
+
+	if len(archive.Points) < archive.Size {
+		archive.Points = append(archive.Points, point)
+	} else {
+		archive.Points[archive.Head] = point
+	}
+	archive.Head = (archive.Head + 1) % archive.Size
+
+	archive.Sums = nil
+	archive.Counts = nil
+}
+
+// Fetch returns the points between from and to from the finest archive that
+// still holds data as old as from. Points are sorted by time.
+func (rrd *RRD) Fetch(from time.Time, to time.Time) (points []RRDPoint) {
+	rrd.mu.Lock()
+	defer rrd.mu.Unlock()
+
+	points = make([]RRDPoint, 0)
+	if len(rrd.archives) == 0 {
+		return points
+	}
+
+	// The archives are not sorted by step so look for the finest one covering
+	// the requested range, defaulting to the coarsest one
+	var selected *rrdArchive
+	for _, archive := range rrd.archives {
+		oldest := archive.Current.Add(-archive.Step * time.Duration(archive.Size))
+		if !oldest.After(from) && (selected == nil || archive.Step < selected.Step) {
+			selected = archive
+		}
+	}
+	if selected == nil {
+		for _, archive := range rrd.archives {
+			if selected == nil || archive.Step > selected.Step {
+				selected = archive
+			}
+		}
+	}
+
+	for _, point := range selected.Points {
+		if point.Time.Before(from) || point.Time.After(to) {
+			continue
+		}
+		points = append(points, point)
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+
+	return points
+}
+
+// Flush writes the store to disk. The file is replaced atomically so a crash
+// while writing doesn't corrupt it.
+func (rrd *RRD) Flush() error {
+	rrd.mu.Lock()
+	content, err := json.Marshal(rrd.archives)
+	rrd.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := rrd.path + ".tmp"
+	err = os.WriteFile(tmpPath, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, rrd.path)
+}
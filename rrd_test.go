@@ -0,0 +1,109 @@
+package sysstats
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRRDConsolidation(t *testing.T) {
+	rrd, err := OpenRRD(filepath.Join(t.TempDir(), "rrd.json"), RRDArchive{Step: time.Minute, Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1700000040, 0)
+	rrd.Add(start, map[string]float64{`cpu`: 10, `mem`: 1})
+	rrd.Add(start.Add(20*time.Second), map[string]float64{`cpu`: 30})
+	// The step is consolidated when the next one starts
+	rrd.Add(start.Add(time.Minute), map[string]float64{`cpu`: 50})
+
+	points := rrd.Fetch(start.Add(-time.Hour), start.Add(time.Hour))
+	if len(points) != 1 {
+		t.Fatalf("Fetch() = %+v, want 1 point", points)
+	}
+	if !points[0].Time.Equal(start) || points[0].Values[`cpu`] != 20 || points[0].Values[`mem`] != 1 {
+		t.Errorf("Fetch() = %+v, want the average of the first step", points[0])
+	}
+}
+
+func TestRRDWrapAround(t *testing.T) {
+	rrd, err := OpenRRD(filepath.Join(t.TempDir(), "rrd.json"), RRDArchive{Step: time.Second, Size: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 6; i++ {
+		rrd.Add(start.Add(time.Duration(i)*time.Second), map[string]float64{`cpu`: float64(i)})
+	}
+
+	// 5 steps consolidated, the last 3 are kept
+	points := rrd.Fetch(start.Add(-time.Hour), start.Add(time.Hour))
+	if len(points) != 3 {
+		t.Fatalf("Fetch() = %+v, want 3 points", points)
+	}
+	for i, point := range points {
+		if want := float64(i + 2); point.Values[`cpu`] != want {
+			t.Errorf("Fetch()[%d] = %v, want %v", i, point.Values[`cpu`], want)
+		}
+	}
+}
+
+func TestRRDReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rrd.json")
+	archives := []RRDArchive{{Step: time.Second, Size: 3}, {Step: time.Minute, Size: 2}}
+	rrd, err := OpenRRD(path, archives...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 5; i++ {
+		rrd.Add(start.Add(time.Duration(i)*time.Second), map[string]float64{`cpu`: float64(i)})
+	}
+	if err := rrd.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := OpenRRD(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := rrd.Fetch(start.Add(2*time.Second), start.Add(time.Hour))
+	got := reloaded.Fetch(start.Add(2*time.Second), start.Add(time.Hour))
+	if len(got) != len(want) || len(got) != 2 || got[0].Values[`cpu`] != want[0].Values[`cpu`] {
+		t.Errorf("Fetch() after reload = %+v, want %+v", got, want)
+	}
+	// The step being consolidated is kept
+	reloaded.Add(start.Add(time.Minute), map[string]float64{`cpu`: 10})
+	if got := reloaded.Fetch(start.Add(-time.Hour), start); len(got) != 1 || got[0].Values[`cpu`] != 2 {
+		t.Errorf("Fetch() of the first minute = %+v, want the average of the values before the reload", got)
+	}
+
+	if _, err := OpenRRD(path, archives...); err != nil {
+		t.Errorf("OpenRRD() with the same archives error = %v", err)
+	}
+	if _, err := OpenRRD(path, RRDArchive{Step: time.Second, Size: 3}); err == nil {
+		t.Error("OpenRRD() with other archives succeeded, want an error")
+	}
+}
+
+func TestOpenRRDCorrupted(t *testing.T) {
+	for name, content := range map[string]string{
+		"zero size":         `[{"step":1000000000,"size":0,"points":[],"head":0}]`,
+		"head overflow":     `[{"step":1000000000,"size":2,"points":[{"time":"2023-11-14T22:13:20Z"},{"time":"2023-11-14T22:13:21Z"}],"head":2}]`,
+		"head mismatch":     `[{"step":1000000000,"size":3,"points":[],"head":1}]`,
+		"too many points":   `[{"step":1000000000,"size":1,"points":[{"time":"2023-11-14T22:13:20Z"},{"time":"2023-11-14T22:13:21Z"}],"head":0}]`,
+		"sum without count": `[{"step":1000000000,"size":1,"points":[],"head":0,"sums":{"cpu":1}}]`,
+		"null archive":      `[null]`,
+	} {
+		path := filepath.Join(t.TempDir(), "rrd.json")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenRRD(path); err == nil {
+			t.Errorf("%s: OpenRRD() succeeded, want an error", name)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package sysstats
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrWarmingUp is returned by the first call of the stateful rate APIs
+// (Sampler, ProcessWatcher, the built-in collectors of a Registry), which
+// only takes the baseline sample the next calls calculate the rates from.
+var ErrWarmingUp = errors.New("The first sample is only the baseline of the rates")
+
+// Sampler takes snapshots of the system and remembers the previous one, so
+// every call to Sample returns the rates since the previous call without
+// sleeping. SampleCpu, SampleNet, SampleDisk and SampleProc do the same with
+// the raw samples of a single collector. Their first call returns
+// ErrWarmingUp, unless a warm-up is set (see WarmUp).
+type Sampler struct {
+	mu       sync.Mutex
+	previous *Snapshot
+	ewma     *EWMA
+	warmUp   time.Duration
+
+	// Previous raw samples of SampleCpu, SampleNet, SampleDisk and
+	// SampleProc
+	cpu  lastSample[CpusRawStats]
+	net  lastSample[NetRawStats]
+	disk lastSample[[]DiskRawStats]
+	proc lastSample[ProcRawStats]
+}
+
+// lastSample is the previous raw sample of a collector.
+type lastSample[R any] struct {
+	sample R
+	ok     bool
+}
+
+// NewSampler returns a new Sampler.
+func NewSampler() *Sampler {
+	return &Sampler{}
+}
+
+// WarmUp makes the first call of every sampling method wait for d after
+// taking the baseline sample and return the rates over d, instead of
+// returning ErrWarmingUp. 0 disables the warm-up again.
+func (s *Sampler) WarmUp(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.warmUp = d
+}
+
+// warmUpThen waits for the warm-up and samples again, or returns
+// ErrWarmingUp if there's no warm-up.
+func warmUpThen[A any](d time.Duration, sample func() (A, error)) (a A, err error) {
+	if d <= 0 {
+		return a, ErrWarmingUp
+	}
+	time.Sleep(d)
+
+	return sample()
+}
+
+// Sample takes a snapshot of the system and returns the rates since the
+// previous call. The first call only takes the baseline snapshot and returns
+// ErrWarmingUp (see WarmUp). When some collectors fail the partial snapshot
+// is still kept as the baseline of the next call, and the comparison is
+// returned without their families along with the error (see
+// FailedCollectors).
+func (s *Sampler) Sample() (comparison Comparison, err error) {
+	snapshot, snapshotErr := getSnapshot()
+	if snapshotErr != nil && !isPartialSnapshot(snapshotErr) {
+		return Comparison{}, snapshotErr
+	}
+
+	s.mu.Lock()
+	previous := s.previous
+	s.previous = &snapshot
+	if previous == nil {
+		warmUp := s.warmUp
+		s.mu.Unlock()
+		return warmUpThen(warmUp, s.Sample)
+	}
+	defer s.mu.Unlock()
+
+	comparison, err = compare(*previous, snapshot)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	if s.ewma != nil {
+		metrics := snapshot.Metrics()
+		for name, value := range comparison.Metrics() {
+			metrics[name] = value
+		}
+		s.ewma.Update(snapshot.Time, metrics)
+	}
+
+	return comparison, snapshotErr
+}
+
+// SampleCpu takes a raw sample of the CPUs and returns the % CPU utilization
+// since the previous call. The first call only takes the baseline sample and
+// returns ErrWarmingUp (see WarmUp). The CPU, network, disk and processes samples are
+// independent of each other and of Sample.
+func (s *Sampler) SampleCpu() (CpusAvgStats, error) {
+	return sampleSince(s, &s.cpu, getCpuRawStats, getCpuAvgStats)
+}
+
+// SampleNet takes a raw sample of the network interfaces and returns the
+// network traffic since the previous call (see SampleCpu).
+func (s *Sampler) SampleNet() (NetAvgStats, error) {
+	return sampleSince(s, &s.net, getNetRawStats, getNetAvgStats)
+}
+
+// SampleDisk takes a raw sample of the disks and returns the IO averages
+// since the previous call (see SampleCpu).
+func (s *Sampler) SampleDisk() ([]DiskAvgStats, error) {
+	return sampleSince(s, &s.disk, getDiskRawStats, getDiskAvgStats)
+}
+
+// SampleProc takes a raw sample of the processes stats and returns the
+// averages since the previous call (see SampleCpu).
+func (s *Sampler) SampleProc() (ProcAvgStats, error) {
+	return sampleSince(s, &s.proc, getProcRawStats, getProcAvgStats)
+}
+
+// sampleSince takes a raw sample, replaces the previous one of the sampler
+// with it and returns the average between them. When there isn't a previous
+// sample it waits for the warm-up and samples again, or returns
+// ErrWarmingUp.
+func sampleSince[R any, A any](s *Sampler, last *lastSample[R], raw func() (R, error), avg func(R, R) (A, error)) (a A, err error) {
+	sample, err := raw()
+	if err != nil {
+		return a, err
+	}
+
+	s.mu.Lock()
+	previous := *last
+	*last = lastSample[R]{sample: sample, ok: true}
+	warmUp := s.warmUp
+	s.mu.Unlock()
+	if !previous.ok {
+		return warmUpThen(warmUp, func() (A, error) {
+			return sampleSince(s, last, raw, avg)
+		})
+	}
+
+	return avg(previous.sample, sample)
+}
+
+// Smooth attaches an EWMA with the given half-life to the sampler. Every
+// sample updates the smoothed value of all the gauges and rates (see
+// Snapshot.Metrics and Comparison.Metrics).
+func (s *Sampler) Smooth(halfLife time.Duration) *EWMA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ewma = NewEWMA(halfLife)
+	return s.ewma
+}
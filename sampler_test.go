@@ -0,0 +1,47 @@
+package sysstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// failingCollector is a custom collector that always fails.
+type failingCollector struct{}
+
+func (failingCollector) Name() string { return `failing` }
+
+func (failingCollector) Collect(ctx context.Context) (Metrics, error) {
+	return nil, errors.New("failing collector")
+}
+
+func TestSamplerSamplePartialSnapshot(t *testing.T) {
+	if err := DefaultRegistry().Register(failingCollector{}); err != nil {
+		t.Fatal(err)
+	}
+	defer DefaultRegistry().Unregister(`failing`)
+
+	s := NewSampler()
+	if _, err := s.Sample(); !errors.Is(err, ErrWarmingUp) {
+		t.Fatalf("first Sample() error = %v, want ErrWarmingUp", err)
+	}
+	if s.previous == nil {
+		t.Fatal("the partial snapshot wasn't kept as the baseline")
+	}
+
+	comparison, err := s.Sample()
+	if !isPartialSnapshot(err) {
+		t.Fatalf("Sample() error = %v, want a partial snapshot error", err)
+	}
+	failed := false
+	for _, name := range FailedCollectors(err) {
+		failed = failed || name == `failing`
+	}
+	if !failed {
+		t.Errorf("FailedCollectors(%v) doesn't have the failing collector", err)
+	}
+	if comparison.To.IsZero() || !comparison.To.After(comparison.From) {
+		t.Errorf("Sample() comparison from %v to %v, want the comparison of the partial snapshots",
+			comparison.From, comparison.To)
+	}
+}
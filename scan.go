@@ -0,0 +1,61 @@
+package sysstats
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// scanBufferSize is the initial size of the buffers of the scanners of the
+// collectors, big enough for the longest lines of /proc.
+const scanBufferSize = 64 * 1024
+
+// scanBuffers are the buffers reused by the scanners of the collectors, so
+// sampling every second doesn't allocate a new one every time.
+var scanBuffers = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, scanBufferSize)
+		return &buffer
+	},
+}
+
+// newStatsScanner returns a line scanner of r using a reused buffer, which is
+// given back by calling release once the scanner isn't used anymore.
+func newStatsScanner(r io.Reader) (scanner *bufio.Scanner, release func()) {
+	buffer := scanBuffers.Get().(*[]byte)
+	scanner = bufio.NewScanner(r)
+	scanner.Buffer(*buffer, bufio.MaxScanTokenSize)
+	scanner.Split(bufio.ScanLines)
+
+	return scanner, func() { scanBuffers.Put(buffer) }
+}
+
+// deviceFilter keeps only the network interfaces and disks whose names match
+// the regexps. A nil regexp keeps all the devices.
+type deviceFilter struct {
+	ifaces *regexp.Regexp
+	disks  *regexp.Regexp
+}
+
+// field returns the nth (0 based) space separated field of s without
+// splitting the rest, or "" if there are less fields.
+func field(s string, n int) string {
+	for i := 0; ; i++ {
+		start := 0
+		for start < len(s) && (s[start] == ' ' || s[start] == '\t') {
+			start++
+		}
+		end := start
+		for end < len(s) && s[end] != ' ' && s[end] != '\t' {
+			end++
+		}
+		if start == end {
+			return ""
+		}
+		if i == n {
+			return s[start:end]
+		}
+		s = s[end:]
+	}
+}
@@ -0,0 +1,140 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchedRawStats represents the scheduler raw stats of a process (all its
+// threads).
+type SchedRawStats struct {
+	Pid        int    `json:"pid"`                        // Process ID
+	RunTime    uint64 `json:"runtime" unit:"ns"`          // Nanoseconds spent on the CPU
+	WaitTime   uint64 `json:"waittime" unit:"ns"`         // Nanoseconds spent waiting on a runqueue
+	Timeslices uint64 `json:"timeslices"`                 // # of timeslices run on a CPU
+	SampleTime int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// SchedAvgStats represents the scheduling delay of a process between 2
+// samples.
+type SchedAvgStats struct {
+	Pid        int     `json:"pid"`                  // Process ID
+	RunTime    float64 `json:"runtime" unit:"s/s"`   // Seconds spent on the CPU per second
+	WaitTime   float64 `json:"waittime" unit:"s/s"`  // Seconds spent waiting on a runqueue per second (scheduling delay)
+	Timeslices float64 `json:"timeslices" unit:"/s"` // # of timeslices per second
+	AvgWait    float64 `json:"avgwait" unit:"ms"`    // Mean wait on a runqueue per timeslice in milliseconds
+}
+
+// getSchedRawStats gets the scheduler raw stats of a process adding the ones
+// of all its threads from the files /proc/[pid]/task/[tid]/schedstat, which
+// have the following format (it requires CONFIG_SCHEDSTATS):
+//   12027445 474130 47
+func getSchedRawStats(pid int) (schedRawStats SchedRawStats, err error) {
+	tasks, err := fs.Glob(getStatsFS(), statsPath(path.Join("/proc", strconv.Itoa(pid), "task/[0-9]*/schedstat")))
+	if err != nil {
+		return SchedRawStats{}, err
+	}
+	if len(tasks) == 0 {
+		return SchedRawStats{}, errors.New("The process " + strconv.Itoa(pid) + " doesn't exist")
+	}
+
+	schedRawStats = SchedRawStats{Pid: pid}
+	for _, task := range tasks {
+		content, err := readStatsFile(task)
+		if err != nil {
+			// The thread exited after listing it
+			continue
+		}
+		fields := strings.Fields(string(content))
+		if len(fields) < 3 {
+			return SchedRawStats{}, errors.New("Couldn't parse " + task + " because there aren't 3 fields")
+		}
+		values := []*uint64{&schedRawStats.RunTime, &schedRawStats.WaitTime, &schedRawStats.Timeslices}
+		for i, value := range values {
+			stat, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return SchedRawStats{}, err
+			}
+			*value += stat
+		}
+	}
+	schedRawStats.SampleTime = time.Now().UnixNano()
+
+	return schedRawStats, nil
+}
+
+// getSchedAvgStats calculates the scheduling delay of a process between 2
+// samples. The threads that exited between the samples can make the
+// counters go backwards, their deltas are 0.
+func getSchedAvgStats(firstSample SchedRawStats, secondSample SchedRawStats) (schedAvgStats SchedAvgStats, err error) {
+	if firstSample.Pid != secondSample.Pid {
+		return SchedAvgStats{}, errors.New("The samples are from different processes")
+	}
+
+	schedAvgStats = SchedAvgStats{Pid: secondSample.Pid}
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	if timeDelta <= 0 {
+		return schedAvgStats, nil
+	}
+
+	delta := func(a uint64, b uint64) float64 {
+		if b < a {
+			return 0
+		}
+		return float64(b - a)
+	}
+	runTime := delta(firstSample.RunTime, secondSample.RunTime)
+	waitTime := delta(firstSample.WaitTime, secondSample.WaitTime)
+	timeslices := delta(firstSample.Timeslices, secondSample.Timeslices)
+
+	schedAvgStats.RunTime = time.Duration(runTime).Seconds() / timeDelta
+	schedAvgStats.WaitTime = time.Duration(waitTime).Seconds() / timeDelta
+	schedAvgStats.Timeslices = timeslices / timeDelta
+	if timeslices > 0 {
+		schedAvgStats.AvgWait = waitTime / timeslices / float64(time.Millisecond)
+	}
+
+	return schedAvgStats, nil
+}
+
+// getSchedStatsOver returns the scheduling delay of several processes
+// between 2 samples taken d apart. The processes that don't exist in both
+// samples are skipped.
+func getSchedStatsOver(pids []int, d time.Duration) (schedsAvgStats map[int]SchedAvgStats, err error) {
+	raw := func() (map[int]SchedRawStats, error) {
+		samples := map[int]SchedRawStats{}
+		for _, pid := range pids {
+			schedRawStats, err := getSchedRawStats(pid)
+			if err != nil {
+				logDebug("skipped process", "pid", pid, "error", err)
+				continue
+			}
+			samples[pid] = schedRawStats
+		}
+		return samples, nil
+	}
+
+	avg := func(firstSamples map[int]SchedRawStats, secondSamples map[int]SchedRawStats) (map[int]SchedAvgStats, error) {
+		schedsAvgStats := map[int]SchedAvgStats{}
+		for pid, secondSample := range secondSamples {
+			firstSample, ok := firstSamples[pid]
+			if !ok {
+				continue
+			}
+			schedAvgStats, err := getSchedAvgStats(firstSample, secondSample)
+			if err != nil {
+				return nil, err
+			}
+			schedsAvgStats[pid] = schedAvgStats
+		}
+		return schedsAvgStats, nil
+	}
+
+	return sampleOver(d, raw, avg)
+}
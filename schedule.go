@@ -0,0 +1,70 @@
+package sysstats
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// schedule runs a function periodically.
+//
+// When align is true the runs are aligned to the wall clock boundaries of the
+// interval (e.g. :00 of every minute for a 1 minute interval), so the samples
+// of a fleet of hosts are taken at the same time. Every run is delayed by a
+// random duration in [0, jitter) to avoid all the hosts hitting a central
+// endpoint at the same moment. The time spent running the function is
+// compensated so the effective interval stays constant; if a run takes longer
+// than the interval the missed runs are skipped.
+type schedule struct {
+	interval time.Duration
+	align    bool
+	jitter   time.Duration
+}
+
+// first returns the time of the first run.
+func (s schedule) first(now time.Time) time.Time {
+	if !s.align {
+		return now
+	}
+
+	return now.Truncate(s.interval).Add(s.interval)
+}
+
+// next returns the time of the run after the one scheduled at previous,
+// skipping the runs that have already been missed.
+func (s schedule) next(previous time.Time, now time.Time) time.Time {
+	next := previous.Add(s.interval)
+	if next.Before(now) {
+		missed := now.Sub(next) / s.interval
+		next = next.Add((missed + 1) * s.interval)
+	}
+
+	return next
+}
+
+// run calls fn at every scheduled time until the context is cancelled.
+func (s schedule) run(ctx context.Context, fn func()) error {
+	if s.interval <= 0 {
+		s.interval = time.Minute
+	}
+
+	scheduled := s.first(time.Now())
+	for {
+		at := scheduled
+		if s.jitter > 0 {
+			at = at.Add(time.Duration(rand.Int63n(int64(s.jitter))))
+		}
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		fn()
+
+		scheduled = s.next(scheduled, time.Now())
+	}
+}
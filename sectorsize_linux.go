@@ -0,0 +1,130 @@
+// +build linux
+
+package sysstats
+
+import (
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// diskstatsSectorSize is the unit of the sectors of /proc/diskstats, which
+// the kernel always counts in 512 bytes whatever the block size of the
+// device.
+const diskstatsSectorSize = 512
+
+// blockSizes represents the block sizes of a device, 0 if it has no queue.
+type blockSizes struct {
+	major    int // Device number, which changes when the device is replaced
+	minor    int
+	logical  uint64
+	physical uint64
+}
+
+// sectorSizes holds the sector sizes forced with SetSectorSize and the block
+// sizes read from sysfs, which don't change while the device is present.
+var sectorSizes = struct {
+	sync.Mutex
+	forced map[string]uint64
+	blocks map[string]blockSizes
+}{forced: map[string]uint64{}, blocks: map[string]blockSizes{}}
+
+// SetSectorSize forces the size in bytes of the sectors of the disk, used to
+// convert the sectors read and written to bytes. By default the sectors are
+// 512 bytes, the unit of /proc/diskstats, whatever the logical block size of
+// the device; it only needs to be forced for the virtual disks and drivers
+// that don't follow it. A size of 0 restores the default.
+func SetSectorSize(disk string, size uint64) {
+	sectorSizes.Lock()
+	defer sectorSizes.Unlock()
+
+	if size == 0 {
+		delete(sectorSizes.forced, disk)
+		return
+	}
+	sectorSizes.forced[disk] = size
+}
+
+// sectorSize returns the size in bytes of the sectors of the disk.
+func sectorSize(disk string) uint64 {
+	sectorSizes.Lock()
+	defer sectorSizes.Unlock()
+
+	if size, ok := sectorSizes.forced[disk]; ok {
+		return size
+	}
+
+	return diskstatsSectorSize
+}
+
+// getBlockSizes returns the logical and physical block sizes of a device from
+// the files /sys/block/[name]/queue/{logical,physical}_block_size of the
+// stats file system (see SetStatsFS). The partitions have no queue, so the
+// one of their disk (/sys/block/[disk]/[name]) is read. They are 0 if the
+// device has no queue (e.g. it disappeared). The sizes are cached by device
+// number, also when there's no queue, so sysfs isn't searched every sample.
+func getBlockSizes(name string, major int, minor int) (logical uint64, physical uint64) {
+	sectorSizes.Lock()
+	sizes, ok := sectorSizes.blocks[name]
+	sectorSizes.Unlock()
+	if ok && sizes.major == major && sizes.minor == minor {
+		return sizes.logical, sizes.physical
+	}
+
+	sizes = blockSizes{major: major, minor: minor}
+	queues := []string{path.Join("/sys/block", name, "queue")}
+	if partitions, err := fs.Glob(getStatsFS(), statsPath(path.Join("/sys/block/*", name))); err == nil && len(partitions) > 0 {
+		queues = append(queues, path.Join("/", path.Dir(partitions[0]), "queue"))
+	}
+	for _, queue := range queues {
+		sizes.logical = readBlockSize(path.Join(queue, "logical_block_size"))
+		sizes.physical = readBlockSize(path.Join(queue, "physical_block_size"))
+		if sizes.logical > 0 {
+			break
+		}
+	}
+	if sizes.logical == 0 {
+		sizes.physical = 0
+	}
+
+	sectorSizes.Lock()
+	sectorSizes.blocks[name] = sizes
+	sectorSizes.Unlock()
+
+	return sizes.logical, sizes.physical
+}
+
+// forgetBlockSizes drops the cached block sizes of the devices that aren't
+// present anymore.
+func forgetBlockSizes(present map[string]bool) {
+	sectorSizes.Lock()
+	defer sectorSizes.Unlock()
+
+	for name := range sectorSizes.blocks {
+		if !present[name] {
+			delete(sectorSizes.blocks, name)
+		}
+	}
+}
+
+// readBlockSize reads a block size file of sysfs, or returns 0 if it can't.
+func readBlockSize(file string) uint64 {
+	content, err := readStatsFile(file)
+	if err != nil {
+		return 0
+	}
+	size, _ := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+
+	return size
+}
+
+// sectorBytes returns the sectors of the sample in bytes.
+func (d DiskRawStats) sectorBytes(sectors uint64) uint64 {
+	if d.SectorSize == 0 {
+		return sectors * diskstatsSectorSize
+	}
+
+	return sectors * d.SectorSize
+}
@@ -0,0 +1,481 @@
+package sysstats
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerConfig represents the configuration of a hardened stats server that
+// can be exposed on untrusted networks.
+type ServerConfig struct {
+	Addr         string        // Address to listen on, e.g. ":9100"
+	TLSConfig    *tls.Config   // Base TLS config (optional)
+	CertFile     string        // Server certificate, enables TLS
+	KeyFile      string        // Server private key
+	ClientCAFile string        // CA used to verify client certificates, enables mTLS
+	Tokens       []string      // Accepted bearer tokens, none means no token auth
+	RateLimit    float64       // Max requests per second per client IP and endpoint, 0 means no limit
+	Burst        int           // Max burst of requests per client IP and endpoint (default 1)
+	Families     []string      // Allowlist of stat families exposed (cpu, mem,...), none means all
+	CacheTTL     time.Duration // Time the snapshot is reused between requests, 0 means no cache
+	Identity     *Identity     // Identity attached to the snapshots served
+}
+
+// NewServer returns an *http.Server serving handler with the authentication,
+// rate limiting and allowlist of the config. If handler is nil the Handler
+// with the families and cache of the config is served. Use
+// ListenAndServeTLS("", "") when TLS is configured.
+func NewServer(handler http.Handler, config ServerConfig) (server *http.Server, err error) {
+	if handler == nil {
+		handler = Handler(HandlerOptions{Families: config.Families, CacheTTL: config.CacheTTL, Identity: config.Identity})
+	}
+
+	// The middlewares are applied from the innermost to the outermost, so
+	// the requests are rate limited before checking their token
+	if len(config.Families) > 0 {
+		handler = allowFamilies(handler, config.Families)
+	}
+	if len(config.Tokens) > 0 {
+		handler = tokenAuth(handler, config.Tokens)
+	}
+	if config.RateLimit > 0 {
+		handler = rateLimit(handler, config.RateLimit, config.Burst)
+	}
+
+	server = &http.Server{
+		Addr:              config.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if config.TLSConfig == nil && config.CertFile == "" && config.ClientCAFile == "" {
+		return server, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLSConfig != nil {
+		tlsConfig = config.TLSConfig.Clone()
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	}
+	if config.ClientCAFile != "" {
+		content, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, errors.New("Couldn't parse any certificate from " + config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	server.TLSConfig = tlsConfig
+
+	return server, nil
+}
+
+// DefaultMaxRatesInterval is the max interval of the rates requests of a
+// Handler by default.
+const DefaultMaxRatesInterval = time.Minute
+
+// rateFamilies are the families of a Snapshot with rates (see Comparison).
+var rateFamilies = []string{`cpu`, `net`, `disk`, `proc`, `mem`}
+
+// HandlerOptions represents the options of the handler returned by Handler.
+type HandlerOptions struct {
+	Families    []string      // Families served (cpu, mem,...), none means all
+	CacheTTL    time.Duration // Time the snapshot is reused between requests, 0 means no cache
+	MaxInterval time.Duration // Max interval of the rates requests (DefaultMaxRatesInterval by default)
+	Identity    *Identity     // Identity attached to the snapshots served (the host name of the system by default)
+}
+
+// Handler returns an http.Handler serving the snapshot of the system as JSON
+// at / and each of its families at /<family> (e.g. /cpu, /mem, /disk, /net).
+// With the query param interval (a duration like 5s, or seconds) the rates
+// between 2 snapshots taken interval apart are served instead (see
+// Comparison), e.g.:
+//   /cpu?interval=5s  % CPU usage of every CPU over 5 seconds
+//   /?interval=10     rates of cpu, net, disk and proc, and the mem change
+// Only cpu, net, disk, proc and mem have rates, and with CacheTTL their
+// snapshots are also the cached ones (taken at least CacheTTL apart). When
+// some collectors fail the families of the others are still served, with
+// the failed ones in the header X-Sysstats-Failed. The handler can be served
+// with authentication and rate limiting passing it to NewServer.
+func Handler(opts HandlerOptions) http.Handler {
+	collect := getSnapshot
+	collectRates := func(collectors []string) (Snapshot, error) {
+		return collectSnapshot(collectors, deviceFilter{})
+	}
+	if opts.CacheTTL > 0 {
+		cache := NewCache(opts.CacheTTL, getSnapshot)
+		collect = cache.Get
+		collectRates = func([]string) (Snapshot, error) { return cache.Get() }
+	}
+	snapshots := snapshotHandler(collect, opts.Families, opts.Identity)
+	rates := ratesHandler(collectRates, opts.CacheTTL, opts.Families, opts.MaxInterval)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has(`interval`) {
+			rates.ServeHTTP(w, r)
+			return
+		}
+		snapshots.ServeHTTP(w, r)
+	})
+}
+
+// ratesHandler serves the rates between 2 snapshots taken the interval of
+// the query apart at / and each of the families with rates at /<family>.
+// Only the given families are served (all of them if none is given). The
+// snapshots are taken with collect, passing it the collectors of the
+// families requested. When they are cached for cacheTTL the snapshots are
+// taken at least cacheTTL apart, so they aren't the same cached one (the
+// actual interval is in the response). When some collectors fail the rates
+// are served without their families, like in snapshotHandler.
+func ratesHandler(collect func(collectors []string) (Snapshot, error), cacheTTL time.Duration, families []string,
+	maxInterval time.Duration) http.Handler {
+	if maxInterval <= 0 {
+		maxInterval = DefaultMaxRatesInterval
+	}
+	allowed := map[string]bool{}
+	for _, family := range families {
+		allowed[family] = true
+	}
+	served := []string{}
+	for _, family := range rateFamilies {
+		if len(allowed) == 0 || allowed[family] {
+			served = append(served, family)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		interval, err := parseInterval(r.URL.Query().Get(`interval`))
+		if err != nil || interval <= 0 || interval > maxInterval {
+			http.Error(w, "The interval must be a duration up to "+maxInterval.String(), http.StatusBadRequest)
+			return
+		}
+		if interval < cacheTTL {
+			interval = cacheTTL
+		}
+
+		collectors := served
+		family := strings.Trim(r.URL.Path, "/")
+		if family != "" {
+			collectors = nil
+			for _, name := range served {
+				if name == family {
+					collectors = []string{family}
+				}
+			}
+			if collectors == nil {
+				http.NotFound(w, r)
+				return
+			}
+		}
+
+		// The partial snapshots are compared, leaving out the families of
+		// the collectors that failed in any of them
+		var collectorErrs []error
+		raw := func() (Snapshot, error) {
+			snapshot, err := collect(collectors)
+			if isPartialSnapshot(err) {
+				if joined, ok := err.(interface{ Unwrap() []error }); ok {
+					collectorErrs = append(collectorErrs, joined.Unwrap()...)
+				} else {
+					collectorErrs = append(collectorErrs, err)
+				}
+				return snapshot, nil
+			}
+			return snapshot, err
+		}
+		comparison, err := sampleOverContext(r.Context(), interval, raw, compare)
+		if err != nil {
+			if r.Context().Err() == nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		err = errors.Join(collectorErrs...)
+		if partialSnapshot(w, r, err) {
+			return
+		}
+		failed := map[string]bool{}
+		for _, name := range FailedCollectors(err) {
+			failed[name] = true
+		}
+
+		// Marshal the comparison to a map so the families can be selected by
+		// name
+		content, err := json.Marshal(comparison)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fields := map[string]json.RawMessage{}
+		err = json.Unmarshal(content, &fields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var body interface{} = fields
+		if family != "" {
+			body = fields[family]
+		} else {
+			selected := map[string]json.RawMessage{`from`: fields[`from`], `to`: fields[`to`], `interval`: fields[`interval`]}
+			for _, family := range collectors {
+				if !failed[family] {
+					selected[family] = fields[family]
+				}
+			}
+			body = selected
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+}
+
+// parseInterval parses the interval of a rates request, a duration (e.g. 5s
+// or 500ms) or a number of seconds.
+func parseInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err == nil {
+		return interval, nil
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// snapshotHandler serves the snapshot of the system at / and each of its
+// families at /<family>. Only the given families are served (all of them if
+// none is given). The snapshot is taken with collect, and the identity is
+// attached to it. When some collectors fail the snapshot is served without
+// their families, which are listed in the header X-Sysstats-Failed, and the
+// requests of those families get a 503.
+func snapshotHandler(collect func() (Snapshot, error), families []string, identity *Identity) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := collect()
+		if err != nil && !isPartialSnapshot(err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if snapshot.Identity == nil {
+			snapshot.Identity = identity
+		}
+		if partialSnapshot(w, r, err) {
+			return
+		}
+
+		// Marshal the snapshot to a map so the families can be selected by name
+		content, err := json.Marshal(snapshot)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		fields := map[string]json.RawMessage{}
+		err = json.Unmarshal(content, &fields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(families) > 0 {
+			allowed := map[string]json.RawMessage{`time`: fields[`time`]}
+			if field, ok := fields[`identity`]; ok {
+				allowed[`identity`] = field
+			}
+			for _, family := range families {
+				if field, ok := fields[family]; ok {
+					allowed[family] = field
+				}
+			}
+			fields = allowed
+		}
+
+		// The whole snapshot carries the wire format version and the host
+		// like an Envelope, so it can be told apart from older versions
+		fields[`version`], _ = json.Marshal(SchemaVersion)
+		fields[`host`], _ = json.Marshal(snapshot.Identity.Host())
+
+		var body interface{} = fields
+		if family := strings.Trim(r.URL.Path, "/"); family != "" {
+			field, ok := fields[family]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			body = field
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+}
+
+// partialSnapshot lists the collectors that failed in the error of a
+// snapshot (or of both snapshots of the rates) in the header
+// X-Sysstats-Failed, and answers with a 503 (returning true) when the family
+// requested is one of them.
+func partialSnapshot(w http.ResponseWriter, r *http.Request, err error) bool {
+	failed := []string{}
+	listed := map[string]bool{}
+	for _, name := range FailedCollectors(err) {
+		if !listed[name] {
+			listed[name] = true
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) == 0 {
+		return false
+	}
+
+	logWarn("partial snapshot", "failed", failed, "error", err)
+	w.Header().Set("X-Sysstats-Failed", strings.Join(failed, ","))
+	family := strings.Trim(r.URL.Path, "/")
+	for _, name := range failed {
+		if name == family {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowFamilies rejects the requests to families that are not in the
+// allowlist. The family is the first element of the request path.
+func allowFamilies(next http.Handler, families []string) http.Handler {
+	allowed := map[string]bool{}
+	for _, family := range families {
+		allowed[family] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		family := requestFamily(r)
+		if family != "" && !allowed[family] {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenAuth rejects the requests without one of the accepted bearer tokens.
+func tokenAuth(next http.Handler, tokens []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") {
+			token := []byte(strings.TrimPrefix(auth, "Bearer "))
+			for _, accepted := range tokens {
+				if subtle.ConstantTimeCompare(token, []byte(accepted)) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sysstats"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// requestFamily returns the family of a request, the first element of its
+// path ("" for the whole snapshot at /).
+func requestFamily(r *http.Request) string {
+	return strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", 2)[0]
+}
+
+// tokenBucket is a token bucket rate limiter.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// maxRateLimitClients is the max number of buckets (client and endpoint)
+// kept by rateLimit.
+const maxRateLimitClients = 1024
+
+// rateLimit rejects the requests exceeding the given rate (requests per
+// second) per client IP and endpoint, so a client polling one family doesn't
+// exhaust its requests to the others. The endpoint is the family of the
+// request path (see requestFamily). The buckets of the idle clients (full
+// again) are forgotten, and when there are still too many buckets the least
+// recently used one is, so the memory used is bounded.
+func rateLimit(next http.Handler, rate float64, burst int) http.Handler {
+	if burst <= 0 {
+		burst = 1
+	}
+	var mu sync.Mutex
+	buckets := map[string]*tokenBucket{}
+	// Time it takes for an empty bucket to be full again
+	refill := time.Duration(float64(burst) / rate * float64(time.Second))
+	lastSweep := time.Now()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		key := client + " " + requestFamily(r)
+		now := time.Now()
+
+		mu.Lock()
+		if now.Sub(lastSweep) >= refill || len(buckets) >= maxRateLimitClients {
+			for k, b := range buckets {
+				if b.tokens+now.Sub(b.last).Seconds()*rate >= float64(burst) {
+					delete(buckets, k)
+				}
+			}
+			lastSweep = now
+		}
+		bucket, ok := buckets[key]
+		if !ok {
+			if len(buckets) >= maxRateLimitClients {
+				oldest := ""
+				for k, b := range buckets {
+					if oldest == "" || b.last.Before(buckets[oldest].last) {
+						oldest = k
+					}
+				}
+				delete(buckets, oldest)
+			}
+			bucket = &tokenBucket{tokens: float64(burst), last: now}
+			buckets[key] = bucket
+		}
+		bucket.tokens += now.Sub(bucket.last).Seconds() * rate
+		if bucket.tokens > float64(burst) {
+			bucket.tokens = float64(burst)
+		}
+		bucket.last = now
+		allowed := bucket.tokens >= 1
+		if allowed {
+			bucket.tokens--
+		}
+		mu.Unlock()
+
+		if !allowed {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,170 @@
+package sysstats
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitPerClient(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := rateLimit(ok, 0.001, 2)
+
+	// The paths of the same endpoint share the bucket of the client
+	codes := []int{}
+	for _, path := range []string{"/cpu", "/cpu/", "//cpu?x=1"} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	want := []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("status codes = %v, want %v", codes, want)
+		}
+	}
+
+	// Other clients and other endpoints have their own bucket
+	for _, request := range []struct{ addr, path string }{
+		{"192.0.2.2:1234", "/cpu"},
+		{"192.0.2.1:1234", "/mem"},
+		{"192.0.2.1:1234", "/"},
+	} {
+		r := httptest.NewRequest("GET", request.path, nil)
+		r.RemoteAddr = request.addr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("status code of %s %s = %d, want %d", request.addr, request.path, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestRateLimitManyClients(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := rateLimit(ok, 0.001, 1)
+
+	first := "198.51.100.1:1"
+	for i := 0; i <= maxRateLimitClients; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = first
+		if i > 0 {
+			r.RemoteAddr = "10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256) + ":1"
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// The bucket of the least recently seen client was forgotten
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = first
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("status code of the evicted client = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestNewServerRateLimitsBeforeAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	server, err := NewServer(ok, ServerConfig{Tokens: []string{"secret"}, RateLimit: 0.001, Burst: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := []int{}
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		r.Header.Set("Authorization", "Bearer wrong")
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusUnauthorized || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("status codes = %v, want [401 429]", codes)
+	}
+}
+
+func TestRatesHandlerPartialSnapshot(t *testing.T) {
+	calls := 0
+	collect := func([]string) (Snapshot, error) {
+		calls++
+		snapshot := Snapshot{Time: time.Now(), Cpu: CpusRawStats{`cpu0`: CpuRawStats{CpuUser: uint64(calls), CpuTotal: uint64(calls)}}}
+		return snapshot, errors.Join(&CollectorError{Collector: `mem`, Err: errors.New("mem failed")})
+	}
+	handler := ratesHandler(collect, 0, nil, 0)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/?interval=1ms", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	if calls != 2 {
+		t.Errorf("collect called %d times, want 2", calls)
+	}
+	if got := w.Header().Get("X-Sysstats-Failed"); got != `mem` {
+		t.Errorf("X-Sysstats-Failed = %q, want mem", got)
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(w.Body.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields[`cpu`]; !ok {
+		t.Errorf("body = %s, want the cpu rates", w.Body)
+	}
+	if _, ok := fields[`mem`]; ok {
+		t.Errorf("body = %s, want the mem family of the failed collector left out", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/mem?interval=1ms", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status code of the failed family = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestRatesHandlerCacheTTL(t *testing.T) {
+	cache := NewCache(20*time.Millisecond, func() (Snapshot, error) {
+		return Snapshot{Time: time.Now()}, nil
+	})
+	handler := ratesHandler(func([]string) (Snapshot, error) { return cache.Get() }, 20*time.Millisecond, nil, 0)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/?interval=1ms", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var comparison struct {
+		Interval float64 `json:"interval"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &comparison); err != nil {
+		t.Fatal(err)
+	}
+	if comparison.Interval < 0.02 {
+		t.Errorf("interval = %v, want at least the TTL of the cache", comparison.Interval)
+	}
+}
+
+func TestSnapshotHandlerIdentity(t *testing.T) {
+	collect := func() (Snapshot, error) { return Snapshot{Time: time.Now()}, nil }
+	handler := snapshotHandler(collect, []string{`mem`}, &Identity{Hostname: "web1", Role: "web"})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	var body struct {
+		Host     string    `json:"host"`
+		Identity *Identity `json:"identity"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Host != "web1" || body.Identity == nil || body.Identity.Role != "web" {
+		t.Errorf("body = %s, want the configured identity", w.Body)
+	}
+}
@@ -0,0 +1,302 @@
+package sysstats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sink is the interface implemented by the outputs the snapshots taken by a
+// Monitor are written to.
+//
+// Built-in sinks: JSONSink (e.g. stdout), FileSink, StatsDSink, InfluxSink,
+// Recorder and Agent (HTTP).
+type Sink interface {
+	Write(snapshot Snapshot) error
+}
+
+// JSONSink writes every snapshot as one line of JSON (wrapped in an Envelope)
+// to an io.Writer.
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink returns a sink writing JSON lines to w, e.g. os.Stdout.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// Write writes the snapshot as one line of JSON.
+func (s *JSONSink) Write(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.NewEncoder(s.w).Encode(NewEnvelope(snapshot))
+}
+
+// FileSink appends every snapshot as one line of JSON to a file.
+type FileSink struct {
+	JSONSink
+	file *os.File
+}
+
+// NewFileSink opens (or creates) the file at path for appending snapshots.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{JSONSink: JSONSink{w: file}, file: file}, nil
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// Write sends the snapshot to the agent URL, spooling it if it can't be
+// delivered, so the Agent can be used as an HTTP sink.
+func (a *Agent) Write(snapshot Snapshot) error {
+	return a.deliver(context.Background(), snapshot)
+}
+
+// ratesSink keeps the previous snapshot to calculate the rates of the sinks
+// exporting flattened metrics, and maps them with the Mapper (if set).
+type ratesSink struct {
+	Mapper *MetricMapper // Renames, drops and labels the metrics
+
+	mu       sync.Mutex
+	previous *Snapshot
+}
+
+// metrics returns the gauges of the snapshot plus the rates since the
+// previous one (if any).
+func (s *ratesSink) metrics(snapshot Snapshot) map[string]float64 {
+	s.mu.Lock()
+	previous := s.previous
+	s.previous = &snapshot
+	s.mu.Unlock()
+
+	metrics := snapshot.Metrics()
+	if previous != nil {
+		comparison, err := compare(*previous, snapshot)
+		if err == nil {
+			for name, value := range comparison.Metrics() {
+				metrics[name] = value
+			}
+		}
+	}
+
+	if s.Mapper != nil {
+		metrics = s.Mapper.Map(metrics)
+	}
+
+	return metrics
+}
+
+// labels returns the labels of the identity of the snapshot plus the static
+// labels of the mapper, which override them.
+func (s *ratesSink) labels(snapshot Snapshot) map[string]string {
+	labels := snapshot.Identity.AllLabels()
+	if s.Mapper != nil {
+		for key, value := range s.Mapper.Labels() {
+			labels[key] = value
+		}
+	}
+
+	return labels
+}
+
+// StatsDSink sends the metrics of every snapshot as StatsD gauges over UDP.
+type StatsDSink struct {
+	ratesSink
+	conn   net.Conn
+	prefix string
+}
+
+// NewStatsDSink returns a sink sending gauges to the StatsD server at addr
+// (e.g. "localhost:8125"). The prefix is prepended to every metric name.
+func NewStatsDSink(addr string, prefix string) (*StatsDSink, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatsDSink{conn: conn, prefix: prefix}, nil
+}
+
+// Write sends the metrics of the snapshot as StatsD gauges. The labels of the
+// identity of the snapshot and the mapper are sent as DogStatsD tags. The
+// metrics are batched in packets smaller than 1432 bytes to avoid
+// fragmentation.
+func (s *StatsDSink) Write(snapshot Snapshot) error {
+	metrics := s.metrics(snapshot)
+
+	tags := ""
+	labels := s.labels(snapshot)
+	for _, key := range sortedKeys(labels) {
+		if tags == "" {
+			tags = "|#"
+		} else {
+			tags += ","
+		}
+		tags += key + ":" + labels[key]
+	}
+
+	var packet strings.Builder
+	for _, name := range sortedMetricNames(metrics) {
+		line := s.prefix + name + ":" + strconv.FormatFloat(metrics[name], 'f', -1, 64) + "|g" + tags + "\n"
+		if packet.Len()+len(line) > 1432 && packet.Len() > 0 {
+			_, err := s.conn.Write([]byte(packet.String()))
+			if err != nil {
+				return err
+			}
+			packet.Reset()
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		_, err := s.conn.Write([]byte(packet.String()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the connection to the StatsD server.
+func (s *StatsDSink) Close() error {
+	return s.conn.Close()
+}
+
+// String returns a description of the sink for error messages.
+func (s *StatsDSink) String() string {
+	return fmt.Sprintf("statsd(%s)", s.conn.RemoteAddr())
+}
+
+// InfluxSink writes the metrics of every snapshot to an io.Writer in InfluxDB
+// line protocol, with the same measurements and tags as InfluxEncoder. The
+// metric "cpu.cpu0.user" is written as the field "user" of the measurement
+// "cpu" with the tag cpu=cpu0 (see influxElementTags). The labels of the
+// identity of the snapshot and the mapper are added as tags.
+type InfluxSink struct {
+	ratesSink
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// influxElementTags are the tags of the element of the metrics of every
+// family, as in the placeholders of their names (see MetricInfo). The
+// elements of the other families (e.g. the custom collectors) are tagged
+// name.
+var influxElementTags = map[string]string{
+	`cpu`:          `cpu`,
+	`net`:          `iface`,
+	`disk`:         `disk`,
+	`diskusage`:    `mount`,
+	`reachability`: `target`,
+	`sysstats`:     `collector`,
+}
+
+// NewInfluxSink returns a sink writing line protocol to w (e.g. a file or a
+// connection to Telegraf).
+func NewInfluxSink(w io.Writer) *InfluxSink {
+	return &InfluxSink{w: w}
+}
+
+// Write writes the metrics of the snapshot in line protocol.
+func (s *InfluxSink) Write(snapshot Snapshot) error {
+	metrics := s.metrics(snapshot)
+	labels := s.labels(snapshot)
+
+	// Group the fields by measurement and element
+	type point struct {
+		measurement string
+		tags        map[string]string
+		fields      map[string]float64
+	}
+	points := map[string]*point{}
+	keys := make([]string, 0)
+	for _, metric := range sortedMetricNames(metrics) {
+		measurement, name, field := splitMetricName(metric)
+		key := measurement + " " + name
+		p, ok := points[key]
+		if !ok {
+			p = &point{measurement: measurement, tags: map[string]string{}, fields: map[string]float64{}}
+			for label, value := range labels {
+				p.tags[label] = value
+			}
+			if name != "" {
+				tag, value := influxElementTag(measurement, name)
+				p.tags[tag] = value
+			}
+			points[key] = p
+			keys = append(keys, key)
+		}
+		p.fields[field] = metrics[metric]
+	}
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		p := points[key]
+		writeInfluxLine(&buf, p.measurement, p.tags, p.fields, snapshot.Time)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// influxElementTag returns the tag of the element of a metric of the family,
+// e.g. cpu.cpu0.user is tagged cpu=cpu0 and sysstats.sinks.influx.written
+// sink=influx.
+func influxElementTag(family string, name string) (tag string, value string) {
+	if family == `sysstats` && strings.HasPrefix(name, `sinks.`) {
+		return `sink`, strings.TrimPrefix(name, `sinks.`)
+	}
+	if tag, ok := influxElementTags[family]; ok {
+		return tag, name
+	}
+
+	return `name`, name
+}
+
+// splitMetricName splits a flattened metric name into the family, the name of
+// the element (cpu, interface, disk,...) and the stat, e.g.:
+//   cpu.cpu0.user -> cpu, cpu0, user
+//   mem.memused   -> mem, "", memused
+func splitMetricName(metric string) (family string, name string, stat string) {
+	first := strings.Index(metric, ".")
+	last := strings.LastIndex(metric, ".")
+	if first < 0 {
+		return metric, "", metric
+	}
+	if first == last {
+		return metric[:first], "", metric[first+1:]
+	}
+
+	return metric[:first], metric[first+1 : last], metric[last+1:]
+}
+
+// sortedMetricNames returns the names of the metrics sorted alphabetically.
+func sortedMetricNames(metrics map[string]float64) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
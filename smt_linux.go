@@ -0,0 +1,133 @@
+// +build linux
+
+package sysstats
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultSmtYield is the extra throughput an SMT sibling adds to a core, as a
+// fraction of the throughput of the core running a single thread.
+const DefaultSmtYield = 0.3
+
+// SmtInfo represents the simultaneous multithreading (hyperthreading) state
+// of a linux system.
+type SmtInfo struct {
+	Enabled        bool       `json:"enabled"`        // True if SMT is active
+	Control        string     `json:"control"`        // SMT control (on, off, forceoff, notsupported, notimplemented)
+	ThreadsPerCore int        `json:"threadspercore"` // Max # of logical CPUs per core
+	Siblings       [][]string `json:"siblings"`       // Groups of sibling CPUs (cpu0, cpu4,...) of the cores with several threads
+}
+
+// EffectiveUtilization represents the CPU utilization measured against the
+// physical core capacity. A core running one busy thread is reported as 1 /
+// (1 + SmtYield) busy instead of 50%, since its sibling can only add
+// SmtYield of throughput.
+type EffectiveUtilization struct {
+	SmtYield  float64            `json:"smtyield"`           // Extra throughput of an SMT sibling
+	Logical   float64            `json:"logical" unit:"%"`   // Mean busy % of the logical CPUs
+	Effective float64            `json:"effective" unit:"%"` // Busy % of the physical core capacity
+	Cores     map[string]float64 `json:"cores" unit:"%"`     // Busy % of the capacity of every core (package0-core0,...)
+}
+
+// getSmtInfo gets the SMT state from /sys/devices/system/cpu/smt and the SMT
+// siblings from the CPU topology.
+func getSmtInfo() (smtInfo SmtInfo, err error) {
+	topology, err := getTopology()
+	if err != nil {
+		return SmtInfo{}, err
+	}
+
+	smtInfo = SmtInfo{}
+	smtInfo.Control = readSysfsString("/sys/devices/system/cpu/smt/control")
+
+	seen := map[string]bool{}
+	for _, cpuTopology := range topology {
+		if len(cpuTopology.Siblings) > smtInfo.ThreadsPerCore {
+			smtInfo.ThreadsPerCore = len(cpuTopology.Siblings)
+		}
+		if len(cpuTopology.Siblings) < 2 || seen[cpuTopology.coreName()] {
+			continue
+		}
+		seen[cpuTopology.coreName()] = true
+
+		siblings := make([]string, 0, len(cpuTopology.Siblings))
+		for _, cpu := range cpuTopology.Siblings {
+			siblings = append(siblings, "cpu"+strconv.Itoa(cpu))
+		}
+		smtInfo.Siblings = append(smtInfo.Siblings, siblings)
+	}
+	sort.Slice(smtInfo.Siblings, func(i, j int) bool {
+		return cpuNumber(smtInfo.Siblings[i][0]) < cpuNumber(smtInfo.Siblings[j][0])
+	})
+
+	if active := readSysfsString("/sys/devices/system/cpu/smt/active"); active != "" {
+		smtInfo.Enabled = active == "1"
+	} else {
+		smtInfo.Enabled = smtInfo.ThreadsPerCore > 1
+	}
+
+	return smtInfo, nil
+}
+
+// getEffectiveUtilization calculates the utilization of the physical core
+// capacity from the CPU stats. The busiest thread of a core counts as a whole
+// core and every other thread as smtYield of a core (DefaultSmtYield if
+// smtYield is 0), against a capacity of 1 + smtYield per extra thread.
+func getEffectiveUtilization(cpusAvgStats CpusAvgStats, smtYield float64) (effectiveUtilization EffectiveUtilization, err error) {
+	topology, err := getTopology()
+	if err != nil {
+		return EffectiveUtilization{}, err
+	}
+	if smtYield <= 0 {
+		smtYield = DefaultSmtYield
+	}
+
+	effectiveUtilization = EffectiveUtilization{}
+	effectiveUtilization.SmtYield = smtYield
+	effectiveUtilization.Cores = map[string]float64{}
+
+	cores := map[string][]float64{}
+	logical := 0
+	for cpuName, cpuStats := range cpusAvgStats {
+		cpuTopology, ok := topology[cpuName]
+		if !ok {
+			continue
+		}
+		cores[cpuTopology.coreName()] = append(cores[cpuTopology.coreName()], cpuStats[`total`])
+		effectiveUtilization.Logical += cpuStats[`total`]
+		logical++
+	}
+	if logical == 0 {
+		return effectiveUtilization, nil
+	}
+	effectiveUtilization.Logical /= float64(logical)
+
+	for coreName, busy := range cores {
+		sort.Float64s(busy)
+		busiest := busy[len(busy)-1]
+		demand := busiest
+		for _, threadBusy := range busy[:len(busy)-1] {
+			demand += smtYield * threadBusy
+		}
+		capacity := 1 + smtYield*float64(len(busy)-1)
+		effectiveUtilization.Cores[coreName] = demand / capacity
+		effectiveUtilization.Effective += demand / capacity
+	}
+	effectiveUtilization.Effective /= float64(len(cores))
+
+	return effectiveUtilization, nil
+}
+
+// cpuNumber returns the number of a CPU name (3 for cpu3), -1 for the
+// aggregated cpu line.
+func cpuNumber(cpuName string) int {
+	number, err := strconv.Atoi(strings.TrimPrefix(cpuName, "cpu"))
+	if err != nil {
+		return -1
+	}
+
+	return number
+}
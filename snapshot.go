@@ -0,0 +1,341 @@
+package sysstats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCollectorConcurrency is the # of collectors of a snapshot that run
+// at the same time by default.
+const DefaultCollectorConcurrency = 4
+
+// collectorConcurrency is the # of collectors of a snapshot that run at the
+// same time, 0 for the default.
+var collectorConcurrency atomic.Int64
+
+// SetCollectorConcurrency sets the # of collectors of the snapshots that run
+// at the same time, so the reads of /proc and /sys don't add up to the
+// latency of every snapshot. It's DefaultCollectorConcurrency by default; 1
+// runs them one after the other, and 0 or less restores the default. The
+// bytes parsed by every collector (see GetCollectorsHealth) are only exact
+// when they run one after the other.
+func SetCollectorConcurrency(n int) {
+	if n <= 0 {
+		n = 0
+	}
+	collectorConcurrency.Store(int64(n))
+}
+
+// getCollectorConcurrency returns the # of collectors that run at the same
+// time.
+func getCollectorConcurrency() int {
+	if n := collectorConcurrency.Load(); n > 0 {
+		return int(n)
+	}
+
+	return DefaultCollectorConcurrency
+}
+
+// CollectorError is the error of one of the collectors of a snapshot. The
+// error of a snapshot joins the ones of all the collectors that failed (see
+// FailedCollectors).
+type CollectorError struct {
+	Collector string // Name of the collector (loadavg, mem, cpu,...)
+	Err       error  // Error of the collector
+}
+
+// Error returns the name of the collector and its error.
+func (e *CollectorError) Error() string {
+	return "Collector " + e.Collector + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the collector.
+func (e *CollectorError) Unwrap() error {
+	return e.Err
+}
+
+// FailedCollectors returns the names of the collectors that failed in the
+// error of a snapshot, whose families are missing from it.
+func FailedCollectors(err error) (collectors []string) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	for _, err := range errs {
+		collectorError := &CollectorError{}
+		if errors.As(err, &collectorError) {
+			collectors = append(collectors, collectorError.Collector)
+		}
+	}
+
+	return collectors
+}
+
+// isPartialSnapshot reports whether the error of a snapshot only comes from
+// some of its collectors, so the snapshot still has the families of the
+// others and can be used.
+func isPartialSnapshot(err error) bool {
+	return err != nil && len(FailedCollectors(err)) > 0
+}
+
+// Snapshot represents all the raw statistics of the system taken at the same
+// moment. It can be persisted (see Save and LoadSnapshot) so the averages can
+// be calculated later against a freshly taken sample.
+type Snapshot struct {
+	Time      time.Time      `json:"time"`      // Time when the snapshot was taken
+	LoadAvg   LoadAvg        `json:"loadavg"`   // Load average
+	Mem       MemStats       `json:"mem"`       // Memory stats
+	Cpu       CpusRawStats   `json:"cpu"`       // CPUs raw stats
+	Net       NetRawStats    `json:"net"`       // Network interfaces raw stats
+	Disk      []DiskRawStats `json:"disk"`      // Disks IO raw stats
+	DiskUsage []DiskUsage    `json:"diskusage"` // File systems disk usage
+	Sock      SockStats      `json:"sock"`      // Socket stats
+	File      FileStats      `json:"file"`      // File descriptor stats
+	Proc      ProcRawStats   `json:"proc"`      // Processes raw stats
+	// Round trip time and loss of the probes (see SetReachabilityTargets)
+	Reachability []ReachabilityStats `json:"reachability,omitempty"`
+	// Time when every collector sampled its family (see Skew)
+	Times map[string]time.Time `json:"times,omitempty"`
+	// Health of the collectors when the snapshot was taken
+	Health map[string]CollectorHealth `json:"health,omitempty"`
+	// Queues of the sinks, set by the Monitor (see Monitor.SinkStats)
+	Sinks []SinkStats `json:"sinks,omitempty"`
+	// Identity of the host, set by the Monitor and the Agent
+	Identity *Identity `json:"identity,omitempty"`
+	// Metrics of the custom collectors registered (see DefaultRegistry)
+	Custom Metrics `json:"custom,omitempty"`
+}
+
+// snapshotCollector fills one of the families of a Snapshot.
+type snapshotCollector struct {
+	name    string
+	collect func(snapshot *Snapshot, filter deviceFilter) error
+}
+
+// snapshotCollectors are the collectors of a Snapshot in the order they run.
+var snapshotCollectors = []snapshotCollector{
+	{`loadavg`, func(s *Snapshot, _ deviceFilter) (err error) { s.LoadAvg, err = getLoadAvg(); return err }},
+	{`mem`, func(s *Snapshot, _ deviceFilter) (err error) { s.Mem, err = getMemStats(); return err }},
+	{`cpu`, func(s *Snapshot, _ deviceFilter) (err error) { s.Cpu, err = getCpuRawStats(); return err }},
+	{`net`, func(s *Snapshot, f deviceFilter) (err error) {
+		s.Net, err = getNetRawStatsMatching(f.ifaces)
+		return err
+	}},
+	{`disk`, func(s *Snapshot, f deviceFilter) (err error) {
+		s.Disk, err = getDiskRawStatsMatching(f.disks)
+		return err
+	}},
+	{`diskusage`, func(s *Snapshot, _ deviceFilter) (err error) { s.DiskUsage, err = getDiskUsage(); return err }},
+	{`sock`, func(s *Snapshot, _ deviceFilter) (err error) { s.Sock, err = getSockStats(); return err }},
+	{`file`, func(s *Snapshot, _ deviceFilter) (err error) { s.File, err = getFileStats(); return err }},
+	{`proc`, func(s *Snapshot, _ deviceFilter) (err error) { s.Proc, err = getProcRawStats(); return err }},
+	{`reachability`, func(s *Snapshot, _ deviceFilter) (err error) {
+		s.Reachability, err = getReachabilityStats()
+		return err
+	}},
+}
+
+// SnapshotCollectors returns the names of the collectors of a Snapshot,
+// which are also the JSON names of its families.
+func SnapshotCollectors() []string {
+	names := make([]string, 0, len(snapshotCollectors))
+	for _, collector := range snapshotCollectors {
+		names = append(names, collector.name)
+	}
+
+	return names
+}
+
+// getSnapshot takes a sample of all the raw statistics of the system.
+func getSnapshot() (snapshot Snapshot, err error) {
+	return collectSnapshot(nil, deviceFilter{})
+}
+
+// collectSnapshot takes a sample of the raw statistics of the given
+// collectors of the default registry (see DefaultRegistry), keeping only the
+// devices that pass the filter.
+func collectSnapshot(collectors []string, filter deviceFilter) (snapshot Snapshot, err error) {
+	return defaultRegistry.collectSnapshot(collectors, filter)
+}
+
+// collectSnapshot takes a sample of the raw statistics of the given
+// collectors of the registry (all of them if none is given), keeping only
+// the devices that pass the filter. The disabled collectors are left out,
+// and the metrics of the custom ones are in Snapshot.Custom. The collectors
+// run concurrently (see SetCollectorConcurrency). A collector that fails or
+// times out (see SetCollectorTimeout) doesn't stop the others: the snapshot
+// is returned without its family along with the errors of all the failed
+// collectors (see CollectorError). The families the OS doesn't have (see
+// ErrNotSupported) are left out without error.
+func (r *Registry) collectSnapshot(names []string, filter deviceFilter) (snapshot Snapshot, err error) {
+	requested := map[string]bool{}
+	for _, name := range names {
+		requested[name] = true
+	}
+
+	r.mu.Lock()
+	for name := range requested {
+		if r.index(name) < 0 {
+			r.mu.Unlock()
+			return Snapshot{}, errors.New("Unknown collector " + name)
+		}
+	}
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, collector := range r.collectors {
+		if !r.disabled[collector.Name()] && (len(requested) == 0 || requested[collector.Name()]) {
+			collectors = append(collectors, collector)
+		}
+	}
+	r.mu.Unlock()
+
+	snapshot = Snapshot{}
+	snapshot.Time = time.Now()
+
+	// Every collector fills its own snapshot, so one that times out and
+	// finishes later doesn't write to the snapshot returned
+	collected := make([]Snapshot, len(collectors))
+	custom := make([]Metrics, len(collectors))
+	times := make([]time.Time, len(collectors))
+	errs := make([]error, len(collectors))
+	notSupported := make([]bool, len(collectors))
+	workers := make(chan struct{}, getCollectorConcurrency())
+	wg := sync.WaitGroup{}
+	for i, collector := range collectors {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			name := collector.Name()
+			start := time.Now()
+			bytes := bytesParsed.Load()
+			family := Snapshot{}
+			var metrics Metrics
+			err := withTimeout(name, func() (err error) {
+				if builtin, ok := collector.(*builtinCollector); ok {
+					return builtin.collector.collect(&family, filter)
+				}
+				metrics, err = collector.Collect(context.Background())
+				return err
+			})
+			if errors.Is(err, ErrNotSupported) {
+				notSupported[i] = true
+				return
+			}
+			recordCollection(name, start, bytesParsed.Load()-bytes, err)
+			if err != nil {
+				errs[i] = &CollectorError{Collector: name, Err: err}
+				return
+			}
+			collected[i] = family
+			custom[i] = metrics
+			// The middle of the collection is the best estimate of when
+			// the family was read
+			times[i] = start.Add(time.Since(start) / 2)
+			logSlow(name, start)
+		}()
+	}
+	wg.Wait()
+
+	snapshot.Times = map[string]time.Time{}
+	for i, collector := range collectors {
+		if errs[i] != nil || notSupported[i] {
+			continue
+		}
+		if _, ok := collector.(*builtinCollector); ok {
+			snapshot.setFamily(collector.Name(), collected[i])
+		} else {
+			if snapshot.Custom == nil {
+				snapshot.Custom = Metrics{}
+			}
+			for key, value := range custom[i] {
+				snapshot.Custom[key] = value
+			}
+		}
+		snapshot.Times[collector.Name()] = times[i]
+	}
+	snapshot.Health = getCollectorsHealth()
+
+	return snapshot, errors.Join(errs...)
+}
+
+// setFamily copies the family of the snapshot whose JSON name is the name of
+// its collector from another snapshot.
+func (s *Snapshot) setFamily(name string, from Snapshot) {
+	to := reflect.ValueOf(s).Elem()
+	for i := 0; i < to.NumField(); i++ {
+		tag, _, _ := strings.Cut(to.Type().Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			to.Field(i).Set(reflect.ValueOf(from).Field(i))
+			return
+		}
+	}
+}
+
+// Skew returns the time between the first and the last family of the
+// snapshot sampled, which tells how aligned the stats of the different
+// collectors (CPU, disks, network,...) are. It's 0 for the snapshots taken
+// by older versions of the package, which didn't record the times.
+func (s Snapshot) Skew() time.Duration {
+	var first, last time.Time
+	for _, t := range s.Times {
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	return last.Sub(first)
+}
+
+// FilterDevices removes the network interfaces and disks (IO stats) whose
+// names don't match the given regexps. A nil regexp keeps all the devices.
+func (s *Snapshot) FilterDevices(ifaces *regexp.Regexp, disks *regexp.Regexp) {
+	if ifaces != nil {
+		for ifaceName := range s.Net {
+			if !ifaces.MatchString(ifaceName) {
+				delete(s.Net, ifaceName)
+			}
+		}
+	}
+
+	if disks != nil {
+		diskRawStatsArr := make([]DiskRawStats, 0, len(s.Disk))
+		for _, diskRawStats := range s.Disk {
+			if disks.MatchString(diskRawStats.Name) {
+				diskRawStatsArr = append(diskRawStatsArr, diskRawStats)
+			}
+		}
+		s.Disk = diskRawStatsArr
+	}
+}
+
+// Save writes the snapshot to w encoded as JSON wrapped in an Envelope.
+func (s Snapshot) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(NewEnvelope(s))
+}
+
+// LoadSnapshot reads a snapshot previously written with Snapshot.Save from r,
+// by this or an older version of the package.
+func LoadSnapshot(r io.Reader) (snapshot Snapshot, err error) {
+	content := json.RawMessage{}
+	err = json.NewDecoder(r).Decode(&content)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return UnmarshalSnapshot(content)
+}
@@ -0,0 +1,78 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snmp6RawStats represents the IPv6 protocol counters of a linux system.
+//
+// Map keys:
+//   Name - Name of the counter as it is on /proc/net/snmp6 (Ip6InReceives,
+//          Ip6OutForwDatagrams, Icmp6InErrors, Udp6InDatagrams,...).
+//   time - Time when the sample was taken (Unix time in nanoseconds).
+type Snmp6RawStats map[string]uint64
+
+// Snmp6AvgStats represents the IPv6 protocol counters per second of a linux
+// system.
+//
+// Map keys:
+//   Name - Name of the counter as it is on /proc/net/snmp6.
+type Snmp6AvgStats map[string]float64
+
+// getSnmp6RawStats gets the IPv6 protocol counters of a linux system from the
+// file /proc/net/snmp6, which has the following format:
+//   Ip6InReceives                       5
+//   Ip6InHdrErrors                      0
+// The file doesn't exist if IPv6 is disabled.
+func getSnmp6RawStats() (snmp6RawStats Snmp6RawStats, err error) {
+	file, err := os.Open("/proc/net/snmp6")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	snmp6RawStats = Snmp6RawStats{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Split(bufio.ScanLines)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		snmp6RawStats[fields[0]] = value
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	snmp6RawStats[`time`] = uint64(time.Now().UnixNano())
+
+	return snmp6RawStats, nil
+}
+
+// getSnmp6AvgStats calculates the IPv6 protocol counters per second between 2
+// samples.
+func getSnmp6AvgStats(firstSample Snmp6RawStats, secondSample Snmp6RawStats) (snmp6AvgStats Snmp6AvgStats, err error) {
+	timeDelta := time.Duration(secondSample[`time`] - firstSample[`time`])
+
+	snmp6AvgStats = RateOver(firstSample, secondSample, timeDelta)
+	delete(snmp6AvgStats, `time`)
+
+	return snmp6AvgStats, nil
+}
+
+// getSnmp6StatsOver returns the IPv6 protocol counters per second between 2
+// samples taken d apart.
+func getSnmp6StatsOver(d time.Duration) (snmp6AvgStats Snmp6AvgStats, err error) {
+	return sampleOver(d, getSnmp6RawStats, getSnmp6AvgStats)
+}
@@ -4,7 +4,6 @@ package sysstats
 
 import (
 	"bufio"
-	"os"
 	"regexp"
 	"strconv"
 )
@@ -23,7 +22,7 @@ type SockStats struct {
 // getSockStats gets the socket statistics of a linux system from the file
 // /proc/net/sockstat
 func getSockStats() (sockStats SockStats, err error) {
-	file, err := os.Open("/proc/net/sockstat")
+	file, err := openStatsFile("/proc/net/sockstat")
 	if err != nil {
 		return SockStats{}, err
 	}
@@ -0,0 +1,40 @@
+package sysstats
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// statsFS is the file system the collectors read /proc and /sys from.
+var statsFS atomic.Pointer[fs.FS]
+
+// SetStatsFS sets the file system the core collectors (stat, meminfo,
+// net/dev, diskstats, sockstat, loadavg,...) read the files of /proc and
+// /sys from, with the paths relative to its root (e.g. proc/stat). It can be
+// os.DirFS("/host") to read the stats of the host from a container, or an
+// fstest.MapFS with captured files to test the parsers. A nil fsys restores
+// the root file system.
+func SetStatsFS(fsys fs.FS) {
+	if fsys == nil {
+		statsFS.Store(nil)
+		return
+	}
+	statsFS.Store(&fsys)
+}
+
+// getStatsFS returns the file system the collectors read from.
+func getStatsFS() fs.FS {
+	if fsys := statsFS.Load(); fsys != nil {
+		return *fsys
+	}
+
+	return os.DirFS("/")
+}
+
+// statsPath returns the path of a file of the collectors (/proc/stat,...) in
+// the file system they read from.
+func statsPath(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
@@ -0,0 +1,122 @@
+// +build linux
+
+package sysstats
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// statsFSKernels are the directories of testdata/statsfs with the /proc and
+// /sys files of every kernel version.
+var statsFSKernels = []string{"linux-3.10", "linux-4.15", "linux-5.4", "linux-6.18"}
+
+// statsFSParsers are the parsers of the core files, by the name of their
+// golden file. The times of the samples are cleared so the output only
+// depends on the files.
+var statsFSParsers = map[string]func() (interface{}, error){
+	"diskstats": func() (interface{}, error) {
+		diskRawStatsArr, err := getDiskRawStats()
+		for i := range diskRawStatsArr {
+			diskRawStatsArr[i].SampleTime = 0
+		}
+		return diskRawStatsArr, err
+	},
+	"meminfo": func() (interface{}, error) { return getMemStats() },
+	"netdev": func() (interface{}, error) {
+		netRawStats, err := getNetRawStats()
+		for _, rawStats := range netRawStats {
+			delete(rawStats, StatTime)
+		}
+		return netRawStats, err
+	},
+	"stat":     func() (interface{}, error) { return getCpuRawStats() },
+	"sockstat": func() (interface{}, error) { return getSockStats() },
+	"loadavg":  func() (interface{}, error) { return getLoadAvg() },
+	"layout":   func() (interface{}, error) { return getKernelLayout(), nil },
+}
+
+// useStatsFS makes the collectors read the files of a directory of testdata
+// until the end of the test.
+func useStatsFS(t testing.TB, dir string) {
+	resetBlockSizes := func() {
+		sectorSizes.Lock()
+		sectorSizes.blocks = map[string]blockSizes{}
+		sectorSizes.Unlock()
+	}
+	SetStatsFS(os.DirFS(dir))
+	resetBlockSizes()
+	t.Cleanup(func() {
+		SetStatsFS(nil)
+		resetBlockSizes()
+	})
+}
+
+func TestStatsFSGolden(t *testing.T) {
+	for _, kernel := range statsFSKernels {
+		for name, parse := range statsFSParsers {
+			t.Run(kernel+"/"+name, func(t *testing.T) {
+				dir := filepath.Join("testdata", "statsfs", kernel)
+				useStatsFS(t, dir)
+
+				stats, err := parse()
+				if err != nil {
+					t.Fatalf("parsing %s: %v", name, err)
+				}
+				got, err := json.MarshalIndent(stats, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, '\n')
+
+				golden := filepath.Join(dir, "golden", name+".json")
+				if *update {
+					if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err := os.WriteFile(golden, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("reading the golden file (run with -update to create it): %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s doesn't match %s:\n%s", name, golden, got)
+				}
+			})
+		}
+	}
+}
+
+func TestStatsFSMissingFile(t *testing.T) {
+	useStatsFS(t, t.TempDir())
+
+	if _, err := getCpuRawStats(); !os.IsNotExist(err) {
+		t.Errorf("getCpuRawStats() error = %v, want a not exist error", err)
+	}
+	if _, err := getLoadAvg(); !os.IsNotExist(err) {
+		t.Errorf("getLoadAvg() error = %v, want a not exist error", err)
+	}
+}
+
+func BenchmarkStatsFSParsers(b *testing.B) {
+	useStatsFS(b, filepath.Join("testdata", "statsfs", "linux-6.18"))
+	for name, parse := range statsFSParsers {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := parse(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
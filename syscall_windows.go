@@ -0,0 +1,28 @@
+// +build windows
+
+package sysstats
+
+import (
+	"syscall"
+)
+
+// The Win32 functions the windows collectors use. They are loaded lazily
+// from the system DLLs, so only the stdlib syscall package is needed.
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	iphlpapi = syscall.NewLazyDLL("iphlpapi.dll")
+	ntdll    = syscall.NewLazyDLL("ntdll.dll")
+
+	procGlobalMemoryStatusEx     = kernel32.NewProc("GlobalMemoryStatusEx")
+	procGetLogicalDriveStringsW  = kernel32.NewProc("GetLogicalDriveStringsW")
+	procGetDriveTypeW            = kernel32.NewProc("GetDriveTypeW")
+	procGetDiskFreeSpaceExW      = kernel32.NewProc("GetDiskFreeSpaceExW")
+	procGetVolumeInformationW    = kernel32.NewProc("GetVolumeInformationW")
+	procGetComputerNameExW       = kernel32.NewProc("GetComputerNameExW")
+	procGetNativeSystemInfo      = kernel32.NewProc("GetNativeSystemInfo")
+	procGetTickCount64           = kernel32.NewProc("GetTickCount64")
+	procGetIfTable2              = iphlpapi.NewProc("GetIfTable2")
+	procFreeMibTable             = iphlpapi.NewProc("FreeMibTable")
+	procNtQuerySystemInformation = ntdll.NewProc("NtQuerySystemInformation")
+	procRtlGetVersion            = ntdll.NewProc("RtlGetVersion")
+)
@@ -0,0 +1,83 @@
+// +build freebsd openbsd netbsd
+
+package sysstats
+
+import (
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SysInfo represents the BSD system info.
+type SysInfo struct {
+	Hostname  string  `json:"hostname"`
+	FQDN      string  `json:"fqdn"`
+	Domain    string  `json:"domain"`
+	OsType    string  `json:"ostype"`
+	OsRelease string  `json:"osrelease"`
+	OsVersion string  `json:"osversion"`
+	OsArch    string  `json:"osarch"`
+	Uptime    float64 `json:"uptime"`
+}
+
+// sysInfoSysctls are the sysctls of the system info, the same uname(3)
+// reads.
+var sysInfoSysctls = map[string]func(sysInfo *SysInfo) *string{
+	`kern.hostname`:   func(sysInfo *SysInfo) *string { return &sysInfo.Hostname },
+	`kern.domainname`: func(sysInfo *SysInfo) *string { return &sysInfo.Domain },
+	`kern.ostype`:     func(sysInfo *SysInfo) *string { return &sysInfo.OsType },
+	`kern.osrelease`:  func(sysInfo *SysInfo) *string { return &sysInfo.OsRelease },
+	`kern.version`:    func(sysInfo *SysInfo) *string { return &sysInfo.OsVersion },
+	`hw.machine`:      func(sysInfo *SysInfo) *string { return &sysInfo.OsArch },
+}
+
+// getSysInfo gets the system info of a BSD system from the kern.* and
+// hw.machine sysctls. The uptime is the time since kern.boottime.
+func getSysInfo() (sysInfo SysInfo, err error) {
+	sysInfo = SysInfo{}
+
+	for name, field := range sysInfoSysctls {
+		value, err := syscall.Sysctl(name)
+		if err != nil {
+			return SysInfo{}, os.NewSyscallError("sysctl "+name, err)
+		}
+		*field(&sysInfo) = strings.TrimSpace(value)
+	}
+
+	boottime, err := getBootTime()
+	if err != nil {
+		return SysInfo{}, err
+	}
+	sysInfo.Uptime = time.Since(boottime).Seconds()
+
+	fqdn, err := getFqdn(sysInfo.Hostname)
+	if err != nil {
+		return SysInfo{}, err
+	}
+	sysInfo.FQDN = fqdn
+
+	return sysInfo, nil
+}
+
+func getFqdn(hostname string) (fqdn string, err error) {
+	// Without external commands the canonical name of the hostname is
+	// resolved the same way hostname -f does
+	if !execAllowed() {
+		cname, err := net.LookupCNAME(hostname)
+		if err != nil {
+			return hostname, nil
+		}
+		return strings.TrimSuffix(cname, "."), nil
+	}
+
+	// Run `hostname -f` to get the FQDN
+	out, err := runCommand("hostname", "-f")
+	if err != nil {
+		return "", err
+	}
+
+	fqdn = strings.TrimSpace(string(out))
+	return fqdn, nil
+}
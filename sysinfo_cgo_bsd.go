@@ -0,0 +1,33 @@
+// +build freebsd openbsd netbsd
+
+package sysstats
+
+/*
+#include <sys/types.h>
+#include <sys/time.h>
+#include <sys/sysctl.h>
+
+// boottime gets the time the system was booted (kern.boottime).
+static int boottime(struct timeval *tv) {
+	int mib[2] = {CTL_KERN, KERN_BOOTTIME};
+	size_t len = sizeof(*tv);
+	return sysctl(mib, 2, tv, &len, NULL, 0);
+}
+*/
+import "C"
+
+import (
+	"os"
+	"time"
+)
+
+// getBootTime gets the time the system was booted from the sysctl
+// kern.boottime.
+func getBootTime() (boottime time.Time, err error) {
+	var tv C.struct_timeval
+	if ret, err := C.boottime(&tv); ret != 0 {
+		return time.Time{}, os.NewSyscallError("sysctl kern.boottime", err)
+	}
+
+	return time.Unix(int64(tv.tv_sec), int64(tv.tv_usec)*1000), nil
+}
@@ -5,9 +5,10 @@ package sysstats
 import (
 	"errors"
 	"io/ioutil"
-	"os/exec"
+	"net"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 // SysInfo represents the linux system info.
@@ -136,14 +137,18 @@ func getOsVersion() (osVersion string, err error) {
 }
 
 func getOsArch() (osArch string, err error) {
-	// Check `uname` path
-	uname, err := exec.LookPath("uname")
-	if err != nil {
-		return "", err
+	// Without external commands the uname syscall gives the same machine
+	if !execAllowed() {
+		utsname := syscall.Utsname{}
+		err := syscall.Uname(&utsname)
+		if err != nil {
+			return "", err
+		}
+		return utsnameString(utsname.Machine[:]), nil
 	}
 
 	// Run `uname -m` to get the OS architecture
-	out, err := exec.Command(uname, "-m").Output()
+	out, err := runCommand("uname", "-m")
 	if err != nil {
 		return "", err
 	}
@@ -172,14 +177,22 @@ func getUptime() (uptime float64, err error) {
 }
 
 func getFqdn() (fqdn string, err error) {
-	// Check `hostname` path
-	hostname, err := exec.LookPath("hostname")
-	if err != nil {
-		return "", err
+	// Without external commands the canonical name of the hostname is
+	// resolved the same way hostname -f does
+	if !execAllowed() {
+		hostname, err := getHostname()
+		if err != nil {
+			return "", err
+		}
+		cname, err := net.LookupCNAME(hostname)
+		if err != nil {
+			return hostname, nil
+		}
+		return strings.TrimSuffix(cname, "."), nil
 	}
 
 	// Run `hostname -f` to get the FQDN
-	out, err := exec.Command(hostname, "-f").Output()
+	out, err := runCommand("hostname", "-f")
 	if err != nil {
 		return "", err
 	}
@@ -187,3 +200,17 @@ func getFqdn() (fqdn string, err error) {
 	fqdn = strings.TrimSpace(string(out))
 	return fqdn, nil
 }
+
+// utsnameString returns the string of a field of syscall.Utsname, which is a
+// NUL terminated array of int8 or uint8 depending on the architecture.
+func utsnameString[T int8 | uint8](chars []T) string {
+	buffer := make([]byte, 0, len(chars))
+	for _, char := range chars {
+		if char == 0 {
+			break
+		}
+		buffer = append(buffer, byte(char))
+	}
+
+	return string(buffer)
+}
@@ -0,0 +1,113 @@
+// +build windows
+
+package sysstats
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// SysInfo represents the Windows system info.
+type SysInfo struct {
+	Hostname  string  `json:"hostname"`
+	FQDN      string  `json:"fqdn"`
+	Domain    string  `json:"domain"`
+	OsType    string  `json:"ostype"`
+	OsRelease string  `json:"osrelease"`
+	OsVersion string  `json:"osversion"`
+	OsArch    string  `json:"osarch"`
+	Uptime    float64 `json:"uptime"`
+}
+
+// The COMPUTER_NAME_FORMAT of GetComputerNameExW.
+const (
+	computerNameDnsHostname       = 1
+	computerNameDnsDomain         = 2
+	computerNameDnsFullyQualified = 3
+)
+
+// osVersionInfo is RTL_OSVERSIONINFOW.
+type osVersionInfo struct {
+	OSVersionInfoSize uint32
+	MajorVersion      uint32
+	MinorVersion      uint32
+	BuildNumber       uint32
+	PlatformId        uint32
+	CSDVersion        [128]uint16
+}
+
+// systemInfo is SYSTEM_INFO.
+type systemInfo struct {
+	ProcessorArchitecture     uint16
+	Reserved                  uint16
+	PageSize                  uint32
+	MinimumApplicationAddress uintptr
+	MaximumApplicationAddress uintptr
+	ActiveProcessorMask       uintptr
+	NumberOfProcessors        uint32
+	ProcessorType             uint32
+	AllocationGranularity     uint32
+	ProcessorLevel            uint16
+	ProcessorRevision         uint16
+}
+
+// processorArchitectures are the names of the PROCESSOR_ARCHITECTURE_* of
+// SYSTEM_INFO, the same uname -m prints.
+var processorArchitectures = map[uint16]string{
+	0:  `x86`,
+	5:  `arm`,
+	9:  `x86_64`,
+	12: `aarch64`,
+}
+
+// getSysInfo gets the system info of a Windows system. The release is the
+// version of the kernel (10.0) and the version is its build number.
+func getSysInfo() (sysInfo SysInfo, err error) {
+	sysInfo = SysInfo{}
+	sysInfo.OsType = `Windows_NT`
+
+	for format, field := range map[uintptr]*string{
+		computerNameDnsHostname:       &sysInfo.Hostname,
+		computerNameDnsDomain:         &sysInfo.Domain,
+		computerNameDnsFullyQualified: &sysInfo.FQDN,
+	} {
+		*field, err = getComputerName(format)
+		if err != nil {
+			return SysInfo{}, err
+		}
+	}
+
+	version := osVersionInfo{}
+	version.OSVersionInfoSize = uint32(unsafe.Sizeof(version))
+	procRtlGetVersion.Call(uintptr(unsafe.Pointer(&version)))
+	sysInfo.OsRelease = strconv.Itoa(int(version.MajorVersion)) + `.` + strconv.Itoa(int(version.MinorVersion))
+	sysInfo.OsVersion = strconv.Itoa(int(version.BuildNumber))
+
+	info := systemInfo{}
+	procGetNativeSystemInfo.Call(uintptr(unsafe.Pointer(&info)))
+	if arch, ok := processorArchitectures[info.ProcessorArchitecture]; ok {
+		sysInfo.OsArch = arch
+	} else {
+		sysInfo.OsArch = `unknown`
+	}
+
+	// Milliseconds since the system was started
+	ticks, _, _ := procGetTickCount64.Call()
+	sysInfo.Uptime = float64(ticks) / 1000
+
+	return sysInfo, nil
+}
+
+// getComputerName returns a name of the computer with GetComputerNameExW.
+func getComputerName(format uintptr) (name string, err error) {
+	buffer := make([]uint16, 256)
+	size := uint32(len(buffer))
+	ret, _, err := procGetComputerNameExW.Call(format, uintptr(unsafe.Pointer(&buffer[0])), uintptr(unsafe.Pointer(&size)))
+	if ret == 0 {
+		return "", os.NewSyscallError("GetComputerNameExW", err)
+	}
+
+	return syscall.UTF16ToString(buffer[:size]), nil
+}
@@ -0,0 +1,115 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TcpChurnRawStats represents the TCP connection counters of a linux system.
+type TcpChurnRawStats struct {
+	ActiveOpens  uint64 `json:"activeopens"`                // # of connections opened by the host since boot
+	PassiveOpens uint64 `json:"passiveopens"`               // # of connections accepted by the host since boot
+	AttemptFails uint64 `json:"attemptfails"`               // # of failed connection attempts since boot
+	EstabResets  uint64 `json:"estabresets"`                // # of established connections reset since boot
+	OutRsts      uint64 `json:"outrsts"`                    // # of segments sent with the RST flag since boot
+	CurrEstab    uint64 `json:"currestab"`                  // # of connections currently established
+	SampleTime   int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// TcpChurnAvgStats represents the TCP connection churn (connections opened
+// and torn down per second) of a linux system.
+type TcpChurnAvgStats struct {
+	ActiveOpens  float64 `json:"activeopens" unit:"/s"`  // # of connections opened by the host per second
+	PassiveOpens float64 `json:"passiveopens" unit:"/s"` // # of connections accepted by the host per second
+	AttemptFails float64 `json:"attemptfails" unit:"/s"` // # of failed connection attempts per second
+	EstabResets  float64 `json:"estabresets" unit:"/s"`  // # of established connections reset per second
+	OutRsts      float64 `json:"outrsts" unit:"/s"`      // # of segments sent with the RST flag per second
+	CurrEstab    uint64  `json:"currestab"`              // # of connections currently established
+}
+
+// getTcpChurnRawStats gets the TCP connection counters of a linux system from
+// the file /proc/net/snmp.
+func getTcpChurnRawStats() (tcpChurnRawStats TcpChurnRawStats, err error) {
+	counters, err := readSnmpCounters("/proc/net/snmp", "Tcp")
+	if err != nil {
+		return TcpChurnRawStats{}, err
+	}
+
+	tcpChurnRawStats = TcpChurnRawStats{}
+	tcpChurnRawStats.ActiveOpens = counters[`ActiveOpens`]
+	tcpChurnRawStats.PassiveOpens = counters[`PassiveOpens`]
+	tcpChurnRawStats.AttemptFails = counters[`AttemptFails`]
+	tcpChurnRawStats.EstabResets = counters[`EstabResets`]
+	tcpChurnRawStats.OutRsts = counters[`OutRsts`]
+	tcpChurnRawStats.CurrEstab = counters[`CurrEstab`]
+	tcpChurnRawStats.SampleTime = time.Now().UnixNano()
+
+	return tcpChurnRawStats, nil
+}
+
+// getTcpChurnAvgStats calculates the TCP connection churn between 2 samples.
+func getTcpChurnAvgStats(firstSample TcpChurnRawStats, secondSample TcpChurnRawStats) (tcpChurnAvgStats TcpChurnAvgStats, err error) {
+	tcpChurnAvgStats = TcpChurnAvgStats{}
+	tcpChurnAvgStats.CurrEstab = secondSample.CurrEstab
+
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	if timeDelta <= 0 {
+		return tcpChurnAvgStats, nil
+	}
+	tcpChurnAvgStats.ActiveOpens = float64(secondSample.ActiveOpens-firstSample.ActiveOpens) / timeDelta
+	tcpChurnAvgStats.PassiveOpens = float64(secondSample.PassiveOpens-firstSample.PassiveOpens) / timeDelta
+	tcpChurnAvgStats.AttemptFails = float64(secondSample.AttemptFails-firstSample.AttemptFails) / timeDelta
+	tcpChurnAvgStats.EstabResets = float64(secondSample.EstabResets-firstSample.EstabResets) / timeDelta
+	tcpChurnAvgStats.OutRsts = float64(secondSample.OutRsts-firstSample.OutRsts) / timeDelta
+
+	return tcpChurnAvgStats, nil
+}
+
+// getTcpChurnStatsOver returns the TCP connection churn between 2 samples
+// taken d apart.
+func getTcpChurnStatsOver(d time.Duration) (tcpChurnAvgStats TcpChurnAvgStats, err error) {
+	return sampleOver(d, getTcpChurnRawStats, getTcpChurnAvgStats)
+}
+
+// readSnmpCounters reads the counters of a protocol from the files
+// /proc/net/snmp and /proc/net/netstat, which have a line with the names of
+// the counters followed by a line with their values:
+//   Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens ...
+//   Tcp: 1 200 120000 -1 36 27 ...
+// The counters that aren't unsigned integers (MaxConn) are skipped.
+func readSnmpCounters(path string, protocol string) (counters map[string]uint64, err error) {
+	var names []string
+	err = scanLines(path, func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != protocol+":" {
+			return
+		}
+		if names == nil {
+			names = fields[1:]
+			return
+		}
+		counters = map[string]uint64{}
+		for i, field := range fields[1:] {
+			if i >= len(names) {
+				break
+			}
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				continue
+			}
+			counters[names[i]] = value
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if counters == nil {
+		return nil, errors.New("Couldn't find the " + protocol + " counters in " + path)
+	}
+
+	return counters, nil
+}
@@ -0,0 +1,153 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"io/fs"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThrashWeights represents the weights of the components of the memory
+// thrash score. A component with weight 0 is ignored.
+type ThrashWeights struct {
+	SwapIn   float64 `json:"swapin"`   // Weight of the pages swapped in
+	SwapOut  float64 `json:"swapout"`  // Weight of the pages swapped out
+	MajFault float64 `json:"majfault"` // Weight of the major page faults
+	Pressure float64 `json:"pressure"` // Weight of the memory pressure (PSI)
+}
+
+// DefaultThrashWeights are the weights used when none is given. Swapping in
+// and stalling on memory hurt the running tasks; swapping out alone can be
+// the kernel getting rid of cold pages.
+var DefaultThrashWeights = ThrashWeights{SwapIn: 0.3, SwapOut: 0.1, MajFault: 0.2, Pressure: 0.4}
+
+// Rates at which the swap and fault components of the score saturate.
+const (
+	thrashSwapScale  = 1000.0 // Pages swapped per second
+	thrashFaultScale = 1000.0 // Major faults per second
+)
+
+// ThrashRawStats represents the raw counters the memory thrash score is
+// calculated from.
+type ThrashRawStats struct {
+	SwapIn     uint64 `json:"swapin" unit:"pages"`        // # of pages swapped in since boot
+	SwapOut    uint64 `json:"swapout" unit:"pages"`       // # of pages swapped out since boot
+	MajFault   uint64 `json:"majfault"`                   // # of major page faults since boot
+	Pressure   bool   `json:"pressure"`                   // PSI is available (Linux 4.20 onward)
+	PsiSome    uint64 `json:"psisome" unit:"us"`          // Microseconds some task stalled on memory since boot
+	PsiFull    uint64 `json:"psifull" unit:"us"`          // Microseconds all the tasks stalled on memory since boot
+	SampleTime int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// ThrashStats represents the memory thrash of a linux system between 2
+// samples.
+type ThrashStats struct {
+	SwapIn   float64 `json:"swapin" unit:"pages/s"`  // # of pages swapped in per second
+	SwapOut  float64 `json:"swapout" unit:"pages/s"` // # of pages swapped out per second
+	MajFault float64 `json:"majfault" unit:"/s"`     // # of major page faults per second
+	PsiSome  float64 `json:"psisome" unit:"%"`       // % of time some task stalled on memory
+	PsiFull  float64 `json:"psifull" unit:"%"`       // % of time all the tasks stalled on memory
+	Score    float64 `json:"score"`                  // Memory thrash score (0-100)
+}
+
+// getThrashRawStats gets the swap and fault counters from the file
+// /proc/vmstat and the memory stall times from the file /proc/pressure/memory.
+func getThrashRawStats() (thrashRawStats ThrashRawStats, err error) {
+	thrashRawStats = ThrashRawStats{}
+	values := map[string]*uint64{
+		`pswpin`:     &thrashRawStats.SwapIn,
+		`pswpout`:    &thrashRawStats.SwapOut,
+		`pgmajfault`: &thrashRawStats.MajFault,
+	}
+	err = scanLines("/proc/vmstat", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return
+		}
+		if value, ok := values[fields[0]]; ok {
+			*value, _ = strconv.ParseUint(fields[1], 10, 64)
+		}
+	})
+	if err != nil {
+		return ThrashRawStats{}, err
+	}
+
+	// PSI is available from Linux 4.20 onward (see parsePressure)
+	content, err := readStatsFile("/proc/pressure/memory")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ThrashRawStats{}, err
+	}
+	if err == nil {
+		memoryPressure, err := parsePressure(string(content))
+		if err != nil {
+			return ThrashRawStats{}, err
+		}
+		thrashRawStats.Pressure = true
+		thrashRawStats.PsiSome = memoryPressure.Some.Total
+		thrashRawStats.PsiFull = memoryPressure.Full.Total
+	}
+	thrashRawStats.SampleTime = time.Now().UnixNano()
+
+	return thrashRawStats, nil
+}
+
+// getThrashStats calculates the memory thrash between 2 samples with the
+// given weights, or DefaultThrashWeights when they're all 0. Every component is scaled to 0-1 (the swap and fault rates
+// saturate at 1000 per second) and the score is their weighted mean in %.
+// The pressure is left out of the score when PSI isn't available.
+func getThrashStats(firstSample ThrashRawStats, secondSample ThrashRawStats, weights ThrashWeights) (thrashStats ThrashStats, err error) {
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime)
+	if timeDelta <= 0 {
+		return ThrashStats{}, errors.New("The second sample must be taken after the first one")
+	}
+	if weights == (ThrashWeights{}) {
+		weights = DefaultThrashWeights
+	}
+
+	thrashStats = ThrashStats{}
+	thrashStats.SwapIn = float64(secondSample.SwapIn-firstSample.SwapIn) / timeDelta.Seconds()
+	thrashStats.SwapOut = float64(secondSample.SwapOut-firstSample.SwapOut) / timeDelta.Seconds()
+	thrashStats.MajFault = float64(secondSample.MajFault-firstSample.MajFault) / timeDelta.Seconds()
+	pressure := firstSample.Pressure && secondSample.Pressure
+	if pressure {
+		thrashStats.PsiSome = float64(secondSample.PsiSome-firstSample.PsiSome) * 100.00 / float64(timeDelta.Microseconds())
+		thrashStats.PsiFull = float64(secondSample.PsiFull-firstSample.PsiFull) * 100.00 / float64(timeDelta.Microseconds())
+	} else {
+		weights.Pressure = 0
+	}
+
+	components := []struct {
+		weight float64
+		value  float64
+	}{
+		{weights.SwapIn, thrashStats.SwapIn / thrashSwapScale},
+		{weights.SwapOut, thrashStats.SwapOut / thrashSwapScale},
+		{weights.MajFault, thrashStats.MajFault / thrashFaultScale},
+		{weights.Pressure, thrashStats.PsiSome / 100},
+	}
+	totalWeight := 0.0
+	for _, component := range components {
+		if component.weight <= 0 {
+			continue
+		}
+		thrashStats.Score += component.weight * math.Min(component.value, 1)
+		totalWeight += component.weight
+	}
+	if totalWeight > 0 {
+		thrashStats.Score = thrashStats.Score * 100.00 / totalWeight
+	}
+
+	return thrashStats, nil
+}
+
+// getThrashStatsOver returns the memory thrash between 2 samples taken d
+// apart with the given weights.
+func getThrashStatsOver(d time.Duration, weights ThrashWeights) (thrashStats ThrashStats, err error) {
+	return sampleOver(d, getThrashRawStats, func(firstSample ThrashRawStats, secondSample ThrashRawStats) (ThrashStats, error) {
+		return getThrashStats(firstSample, secondSample, weights)
+	})
+}
@@ -0,0 +1,52 @@
+// +build linux
+
+package sysstats
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestThrashStatsDefaultWeights(t *testing.T) {
+	first := ThrashRawStats{Pressure: true, SampleTime: time.Unix(0, 0).UnixNano()}
+	second := ThrashRawStats{SwapIn: 500, SwapOut: 100, MajFault: 200, Pressure: true, PsiSome: 250000,
+		SampleTime: time.Unix(1, 0).UnixNano()}
+
+	want, err := getThrashStats(first, second, DefaultThrashWeights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := getThrashStats(first, second, ThrashWeights{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Score == 0 || got.Score != want.Score {
+		t.Errorf("getThrashStats() without weights score = %f, want %f", got.Score, want.Score)
+	}
+}
+
+func TestThrashRawStatsPressure(t *testing.T) {
+	SetStatsFS(fstest.MapFS{"proc/pressure/memory": {Data: []byte(
+		"some avg10=0.00 avg60=0.12 avg300=0.08 total=3178403\n" +
+			"full avg10=0.00 avg60=0.05 avg300=0.03 total=1709217\n")}})
+	defer SetStatsFS(nil)
+
+	thrashRawStats, err := getThrashRawStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !thrashRawStats.Pressure || thrashRawStats.PsiSome != 3178403 || thrashRawStats.PsiFull != 1709217 {
+		t.Errorf("getThrashRawStats() = %+v, want the memory pressure totals", thrashRawStats)
+	}
+
+	// PSI isn't available before Linux 4.20
+	SetStatsFS(fstest.MapFS{})
+	thrashRawStats, err = getThrashRawStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thrashRawStats.Pressure {
+		t.Errorf("getThrashRawStats() = %+v, want no pressure", thrashRawStats)
+	}
+}
@@ -0,0 +1,127 @@
+package sysstats
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCollectorTimeout is the time a collector of the snapshots (or an
+// external command like df) can take before it's given up.
+const DefaultCollectorTimeout = 10 * time.Second
+
+// collectorTimeout is the timeout of the collectors in nanoseconds, or -1 if
+// they don't time out.
+var collectorTimeout atomic.Int64
+
+// TimeoutError is returned for a collector (or an external command) that
+// didn't finish in time, e.g. df stuck on a hung NFS mount or a /proc read
+// under heavy memory pressure.
+type TimeoutError struct {
+	Collector string        // Name of the collector (disk, diskusage,...) or the command
+	Timeout   time.Duration // Timeout that expired
+}
+
+// Error returns the description of the timeout.
+func (e *TimeoutError) Error() string {
+	return "Collector " + e.Collector + " timed out after " + e.Timeout.String()
+}
+
+// SetCollectorTimeout sets the time each collector of the snapshots and each
+// external command (df, uname,...) can take before it's given up, so a hung
+// mount or a stuck read can't wedge the whole snapshot. It's
+// DefaultCollectorTimeout by default; 0 disables the timeouts.
+func SetCollectorTimeout(d time.Duration) {
+	if d <= 0 {
+		collectorTimeout.Store(-1)
+		return
+	}
+	collectorTimeout.Store(int64(d))
+}
+
+// getCollectorTimeout returns the timeout of the collectors, 0 if they don't
+// time out.
+func getCollectorTimeout() time.Duration {
+	switch d := collectorTimeout.Load(); {
+	case d < 0:
+		return 0
+	case d == 0:
+		return DefaultCollectorTimeout
+	default:
+		return time.Duration(d)
+	}
+}
+
+// hungCollectors are the names of the collectors that timed out and are still
+// running in the background.
+var hungCollectors sync.Map
+
+// withTimeout runs collect and waits for it at most the collectors timeout.
+// A collector that times out keeps running in the background (a blocked read
+// can't be interrupted), so it must not share state with the caller. Until
+// it finishes the next runs of the same collector time out right away
+// instead of starting another goroutine, so a hung collector leaks at most
+// one goroutine.
+func withTimeout(name string, collect func() error) error {
+	timeout := getCollectorTimeout()
+	if timeout == 0 {
+		return collect()
+	}
+	if _, hung := hungCollectors.Load(name); hung {
+		logDebug("collector still hung", "collector", name)
+		return &TimeoutError{Collector: name, Timeout: timeout}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- collect()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		hungCollectors.Store(name, struct{}{})
+		go func() {
+			<-done
+			hungCollectors.Delete(name)
+		}()
+		logWarn("collector timed out", "collector", name, "timeout", timeout)
+		return &TimeoutError{Collector: name, Timeout: timeout}
+	}
+}
+
+// runCommand runs an external command (df, uname,...) found in the PATH and
+// returns its output. The command is killed if it takes longer than the
+// collectors timeout. It returns ErrExecDisabled if the external commands
+// are disabled (see SetExecDisabled).
+func runCommand(name string, args ...string) (out []byte, err error) {
+	if !execAllowed() {
+		return nil, ErrExecDisabled
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	timeout := getCollectorTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	out, err = exec.CommandContext(ctx, path, args...).Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, &TimeoutError{Collector: name, Timeout: timeout}
+	}
+	bytesParsed.Add(uint64(len(out)))
+
+	return out, err
+}
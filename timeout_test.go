@@ -0,0 +1,46 @@
+package sysstats
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithTimeoutHungCollector(t *testing.T) {
+	SetCollectorTimeout(10 * time.Millisecond)
+	defer SetCollectorTimeout(DefaultCollectorTimeout)
+
+	release := make(chan struct{})
+	var runs atomic.Int64
+	hung := func() error {
+		runs.Add(1)
+		<-release
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		err := withTimeout("test-hung", hung)
+		timeoutErr := &TimeoutError{}
+		if !errors.As(err, &timeoutErr) {
+			t.Fatalf("withTimeout() run %d error = %v, want a *TimeoutError", i, err)
+		}
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("the hung collector ran %d times, want 1 until it finishes", n)
+	}
+
+	close(release)
+	for deadline := time.Now().Add(time.Second); ; {
+		if _, stillHung := hungCollectors.Load("test-hung"); !stillHung {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the collector is still marked as hung after finishing")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := withTimeout("test-hung", func() error { return nil }); err != nil {
+		t.Errorf("withTimeout() after the collector finished error = %v", err)
+	}
+}
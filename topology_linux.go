@@ -0,0 +1,131 @@
+// +build linux
+
+package sysstats
+
+import (
+	"path/filepath"
+	"strconv"
+)
+
+// CpuTopology represents the position of *one* logical CPU of a linux system
+// in the physical topology.
+type CpuTopology struct {
+	Package  int   `json:"package"`  // Physical package (socket) ID
+	Core     int   `json:"core"`     // Core ID (unique within the package)
+	Siblings []int `json:"siblings"` // Logical CPUs sharing the core (SMT siblings), including itself
+}
+
+// Topology represents the physical topology of *all* the CPUs of a linux
+// system.
+//
+// Map keys:
+//   Name - Name of the CPU (as it is on /proc/stat: cpu0, cpu1,...).
+type Topology map[string]CpuTopology
+
+// getTopology gets the topology of the CPUs from the directories
+// /sys/devices/system/cpu/cpu*/topology. The offline CPUs don't have one.
+func getTopology() (topology Topology, err error) {
+	dirs, err := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/topology")
+	if err != nil {
+		return nil, err
+	}
+
+	topology = Topology{}
+	for _, dir := range dirs {
+		cpuName := filepath.Base(filepath.Dir(dir))
+
+		cpuTopology := CpuTopology{}
+		cpuTopology.Package, err = strconv.Atoi(readSysfsString(filepath.Join(dir, "physical_package_id")))
+		if err != nil {
+			continue
+		}
+		cpuTopology.Core, err = strconv.Atoi(readSysfsString(filepath.Join(dir, "core_id")))
+		if err != nil {
+			continue
+		}
+		cpuTopology.Siblings, err = readCpuList(filepath.Join(dir, "thread_siblings_list"))
+		if err != nil {
+			continue
+		}
+
+		topology[cpuName] = cpuTopology
+	}
+
+	return topology, nil
+}
+
+// packageName returns the name of the package of a CPU (package0,...).
+func (t CpuTopology) packageName() string {
+	return "package" + strconv.Itoa(t.Package)
+}
+
+// coreName returns the name of the physical core of a CPU, which is unique
+// across packages (package0-core3,...).
+func (t CpuTopology) coreName() string {
+	return t.packageName() + "-core" + strconv.Itoa(t.Core)
+}
+
+// groups returns the name of the group of every CPU of the topology, as
+// returned by group.
+func (t Topology) groups(group func(CpuTopology) string) (groups map[string]string) {
+	groups = map[string]string{}
+	for cpuName, cpuTopology := range t {
+		groups[cpuName] = group(cpuTopology)
+	}
+
+	return groups
+}
+
+// aggregateCpuAvgStats returns the mean of the stats of the CPUs grouped by
+// the given names of their groups (cpu0 -> package0,...). The aggregated cpu
+// line and the CPUs without group are skipped.
+func aggregateCpuAvgStats(cpusAvgStats CpusAvgStats, groups map[string]string) (groupsAvgStats CpusAvgStats) {
+	groupsAvgStats = CpusAvgStats{}
+	members := map[string]int{}
+
+	for cpuName, cpuStats := range cpusAvgStats {
+		groupName, ok := groups[cpuName]
+		if !ok {
+			continue
+		}
+		groupStats, ok := groupsAvgStats[groupName]
+		if !ok {
+			groupStats = CpuAvgStats{}
+			groupsAvgStats[groupName] = groupStats
+		}
+		for key, value := range cpuStats {
+			groupStats[key] += value
+		}
+		members[groupName]++
+	}
+
+	for groupName, groupStats := range groupsAvgStats {
+		for key := range groupStats {
+			groupStats[key] /= float64(members[groupName])
+		}
+	}
+
+	return groupsAvgStats
+}
+
+// getCpuStatsByPackage aggregates the CPU stats by physical package (socket).
+// The keys of the result are package0, package1,...
+func getCpuStatsByPackage(cpusAvgStats CpusAvgStats) (packagesAvgStats CpusAvgStats, err error) {
+	topology, err := getTopology()
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregateCpuAvgStats(cpusAvgStats, topology.groups(CpuTopology.packageName)), nil
+}
+
+// getCpuStatsByCore aggregates the CPU stats by physical core, merging the
+// SMT siblings. The keys of the result are package0-core0, package0-core1,...
+func getCpuStatsByCore(cpusAvgStats CpusAvgStats) (coresAvgStats CpusAvgStats, err error) {
+	topology, err := getTopology()
+	if err != nil {
+		return nil, err
+	}
+
+	return aggregateCpuAvgStats(cpusAvgStats, topology.groups(CpuTopology.coreName)), nil
+}
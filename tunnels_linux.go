@@ -0,0 +1,261 @@
+// +build linux
+
+package sysstats
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// TunnelStats represents the health of a tunnel (VPN or overlay network)
+// interface of a linux system.
+type TunnelStats struct {
+	Name      string `json:"name"`                 // Interface name
+	Kind      string `json:"kind"`                 // wireguard, tun, tap, gre, gretap, ipip, sit, vxlan,...
+	OperState string `json:"operstate"`            // Operational state (up, down, unknown,...)
+	RxBytes   uint64 `json:"rxbytes" unit:"bytes"` // # of bytes received since the interface was created
+	TxBytes   uint64 `json:"txbytes" unit:"bytes"` // # of bytes transmitted since the interface was created
+	RxErrs    uint64 `json:"rxerrs"`               // # of receive errors
+	TxErrs    uint64 `json:"txerrs"`               // # of transmit errors (no route to the peer,...)
+	RxDrop    uint64 `json:"rxdrop"`               // # of received packets dropped
+	TxDrop    uint64 `json:"txdrop"`               // # of transmitted packets dropped
+	// Peers of the WireGuard interfaces (nil if the kind isn't wireguard or
+	// they can't be read, which requires CAP_NET_ADMIN)
+	Peers []WireguardPeer `json:"peers,omitempty"`
+}
+
+// WireguardPeer represents the state of a peer of a WireGuard interface.
+type WireguardPeer struct {
+	PublicKey     string  `json:"publickey"`                     // Public key of the peer (base64)
+	Endpoint      string  `json:"endpoint"`                      // Last known address of the peer (host:port, empty if unknown)
+	LastHandshake int64   `json:"lasthandshake" unit:"unixnano"` // Time of the latest handshake (Unix time in nanoseconds, 0 if never)
+	HandshakeAge  float64 `json:"handshakeage" unit:"s"`         // Seconds since the latest handshake (-1 if never)
+	RxBytes       uint64  `json:"rxbytes" unit:"bytes"`          // # of bytes received from the peer
+	TxBytes       uint64  `json:"txbytes" unit:"bytes"`          // # of bytes transmitted to the peer
+	// Stale is true if there's no session with the peer: there was never a
+	// handshake or the latest one is older than the WireGuard reject time
+	// (3 minutes), so no traffic can flow until a new one succeeds.
+	Stale bool `json:"stale"`
+}
+
+// WireGuard generic netlink constants (linux/wireguard.h)
+const (
+	wgCmdGetDevice           = 0 // WG_CMD_GET_DEVICE
+	wgDeviceAIfname          = 2 // WGDEVICE_A_IFNAME
+	wgDeviceAPeers           = 8 // WGDEVICE_A_PEERS
+	wgPeerAPublicKey         = 1 // WGPEER_A_PUBLIC_KEY
+	wgPeerAEndpoint          = 4 // WGPEER_A_ENDPOINT
+	wgPeerALastHandshakeTime = 6 // WGPEER_A_LAST_HANDSHAKE_TIME
+	wgPeerARxBytes           = 7 // WGPEER_A_RX_BYTES
+	wgPeerATxBytes           = 8 // WGPEER_A_TX_BYTES
+	wireguardRejectAfterTime = 180 * time.Second
+	tunFlagTap               = 0x0002 // IFF_TAP
+)
+
+// tunnelDevTypes are the DEVTYPEs (see /sys/class/net/[iface]/uevent) of the
+// tunnel interfaces.
+var tunnelDevTypes = map[string]bool{
+	`wireguard`: true,
+	`vxlan`:     true,
+	`geneve`:    true,
+	`gretap`:    true,
+	`ip6gretap`: true,
+}
+
+// tunnelLinkTypes are the kinds of the tunnel interfaces by their link type
+// (ARPHRD_* in /sys/class/net/[iface]/type).
+var tunnelLinkTypes = map[string]string{
+	`768`: `ipip`,
+	`769`: `ip6tnl`,
+	`776`: `sit`,
+	`778`: `gre`,
+	`823`: `ip6gre`,
+}
+
+// getTunnelStats gets the health of the tunnel interfaces of the system:
+// their state and counters from /sys/class/net and /proc/net/dev, and the
+// peers of the WireGuard interfaces with the wireguard generic netlink
+// family.
+func getTunnelStats() (tunnelStatsArr []TunnelStats, err error) {
+	ifaces, err := filepath.Glob("/sys/class/net/*")
+	if err != nil {
+		return nil, err
+	}
+
+	netRawStats, err := getNetRawStats()
+	if err != nil {
+		return nil, err
+	}
+
+	tunnelStatsArr = []TunnelStats{}
+	for _, dir := range ifaces {
+		kind := getTunnelKind(dir)
+		if kind == "" {
+			continue
+		}
+		name := filepath.Base(dir)
+		rawStats := netRawStats[name]
+		tunnelStats := TunnelStats{
+			Name:      name,
+			Kind:      kind,
+			OperState: readSysfsString(filepath.Join(dir, "operstate")),
+			RxBytes:   rawStats[IfaceRxBytes],
+			TxBytes:   rawStats[IfaceTxBytes],
+			RxErrs:    rawStats[IfaceRxErrs],
+			TxErrs:    rawStats[IfaceTxErrs],
+			RxDrop:    rawStats[IfaceRxDrop],
+			TxDrop:    rawStats[IfaceTxDrop],
+		}
+		if kind == `wireguard` {
+			peers, err := getWireguardPeers(name)
+			if err != nil {
+				logDebug("skipped wireguard peers", "iface", name, "error", err)
+			} else {
+				tunnelStats.Peers = peers
+			}
+		}
+		tunnelStatsArr = append(tunnelStatsArr, tunnelStats)
+	}
+
+	sort.Slice(tunnelStatsArr, func(i, j int) bool { return tunnelStatsArr[i].Name < tunnelStatsArr[j].Name })
+
+	return tunnelStatsArr, nil
+}
+
+// getTunnelKind returns the kind of tunnel of the interface of a
+// /sys/class/net directory, or an empty string if it isn't a tunnel.
+func getTunnelKind(dir string) string {
+	// tun and tap interfaces have their flags in tun_flags
+	if flags := readSysfsString(filepath.Join(dir, "tun_flags")); flags != "" {
+		value, err := strconv.ParseUint(flags, 0, 32)
+		if err == nil && value&tunFlagTap != 0 {
+			return `tap`
+		}
+		return `tun`
+	}
+
+	for _, line := range strings.Split(readSysfsString(filepath.Join(dir, "uevent")), "\n") {
+		devType := strings.TrimPrefix(line, "DEVTYPE=")
+		if devType != line && tunnelDevTypes[devType] {
+			return devType
+		}
+	}
+
+	return tunnelLinkTypes[readSysfsString(filepath.Join(dir, "type"))]
+}
+
+// getWireguardPeers gets the peers of a WireGuard interface with the
+// WG_CMD_GET_DEVICE command (which requires CAP_NET_ADMIN).
+func getWireguardPeers(iface string) (peers []WireguardPeer, err error) {
+	conn, err := newGenlConn()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	family, err := conn.familyId("wireguard")
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := conn.dump(family, wgCmdGetDevice, 1, nlAttr(wgDeviceAIfname, nlString(iface)))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWireguardPeers(replies, time.Now()), nil
+}
+
+// parseWireguardPeers parses the peers of the replies to WG_CMD_GET_DEVICE.
+// The peers with many allowed IPs are split across several replies, only
+// the first part of every peer has its stats.
+func parseWireguardPeers(replies [][]byte, now time.Time) (peers []WireguardPeer) {
+	peers = []WireguardPeer{}
+	seen := map[string]bool{}
+
+	for _, reply := range replies {
+		peersAttr, ok := parseNlAttrs(reply)[wgDeviceAPeers]
+		if !ok {
+			continue
+		}
+		// The peers are nested attributes indexed by their position
+		for _, peerAttr := range parseNlAttrOrdered(peersAttr) {
+			attrs := parseNlAttrs(peerAttr)
+			publicKey := base64.StdEncoding.EncodeToString(attrs[wgPeerAPublicKey])
+			if seen[publicKey] {
+				continue
+			}
+			seen[publicKey] = true
+
+			peer := WireguardPeer{PublicKey: publicKey, HandshakeAge: -1, Stale: true}
+			peer.Endpoint = parseSockaddr(attrs[wgPeerAEndpoint])
+			if value := attrs[wgPeerALastHandshakeTime]; len(value) >= 16 {
+				// struct __kernel_timespec
+				sec := int64(binary.NativeEndian.Uint64(value[0:8]))
+				nsec := int64(binary.NativeEndian.Uint64(value[8:16]))
+				if sec != 0 || nsec != 0 {
+					handshake := time.Unix(sec, nsec)
+					peer.LastHandshake = handshake.UnixNano()
+					peer.HandshakeAge = now.Sub(handshake).Seconds()
+					peer.Stale = now.Sub(handshake) > wireguardRejectAfterTime
+				}
+			}
+			if value := attrs[wgPeerARxBytes]; len(value) >= 8 {
+				peer.RxBytes = binary.NativeEndian.Uint64(value)
+			}
+			if value := attrs[wgPeerATxBytes]; len(value) >= 8 {
+				peer.TxBytes = binary.NativeEndian.Uint64(value)
+			}
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers
+}
+
+// parseNlAttrOrdered decodes the netlink attributes of an array (nested
+// attributes whose types are their indexes) in order.
+func parseNlAttrOrdered(b []byte) (values [][]byte) {
+	attrs := parseNlAttrs(b)
+	indexes := make([]int, 0, len(attrs))
+	for index := range attrs {
+		indexes = append(indexes, int(index))
+	}
+	sort.Ints(indexes)
+
+	values = make([][]byte, 0, len(indexes))
+	for _, index := range indexes {
+		values = append(values, attrs[uint16(index)])
+	}
+
+	return values
+}
+
+// parseSockaddr returns the host:port of a struct sockaddr_in or
+// sockaddr_in6, or an empty string if it's neither.
+func parseSockaddr(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	port := strconv.Itoa(int(binary.BigEndian.Uint16(b[2:4])))
+
+	switch binary.NativeEndian.Uint16(b[0:2]) {
+	case syscall.AF_INET:
+		if len(b) >= 8 {
+			return net.JoinHostPort(net.IP(b[4:8]).String(), port)
+		}
+	case syscall.AF_INET6:
+		if len(b) >= 24 {
+			return net.JoinHostPort(net.IP(b[8:24]).String(), port)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package sysstats
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Units of the stats. The fields of the stats structs have them in their unit
+// tag (see FieldUnits) and the metrics in their MetricInfo (see
+// LookupMetricInfo). The fields and metrics without unit are plain numbers
+// (# of processes, sockets, IOs in progress,...).
+const (
+	UnitBytes            = `bytes`     // Bytes
+	UnitKibibytes        = `kB`        // Kibibytes (1024 bytes)
+	UnitPages            = `pages`     // Pages of memory
+	UnitSectors          = `sectors`   // Sectors of the disk (see DiskRawStats.SectorSize)
+	UnitPercent          = `%`         // Percentage (0-100)
+	UnitPerSecond        = `/s`        // Operations (IOs, forks, errors,...) per second
+	UnitBytesPerSec      = `bytes/s`   // Bytes per second
+	UnitBytesPerHour     = `bytes/h`   // Bytes per hour
+	UnitKibibytesPerSec  = `kB/s`      // Kibibytes per second
+	UnitKibibytesPerHour = `kB/h`      // Kibibytes per hour
+	UnitPagesPerSec      = `pages/s`   // Pages of memory per second
+	UnitPacketsPerSec    = `packets/s` // Packets per second
+	UnitNanoseconds      = `ns`        // Nanoseconds (also the time.Duration fields)
+	UnitMicroseconds     = `us`        // Microseconds (µs)
+	UnitMilliseconds     = `ms`        // Milliseconds
+	UnitCentiseconds     = `cs`        // Centiseconds
+	UnitSeconds          = `s`         // Seconds
+	UnitHours            = `h`         // Hours
+	UnitSecondsPerSec    = `s/s`       // Seconds per second (e.g. CPU time of a process)
+	UnitJiffies          = `jiffies`   // Clock ticks of the kernel (USER_HZ)
+	Unit100Nanoseconds   = `100ns`     // Ticks of 100 nanoseconds (CPU time on Windows)
+	UnitUnixNano         = `unixnano`  // Time as Unix time in nanoseconds
+	UnitUnixTime         = `unixtime`  // Time as Unix time in seconds
+	UnitMegahertz        = `MHz`       // Megahertz
+	UnitCelsius          = `°C`        // Degrees Celsius
+)
+
+// FieldUnits returns the units of the fields of a stats struct (or a pointer
+// to it), e.g. DiskRawStats or ReachabilityStats, by their JSON name:
+//   units := sysstats.FieldUnits(sysstats.DiskRawStats{})
+//   units["readsectors"] // sectors
+//   units["readticks"]   // ms
+// The fields of the embedded structs are included, and the fields without
+// unit are left out. For the raw CPU stats (CpuRawStats and CpusRawStats) it
+// returns the unit of their keys, which is UnitJiffies (Unit100Nanoseconds
+// on Windows). It returns an empty map for the other stats that are maps
+// (memory and network), whose units are in the metadata of their metrics (see
+// LookupMetricInfo).
+func FieldUnits(stats interface{}) map[string]string {
+	units := map[string]string{}
+	switch stats.(type) {
+	case CpuRawStats, *CpuRawStats, CpusRawStats, *CpusRawStats:
+		for _, key := range CpuStatKeys() {
+			units[key] = cpuRawStatsUnit
+		}
+		return units
+	}
+
+	t := reflect.TypeOf(stats)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return units
+	}
+	addFieldUnits(t, units)
+
+	return units
+}
+
+// addFieldUnits adds the units of the fields of a struct type to the map.
+func addFieldUnits(t reflect.Type, units map[string]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			addFieldUnits(field.Type, units)
+			continue
+		}
+		unit := field.Tag.Get(`unit`)
+		if unit == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get(`json`), `,`)
+		if name == "" {
+			name = field.Name
+		}
+		units[name] = unit
+	}
+}
+
+// FormatBytes returns a human readable representation of a number of bytes
+// using binary prefixes (e.g. 1.50 GiB).
+func FormatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.2f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,45 @@
+package sysstats
+
+import "testing"
+
+func TestFieldUnits(t *testing.T) {
+	units := FieldUnits(&DiskRawStats{})
+	if units[`readsectors`] != UnitSectors || units[`readticks`] != UnitMilliseconds {
+		t.Errorf("FieldUnits(DiskRawStats) = %v", units)
+	}
+	if _, ok := units[`name`]; ok {
+		t.Error("FieldUnits(DiskRawStats) has the unit of name, which has none")
+	}
+
+	units = FieldUnits(CollectorHealth{})
+	if units[`duration`] != UnitNanoseconds || units[`bytes`] != UnitBytes {
+		t.Errorf("FieldUnits(CollectorHealth) = %v", units)
+	}
+
+	for _, stats := range []interface{}{CpuRawStats{}, CpusRawStats{}} {
+		units = FieldUnits(stats)
+		if units[CpuUser] != cpuRawStatsUnit || units[CpuTotal] != cpuRawStatsUnit {
+			t.Errorf("FieldUnits(%T) = %v, want %s", stats, units, cpuRawStatsUnit)
+		}
+	}
+
+	if units = FieldUnits(MemStats{}); len(units) != 0 {
+		t.Errorf("FieldUnits(MemStats) = %v, want none", units)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for bytes, want := range map[uint64]string{
+		0:          `0 B`,
+		1023:       `1023 B`,
+		1024:       `1.00 KiB`,
+		1536:       `1.50 KiB`,
+		3 << 29:    `1.50 GiB`,
+		1 << 60:    `1.00 EiB`,
+		^uint64(0): `16.00 EiB`,
+	} {
+		if got := FormatBytes(bytes); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package sysstats
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotSupported is returned by the collectors of the stats that the OS
+// doesn't have or that aren't implemented on it yet (e.g. the socket stats
+// outside linux). The snapshots leave their families out without failing.
+var ErrNotSupported = errors.New("Not supported on this OS")
+
+// errNoCgo is returned by the collectors that read the stats through the C
+// library (BSD and darwin) when the package is built without cgo.
+var errNoCgo = fmt.Errorf("%w without cgo", ErrNotSupported)
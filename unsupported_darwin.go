@@ -0,0 +1,28 @@
+// +build darwin
+
+package sysstats
+
+import "regexp"
+
+// SysInfo represents the OSX system info.
+type SysInfo struct {
+	Hostname  string  `json:"hostname"`
+	FQDN      string  `json:"fqdn"`
+	Domain    string  `json:"domain"`
+	OsType    string  `json:"ostype"`
+	OsRelease string  `json:"osrelease"`
+	OsVersion string  `json:"osversion"`
+	OsArch    string  `json:"osarch"`
+	Uptime    float64 `json:"uptime"`
+}
+
+// getSysInfo isn't implemented on OSX yet.
+func getSysInfo() (sysInfo SysInfo, err error) {
+	return SysInfo{}, ErrNotSupported
+}
+
+// getNetRawStatsMatching isn't implemented on OSX yet. The types and the
+// averages of the network stats are the BSD ones (see netstats_bsd.go).
+func getNetRawStatsMatching(ifaces *regexp.Regexp) (netRawStats NetRawStats, err error) {
+	return nil, ErrNotSupported
+}
@@ -0,0 +1,45 @@
+// +build !linux
+
+package sysstats
+
+// SockStats represents the socket statistics of the system. They are only
+// collected on linux (/proc/net/sockstat).
+type SockStats struct {
+	Used        uint64 `json:"used"`        // Total number of used sockets
+	TcpInUse    uint64 `json:"tcpinuse"`    // TCP sockets in use
+	TcpOrphaned uint64 `json:"tcporphaned"` // TCP sockets orphaned
+	TcpTimeWait uint64 `json:"tcptimewait"` // TCP sockets in TIME_WAIT
+	UdpInUse    uint64 `json:"udpinuse"`    // UDP sockets in use
+	Raw         uint64 `json:"raw"`         // RAW sockets in use
+	IpFrag      uint64 `json:"ipfrag"`      // # of IP fragments in use
+}
+
+// FileStats represents the file descriptor stats. They are only collected
+// on linux (/proc/sys/fs).
+type FileStats struct {
+	FhAlloc uint64 `json:"fhalloc"` // # of allocated file handlers (# files currently opened)
+	FhFree  uint64 `json:"fhfree"`  // # of free file handlers
+	FhMax   uint64 `json:"fhmax"`   // maximum # of file handlers
+	InAlloc uint64 `json:"inalloc"` // # of inodes the system has allocated
+	InFree  uint64 `json:"infree"`  // # of free inodes
+}
+
+// getSockStats isn't supported outside linux.
+func getSockStats() (sockStats SockStats, err error) {
+	return SockStats{}, ErrNotSupported
+}
+
+// getFileStats isn't supported outside linux.
+func getFileStats() (fileStats FileStats, err error) {
+	return FileStats{}, ErrNotSupported
+}
+
+// getProcRawStats isn't supported outside linux.
+func getProcRawStats() (procRawStats ProcRawStats, err error) {
+	return ProcRawStats{}, ErrNotSupported
+}
+
+// getSystemState isn't supported outside linux.
+func getSystemState() (state SystemState, err error) {
+	return SystemState{}, ErrNotSupported
+}
@@ -0,0 +1,141 @@
+package sysstats
+
+import (
+	"time"
+
+	v1 "github.com/rafacas/sysstats"
+)
+
+// CPUTimes represents the raw times of one CPU, in units of USER_HZ.
+type CPUTimes struct {
+	User      uint64 `json:"user"`
+	Nice      uint64 `json:"nice"`
+	System    uint64 `json:"system"`
+	Idle      uint64 `json:"idle"`
+	IOWait    uint64 `json:"iowait"`
+	IRQ       uint64 `json:"irq"`
+	SoftIRQ   uint64 `json:"softirq"`
+	Steal     uint64 `json:"steal"`
+	Guest     uint64 `json:"guest"`
+	GuestNice uint64 `json:"guestnice"`
+	Total     uint64 `json:"total"`
+}
+
+// CPUStats represents the % usage of one CPU between 2 samples.
+type CPUStats struct {
+	User      float64 `json:"user"`
+	Nice      float64 `json:"nice"`
+	System    float64 `json:"system"`
+	Idle      float64 `json:"idle"`
+	IOWait    float64 `json:"iowait"`
+	IRQ       float64 `json:"irq"`
+	SoftIRQ   float64 `json:"softirq"`
+	Steal     float64 `json:"steal"`
+	Guest     float64 `json:"guest"`
+	GuestNice float64 `json:"guestnice"`
+	Total     float64 `json:"total"`
+}
+
+// CPURawStats represents the raw times of all the CPUs by name (cpu, cpu0,
+// cpu1,...).
+type CPURawStats map[string]CPUTimes
+
+// CPUStatsByName represents the % usage of all the CPUs by name.
+type CPUStatsByName map[string]CPUStats
+
+// GetCPURawStats returns the raw times of all the CPUs.
+func GetCPURawStats() (CPURawStats, error) {
+	raw, err := v1.GetCpuRawStats()
+	if err != nil {
+		return nil, err
+	}
+
+	return fromV1CPURaw(raw), nil
+}
+
+// GetCPUStats returns the % usage of all the CPUs between 2 samples.
+func GetCPUStats(first CPURawStats, second CPURawStats) (CPUStatsByName, error) {
+	avg, err := v1.GetCpuAvgStats(toV1CPURaw(first), toV1CPURaw(second))
+	if err != nil {
+		return nil, err
+	}
+
+	return fromV1CPUAvg(avg), nil
+}
+
+// GetCPUStatsOver returns the % usage of all the CPUs between 2 samples
+// taken d apart.
+func GetCPUStatsOver(d time.Duration) (CPUStatsByName, error) {
+	avg, err := v1.GetCpuStatsOver(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromV1CPUAvg(avg), nil
+}
+
+// fromV1CPURaw converts the v1 CPU raw stats.
+func fromV1CPURaw(raw v1.CpusRawStats) CPURawStats {
+	stats := CPURawStats{}
+	for name, m := range raw {
+		stats[name] = CPUTimes{
+			User:      m[`user`],
+			Nice:      m[`nice`],
+			System:    m[`system`],
+			Idle:      m[`idle`],
+			IOWait:    m[`iowait`],
+			IRQ:       m[`irq`],
+			SoftIRQ:   m[`softirq`],
+			Steal:     m[`steal`],
+			Guest:     m[`guest`],
+			GuestNice: m[`guestnice`],
+			Total:     m[`total`],
+		}
+	}
+
+	return stats
+}
+
+// toV1CPURaw converts the CPU raw stats to the v1 ones.
+func toV1CPURaw(stats CPURawStats) v1.CpusRawStats {
+	raw := v1.CpusRawStats{}
+	for name, t := range stats {
+		raw[name] = v1.CpuRawStats{
+			`user`:      t.User,
+			`nice`:      t.Nice,
+			`system`:    t.System,
+			`idle`:      t.Idle,
+			`iowait`:    t.IOWait,
+			`irq`:       t.IRQ,
+			`softirq`:   t.SoftIRQ,
+			`steal`:     t.Steal,
+			`guest`:     t.Guest,
+			`guestnice`: t.GuestNice,
+			`total`:     t.Total,
+		}
+	}
+
+	return raw
+}
+
+// fromV1CPUAvg converts the v1 CPU average stats.
+func fromV1CPUAvg(avg v1.CpusAvgStats) CPUStatsByName {
+	stats := CPUStatsByName{}
+	for name, m := range avg {
+		stats[name] = CPUStats{
+			User:      m[`user`],
+			Nice:      m[`nice`],
+			System:    m[`system`],
+			Idle:      m[`idle`],
+			IOWait:    m[`iowait`],
+			IRQ:       m[`irq`],
+			SoftIRQ:   m[`softirq`],
+			Steal:     m[`steal`],
+			Guest:     m[`guest`],
+			GuestNice: m[`guestnice`],
+			Total:     m[`total`],
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,26 @@
+// Package sysstats (v2) provides system statistics with consistent naming,
+// Go-style casing, time.Duration intervals and typed structs everywhere.
+//
+// It is a layer over the v1 package (github.com/rafacas/sysstats), which
+// keeps working unchanged, so v1 callers can migrate one call at a time.
+//
+// Naming changes from v1:
+//   v1                              v2
+//   GetCpuRawStats                  GetCPURawStats
+//   GetCpuAvgStats                  GetCPUStats
+//   GetCpuStatsInterval(seconds)    GetCPUStatsOver(time.Duration)
+//   GetNetRawStats                  GetNetRawStats
+//   GetNetAvgStats                  GetNetStats
+//   GetNetStatsInterval(seconds)    GetNetStatsOver(time.Duration)
+//   GetDiskAvgStats                 GetDiskStats
+//   GetDiskStatsInterval(seconds)   GetDiskStatsOver(time.Duration)
+//   GetProcAvgStats                 GetProcStats
+//   GetProcStatsInterval(seconds)   GetProcStatsOver(time.Duration)
+//   CpuRawStats map["user"]         CPUTimes.User
+//   CpuAvgStats map["user"]         CPUStats.User
+//   IfaceRawStats map["rxbytes"]    IfaceCounters.RxBytes
+//   IfaceAvgStats map["rxbytes"]    IfaceStats.RxBytes
+//   MemStats map["memtotal"]        MemStats.MemTotal
+//   SockStats.TcpInUse              SockStats.TCPInUse
+//   SysInfo.OsType                  SysInfo.OSType
+package sysstats
@@ -0,0 +1,154 @@
+package sysstats
+
+import (
+	"time"
+
+	v1 "github.com/rafacas/sysstats"
+)
+
+// IfaceCounters represents the raw counters of one network interface.
+type IfaceCounters struct {
+	RxBytes      uint64    `json:"rxbytes"`
+	RxPackets    uint64    `json:"rxpackets"`
+	RxErrors     uint64    `json:"rxerrors"`
+	RxDropped    uint64    `json:"rxdropped"`
+	RxFIFO       uint64    `json:"rxfifo"`
+	RxFrame      uint64    `json:"rxframe"`
+	RxCompressed uint64    `json:"rxcompressed"`
+	RxMulticast  uint64    `json:"rxmulticast"`
+	TxBytes      uint64    `json:"txbytes"`
+	TxPackets    uint64    `json:"txpackets"`
+	TxErrors     uint64    `json:"txerrors"`
+	TxDropped    uint64    `json:"txdropped"`
+	TxFIFO       uint64    `json:"txfifo"`
+	TxCollisions uint64    `json:"txcollisions"`
+	TxCarrier    uint64    `json:"txcarrier"`
+	TxCompressed uint64    `json:"txcompressed"`
+	Speed        uint64    `json:"speed,omitempty"` // Link speed in bits per second, 0 if it's unknown
+	Time         time.Time `json:"time"`            // Time when the sample was taken
+}
+
+// IfaceStats represents the per second rates of one network interface.
+type IfaceStats struct {
+	RxBytes      float64 `json:"rxbytes"`
+	RxPackets    float64 `json:"rxpackets"`
+	RxErrors     float64 `json:"rxerrors"`
+	RxDropped    float64 `json:"rxdropped"`
+	RxFIFO       float64 `json:"rxfifo"`
+	RxFrame      float64 `json:"rxframe"`
+	RxCompressed float64 `json:"rxcompressed"`
+	RxMulticast  float64 `json:"rxmulticast"`
+	TxBytes      float64 `json:"txbytes"`
+	TxPackets    float64 `json:"txpackets"`
+	TxErrors     float64 `json:"txerrors"`
+	TxDropped    float64 `json:"txdropped"`
+	TxFIFO       float64 `json:"txfifo"`
+	TxCollisions float64 `json:"txcollisions"`
+	TxCarrier    float64 `json:"txcarrier"`
+	TxCompressed float64 `json:"txcompressed"`
+	RxUtil       float64 `json:"rxutil"` // % of the link speed used receiving, 0 if the speed is unknown
+	TxUtil       float64 `json:"txutil"` // % of the link speed used transmitting, 0 if the speed is unknown
+}
+
+// NetRawStats represents the raw counters of all the network interfaces by
+// name.
+type NetRawStats map[string]IfaceCounters
+
+// NetStats represents the rates of all the network interfaces by name.
+type NetStats map[string]IfaceStats
+
+// v1 keys of the network counters in the order of the IfaceCounters fields.
+var v1NetKeys = []string{`rxbytes`, `rxpkts`, `rxerrs`, `rxdrop`, `rxfifo`,
+	`rxframe`, `rxcompr`, `rxmulti`, `txbytes`, `txpkts`, `txerrs`, `txdrop`,
+	`txfifo`, `txcolls`, `txcarr`, `txcompr`}
+
+// GetNetRawStats returns the raw counters of all the network interfaces.
+func GetNetRawStats() (NetRawStats, error) {
+	raw, err := v1.GetNetRawStats()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := NetRawStats{}
+	for name, m := range raw {
+		c := IfaceCounters{Speed: m[`speed`], Time: time.Unix(0, int64(m[`time`]))}
+		fields := c.fields()
+		for i, key := range v1NetKeys {
+			*fields[i] = m[key]
+		}
+		stats[name] = c
+	}
+
+	return stats, nil
+}
+
+// GetNetStats returns the rates of all the network interfaces between 2
+// samples.
+func GetNetStats(first NetRawStats, second NetRawStats) (NetStats, error) {
+	avg, err := v1.GetNetAvgStats(toV1NetRaw(first), toV1NetRaw(second))
+	if err != nil {
+		return nil, err
+	}
+
+	return fromV1NetAvg(avg), nil
+}
+
+// GetNetStatsOver returns the rates of all the network interfaces between 2
+// samples taken d apart.
+func GetNetStatsOver(d time.Duration) (NetStats, error) {
+	avg, err := v1.GetNetStatsOver(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return fromV1NetAvg(avg), nil
+}
+
+// fields returns pointers to the counters in the order of v1NetKeys.
+func (c *IfaceCounters) fields() []*uint64 {
+	return []*uint64{&c.RxBytes, &c.RxPackets, &c.RxErrors, &c.RxDropped,
+		&c.RxFIFO, &c.RxFrame, &c.RxCompressed, &c.RxMulticast, &c.TxBytes,
+		&c.TxPackets, &c.TxErrors, &c.TxDropped, &c.TxFIFO, &c.TxCollisions,
+		&c.TxCarrier, &c.TxCompressed}
+}
+
+// fields returns pointers to the rates in the order of v1NetKeys.
+func (s *IfaceStats) fields() []*float64 {
+	return []*float64{&s.RxBytes, &s.RxPackets, &s.RxErrors, &s.RxDropped,
+		&s.RxFIFO, &s.RxFrame, &s.RxCompressed, &s.RxMulticast, &s.TxBytes,
+		&s.TxPackets, &s.TxErrors, &s.TxDropped, &s.TxFIFO, &s.TxCollisions,
+		&s.TxCarrier, &s.TxCompressed}
+}
+
+// toV1NetRaw converts the network raw stats to the v1 ones.
+func toV1NetRaw(stats NetRawStats) v1.NetRawStats {
+	raw := v1.NetRawStats{}
+	for name, c := range stats {
+		m := v1.IfaceRawStats{`time`: uint64(c.Time.UnixNano())}
+		if c.Speed > 0 {
+			m[`speed`] = c.Speed
+		}
+		for i, field := range c.fields() {
+			m[v1NetKeys[i]] = *field
+		}
+		raw[name] = m
+	}
+
+	return raw
+}
+
+// fromV1NetAvg converts the v1 network average stats.
+func fromV1NetAvg(avg v1.NetAvgStats) NetStats {
+	stats := NetStats{}
+	for name, m := range avg {
+		s := IfaceStats{}
+		for i, field := range s.fields() {
+			*field = m[v1NetKeys[i]]
+		}
+		s.RxUtil = m[v1.IfaceRxUtil]
+		s.TxUtil = m[v1.IfaceTxUtil]
+		stats[name] = s
+	}
+
+	return stats
+}
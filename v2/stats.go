@@ -0,0 +1,186 @@
+package sysstats
+
+import (
+	"time"
+
+	v1 "github.com/rafacas/sysstats"
+)
+
+// LoadAvg represents the load average of the system.
+type LoadAvg = v1.LoadAvg
+
+// DiskRawStats represents the raw IO counters of a disk.
+type DiskRawStats = v1.DiskRawStats
+
+// DiskStats represents the IO rates of a disk.
+type DiskStats = v1.DiskAvgStats
+
+// DiskUsage represents the disk space usage of a file system.
+type DiskUsage = v1.DiskUsage
+
+// FileStats represents the file descriptor stats.
+type FileStats = v1.FileStats
+
+// ProcRawStats represents the raw processes stats.
+type ProcRawStats = v1.ProcRawStats
+
+// ProcStats represents the processes stats between 2 samples.
+type ProcStats = v1.ProcAvgStats
+
+// MemStats represents the memory stats of the system, in kilobytes.
+type MemStats struct {
+	MemTotal    uint64 `json:"memtotal"`
+	MemFree     uint64 `json:"memfree"`
+	MemUsed     uint64 `json:"memused"`
+	RealFree    uint64 `json:"realfree"`
+	Buffers     uint64 `json:"buffers"`
+	Cached      uint64 `json:"cached"`
+	SwapTotal   uint64 `json:"swaptotal"`
+	SwapFree    uint64 `json:"swapfree"`
+	SwapUsed    uint64 `json:"swapused"`
+	SwapCached  uint64 `json:"swapcached"`
+	Active      uint64 `json:"active"`
+	Inactive    uint64 `json:"inactive"`
+	Slab        uint64 `json:"slab"`
+	Dirty       uint64 `json:"dirty"`
+	Mapped      uint64 `json:"mapped"`
+	Writeback   uint64 `json:"writeback"`
+	CommittedAS uint64 `json:"committedas"`
+	CommitLimit uint64 `json:"commitlimit"`
+}
+
+// SockStats represents the socket stats of the system.
+type SockStats struct {
+	Used        uint64 `json:"used"`
+	TCPInUse    uint64 `json:"tcpinuse"`
+	TCPOrphaned uint64 `json:"tcporphaned"`
+	TCPTimeWait uint64 `json:"tcptimewait"`
+	UDPInUse    uint64 `json:"udpinuse"`
+	Raw         uint64 `json:"raw"`
+	IPFrag      uint64 `json:"ipfrag"`
+}
+
+// SysInfo represents the system info.
+type SysInfo struct {
+	Hostname  string        `json:"hostname"`
+	FQDN      string        `json:"fqdn"`
+	Domain    string        `json:"domain"`
+	OSType    string        `json:"ostype"`
+	OSRelease string        `json:"osrelease"`
+	OSVersion string        `json:"osversion"`
+	OSArch    string        `json:"osarch"`
+	Uptime    time.Duration `json:"uptime"`
+}
+
+// GetLoadAvg returns the load average of the system.
+func GetLoadAvg() (LoadAvg, error) {
+	return v1.GetLoadAvg()
+}
+
+// GetMemStats returns the memory stats of the system.
+func GetMemStats() (MemStats, error) {
+	m, err := v1.GetMemStats()
+	if err != nil {
+		return MemStats{}, err
+	}
+
+	return MemStats{
+		MemTotal:    m[`memtotal`],
+		MemFree:     m[`memfree`],
+		MemUsed:     m[`memused`],
+		RealFree:    m[`realfree`],
+		Buffers:     m[`buffers`],
+		Cached:      m[`cached`],
+		SwapTotal:   m[`swaptotal`],
+		SwapFree:    m[`swapfree`],
+		SwapUsed:    m[`swapused`],
+		SwapCached:  m[`swapcached`],
+		Active:      m[`active`],
+		Inactive:    m[`inactive`],
+		Slab:        m[`slab`],
+		Dirty:       m[`dirty`],
+		Mapped:      m[`mapped`],
+		Writeback:   m[`writeback`],
+		CommittedAS: m[`committed_as`],
+		CommitLimit: m[`commitlimit`],
+	}, nil
+}
+
+// GetDiskRawStats returns the raw IO counters of all the disks.
+func GetDiskRawStats() ([]DiskRawStats, error) {
+	return v1.GetDiskRawStats()
+}
+
+// GetDiskStats returns the IO rates of all the disks between 2 samples.
+func GetDiskStats(first []DiskRawStats, second []DiskRawStats) ([]DiskStats, error) {
+	return v1.GetDiskAvgStats(first, second)
+}
+
+// GetDiskStatsOver returns the IO rates of all the disks between 2 samples
+// taken d apart.
+func GetDiskStatsOver(d time.Duration) ([]DiskStats, error) {
+	return v1.GetDiskStatsOver(d)
+}
+
+// GetDiskUsage returns the disk space usage of all the file systems.
+func GetDiskUsage() ([]DiskUsage, error) {
+	return v1.GetDiskUsage()
+}
+
+// GetFileStats returns the file descriptor stats of the system.
+func GetFileStats() (FileStats, error) {
+	return v1.GetFileStats()
+}
+
+// GetProcRawStats returns the raw processes stats of the system.
+func GetProcRawStats() (ProcRawStats, error) {
+	return v1.GetProcRawStats()
+}
+
+// GetProcStats returns the processes stats between 2 samples.
+func GetProcStats(first ProcRawStats, second ProcRawStats) (ProcStats, error) {
+	return v1.GetProcAvgStats(first, second)
+}
+
+// GetProcStatsOver returns the processes stats between 2 samples taken d
+// apart.
+func GetProcStatsOver(d time.Duration) (ProcStats, error) {
+	return v1.GetProcStatsOver(d)
+}
+
+// GetSockStats returns the socket stats of the system.
+func GetSockStats() (SockStats, error) {
+	s, err := v1.GetSockStats()
+	if err != nil {
+		return SockStats{}, err
+	}
+
+	return SockStats{
+		Used:        s.Used,
+		TCPInUse:    s.TcpInUse,
+		TCPOrphaned: s.TcpOrphaned,
+		TCPTimeWait: s.TcpTimeWait,
+		UDPInUse:    s.UdpInUse,
+		Raw:         s.Raw,
+		IPFrag:      s.IpFrag,
+	}, nil
+}
+
+// GetSysInfo returns the system info.
+func GetSysInfo() (SysInfo, error) {
+	s, err := v1.GetSysInfo()
+	if err != nil {
+		return SysInfo{}, err
+	}
+
+	return SysInfo{
+		Hostname:  s.Hostname,
+		FQDN:      s.FQDN,
+		Domain:    s.Domain,
+		OSType:    s.OsType,
+		OSRelease: s.OsRelease,
+		OSVersion: s.OsVersion,
+		OSArch:    s.OsArch,
+		Uptime:    time.Duration(s.Uptime * float64(time.Second)),
+	}, nil
+}
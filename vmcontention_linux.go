@@ -0,0 +1,96 @@
+// +build linux
+
+package sysstats
+
+import (
+	"strings"
+	"time"
+)
+
+// userHz is the unit of the CPU times of /proc/stat (USER_HZ), 100 on all the
+// architectures supported by Linux.
+const userHz = 100
+
+// DefaultStealThreshold is the steal % of all the CPUs above which a VM is
+// reported as contended.
+const DefaultStealThreshold = 10.0
+
+// VmContention represents the CPU time stolen from a VM by the hypervisor
+// over an interval.
+type VmContention struct {
+	Hypervisor   string             `json:"hypervisor"`            // Hypervisor (kvm, xen, vmware, hyperv,...), empty if none was detected
+	Steal        map[string]float64 `json:"steal" unit:"%"`        // % of stolen CPU time by CPU (cpu, cpu0,...)
+	StealSeconds float64            `json:"stealseconds" unit:"s"` // Seconds of CPU time stolen (all the CPUs)
+	Threshold    float64            `json:"threshold" unit:"%"`    // Steal % above which the VM is contended
+	Contended    bool               `json:"contended"`             // True if the steal % of all the CPUs is above the threshold
+}
+
+// hypervisorVendors maps the DMI system vendors and product names to the
+// hypervisors.
+var hypervisorVendors = []struct {
+	match      string
+	hypervisor string
+}{
+	{"KVM", "kvm"},
+	{"QEMU", "kvm"},
+	{"Amazon EC2", "kvm"},
+	{"Google", "kvm"},
+	{"VMware", "vmware"},
+	{"Microsoft Corporation", "hyperv"},
+	{"Xen", "xen"},
+	{"innotek GmbH", "virtualbox"},
+	{"VirtualBox", "virtualbox"},
+	{"Parallels", "parallels"},
+}
+
+// getHypervisor detects the hypervisor the system runs on from
+// /sys/hypervisor/type, the DMI info and the hypervisor flag of
+// /proc/cpuinfo. It returns an empty string on bare metal and "unknown" if
+// the system is virtualized but the hypervisor can't be identified.
+func getHypervisor() (hypervisor string) {
+	if hypervisorType := readSysfsString("/sys/hypervisor/type"); hypervisorType != "" {
+		return hypervisorType
+	}
+
+	for _, path := range []string{"/sys/class/dmi/id/sys_vendor", "/sys/class/dmi/id/product_name"} {
+		value := readSysfsString(path)
+		for _, vendor := range hypervisorVendors {
+			if value != "" && strings.Contains(value, vendor.match) {
+				return vendor.hypervisor
+			}
+		}
+	}
+
+	virtualized := false
+	scanLines("/proc/cpuinfo", func(line string) {
+		if strings.HasPrefix(line, "flags") && strings.Contains(line, " hypervisor") {
+			virtualized = true
+		}
+	})
+	if virtualized {
+		return "unknown"
+	}
+
+	return ""
+}
+
+// getVmContention returns the CPU time stolen by the hypervisor between 2
+// samples taken d apart.
+func getVmContention(d time.Duration) (vmContention VmContention, err error) {
+	deltas, err := sampleOver(d, getCpuRawStats, cpuTimesDelta)
+	if err != nil {
+		return VmContention{}, err
+	}
+
+	vmContention = VmContention{}
+	vmContention.Hypervisor = getHypervisor()
+	vmContention.Threshold = DefaultStealThreshold
+	vmContention.Steal = map[string]float64{}
+	for cpuName, delta := range deltas {
+		vmContention.Steal[cpuName] = cpuPercent(delta)[`steal`]
+	}
+	vmContention.StealSeconds = float64(deltas[`cpu`][`steal`]) / userHz
+	vmContention.Contended = vmContention.Steal[`cpu`] > vmContention.Threshold
+
+	return vmContention, nil
+}
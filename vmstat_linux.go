@@ -0,0 +1,33 @@
+// +build linux
+
+package sysstats
+
+import (
+	"strconv"
+	"strings"
+)
+
+// readVmstat reads the counters of the file /proc/vmstat, which has the
+// following format:
+//   nr_dirtied 1832513
+//   nr_written 1763210
+// The lines that can't be parsed are skipped.
+func readVmstat() (vmstat map[string]uint64, err error) {
+	vmstat = map[string]uint64{}
+	err = scanLines("/proc/vmstat", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return
+		}
+		vmstat[fields[0]] = value
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vmstat, nil
+}
@@ -0,0 +1,109 @@
+package sysstats
+
+import (
+	"context"
+	"time"
+)
+
+// WatchCpuStats emits the % CPU utilization of every interval on a channel,
+// e.g.:
+//   cpuStats, err := sysstats.WatchCpuStats(ctx, 10*time.Second)
+//   ...
+//   for cpusAvgStats := range cpuStats {
+//       ...
+//   }
+// The error is the one of the baseline sample, taken before returning, or an
+// error if the interval isn't greater than 0. The intervals whose samples
+// fail are skipped (and logged, see SetLogger). The channel is closed when
+// the context is cancelled.
+func WatchCpuStats(ctx context.Context, interval time.Duration) (<-chan CpusAvgStats, error) {
+	return watch(ctx, interval, getCpuRawStats, getCpuAvgStats)
+}
+
+// WatchMemStats emits the memory stats taken every interval on a channel
+// (see WatchCpuStats). The first stats are emitted immediately.
+func WatchMemStats(ctx context.Context, interval time.Duration) (<-chan MemStats, error) {
+	return watchGauge(ctx, interval, getMemStats)
+}
+
+// WatchNetStats emits the network traffic of every interval on a channel
+// (see WatchCpuStats).
+func WatchNetStats(ctx context.Context, interval time.Duration) (<-chan NetAvgStats, error) {
+	return watch(ctx, interval, getNetRawStats, getNetAvgStats)
+}
+
+// WatchDiskStats emits the IO averages of every interval on a channel (see
+// WatchCpuStats).
+func WatchDiskStats(ctx context.Context, interval time.Duration) (<-chan []DiskAvgStats, error) {
+	return watch(ctx, interval, getDiskRawStats, getDiskAvgStats)
+}
+
+// WatchProcStats emits the processes stats of every interval on a channel
+// (see WatchCpuStats).
+func WatchProcStats(ctx context.Context, interval time.Duration) (<-chan ProcAvgStats, error) {
+	return watch(ctx, interval, getProcRawStats, getProcAvgStats)
+}
+
+// watch takes a baseline raw sample and emits the averages between the raw
+// samples taken every interval until the context is cancelled.
+func watch[R any, A any](ctx context.Context, interval time.Duration, raw func() (R, error), avg func(R, R) (A, error)) (<-chan A, error) {
+	if interval <= 0 {
+		return nil, errInvalidInterval
+	}
+	previousSample, err := raw()
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan A)
+	go func() {
+		defer close(c)
+		sampleLoop(ctx, interval, previousSample, raw, avg, func(a A, err error) bool {
+			return send(ctx, c, a, err)
+		})
+	}()
+
+	return c, nil
+}
+
+// send sends the value on the channel unless the context is cancelled
+// first. The errors are logged and skipped. It returns false if the context
+// was cancelled.
+func send[T any](ctx context.Context, c chan<- T, t T, err error) bool {
+	if err != nil {
+		logDebug("skipped sample", "error", err)
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case c <- t:
+		return true
+	}
+}
+
+// watchGauge emits the samples taken every interval until the context is
+// cancelled. The first sample is taken before returning.
+func watchGauge[G any](ctx context.Context, interval time.Duration, get func() (G, error)) (<-chan G, error) {
+	if interval <= 0 {
+		return nil, errInvalidInterval
+	}
+	g, err := get()
+	if err != nil {
+		return nil, err
+	}
+
+	c := make(chan G)
+	go func() {
+		defer close(c)
+		if !send(ctx, c, g, nil) {
+			return
+		}
+		sampleLoop(ctx, interval, g, get, latest[G], func(g G, err error) bool {
+			return send(ctx, c, g, err)
+		})
+	}()
+
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package sysstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWatchInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, err := WatchCpuStats(context.Background(), interval); err != errInvalidInterval {
+			t.Errorf("WatchCpuStats(%v) error = %v, want %v", interval, err, errInvalidInterval)
+		}
+		if _, err := WatchMemStats(context.Background(), interval); err != errInvalidInterval {
+			t.Errorf("WatchMemStats(%v) error = %v, want %v", interval, err, errInvalidInterval)
+		}
+	}
+}
+
+func TestWatchSkipsErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := 0
+	raw := func() (int, error) {
+		n++
+		if n == 2 {
+			return 0, errors.New("failed sample")
+		}
+		return n, nil
+	}
+	avg := func(first int, second int) (int, error) { return second - first, nil }
+
+	c, err := watch(ctx, time.Millisecond, raw, avg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The baseline is 1, the 2nd sample fails and the next ones are 3 and 4
+	for _, want := range []int{2, 1} {
+		if got := <-c; got != want {
+			t.Errorf("average = %d, want %d", got, want)
+		}
+	}
+
+	cancel()
+	for range c {
+	}
+}
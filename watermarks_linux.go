@@ -0,0 +1,141 @@
+// +build linux
+
+package sysstats
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ZoneWatermarks represents the free memory of a memory zone compared to its
+// watermarks. kswapd starts reclaiming when the free memory falls below the
+// low watermark, and the allocations stall in direct reclaim below the min
+// one.
+type ZoneWatermarks struct {
+	Node     int     `json:"node"`              // NUMA node
+	Zone     string  `json:"zone"`              // Zone name (DMA, DMA32, Normal,...)
+	Free     uint64  `json:"free" unit:"kB"`    // Free memory in kilobytes
+	Min      uint64  `json:"min" unit:"kB"`     // Min watermark in kilobytes
+	Low      uint64  `json:"low" unit:"kB"`     // Low watermark in kilobytes
+	High     uint64  `json:"high" unit:"kB"`    // High watermark in kilobytes
+	Managed  uint64  `json:"managed" unit:"kB"` // Memory managed by the buddy allocator in kilobytes
+	LowRatio float64 `json:"lowratio"`          // Free / low (below 1 kswapd is reclaiming)
+	MinRatio float64 `json:"minratio"`          // Free / min (below 1 allocations go to direct reclaim)
+}
+
+// WatermarkStats represents how close the memory zones of a linux system are
+// to direct reclaim.
+type WatermarkStats struct {
+	MinFreeKbytes uint64           `json:"minfreekbytes" unit:"kB"` // vm.min_free_kbytes, which the min watermarks derive from
+	Zones         []ZoneWatermarks `json:"zones"`                   // Zones with managed memory
+	Closest       string           `json:"closest"`                 // Zone closest to direct reclaim (node/zone)
+	MinRatio      float64          `json:"minratio"`                // Free / min of the closest zone
+	BelowLow      bool             `json:"belowlow"`                // Some zone is below its low watermark
+	BelowMin      bool             `json:"belowmin"`                // Some zone is below its min watermark
+}
+
+// getWatermarkStats gets the free memory and watermarks of the zones from
+// the file /proc/zoneinfo, which has the following format (in pages):
+//   Node 0, zone   Normal
+//     per-node stats
+//         nr_inactive_anon 11
+//         ...
+//     pages free     75260
+//           boost    0
+//           min      7509
+//           low      9386
+//           high     11263
+//           spanned  786432
+//           present  786432
+//           managed  622592
+// The zones without managed memory (e.g. an empty Movable zone) are left
+// out.
+func getWatermarkStats() (watermarkStats WatermarkStats, err error) {
+	watermarkStats = WatermarkStats{Zones: []ZoneWatermarks{}}
+
+	content, err := readStatsFile("/proc/sys/vm/min_free_kbytes")
+	if err != nil {
+		return WatermarkStats{}, err
+	}
+	watermarkStats.MinFreeKbytes, err = strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return WatermarkStats{}, err
+	}
+
+	zones := []ZoneWatermarks{}
+	var zone *ZoneWatermarks
+	inPages := false
+	err = scanLines("/proc/zoneinfo", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return
+		}
+		if fields[0] == "Node" && len(fields) == 4 && fields[2] == "zone" {
+			node, err := strconv.Atoi(strings.TrimSuffix(fields[1], ","))
+			if err != nil {
+				zone = nil
+				return
+			}
+			zones = append(zones, ZoneWatermarks{Node: node, Zone: fields[3]})
+			zone = &zones[len(zones)-1]
+			inPages = false
+			return
+		}
+		if zone == nil {
+			return
+		}
+
+		// The watermarks follow the free pages, the per-node stats come
+		// before them
+		if fields[0] == "pages" && len(fields) == 3 && fields[1] == "free" {
+			inPages = true
+			zone.Free = parseZonePages(fields[2])
+			return
+		}
+		if !inPages || len(fields) != 2 {
+			return
+		}
+		switch fields[0] {
+		case "min":
+			zone.Min = parseZonePages(fields[1])
+		case "low":
+			zone.Low = parseZonePages(fields[1])
+		case "high":
+			zone.High = parseZonePages(fields[1])
+		case "managed":
+			zone.Managed = parseZonePages(fields[1])
+		}
+	})
+	if err != nil {
+		return WatermarkStats{}, err
+	}
+
+	for _, zone := range zones {
+		if zone.Managed == 0 {
+			continue
+		}
+		if zone.Low > 0 {
+			zone.LowRatio = float64(zone.Free) / float64(zone.Low)
+			watermarkStats.BelowLow = watermarkStats.BelowLow || zone.Free < zone.Low
+		}
+		if zone.Min > 0 {
+			zone.MinRatio = float64(zone.Free) / float64(zone.Min)
+			watermarkStats.BelowMin = watermarkStats.BelowMin || zone.Free < zone.Min
+			if watermarkStats.Closest == "" || zone.MinRatio < watermarkStats.MinRatio {
+				watermarkStats.Closest = strconv.Itoa(zone.Node) + "/" + zone.Zone
+				watermarkStats.MinRatio = zone.MinRatio
+			}
+		}
+		watermarkStats.Zones = append(watermarkStats.Zones, zone)
+	}
+
+	return watermarkStats, nil
+}
+
+// parseZonePages parses a # of pages of /proc/zoneinfo and returns it in
+// kilobytes.
+func parseZonePages(s string) uint64 {
+	pages, _ := strconv.ParseUint(s, 10, 64)
+
+	return pages * pageSizeKB
+}
@@ -0,0 +1,108 @@
+package sysstats
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// SchemaVersion is the version of the wire format of the serialized
+// snapshots. It's increased when a change can't be read by older versions.
+//
+// Versions:
+//   1 - The bare Snapshot (before the envelope was added). The sample times
+//       of the disks, network interfaces and processes are in Unix seconds
+//       in the oldest ones and in Unix nanoseconds in the newer ones.
+//   2 - The Envelope wrapping the Snapshot.
+const SchemaVersion = 2
+
+// Envelope wraps a serialized snapshot with the version of the wire format
+// and the identity of the host, so the snapshots of fleets running mixed
+// versions of the package can be aggregated centrally.
+type Envelope struct {
+	Version  int               `json:"version"`          // Version of the wire format (SchemaVersion)
+	Host     string            `json:"host"`             // Name of the host the snapshot was taken on
+	Labels   map[string]string `json:"labels,omitempty"` // Labels of the host (see Identity)
+	Time     time.Time         `json:"time"`             // Time when the snapshot was taken
+	Snapshot Snapshot          `json:"snapshot"`         // Snapshot
+}
+
+// NewEnvelope wraps the snapshot in an envelope of the current version with
+// the host name of its identity (the one of the system if it has none).
+func NewEnvelope(snapshot Snapshot) Envelope {
+	return Envelope{
+		Version:  SchemaVersion,
+		Host:     snapshot.Identity.Host(),
+		Labels:   snapshot.Identity.AllLabels(),
+		Time:     snapshot.Time,
+		Snapshot: snapshot,
+	}
+}
+
+// MarshalSnapshot encodes the snapshot as JSON wrapped in an envelope of the
+// current version.
+func MarshalSnapshot(snapshot Snapshot) ([]byte, error) {
+	return json.Marshal(NewEnvelope(snapshot))
+}
+
+// UnmarshalEnvelope decodes a snapshot serialized by any version of the
+// package. The snapshots of version 1 (no envelope) are returned in an
+// envelope without host, with their sample times in Unix nanoseconds. The
+// fields added by newer versions are ignored.
+func UnmarshalEnvelope(data []byte) (envelope Envelope, err error) {
+	fields := map[string]json.RawMessage{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	if _, ok := fields[`version`]; !ok {
+		snapshot := Snapshot{}
+		err = json.Unmarshal(data, &snapshot)
+		if err != nil {
+			return Envelope{}, err
+		}
+		normalizeSampleTimes(&snapshot)
+		return Envelope{Version: 1, Time: snapshot.Time, Snapshot: snapshot}, nil
+	}
+
+	envelope = Envelope{}
+	err = json.Unmarshal(data, &envelope)
+	if err != nil {
+		return Envelope{}, err
+	}
+	if envelope.Version < 2 {
+		return Envelope{}, errors.New("Unknown wire format version " + strconv.Itoa(envelope.Version))
+	}
+	if envelope.Version > SchemaVersion {
+		logDebug("decoding a snapshot of a newer wire format", "version", envelope.Version)
+	}
+
+	return envelope, nil
+}
+
+// UnmarshalSnapshot decodes a snapshot serialized by any version of the
+// package, discarding the envelope.
+func UnmarshalSnapshot(data []byte) (snapshot Snapshot, err error) {
+	envelope, err := UnmarshalEnvelope(data)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return envelope.Snapshot, nil
+}
+
+// normalizeSampleTimes converts the sample times of a snapshot of version 1
+// in Unix seconds to Unix nanoseconds (see sampleTimeNano).
+func normalizeSampleTimes(snapshot *Snapshot) {
+	for _, rawStats := range snapshot.Net {
+		if t, ok := rawStats[StatTime]; ok {
+			rawStats[StatTime] = uint64(sampleTimeNano(int64(t)))
+		}
+	}
+	for i := range snapshot.Disk {
+		snapshot.Disk[i].SampleTime = sampleTimeNano(snapshot.Disk[i].SampleTime)
+	}
+	snapshot.Proc.Time = sampleTimeNano(snapshot.Proc.Time)
+}
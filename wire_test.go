@@ -0,0 +1,57 @@
+package sysstats
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalEnvelopeV1SampleTimes(t *testing.T) {
+	sampled := time.Unix(1500000000, 0)
+	for name, sampleTime := range map[string]int64{
+		"seconds":     sampled.Unix(),
+		"nanoseconds": sampled.UnixNano(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			v1 := []byte(`{"time":"2017-07-14T02:40:00Z",` +
+				`"net":{"eth0":{"rxbytes":1,"time":` + strconv.FormatInt(sampleTime, 10) + `}},` +
+				`"disk":[{"name":"sda","sampletime":` + strconv.FormatInt(sampleTime, 10) + `}],` +
+				`"proc":{"processes":1,"time":` + strconv.FormatInt(sampleTime, 10) + `}}`)
+
+			envelope, err := UnmarshalEnvelope(v1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if envelope.Version != 1 {
+				t.Errorf("Version = %d, want 1", envelope.Version)
+			}
+			snapshot := envelope.Snapshot
+			want := sampled.UnixNano()
+			if got := int64(snapshot.Net["eth0"][StatTime]); got != want {
+				t.Errorf("net time = %d, want %d", got, want)
+			}
+			if got := snapshot.Disk[0].SampleTime; got != want {
+				t.Errorf("disk sampletime = %d, want %d", got, want)
+			}
+			if got := snapshot.Proc.Time; got != want {
+				t.Errorf("proc time = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestMarshalSnapshotRoundTrip(t *testing.T) {
+	snapshot := Snapshot{Time: time.Unix(1700000000, 0).UTC(), Identity: &Identity{Hostname: "db1"}}
+
+	content, err := MarshalSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	envelope, err := UnmarshalEnvelope(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Version != SchemaVersion || envelope.Host != "db1" || !envelope.Snapshot.Time.Equal(snapshot.Time) {
+		t.Errorf("UnmarshalEnvelope() = %+v", envelope)
+	}
+}
@@ -0,0 +1,200 @@
+// +build linux
+
+package sysstats
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SsdWriteRawStats represents the bytes written to an SSD since boot as
+// counted by the host and the bytes written since it was manufactured as
+// counted by the device.
+type SsdWriteRawStats struct {
+	Name        string `json:"name"`                       // Disk name
+	HostBytes   uint64 `json:"hostbytes" unit:"bytes"`     // # of bytes written by the host since boot (diskstats)
+	DeviceBytes uint64 `json:"devicebytes" unit:"bytes"`   // # of bytes written counted by the device (SMART)
+	Nand        bool   `json:"nand"`                       // DeviceBytes are the writes to the NAND (not the host writes it received)
+	SampleTime  int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// WriteAmplification represents the estimated write amplification of an SSD
+// between 2 samples: the bytes the device wrote for every byte written by
+// the host. It's only the real write amplification of the flash (garbage
+// collection, wear leveling,...) when the device reports its NAND writes;
+// otherwise it compares the host writes seen by the kernel and by the
+// device, which tells the writes of the firmware and other hosts apart.
+type WriteAmplification struct {
+	Name          string  `json:"name"`                       // Disk name
+	HostWritten   uint64  `json:"hostwritten" unit:"bytes"`   // # of bytes written by the host
+	DeviceWritten uint64  `json:"devicewritten" unit:"bytes"` // # of bytes written counted by the device
+	Factor        float64 `json:"factor"`                     // DeviceWritten / HostWritten (0 if the host didn't write)
+	Nand          bool    `json:"nand"`                       // DeviceWritten are the writes to the NAND
+}
+
+// smartctlOutput is the part of the output of smartctl -j -A with the bytes
+// written:
+//   {"nvme_smart_health_information_log": {"data_units_written": 1234},
+//    "ata_smart_attributes": {"table": [{"id": 241,
+//      "name": "Total_LBAs_Written", "raw": {"value": 5678}}]}}
+type smartctlOutput struct {
+	Nvme *struct {
+		DataUnitsWritten uint64 `json:"data_units_written"`
+	} `json:"nvme_smart_health_information_log"`
+	Ata *struct {
+		Table []struct {
+			Id   int    `json:"id"`
+			Name string `json:"name"`
+			Raw  struct {
+				Value uint64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+}
+
+// ataWriteAttributes are the units in bytes of the SMART attributes with the
+// bytes written by the SATA SSDs, by name. The ones of the NAND writes are
+// preferred when the device has both.
+var ataWriteAttributes = map[string]struct {
+	unit uint64
+	nand bool
+}{
+	`NAND_Writes_1GiB`:   {1 << 30, true},
+	`NAND_Writes_GiB`:    {1 << 30, true},
+	`NAND_Writes_32MiB`:  {32 << 20, true},
+	`Host_Writes_32MiB`:  {32 << 20, false},
+	`Host_Writes_GiB`:    {1 << 30, false},
+	`Total_LBAs_Written`: {0, false}, // Logical blocks
+}
+
+// getSsdWriteRawStats gets the bytes written to the SSDs (the non rotational
+// disks with a device, /sys/block/[name]/queue/rotational) from the file
+// /proc/diskstats and running smartctl -j -A /dev/[name] (it needs root). The
+// SSDs whose SMART data can't be read or has no bytes written are skipped.
+func getSsdWriteRawStats() (ssdWriteRawStatsArr []SsdWriteRawStats, err error) {
+	diskRawStatsArr, err := getDiskRawStats()
+	if err != nil {
+		return nil, err
+	}
+
+	ssdWriteRawStatsArr = []SsdWriteRawStats{}
+	for _, diskRawStats := range diskRawStatsArr {
+		path := filepath.Join("/sys/block", diskRawStats.Name)
+		if readSysfsString(filepath.Join(path, "queue", "rotational")) != "0" {
+			continue
+		}
+		if _, err := ioutil.ReadDir(filepath.Join(path, "device")); err != nil {
+			continue
+		}
+
+		out, err := runCommand("smartctl", "-j", "-A", "/dev/"+diskRawStats.Name)
+		if err == ErrExecDisabled {
+			return nil, err
+		}
+		// smartctl exits with a bitmask of the issues found, and still
+		// prints the attributes
+		if len(out) == 0 {
+			logDebug("skipped SSD without SMART data", "disk", diskRawStats.Name, "error", err)
+			continue
+		}
+		deviceBytes, nand, err := parseSmartctlWrites(out, diskRawStats.LogicalBlock)
+		if err != nil {
+			logDebug("skipped SSD without SMART data", "disk", diskRawStats.Name, "error", err)
+			continue
+		}
+
+		ssdWriteRawStatsArr = append(ssdWriteRawStatsArr, SsdWriteRawStats{
+			Name:        diskRawStats.Name,
+			HostBytes:   diskRawStats.sectorBytes(diskRawStats.WriteSectors),
+			DeviceBytes: deviceBytes,
+			Nand:        nand,
+			SampleTime:  time.Now().UnixNano(),
+		})
+	}
+
+	return ssdWriteRawStatsArr, nil
+}
+
+// parseSmartctlWrites parses the bytes written of the output of smartctl -j
+// -A. The NVMe data units are thousands of 512 bytes; the logical blocks of
+// the SATA SSDs are logicalBlock bytes (512 if it's 0).
+func parseSmartctlWrites(out []byte, logicalBlock uint64) (bytes uint64, nand bool, err error) {
+	output := smartctlOutput{}
+	err = json.Unmarshal(out, &output)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if output.Nvme != nil {
+		return output.Nvme.DataUnitsWritten * 512000, false, nil
+	}
+
+	if output.Ata != nil {
+		if logicalBlock == 0 {
+			logicalBlock = 512
+		}
+		found := false
+		for _, attribute := range output.Ata.Table {
+			name := strings.TrimSpace(attribute.Name)
+			writes, ok := ataWriteAttributes[name]
+			if !ok || (found && !writes.nand) || nand {
+				continue
+			}
+			unit := writes.unit
+			if unit == 0 {
+				unit = logicalBlock
+			}
+			bytes, nand, found = attribute.Raw.Value*unit, writes.nand, true
+		}
+		if found {
+			return bytes, nand, nil
+		}
+	}
+
+	return 0, false, errors.New("No bytes written in the SMART data")
+}
+
+// getWriteAmplification calculates the write amplification of the SSDs
+// between 2 samples. The SSDs that aren't in both samples, or whose counters
+// went backwards (reboot, device replaced), are skipped.
+func getWriteAmplification(firstSampleArr []SsdWriteRawStats, secondSampleArr []SsdWriteRawStats) (writeAmplificationArr []WriteAmplification, err error) {
+	first := map[string]SsdWriteRawStats{}
+	for _, firstSample := range firstSampleArr {
+		first[firstSample.Name] = firstSample
+	}
+
+	writeAmplificationArr = make([]WriteAmplification, 0, len(secondSampleArr))
+	for _, secondSample := range secondSampleArr {
+		firstSample, ok := first[secondSample.Name]
+		if !ok || firstSample.Nand != secondSample.Nand ||
+			secondSample.HostBytes < firstSample.HostBytes || secondSample.DeviceBytes < firstSample.DeviceBytes {
+			continue
+		}
+		if secondSample.SampleTime <= firstSample.SampleTime {
+			return nil, errors.New("The second sample must be taken after the first one")
+		}
+
+		writeAmplification := WriteAmplification{
+			Name:          secondSample.Name,
+			HostWritten:   secondSample.HostBytes - firstSample.HostBytes,
+			DeviceWritten: secondSample.DeviceBytes - firstSample.DeviceBytes,
+			Nand:          secondSample.Nand,
+		}
+		if writeAmplification.HostWritten > 0 {
+			writeAmplification.Factor = float64(writeAmplification.DeviceWritten) / float64(writeAmplification.HostWritten)
+		}
+		writeAmplificationArr = append(writeAmplificationArr, writeAmplification)
+	}
+
+	return writeAmplificationArr, nil
+}
+
+// getWriteAmplificationOver returns the write amplification of the SSDs
+// between 2 samples taken d apart.
+func getWriteAmplificationOver(d time.Duration) (writeAmplificationArr []WriteAmplification, err error) {
+	return sampleOver(d, getSsdWriteRawStats, getWriteAmplification)
+}
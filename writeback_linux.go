@@ -0,0 +1,232 @@
+// +build linux
+
+package sysstats
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BdiStats represents the writeback settings and stats of a backing device
+// (bdi).
+type BdiStats struct {
+	Name        string `json:"name"`                  // Block device name (major:minor if unknown)
+	ReadAheadKB uint64 `json:"readaheadkb" unit:"kB"` // Readahead window in kilobytes
+	MinRatio    uint64 `json:"minratio" unit:"%"`     // Min % of the dirty threshold reserved for the device
+	MaxRatio    uint64 `json:"maxratio" unit:"%"`     // Max % of the dirty threshold the device can use
+	StrictLimit bool   `json:"strictlimit"`           // Device is throttled at its own threshold
+	// The following stats are only available when debugfs is mounted
+	Writeback   uint64 `json:"writeback" unit:"kB"`   // Size of the pages under writeback in kilobytes
+	Reclaimable uint64 `json:"reclaimable" unit:"kB"` // Size of the dirty pages in kilobytes
+	DirtyThresh uint64 `json:"dirtythresh" unit:"kB"` // Dirty threshold of the device in kilobytes
+}
+
+// WritebackStats represents the dirty page and writeback pressure of a linux
+// system.
+type WritebackStats struct {
+	Dirty        uint64 `json:"dirty" unit:"kB"`        // Size of the dirty pages in kilobytes
+	Writeback    uint64 `json:"writeback" unit:"kB"`    // Size of the pages under writeback in kilobytes
+	WritebackTmp uint64 `json:"writebacktmp" unit:"kB"` // Size of the FUSE writeback buffers in kilobytes
+	Dirtyable    uint64 `json:"dirtyable" unit:"kB"`    // Approximate size of the memory that can be dirty in kilobytes
+	// Sysctls vm.dirty_* (the bytes settings override the ratios when set)
+	DirtyRatio              uint64 `json:"dirtyratio" unit:"%"`               // % of dirtyable memory at which writers are throttled
+	DirtyBackgroundRatio    uint64 `json:"dirtybackgroundratio" unit:"%"`     // % of dirtyable memory at which background writeback starts
+	DirtyBytes              uint64 `json:"dirtybytes" unit:"bytes"`           // Bytes at which writers are throttled
+	DirtyBackgroundBytes    uint64 `json:"dirtybackgroundbytes" unit:"bytes"` // Bytes at which background writeback starts
+	DirtyExpireCentisecs    uint64 `json:"dirtyexpirecentisecs" unit:"cs"`    // Age at which dirty pages are written back
+	DirtyWritebackCentisecs uint64 `json:"dirtywritebackcentisecs" unit:"cs"` // Interval of the writeback threads
+	// Thresholds computed from the sysctls
+	Threshold           uint64     `json:"threshold" unit:"kB"`           // Dirty threshold in kilobytes
+	BackgroundThreshold uint64     `json:"backgroundthreshold" unit:"kB"` // Background writeback threshold in kilobytes
+	ThresholdUsedPer    float64    `json:"thresholdusedper" unit:"%"`     // % of the dirty threshold used (dirty + writeback)
+	Bdis                []BdiStats `json:"bdis"`                          // Backing devices
+}
+
+// WritebackRawStats represents the raw dirty page and writeback counters of
+// a linux system.
+type WritebackRawStats struct {
+	Dirty      uint64 `json:"dirty" unit:"kB"`            // Size of the dirty pages in kilobytes
+	Writeback  uint64 `json:"writeback" unit:"kB"`        // Size of the pages under writeback in kilobytes
+	Dirtied    uint64 `json:"dirtied" unit:"pages"`       // # of pages dirtied since boot
+	Written    uint64 `json:"written" unit:"pages"`       // # of pages written back since boot
+	SampleTime int64  `json:"sampletime" unit:"unixnano"` // Time when the sample was taken (Unix time in nanoseconds)
+}
+
+// WritebackRateStats represents the rates at which pages are dirtied and
+// written back between 2 samples. A sustained positive Backlog means the
+// disks can't keep up with the writers.
+type WritebackRateStats struct {
+	Dirty     uint64  `json:"dirty" unit:"kB"`     // Size of the dirty pages in kilobytes (second sample)
+	Writeback uint64  `json:"writeback" unit:"kB"` // Size of the pages under writeback in kilobytes (second sample)
+	Dirtied   float64 `json:"dirtied" unit:"kB/s"` // Kilobytes dirtied per second
+	Written   float64 `json:"written" unit:"kB/s"` // Kilobytes written back per second
+	Backlog   float64 `json:"backlog" unit:"kB/s"` // Growth of the dirty pages in kilobytes per second (dirtied - written)
+}
+
+// getWritebackRawStats gets the dirty and writeback sizes from the file
+// /proc/meminfo and the counters nr_dirtied and nr_written from the file
+// /proc/vmstat.
+func getWritebackRawStats() (writebackRawStats WritebackRawStats, err error) {
+	memStats, err := getMemStats()
+	if err != nil {
+		return WritebackRawStats{}, err
+	}
+	vmstat, err := readVmstat()
+	if err != nil {
+		return WritebackRawStats{}, err
+	}
+
+	writebackRawStats = WritebackRawStats{
+		Dirty:      memStats[MemDirty],
+		Writeback:  memStats[MemWriteback],
+		Dirtied:    vmstat[`nr_dirtied`],
+		Written:    vmstat[`nr_written`],
+		SampleTime: time.Now().UnixNano(),
+	}
+
+	return writebackRawStats, nil
+}
+
+// getWritebackRateStats calculates the dirty and writeback rates between 2
+// samples.
+func getWritebackRateStats(firstSample WritebackRawStats, secondSample WritebackRawStats) (writebackRateStats WritebackRateStats, err error) {
+	timeDelta := time.Duration(secondSample.SampleTime - firstSample.SampleTime).Seconds()
+	if timeDelta <= 0 {
+		return WritebackRateStats{}, errors.New("The second sample must be taken after the first one")
+	}
+
+	writebackRateStats = WritebackRateStats{}
+	writebackRateStats.Dirty = secondSample.Dirty
+	writebackRateStats.Writeback = secondSample.Writeback
+	writebackRateStats.Dirtied = float64((secondSample.Dirtied-firstSample.Dirtied)*pageSizeKB) / timeDelta
+	writebackRateStats.Written = float64((secondSample.Written-firstSample.Written)*pageSizeKB) / timeDelta
+	writebackRateStats.Backlog = writebackRateStats.Dirtied - writebackRateStats.Written
+
+	return writebackRateStats, nil
+}
+
+// getWritebackRatesOver returns the dirty and writeback rates between 2
+// samples taken d apart.
+func getWritebackRatesOver(d time.Duration) (writebackRateStats WritebackRateStats, err error) {
+	return sampleOver(d, getWritebackRawStats, getWritebackRateStats)
+}
+
+// getWritebackStats gets the dirty page and writeback pressure from the file
+// /proc/meminfo, the sysctls /proc/sys/vm/dirty_* and the backing devices in
+// /sys/class/bdi (and /sys/kernel/debug/bdi when debugfs is mounted). Writers
+// stall in balance_dirty_pages when Dirty + Writeback reaches the threshold.
+func getWritebackStats() (writebackStats WritebackStats, err error) {
+	writebackStats = WritebackStats{}
+
+	meminfo := map[string]uint64{}
+	err = scanLines("/proc/meminfo", func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return
+		}
+		meminfo[strings.TrimSuffix(fields[0], ":")] = value
+	})
+	if err != nil {
+		return WritebackStats{}, err
+	}
+	writebackStats.Dirty = meminfo[`Dirty`]
+	writebackStats.Writeback = meminfo[`Writeback`]
+	writebackStats.WritebackTmp = meminfo[`WritebackTmp`]
+	// The kernel also subtracts the reserved pages, which aren't exposed
+	writebackStats.Dirtyable = meminfo[`MemFree`] + meminfo[`Active(file)`] + meminfo[`Inactive(file)`]
+
+	sysctls := map[string]*uint64{
+		`dirty_ratio`:               &writebackStats.DirtyRatio,
+		`dirty_background_ratio`:    &writebackStats.DirtyBackgroundRatio,
+		`dirty_bytes`:               &writebackStats.DirtyBytes,
+		`dirty_background_bytes`:    &writebackStats.DirtyBackgroundBytes,
+		`dirty_expire_centisecs`:    &writebackStats.DirtyExpireCentisecs,
+		`dirty_writeback_centisecs`: &writebackStats.DirtyWritebackCentisecs,
+	}
+	for name, value := range sysctls {
+		content, err := readStatsFile("/proc/sys/vm/" + name)
+		if err != nil {
+			return WritebackStats{}, err
+		}
+		*value, err = strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+		if err != nil {
+			return WritebackStats{}, err
+		}
+	}
+
+	writebackStats.Threshold = writebackStats.Dirtyable * writebackStats.DirtyRatio / 100
+	if writebackStats.DirtyBytes > 0 {
+		writebackStats.Threshold = writebackStats.DirtyBytes / 1024
+	}
+	writebackStats.BackgroundThreshold = writebackStats.Dirtyable * writebackStats.DirtyBackgroundRatio / 100
+	if writebackStats.DirtyBackgroundBytes > 0 {
+		writebackStats.BackgroundThreshold = writebackStats.DirtyBackgroundBytes / 1024
+	}
+	if writebackStats.Threshold > 0 {
+		writebackStats.ThresholdUsedPer = float64(writebackStats.Dirty+writebackStats.Writeback) * 100.00 /
+			float64(writebackStats.Threshold)
+	}
+
+	writebackStats.Bdis, err = getBdisStats()
+	if err != nil {
+		return WritebackStats{}, err
+	}
+
+	return writebackStats, nil
+}
+
+// getBdisStats gets the settings of the backing devices from the directories
+// /sys/class/bdi/[major:minor], and their writeback stats from the files
+// /sys/kernel/debug/bdi/[major:minor]/stats when they are readable.
+func getBdisStats() (bdisStats []BdiStats, err error) {
+	dirs, err := ioutil.ReadDir("/sys/class/bdi")
+	if err != nil {
+		return nil, err
+	}
+
+	bdisStats = []BdiStats{}
+	for _, dir := range dirs {
+		path := filepath.Join("/sys/class/bdi", dir.Name())
+		bdiStats := BdiStats{Name: dir.Name()}
+		if device, err := filepath.EvalSymlinks(filepath.Join("/sys/dev/block", dir.Name())); err == nil {
+			bdiStats.Name = filepath.Base(device)
+		}
+		bdiStats.ReadAheadKB, _ = strconv.ParseUint(readSysfsString(filepath.Join(path, "read_ahead_kb")), 10, 64)
+		bdiStats.MinRatio, _ = strconv.ParseUint(readSysfsString(filepath.Join(path, "min_ratio")), 10, 64)
+		bdiStats.MaxRatio, _ = strconv.ParseUint(readSysfsString(filepath.Join(path, "max_ratio")), 10, 64)
+		bdiStats.StrictLimit = readSysfsString(filepath.Join(path, "strict_limit")) == "1"
+
+		values := map[string]*uint64{
+			`BdiWriteback`:   &bdiStats.Writeback,
+			`BdiReclaimable`: &bdiStats.Reclaimable,
+			`BdiDirtyThresh`: &bdiStats.DirtyThresh,
+		}
+		// The file has the following format:
+		//   BdiWriteback:            0 kB
+		//   BdiReclaimable:       1224 kB
+		//   BdiDirtyThresh:     184832 kB
+		scanLines(filepath.Join("/sys/kernel/debug/bdi", dir.Name(), "stats"), func(line string) {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return
+			}
+			value, ok := values[strings.TrimSuffix(fields[0], ":")]
+			if !ok {
+				return
+			}
+			*value, _ = strconv.ParseUint(fields[1], 10, 64)
+		})
+
+		bdisStats = append(bdisStats, bdiStats)
+	}
+
+	return bdisStats, nil
+}